@@ -1,5 +1,5 @@
--- Clear all survey responses but keep user accounts
-DELETE FROM responses;
+-- Clear all survey responses (including revision history) but keep user accounts
+DELETE FROM response_revisions;
 
 -- Reset sequence if needed
--- ALTER SEQUENCE responses_id_seq RESTART WITH 1;
+-- ALTER SEQUENCE response_revisions_id_seq RESTART WITH 1;
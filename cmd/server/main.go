@@ -1,11 +1,31 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+
+	"opslab-survey/internal/backup"
 	"opslab-survey/internal/server"
 )
 
 func main() {
+	// `server backup …`, `server restore …` and the key commands run once
+	// and exit.
+	if len(os.Args) > 1 {
+		ctx := context.Background()
+		var err error
+		switch os.Args[1] {
+		case "generate-key", "rotate-keys":
+			err = keysCommand(ctx, os.Args[1], os.Stdout)
+		default:
+			err = backup.Command(ctx, os.Args[1], os.Args[2:], os.Stdout)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	if err := server.Start(); err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,235 @@
+package analytics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/seed"
+)
+
+// ReliabilityItems are the peer scales combined into one "working
+// relationship" score for internal consistency and inter-rater reliability.
+var ReliabilityItems = []string{"peer:collaboration-quality", "peer:reliability", "peer:trust-level"}
+
+// QuestionStats are the descriptives for one scale question.
+type QuestionStats struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Scope    string `json:"scope"`
+	ScaleMax int    `json:"scaleMax"`
+	Descriptives
+	// PerRatee breaks peer questions down by the colleague being rated.
+	PerRatee []RateeStats `json:"perRatee,omitempty"`
+}
+
+// RateeStats are descriptives for one colleague on one peer question.
+type RateeStats struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+	Descriptives
+}
+
+// RateeICC is inter-rater agreement about one colleague: how consistently
+// their raters scored them across the reliability items.
+type RateeICC struct {
+	Code    string   `json:"code"`
+	Name    string   `json:"name"`
+	Raters  int      `json:"raters"`
+	ICC     *float64 `json:"icc"`
+	ICCMean *float64 `json:"iccMean"`
+}
+
+// Reliability reports internal consistency and inter-rater reliability of
+// the peer scale set.
+type Reliability struct {
+	Items []string `json:"items"`
+	// Alpha is Cronbach's alpha over rater×ratee pairs that answered every item.
+	Alpha      *float64 `json:"alpha"`
+	AlphaCases int      `json:"alphaCases"`
+	// ICC treats each ratee as a target and the composite (mean of items)
+	// from each rater as one rating: do raters agree on who scores high?
+	ICC       *float64 `json:"icc"`
+	ICCMean   *float64 `json:"iccMean"`
+	ICCRaters *float64 `json:"iccRaters"`
+	// PerRatee treats the items as targets and the raters of one colleague
+	// as judges.
+	PerRatee []RateeICC `json:"perRatee"`
+}
+
+// Report is everything the analytics dashboard charts.
+type Report struct {
+	Raters      int             `json:"raters"`
+	Common      []QuestionStats `json:"common"`
+	Peer        []QuestionStats `json:"peer"`
+	Reliability Reliability     `json:"reliability"`
+}
+
+// Compute builds the report from submitted responses and the counts of their
+// numeric answers (see store.Responses.CountValues), which the descriptives
+// are taken from; reliability needs the answers of each rater and uses the
+// responses. Admin participants are neither raters' targets nor counted as
+// expected raters.
+func Compute(participants []models.Participant, responses []models.ResponseRecord, counts []models.ValueCount) Report {
+	var ratees []models.Participant
+	member := map[string]bool{}
+	for _, p := range participants {
+		if !p.IsAdmin {
+			ratees = append(ratees, p)
+			member[p.Code] = true
+		}
+	}
+
+	// scores[key][rater][ratee] holds numeric answers for reliability;
+	// common questions use an empty ratee.
+	scores := map[string]map[string]map[string]float64{}
+	set := func(key, rater, ratee string, v float64) {
+		if scores[key] == nil {
+			scores[key] = map[string]map[string]float64{}
+		}
+		if scores[key][rater] == nil {
+			scores[key][rater] = map[string]float64{}
+		}
+		scores[key][rater][ratee] = v
+	}
+	raters := 0
+	for _, r := range responses {
+		if !member[r.ParticipantCode] {
+			continue
+		}
+		raters++
+		for _, a := range r.Answers {
+			v, ok := number(a.Value)
+			if !ok {
+				continue
+			}
+			key, ratee := seed.SplitQuestionID(a.QuestionID)
+			set(key, r.ParticipantCode, ratee, v)
+		}
+	}
+	report := Report{Raters: raters}
+
+	// values[key][ratee] lists the counted answers; common questions use an
+	// empty ratee.
+	values := map[string]map[string][]float64{}
+	for _, c := range counts {
+		if values[c.QuestionID] == nil {
+			values[c.QuestionID] = map[string][]float64{}
+		}
+		for range c.Count {
+			values[c.QuestionID][c.PeerCode] = append(values[c.QuestionID][c.PeerCode], c.Value)
+		}
+	}
+
+	for _, q := range seed.CommonQuestions() {
+		if q.Type != "scale" {
+			continue
+		}
+		report.Common = append(report.Common, QuestionStats{
+			ID: q.ID, Title: q.Title, Scope: "common", ScaleMax: q.ScaleMax,
+			Descriptives: Describe(values[q.ID][""], q.ScaleMax, raters),
+		})
+	}
+
+	// expectedFor counts raters who should have rated a colleague: every
+	// member who responded except the colleague themselves.
+	expectedFor := func(code string) int {
+		n := 0
+		for _, r := range responses {
+			if r.ParticipantCode != code && member[r.ParticipantCode] {
+				n++
+			}
+		}
+		return n
+	}
+	for _, t := range seed.PeerTemplates() {
+		if t.Type != "scale" {
+			continue
+		}
+		qs := QuestionStats{ID: t.ID, Title: fmt.Sprintf(t.TitleFmt, "…"), Scope: "peer", ScaleMax: t.ScaleMax}
+		var all []float64
+		expected := 0
+		for _, ratee := range ratees {
+			rated := values[t.ID][ratee.Code]
+			all = append(all, rated...)
+			expected += expectedFor(ratee.Code)
+			qs.PerRatee = append(qs.PerRatee, RateeStats{
+				Code: ratee.Code, Name: ratee.Name,
+				Descriptives: Describe(rated, t.ScaleMax, expectedFor(ratee.Code)),
+			})
+		}
+		qs.Descriptives = Describe(all, t.ScaleMax, expected)
+		report.Peer = append(report.Peer, qs)
+	}
+
+	report.Reliability = reliability(ratees, member, responses, scores)
+	return report
+}
+
+func reliability(ratees []models.Participant, member map[string]bool, responses []models.ResponseRecord, scores map[string]map[string]map[string]float64) Reliability {
+	rel := Reliability{Items: ReliabilityItems, PerRatee: []RateeICC{}}
+
+	// complete returns the rater's item scores for a ratee if all are present.
+	complete := func(rater, ratee string) ([]float64, bool) {
+		items := make([]float64, len(ReliabilityItems))
+		for i, key := range ReliabilityItems {
+			v, ok := scores[key][rater][ratee]
+			if !ok {
+				return nil, false
+			}
+			items[i] = v
+		}
+		return items, true
+	}
+
+	var cases [][]float64
+	var composites [][]float64
+	for _, ratee := range ratees {
+		var group []float64
+		byItem := make([][]float64, len(ReliabilityItems))
+		for _, r := range responses {
+			if r.ParticipantCode == ratee.Code || !member[r.ParticipantCode] {
+				continue
+			}
+			items, ok := complete(r.ParticipantCode, ratee.Code)
+			if !ok {
+				continue
+			}
+			cases = append(cases, items)
+			group = append(group, mean(items))
+			for i, v := range items {
+				byItem[i] = append(byItem[i], v)
+			}
+		}
+		composites = append(composites, group)
+
+		icc := OneWayICC(byItem)
+		rel.PerRatee = append(rel.PerRatee, RateeICC{
+			Code: ratee.Code, Name: ratee.Name, Raters: len(group),
+			ICC: ptr(icc.Single), ICCMean: ptr(icc.Average),
+		})
+	}
+
+	rel.AlphaCases = len(cases)
+	rel.Alpha = ptr(CronbachAlpha(cases))
+	icc := OneWayICC(composites)
+	rel.ICC = ptr(icc.Single)
+	rel.ICCMean = ptr(icc.Average)
+	if icc.Raters > 0 {
+		rel.ICCRaters = ptr(icc.Raters)
+	}
+	return rel
+}
+
+func number(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
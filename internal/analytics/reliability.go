@@ -0,0 +1,92 @@
+package analytics
+
+import "math"
+
+// CronbachAlpha computes internal consistency for cases × items. Every case
+// must have a value for every item; callers drop incomplete cases first.
+// It returns NaN when there are fewer than two items or cases, or when the
+// total score does not vary.
+func CronbachAlpha(cases [][]float64) float64 {
+	if len(cases) < 2 {
+		return math.NaN()
+	}
+	k := len(cases[0])
+	if k < 2 {
+		return math.NaN()
+	}
+	itemVar := 0.0
+	for j := 0; j < k; j++ {
+		col := make([]float64, len(cases))
+		for i, c := range cases {
+			col[i] = c[j]
+		}
+		itemVar += variance(col)
+	}
+	totals := make([]float64, len(cases))
+	for i, c := range cases {
+		for _, v := range c {
+			totals[i] += v
+		}
+	}
+	totalVar := variance(totals)
+	if totalVar == 0 {
+		return math.NaN()
+	}
+	return float64(k) / float64(k-1) * (1 - itemVar/totalVar)
+}
+
+// ICC holds one-way random-effects intraclass correlations (Shrout & Fleiss
+// ICC(1) for a single rater and ICC(1,k) for the mean of k raters).
+type ICC struct {
+	Single  float64
+	Average float64
+	Targets int
+	Raters  float64 // average group size; k0 when groups are unbalanced
+}
+
+// OneWayICC computes ICC(1) and ICC(1,k) for groups of ratings, one group per
+// target. Groups may differ in size (the usual k0 correction is applied);
+// groups with fewer than one rating are ignored.
+func OneWayICC(groups [][]float64) ICC {
+	var kept [][]float64
+	total, sumSq := 0, 0
+	grand := 0.0
+	for _, g := range groups {
+		if len(g) == 0 {
+			continue
+		}
+		kept = append(kept, g)
+		total += len(g)
+		sumSq += len(g) * len(g)
+		for _, v := range g {
+			grand += v
+		}
+	}
+	n := len(kept)
+	out := ICC{Single: math.NaN(), Average: math.NaN(), Targets: n}
+	if n < 2 || total <= n {
+		return out
+	}
+	grand /= float64(total)
+
+	ssb, ssw := 0.0, 0.0
+	for _, g := range kept {
+		m := mean(g)
+		ssb += float64(len(g)) * (m - grand) * (m - grand)
+		for _, v := range g {
+			ssw += (v - m) * (v - m)
+		}
+	}
+	msb := ssb / float64(n-1)
+	msw := ssw / float64(total-n)
+	k0 := (float64(total) - float64(sumSq)/float64(total)) / float64(n-1)
+	out.Raters = k0
+
+	if denom := msb + (k0-1)*msw; denom != 0 {
+		out.Single = (msb - msw) / denom
+	}
+	if msb != 0 {
+		out.Average = (msb - msw) / msb
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+)
+
+// Descriptives summarises the answers to one scale question.
+type Descriptives struct {
+	N       int      `json:"n"`
+	Missing int      `json:"missing"`
+	Mean    *float64 `json:"mean"`
+	Median  *float64 `json:"median"`
+	SD      *float64 `json:"sd"`
+	Min     *float64 `json:"min"`
+	Max     *float64 `json:"max"`
+	// Distribution counts answers per scale point: Distribution[0] is the
+	// number of 1s, Distribution[ScaleMax-1] the number of ScaleMax answers.
+	Distribution []int `json:"distribution"`
+}
+
+// Describe computes descriptives for values on a 1..scaleMax scale. expected
+// is how many answers there should have been; the shortfall is reported as
+// missing.
+func Describe(values []float64, scaleMax, expected int) Descriptives {
+	d := Descriptives{N: len(values), Distribution: make([]int, scaleMax)}
+	if expected > len(values) {
+		d.Missing = expected - len(values)
+	}
+	for _, v := range values {
+		if i := int(math.Round(v)) - 1; i >= 0 && i < scaleMax {
+			d.Distribution[i]++
+		}
+	}
+	if len(values) == 0 {
+		return d
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	d.Mean = ptr(mean(values))
+	d.Median = ptr(median(sorted))
+	d.Min = ptr(sorted[0])
+	d.Max = ptr(sorted[len(sorted)-1])
+	if len(values) > 1 {
+		d.SD = ptr(math.Sqrt(variance(values)))
+	}
+	return d
+}
+
+func mean(xs []float64) float64 {
+	sum := 0.0
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
+}
+
+func median(sorted []float64) float64 {
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// variance is the sample (n-1) variance.
+func variance(xs []float64) float64 {
+	m := mean(xs)
+	ss := 0.0
+	for _, x := range xs {
+		ss += (x - m) * (x - m)
+	}
+	return ss / float64(len(xs)-1)
+}
+
+// ptr rounds to four decimals and returns nil for NaN/Inf so undefined
+// statistics serialise as null.
+func ptr(v float64) *float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	v = math.Round(v*10000) / 10000
+	return &v
+}
@@ -0,0 +1,185 @@
+// Package audit defines the actions recorded in the audit log and the hash
+// chain that makes edits to it detectable. Every entry stores the SHA-256 of
+// its own fields and of the previous entry's hash, so changing, removing or
+// reordering a row breaks the chain from that point on.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+	"time"
+
+	"opslab-survey/internal/models"
+)
+
+// Actions recorded by the server.
+const (
+	ActionLogin           = "auth.login"
+	ActionLogout          = "auth.logout"
+	ActionResponseSubmit  = "response.submit"
+	ActionStatsView       = "stats.view"
+	ActionResponsesList   = "responses.list"
+	ActionResponseView    = "response.view"
+	ActionRevisionsView   = "revisions.view"
+	ActionExport          = "export.download"
+	ActionAnalyticsView   = "analytics.view"
+	ActionTextView        = "text.view"
+	ActionNetworkExport   = "network.download"
+	ActionReciprocityView = "reciprocity.view"
+	ActionCommunitiesView = "communities.view"
+	ActionSociogramView   = "sociogram.view"
+	ActionTestData        = "testdata.load"
+	ActionResetRequest    = "reset.request"
+	ActionReset           = "responses.reset"
+	ActionSnapshotsList   = "snapshots.list"
+	ActionSnapshotRestore = "snapshot.restore"
+	ActionRoundsList      = "rounds.list"
+	ActionRoundCreate     = "round.create"
+	ActionRoundUpdate     = "round.update"
+	ActionExtensionsList  = "extensions.list"
+	ActionExtensionGrant  = "extension.grant"
+	ActionRemindersList   = "reminders.list"
+	ActionRemindersNudge  = "reminders.nudge"
+	ActionWebhooksList    = "webhooks.list"
+	ActionWebhookCreate   = "webhook.create"
+	ActionWebhookDelete   = "webhook.delete"
+	ActionDeliveriesList  = "webhooks.deliveries"
+	ActionAuditView       = "audit.view"
+	ActionBackupDownload  = "backup.download"
+	ActionBackupRestore   = "backup.restore"
+	ActionAuditVerify     = "audit.verify"
+	ActionKeysRotate      = "keys.rotate"
+	ActionSubjectExport   = "gdpr.export"
+	ActionEraseRequest    = "gdpr.erase.request"
+	ActionErase           = "gdpr.erase"
+	ActionAnonymizedView  = "anonymized.view"
+	ActionRetentionView   = "retention.view"
+	ActionRetentionRun    = "retention.run"
+	ActionConsentAccept   = "consent.accept"
+	ActionConsentView     = "consent.view"
+	ActionRedactionsView  = "redactions.view"
+
+	// Recorded by the retention policy with the actor "retention".
+	ActionRetentionAnonymize = "retention.anonymize"
+	ActionRetentionPurge     = "retention.purge"
+)
+
+// Outcome classifies an HTTP status for the log.
+func Outcome(status int) string {
+	switch {
+	case status == 401 || status == 403:
+		return models.AuditDenied
+	case status >= 400:
+		return models.AuditFailure
+	default:
+		return models.AuditSuccess
+	}
+}
+
+// Hash returns the chain hash of e following an entry whose hash is prev.
+// The first entry of the log follows "". ID and Hash itself are not covered;
+// OccurredAt is hashed at microsecond precision in UTC, as stored.
+func Hash(prev string, e models.AuditEntry) string {
+	h := sha256.New()
+	for _, field := range []string{
+		prev,
+		e.OccurredAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+		e.Actor,
+		e.Action,
+		e.Target,
+		e.IP,
+		e.UserAgent,
+		e.Outcome,
+		strconv.Itoa(e.Status),
+	} {
+		writeField(h, field)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeField length-prefixes each field so that no two different entries
+// hash the same input.
+func writeField(h hash.Hash, s string) {
+	fmt.Fprintf(h, "%d:%s;", len(s), s)
+}
+
+// Verification is the result of checking the chain.
+type Verification struct {
+	Valid    bool   `json:"valid"`
+	Entries  int    `json:"entries"`
+	Head     string `json:"head"`               // hash of the last entry checked
+	BrokenAt int64  `json:"brokenAt,omitempty"` // ID of the first entry that does not verify
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Verifier checks the chain incrementally, one page of entries in ID order
+// at a time.
+type Verifier struct {
+	res  Verification
+	done bool
+}
+
+// NewVerifier starts a check from the beginning of the log.
+func NewVerifier() *Verifier {
+	return &Verifier{res: Verification{Valid: true}}
+}
+
+// Add checks the next entries. It returns false once the chain is broken;
+// later calls are ignored.
+func (v *Verifier) Add(entries []models.AuditEntry) bool {
+	for _, e := range entries {
+		if v.done {
+			return false
+		}
+		switch {
+		case e.PrevHash != v.res.Head:
+			v.fail(e.ID, "previous hash does not match the preceding entry")
+		case Hash(e.PrevHash, e) != e.Hash:
+			v.fail(e.ID, "entry does not match its hash")
+		default:
+			v.res.Entries++
+			v.res.Head = e.Hash
+		}
+	}
+	return !v.done
+}
+
+func (v *Verifier) fail(id int64, reason string) {
+	v.res.Valid = false
+	v.res.BrokenAt = id
+	v.res.Reason = reason
+	v.done = true
+}
+
+// Result returns the outcome so far.
+func (v *Verifier) Result() Verification {
+	return v.res
+}
+
+// Matches reports whether e passes the filter, for backends that filter in Go.
+func Matches(f models.AuditFilter, e models.AuditEntry) bool {
+	switch {
+	case f.Actor != "" && e.Actor != f.Actor,
+		f.Target != "" && e.Target != f.Target,
+		f.Outcome != "" && e.Outcome != f.Outcome,
+		f.Action != "" && !MatchAction(f.Action, e.Action),
+		f.From != nil && e.OccurredAt.Before(*f.From),
+		f.To != nil && !e.OccurredAt.Before(*f.To),
+		f.BeforeID != 0 && e.ID >= f.BeforeID:
+		return false
+	}
+	return true
+}
+
+// MatchAction compares an action with a filter that is either an exact
+// action name or a prefix ending in ".".
+func MatchAction(filter, action string) bool {
+	if strings.HasSuffix(filter, ".") {
+		return strings.HasPrefix(action, filter)
+	}
+	return action == filter
+}
@@ -1,6 +1,11 @@
 package auth
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"time"
 
@@ -29,6 +34,7 @@ func (m *Manager) Issue(code string, admin bool) (string, error) {
 		Code:    code,
 		IsAdmin: admin,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        newSessionID(),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(30 * 24 * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
@@ -52,3 +58,70 @@ func (m *Manager) Parse(token string) (*Claims, error) {
 	}
 	return nil, errors.New("invalid token")
 }
+
+// newSessionID returns a random identifier stored as the token's jti so
+// submissions can be traced back to the login that produced them.
+func newSessionID() string {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
+
+// ConfirmClaims authorize one destructive admin action that was previewed
+// earlier. Subject names the action and Payload pins down what it covers.
+type ConfirmClaims struct {
+	Code    string          `json:"code"`
+	Payload json.RawMessage `json:"payload"`
+	jwt.RegisteredClaims
+}
+
+// IssueConfirmation signs a short-lived confirmation token. It uses a key
+// derived from the session secret so it is never accepted as a session.
+func (m *Manager) IssueConfirmation(code, action string, payload interface{}, ttl time.Duration) (string, time.Time, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expires := time.Now().Add(ttl)
+	claims := ConfirmClaims{
+		Code:    code,
+		Payload: raw,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        newSessionID(),
+			Subject:   action,
+			ExpiresAt: jwt.NewNumericDate(expires),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.confirmKey())
+	return token, expires, err
+}
+
+// ParseConfirmation checks a confirmation token for action issued to code.
+func (m *Manager) ParseConfirmation(token, code, action string) (*ConfirmClaims, error) {
+	parsed, err := jwt.ParseWithClaims(token, &ConfirmClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return m.confirmKey(), nil
+	}, jwt.WithSubject(action))
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := parsed.Claims.(*ConfirmClaims)
+	if !ok || !parsed.Valid {
+		return nil, errors.New("invalid token")
+	}
+	if claims.Code != code {
+		return nil, errors.New("token issued to another user")
+	}
+	return claims, nil
+}
+
+func (m *Manager) confirmKey() []byte {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte("confirmation"))
+	return mac.Sum(nil)
+}
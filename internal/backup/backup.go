@@ -0,0 +1,362 @@
+// Package backup reads and writes portable archives of the survey data: a
+// tar.gz with a manifest and one JSON-lines file per table. The manifest
+// carries the format version and a SHA-256 and record count for every file,
+// so a truncated or edited archive is rejected before anything is restored.
+// Rounds that retention anonymized travel as their anonymous responses, and
+// the privacy notice acceptances travel with the answers given under them.
+//
+// Audit entries, webhooks, drafts and reset snapshots are not part of a
+// backup; they describe one deployment rather than the survey results.
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/seed"
+	"opslab-survey/internal/store"
+)
+
+// Format names the archive type in the manifest.
+const Format = "opslab-survey-backup"
+
+// SchemaVersion is the archive layout this build writes and reads. Bump it
+// when a file or field changes meaning, and teach Read the old layout.
+// Version 2 added AnonymousFile and version 3 ConsentsFile; older archives
+// simply lack them.
+const SchemaVersion = 3
+
+// ManifestName is the first entry of every archive.
+const ManifestName = "manifest.json"
+
+// Data files, in archive order.
+const (
+	ParticipantsFile = "participants.jsonl"
+	QuestionsFile    = "questions.jsonl"
+	RoundsFile       = "rounds.jsonl"
+	ResponsesFile    = "responses.jsonl"
+	AnonymousFile    = "anonymous_responses.jsonl"
+	ConsentsFile     = "consents.jsonl"
+)
+
+// ErrUnsupportedVersion is returned for archives of a newer or unknown
+// schema version.
+var ErrUnsupportedVersion = errors.New("unsupported backup schema version")
+
+// Manifest describes an archive.
+type Manifest struct {
+	Format        string     `json:"format"`
+	SchemaVersion int        `json:"schemaVersion"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	Files         []FileInfo `json:"files"`
+}
+
+// FileInfo is the checksum and record count of one data file.
+type FileInfo struct {
+	Name    string `json:"name"`
+	Records int    `json:"records"`
+	SHA256  string `json:"sha256"`
+}
+
+// Collect reads the dataset from st. Responses are read first: rounds and
+// participants are never deleted outside a restore, so everything a
+// response refers to is still there when they are listed afterwards.
+func Collect(ctx context.Context, st store.Store) (*models.Dataset, error) {
+	revisions, err := st.ExportRevisions(ctx, models.RevisionFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("responses: %w", err)
+	}
+	rounds, err := st.ListRounds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("rounds: %w", err)
+	}
+	sort.Slice(rounds, func(i, j int) bool { return rounds[i].ID < rounds[j].ID })
+	participants, err := st.ListParticipants(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("participants: %w", err)
+	}
+	sort.Slice(participants, func(i, j int) bool { return participants[i].Code < participants[j].Code })
+	var anonymous []models.AnonymousResponse
+	for _, round := range rounds {
+		responses, err := st.ListAnonymousResponses(ctx, round.ID)
+		if err != nil {
+			return nil, fmt.Errorf("anonymous responses: %w", err)
+		}
+		anonymous = append(anonymous, responses...)
+	}
+	consents, err := st.ListConsents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("consents: %w", err)
+	}
+	return &models.Dataset{
+		Participants: participants,
+		Questions:    Questions(participants),
+		Rounds:       rounds,
+		Revisions:    revisions,
+		Anonymous:    anonymous,
+		Consents:     consents,
+	}, nil
+}
+
+// Questions lists every question answers can refer to: the common ones and
+// the peer questions about each non-admin participant. They are defined in
+// code, so they travel with the archive for readers but are not restored.
+func Questions(participants []models.Participant) []models.Question {
+	var peers []models.Participant
+	for _, p := range participants {
+		if !p.IsAdmin {
+			peers = append(peers, p)
+		}
+	}
+	return append(seed.CommonQuestions(), seed.BuildPeerQuestions(peers)...)
+}
+
+// Write stores d as an archive and returns its manifest.
+func Write(w io.Writer, d *models.Dataset, createdAt time.Time) (*Manifest, error) {
+	files := []struct {
+		name string
+		rows int
+		data []byte
+	}{
+		{name: ParticipantsFile, rows: len(d.Participants)},
+		{name: QuestionsFile, rows: len(d.Questions)},
+		{name: RoundsFile, rows: len(d.Rounds)},
+		{name: ResponsesFile, rows: len(d.Revisions)},
+		{name: AnonymousFile, rows: len(d.Anonymous)},
+		{name: ConsentsFile, rows: len(d.Consents)},
+	}
+	var err error
+	if files[0].data, err = jsonLines(d.Participants); err != nil {
+		return nil, err
+	}
+	if files[1].data, err = jsonLines(d.Questions); err != nil {
+		return nil, err
+	}
+	if files[2].data, err = jsonLines(d.Rounds); err != nil {
+		return nil, err
+	}
+	if files[3].data, err = jsonLines(d.Revisions); err != nil {
+		return nil, err
+	}
+	if files[4].data, err = jsonLines(d.Anonymous); err != nil {
+		return nil, err
+	}
+	if files[5].data, err = jsonLines(d.Consents); err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{Format: Format, SchemaVersion: SchemaVersion, CreatedAt: createdAt.UTC()}
+	for _, f := range files {
+		sum := sha256.Sum256(f.data)
+		m.Files = append(m.Files, FileInfo{Name: f.name, Records: f.rows, SHA256: hex.EncodeToString(sum[:])})
+	}
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+	if err := writeEntry(tw, ManifestName, manifest, m.CreatedAt); err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if err := writeEntry(tw, f.name, f.data, m.CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func jsonLines[T any](rows []T) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	for _, row := range rows {
+		if err := enc.Encode(row); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func writeEntry(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), ModTime: modTime, Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// Read parses an archive, checking the format, schema version, checksums,
+// record counts and that every response refers to a round and participant
+// in the archive. Archives of older schema versions are read too.
+func Read(r io.Reader) (*models.Dataset, *Manifest, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("not a gzip archive: %w", err)
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+
+	var m *Manifest
+	files := map[string][]byte{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("read archive: %w", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read %s: %w", hdr.Name, err)
+		}
+		if hdr.Name == ManifestName {
+			m = &Manifest{}
+			if err := json.Unmarshal(data, m); err != nil {
+				return nil, nil, fmt.Errorf("read manifest: %w", err)
+			}
+			if m.Format != Format {
+				return nil, nil, fmt.Errorf("not a survey backup (format %q)", m.Format)
+			}
+			if m.SchemaVersion < 1 || m.SchemaVersion > SchemaVersion {
+				return nil, nil, fmt.Errorf("%w %d, this build reads version %d", ErrUnsupportedVersion, m.SchemaVersion, SchemaVersion)
+			}
+			continue
+		}
+		files[hdr.Name] = data
+	}
+	if m == nil {
+		return nil, nil, errors.New("archive has no " + ManifestName)
+	}
+
+	d := &models.Dataset{}
+	for _, f := range m.Files {
+		data, ok := files[f.Name]
+		if !ok {
+			return nil, nil, fmt.Errorf("archive is missing %s", f.Name)
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != f.SHA256 {
+			return nil, nil, fmt.Errorf("%s: checksum mismatch", f.Name)
+		}
+		var n int
+		switch f.Name {
+		case ParticipantsFile:
+			d.Participants, err = readLines[models.Participant](data)
+			n = len(d.Participants)
+		case QuestionsFile:
+			d.Questions, err = readLines[models.Question](data)
+			n = len(d.Questions)
+		case RoundsFile:
+			d.Rounds, err = readLines[models.Round](data)
+			n = len(d.Rounds)
+		case ResponsesFile:
+			d.Revisions, err = readLines[models.ResponseRevision](data)
+			n = len(d.Revisions)
+		case AnonymousFile:
+			d.Anonymous, err = readLines[models.AnonymousResponse](data)
+			n = len(d.Anonymous)
+		case ConsentsFile:
+			d.Consents, err = readLines[models.Consent](data)
+			n = len(d.Consents)
+		default:
+			return nil, nil, fmt.Errorf("unexpected file %s", f.Name)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		if n != f.Records {
+			return nil, nil, fmt.Errorf("%s: %d records, manifest says %d", f.Name, n, f.Records)
+		}
+	}
+	if err := validate(d); err != nil {
+		return nil, nil, err
+	}
+	return d, m, nil
+}
+
+func readLines[T any](data []byte) ([]T, error) {
+	var rows []T
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for line := 1; sc.Scan(); line++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var row T
+		if err := json.Unmarshal(sc.Bytes(), &row); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, sc.Err()
+}
+
+// validate checks that the archive is self-contained.
+func validate(d *models.Dataset) error {
+	if len(d.Rounds) == 0 {
+		return errors.New("archive has no rounds")
+	}
+	codes := map[string]bool{}
+	for _, p := range d.Participants {
+		if p.Code == "" || codes[p.Code] {
+			return fmt.Errorf("participant %q is empty or duplicated", p.Code)
+		}
+		codes[p.Code] = true
+	}
+	rounds := map[int64]bool{}
+	for _, r := range d.Rounds {
+		if r.ID <= 0 || rounds[r.ID] {
+			return fmt.Errorf("round %d is invalid or duplicated", r.ID)
+		}
+		if !models.ValidRoundState(r.State) {
+			return fmt.Errorf("round %d: invalid state %q", r.ID, r.State)
+		}
+		rounds[r.ID] = true
+	}
+	revisions := map[int64]bool{}
+	for _, rev := range d.Revisions {
+		switch {
+		case rev.ID <= 0 || revisions[rev.ID]:
+			return fmt.Errorf("response %d is invalid or duplicated", rev.ID)
+		case !rounds[rev.RoundID]:
+			return fmt.Errorf("response %d: round %d is not in the archive", rev.ID, rev.RoundID)
+		case !codes[rev.ParticipantCode]:
+			return fmt.Errorf("response %d: participant %s is not in the archive", rev.ID, rev.ParticipantCode)
+		}
+		revisions[rev.ID] = true
+	}
+	for _, a := range d.Anonymous {
+		if !rounds[a.RoundID] {
+			return fmt.Errorf("anonymous response: round %d is not in the archive", a.RoundID)
+		}
+	}
+	for _, c := range d.Consents {
+		if !codes[c.ParticipantCode] {
+			return fmt.Errorf("consent: participant %s is not in the archive", c.ParticipantCode)
+		}
+	}
+	return nil
+}
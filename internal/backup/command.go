@@ -0,0 +1,175 @@
+package backup
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"opslab-survey/internal/audit"
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/store"
+)
+
+// Command runs a backup subcommand against the database in DATABASE_URL:
+//
+//	server backup [-o file]                  write an archive (-o - for stdout)
+//	server restore [-mode merge|replace] file
+//	server restore -mode replace -yes [-o pre-restore-file] file
+//
+// A replace deletes every round and response, so it only runs with -yes and
+// first writes a backup of the current data (opslab-survey-pre-restore-<time>.tar.gz
+// by default), which restores them with another replace. Both commands are
+// recorded in the audit log with the actor "cli".
+func Command(ctx context.Context, name string, args []string, stdout io.Writer) error {
+	switch name {
+	case "backup":
+		return backupCommand(ctx, args, stdout)
+	case "restore":
+		return restoreCommand(ctx, args, stdout)
+	default:
+		return fmt.Errorf("unknown command %q (want backup or restore)", name)
+	}
+}
+
+func backupCommand(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	out := fs.String("o", "", "archive to write (default opslab-survey-<time>.tar.gz, - for stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	st, err := store.OpenEnv(ctx)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	d, err := Collect(ctx, st)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	path := *out
+	if path == "" {
+		path = FileName(now)
+	}
+	var m *Manifest
+	if path == "-" {
+		m, err = Write(stdout, d, now)
+	} else {
+		m, err = writeFile(path, d, now)
+	}
+	if err != nil {
+		return err
+	}
+	recordCLI(ctx, st, audit.ActionBackupDownload, "file:"+path, models.AuditSuccess)
+	if path != "-" {
+		fmt.Fprintf(stdout, "wrote %s: %s\n", path, summary(m))
+	}
+	return nil
+}
+
+func restoreCommand(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	mode := fs.String("mode", models.RestoreMerge, "merge keeps existing rows, replace drops all rounds and responses first")
+	yes := fs.Bool("yes", false, "confirm a replace")
+	out := fs.String("o", "", "backup of the current data written before a replace (default opslab-survey-pre-restore-<time>.tar.gz)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: restore [-mode merge|replace -yes] [-o pre-restore.tar.gz] archive.tar.gz")
+	}
+	if *mode != models.RestoreMerge && *mode != models.RestoreReplace {
+		return fmt.Errorf("mode must be %s or %s", models.RestoreMerge, models.RestoreReplace)
+	}
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	d, m, err := Read(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fs.Arg(0), err)
+	}
+
+	st, err := store.OpenEnv(ctx)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	if *mode == models.RestoreReplace {
+		current, err := Collect(ctx, st)
+		if err != nil {
+			return err
+		}
+		if !*yes {
+			return fmt.Errorf("replace would delete %d rounds and %d responses and load %d rounds and %d responses from %s; run it again with -yes",
+				len(current.Rounds), len(current.Revisions), len(d.Rounds), len(d.Revisions), fs.Arg(0))
+		}
+		now := time.Now()
+		path := *out
+		if path == "" {
+			path = PreRestoreFileName(now)
+		}
+		kept, err := writeFile(path, current, now)
+		if err != nil {
+			return fmt.Errorf("backup before replace: %w", err)
+		}
+		recordCLI(ctx, st, audit.ActionBackupDownload, "file:"+path, models.AuditSuccess)
+		fmt.Fprintf(stdout, "wrote %s: %s\n", path, summary(kept))
+	}
+	res, err := st.RestoreDataset(ctx, *d, *mode)
+	if err != nil {
+		recordCLI(ctx, st, audit.ActionBackupRestore, "mode:"+*mode, models.AuditFailure)
+		return err
+	}
+	recordCLI(ctx, st, audit.ActionBackupRestore, "mode:"+*mode, models.AuditSuccess)
+	fmt.Fprintf(stdout, "restored %s from %s (%s): %d participants, %d rounds (%d kept), %d responses (%d kept)\n",
+		res.Mode, fs.Arg(0), m.CreatedAt.Format(time.RFC3339), res.Participants, res.Rounds, res.SkippedRounds, res.Revisions, res.SkippedRevisions)
+	return nil
+}
+
+// FileName is the default archive name for a backup taken at t.
+func FileName(t time.Time) string {
+	return "opslab-survey-" + t.UTC().Format("20060102-150405") + ".tar.gz"
+}
+
+// PreRestoreFileName is the default name of the backup a replacing restore
+// at t writes first.
+func PreRestoreFileName(t time.Time) string {
+	return "opslab-survey-pre-restore-" + t.UTC().Format("20060102-150405") + ".tar.gz"
+}
+
+func summary(m *Manifest) string {
+	var s string
+	for i, f := range m.Files {
+		if i > 0 {
+			s += ", "
+		}
+		s += fmt.Sprintf("%s %d", f.Name, f.Records)
+	}
+	return s
+}
+
+func writeFile(path string, d *models.Dataset, now time.Time) (*Manifest, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := Write(f, d, now)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return m, f.Close()
+}
+
+func recordCLI(ctx context.Context, st store.Store, action, target, outcome string) {
+	_, err := st.AppendAudit(ctx, models.AuditEntry{Actor: "cli", Action: action, Target: target, Outcome: outcome})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "audit:", err)
+	}
+}
@@ -0,0 +1,90 @@
+package backup
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/seed"
+	"opslab-survey/internal/store/sqlite"
+)
+
+func TestRestoreCommandReplace(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	db := filepath.Join(dir, "survey.db")
+	t.Setenv("DATABASE_URL", "sqlite://"+db)
+	file := func(name string) string { return filepath.Join(dir, name) }
+	submit := func(code string) {
+		t.Helper()
+		st, err := sqlite.Open(ctx, db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer st.Close()
+		if err := st.EnsureSchema(ctx, seed.Participants()); err != nil {
+			t.Fatal(err)
+		}
+		answers := []models.AnswerPayload{{QuestionID: "common:trust-level", Value: 7.0}}
+		if err := st.UpsertResponse(ctx, 1, code, answers, nil, false, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	responses := func() int {
+		t.Helper()
+		st, err := sqlite.Open(ctx, db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer st.Close()
+		revs, err := st.ExportRevisions(ctx, models.RevisionFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(revs)
+	}
+	run := func(name string, args ...string) error {
+		return Command(ctx, name, args, io.Discard)
+	}
+
+	submit("1425")
+	if err := run("backup", "-o", file("one.tar.gz")); err != nil {
+		t.Fatal(err)
+	}
+	submit("1122")
+
+	// Without -yes a replace only says what it would do.
+	err := run("restore", "-mode", "replace", file("one.tar.gz"))
+	if err == nil || !strings.Contains(err.Error(), "delete 1 rounds and 2 responses") || !strings.Contains(err.Error(), "-yes") {
+		t.Fatalf("replace without -yes = %v", err)
+	}
+	if n := responses(); n != 2 {
+		t.Fatalf("responses after an unconfirmed replace = %d, want 2", n)
+	}
+
+	// With it, the current data is written to a backup first, which brings
+	// them back with another replace.
+	if err := run("restore", "-mode", "replace", "-yes", "-o", file("before.tar.gz"), file("one.tar.gz")); err != nil {
+		t.Fatal(err)
+	}
+	if n := responses(); n != 1 {
+		t.Errorf("responses after replace = %d, want 1", n)
+	}
+	f, err := os.Open(file("before.tar.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if d, _, err := Read(f); err != nil || len(d.Revisions) != 2 {
+		t.Fatalf("pre-restore backup = %+v, %v", d, err)
+	}
+	if err := run("restore", "-mode", "replace", "-yes", "-o", file("after.tar.gz"), file("before.tar.gz")); err != nil {
+		t.Fatal(err)
+	}
+	if n := responses(); n != 2 {
+		t.Errorf("responses after going back = %d, want 2", n)
+	}
+}
@@ -0,0 +1,280 @@
+// Package envelope encrypts answer payloads at rest. Every value is sealed
+// with its own random AES-256-GCM data key, and the data key is stored next
+// to the ciphertext, wrapped with AES-256-GCM under a master key that never
+// reaches the database. The stored form is a small JSON object, so sealed
+// values fit the same jsonb and text columns as the plaintext they replace:
+//
+//	{"v":1,"kid":"3f2a9c1e","key":"<nonce|wrapped data key>","data":"<nonce|ciphertext>"}
+//
+// A nil *Keyring leaves values in plaintext and still reads them, which is
+// also how rows written before encryption was enabled keep working.
+package envelope
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Version is the envelope layout written by Seal.
+const Version = 1
+
+// Labels of the values the stores seal.
+const (
+	LabelAnswers       = "answers"
+	LabelRankings      = "rankings"
+	LabelDraft         = "draft"
+	LabelDraftRankings = "draft-rankings"
+	LabelSnapshot      = "snapshot"
+)
+
+// RankingsLabel returns the label of the rankings stored next to answers
+// sealed with label.
+func RankingsLabel(label string) string {
+	if label == LabelDraft {
+		return LabelDraftRankings
+	}
+	return LabelRankings
+}
+
+// KeySize is the length of master and data keys in bytes.
+const KeySize = 32
+
+// ErrNoKey is returned when a sealed value is read without a keyring, or
+// with one that lacks the master key it was sealed with.
+var ErrNoKey = errors.New("encrypted value: master key not available")
+
+type sealed struct {
+	V    int    `json:"v"`
+	KID  string `json:"kid"`
+	Key  []byte `json:"key"`
+	Data []byte `json:"data"`
+}
+
+type masterKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// Keyring holds the current master key, which seals new values, and
+// previous ones, which are only used to open values until they are rotated.
+type Keyring struct {
+	current masterKey
+	byID    map[string]masterKey
+}
+
+// New builds a keyring from raw 32-byte master keys.
+func New(current []byte, previous ...[]byte) (*Keyring, error) {
+	k := &Keyring{byID: map[string]masterKey{}}
+	for i, raw := range append([][]byte{current}, previous...) {
+		mk, err := newMasterKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("master key %d: %w", i+1, err)
+		}
+		if i == 0 {
+			k.current = mk
+		}
+		if _, dup := k.byID[mk.id]; !dup {
+			k.byID[mk.id] = mk
+		}
+	}
+	return k, nil
+}
+
+func newMasterKey(raw []byte) (masterKey, error) {
+	if len(raw) != KeySize {
+		return masterKey{}, fmt.Errorf("need %d bytes, got %d", KeySize, len(raw))
+	}
+	aead, err := newAEAD(raw)
+	if err != nil {
+		return masterKey{}, err
+	}
+	sum := sha256.Sum256(raw)
+	return masterKey{id: hex.EncodeToString(sum[:4]), aead: aead}, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// FromEnv loads the keyring configured by
+//
+//	ENCRYPTION_KEY            base64 master key
+//	ENCRYPTION_KEY_FILE       file with the base64 master key on the first line
+//	                          and, optionally, previous keys on the next ones
+//	ENCRYPTION_PREVIOUS_KEYS  comma-separated base64 keys still accepted for reading
+//
+// It returns nil when no key is configured.
+func FromEnv() (*Keyring, error) {
+	var keys []string
+	inline, path := os.Getenv("ENCRYPTION_KEY"), os.Getenv("ENCRYPTION_KEY_FILE")
+	switch {
+	case inline != "" && path != "":
+		return nil, errors.New("set ENCRYPTION_KEY or ENCRYPTION_KEY_FILE, not both")
+	case inline != "":
+		keys = append(keys, inline)
+	case path != "":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("ENCRYPTION_KEY_FILE: %w", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				keys = append(keys, line)
+			}
+		}
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("ENCRYPTION_KEY_FILE %s has no key", path)
+		}
+	}
+	if prev := os.Getenv("ENCRYPTION_PREVIOUS_KEYS"); prev != "" {
+		if len(keys) == 0 {
+			return nil, errors.New("ENCRYPTION_PREVIOUS_KEYS needs a current ENCRYPTION_KEY")
+		}
+		for _, key := range strings.Split(prev, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				keys = append(keys, key)
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	raw := make([][]byte, len(keys))
+	for i, key := range keys {
+		b, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return nil, fmt.Errorf("master key %d is not base64: %w", i+1, err)
+		}
+		raw[i] = b
+	}
+	return New(raw[0], raw[1:]...)
+}
+
+// GenerateKey returns a new random master key, base64-encoded.
+func GenerateKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// KeyID identifies the current master key; it is derived from the key and
+// stored with every value so the right key can be picked for opening.
+func (k *Keyring) KeyID() string {
+	if k == nil {
+		return ""
+	}
+	return k.current.id
+}
+
+// Seal encrypts plaintext under a new data key. label names the kind of
+// value (e.g. "answers") and is authenticated, so a sealed value cannot be
+// moved into a column of another kind. A nil keyring returns plaintext.
+func (k *Keyring) Seal(plaintext []byte, label string) ([]byte, error) {
+	if k == nil {
+		return plaintext, nil
+	}
+	dataKey := make([]byte, KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	data, err := seal(aead, plaintext, []byte(label))
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := seal(k.current.aead, dataKey, []byte(k.current.id))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(sealed{V: Version, KID: k.current.id, Key: wrapped, Data: data})
+}
+
+// Open returns the plaintext of a stored value. Values that were never
+// sealed are returned unchanged.
+func (k *Keyring) Open(stored []byte, label string) ([]byte, error) {
+	env, ok := parse(stored)
+	if !ok {
+		return stored, nil
+	}
+	if k == nil {
+		return nil, ErrNoKey
+	}
+	mk, found := k.byID[env.KID]
+	if !found {
+		return nil, fmt.Errorf("%w (key %s)", ErrNoKey, env.KID)
+	}
+	dataKey, err := open(mk.aead, env.Key, []byte(env.KID))
+	if err != nil {
+		return nil, fmt.Errorf("unwrap data key: %w", err)
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := open(aead, env.Data, []byte(label))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt %s: %w", label, err)
+	}
+	return plaintext, nil
+}
+
+// Current reports whether stored is sealed with the current master key,
+// i.e. whether rotation can leave it alone.
+func (k *Keyring) Current(stored []byte) bool {
+	env, ok := parse(stored)
+	return ok && k != nil && env.KID == k.current.id
+}
+
+// IsSealed reports whether stored is an envelope rather than plaintext.
+func IsSealed(stored []byte) bool {
+	_, ok := parse(stored)
+	return ok
+}
+
+// parse recognises envelopes. Plaintext payloads are JSON arrays, null or
+// gzip data, never an object with a version and key ID.
+func parse(stored []byte) (sealed, bool) {
+	var env sealed
+	trimmed := bytes.TrimSpace(stored)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return env, false
+	}
+	if err := json.Unmarshal(trimmed, &env); err != nil || env.V != Version || env.KID == "" {
+		return env, false
+	}
+	return env, true
+}
+
+func seal(aead cipher.AEAD, plaintext, ad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, ad), nil
+}
+
+func open(aead cipher.AEAD, box, ad []byte) ([]byte, error) {
+	if len(box) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return aead.Open(nil, box[:aead.NonceSize()], box[aead.NonceSize():], ad)
+}
@@ -0,0 +1,126 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// Event types pushed to the admin dashboard.
+const (
+	TypeCompletion = "completion"
+	TypeSubmission = "submission"
+	TypeDraft      = "draft"
+	TypeReset      = "reset"
+)
+
+// Event is one dashboard update.
+type Event struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+	At   time.Time       `json:"at"`
+}
+
+// Relay carries events between server instances. The Postgres store
+// implements it with LISTEN/NOTIFY.
+type Relay interface {
+	Notify(ctx context.Context, channel, payload string) error
+	Listen(ctx context.Context, channel string, fn func(payload string)) error
+}
+
+const channel = "opslab_events"
+
+// Broker fans events out to subscribers. Without a relay it is purely
+// in-process; with one, every Publish goes through the relay so that
+// subscribers on all instances see it.
+type Broker struct {
+	relay Relay
+
+	mu   sync.Mutex
+	subs map[chan Event]struct{}
+}
+
+func NewBroker(relay Relay) *Broker {
+	return &Broker{relay: relay, subs: map[chan Event]struct{}{}}
+}
+
+// Subscribe registers a listener. The returned cancel func must be called to
+// release it. Slow subscribers drop events rather than block publishers.
+func (b *Broker) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, 32)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch, func() {
+		b.mu.Lock()
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+		b.mu.Unlock()
+	}
+}
+
+// Publish sends an event to every subscriber.
+func (b *Broker) Publish(ctx context.Context, typ string, data interface{}) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("events: marshal %s: %v", typ, err)
+		return
+	}
+	ev := Event{Type: typ, Data: raw, At: time.Now().UTC()}
+	if b.relay == nil {
+		b.fanOut(ev)
+		return
+	}
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("events: marshal %s: %v", typ, err)
+		return
+	}
+	if err := b.relay.Notify(ctx, channel, string(payload)); err != nil {
+		// Fall back to local delivery so this instance's dashboards still update.
+		log.Printf("events: notify %s: %v", typ, err)
+		b.fanOut(ev)
+	}
+}
+
+// Run relays notifications from other instances (and this one) to local
+// subscribers, reconnecting until ctx is cancelled. It is a no-op without a relay.
+func (b *Broker) Run(ctx context.Context) {
+	if b.relay == nil {
+		return
+	}
+	for {
+		err := b.relay.Listen(ctx, channel, func(payload string) {
+			var ev Event
+			if err := json.Unmarshal([]byte(payload), &ev); err != nil {
+				log.Println("events: decode notification:", err)
+				return
+			}
+			b.fanOut(ev)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		log.Println("events: listen:", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+func (b *Broker) fanOut(ev Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
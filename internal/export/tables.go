@@ -0,0 +1,149 @@
+package export
+
+import (
+	"sort"
+	"strings"
+
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/seed"
+)
+
+// Table is one sheet (XLSX) or file (CSV) of an export. Cells hold strings,
+// ints, float64s or bools so spreadsheets keep numbers numeric.
+type Table struct {
+	Name   string
+	Header []string
+	Rows   [][]interface{}
+}
+
+// QuestionMeta describes an answer's question ID once the peer code and
+// template index are split off: "peer:trust-level:1122:4" has key
+// "peer:trust-level" and ratee "1122".
+type QuestionMeta struct {
+	Key   string
+	Scope string
+	Type  string
+	Ratee string
+}
+
+// ParseQuestionID resolves a stored question ID against the seeded questions.
+func ParseQuestionID(id string) QuestionMeta {
+	key, ratee := seed.SplitQuestionID(id)
+	if key != id {
+		for _, t := range seed.PeerTemplates() {
+			if t.ID == key {
+				return QuestionMeta{Key: t.ID, Scope: "peer", Type: t.Type, Ratee: ratee}
+			}
+		}
+	}
+	for _, q := range seed.CommonQuestions() {
+		if q.ID == id {
+			return QuestionMeta{Key: q.ID, Scope: "common", Type: q.Type}
+		}
+	}
+	return QuestionMeta{Key: id, Type: "unknown"}
+}
+
+// Tables builds the long-format export: answers, rankings, perception
+// guesses and participants.
+func Tables(participants []models.Participant, responses []models.ResponseRecord) []Table {
+	names := map[string]string{}
+	for _, p := range participants {
+		names[p.Code] = p.Name
+	}
+	responses = sortedResponses(responses)
+
+	answers := Table{
+		Name:   "answers",
+		Header: []string{"rater_code", "rater_name", "ratee_code", "ratee_name", "question_id", "question_key", "type", "value"},
+	}
+	rankings := Table{
+		Name:   "rankings",
+		Header: []string{"rater_code", "rater_name", "criterion", "position", "ratee_code", "ratee_name"},
+	}
+	perception := Table{
+		Name:   "perception_guesses",
+		Header: []string{"rater_code", "rater_name", "criterion", "colleague_code", "colleague_name", "guessed_position"},
+	}
+
+	for _, resp := range responses {
+		rater := resp.ParticipantCode
+		for _, a := range resp.Answers {
+			meta := ParseQuestionID(a.QuestionID)
+			answers.Rows = append(answers.Rows, []interface{}{
+				rater, names[rater], meta.Ratee, names[meta.Ratee], a.QuestionID, meta.Key, meta.Type, Cell(a.Value),
+			})
+		}
+		for _, rk := range resp.Rankings {
+			for i, ratee := range rk.Order {
+				rankings.Rows = append(rankings.Rows, []interface{}{
+					rater, names[rater], rk.Criteria, i + 1, ratee, names[ratee],
+				})
+			}
+			colleagues := make([]string, 0, len(rk.PeerRankings))
+			for code := range rk.PeerRankings {
+				colleagues = append(colleagues, code)
+			}
+			sort.Strings(colleagues)
+			for _, code := range colleagues {
+				perception.Rows = append(perception.Rows, []interface{}{
+					rater, names[rater], rk.Criteria, code, names[code], rk.PeerRankings[code],
+				})
+			}
+		}
+	}
+
+	return []Table{answers, rankings, perception, ParticipantsTable(participants, responses)}
+}
+
+// ParticipantsTable lists everyone with their completion status.
+func ParticipantsTable(participants []models.Participant, responses []models.ResponseRecord) Table {
+	byCode := map[string]models.ResponseRecord{}
+	for _, r := range responses {
+		byCode[r.ParticipantCode] = r
+	}
+	t := Table{
+		Name:   "participants",
+		Header: []string{"code", "name", "email", "is_admin", "completed", "submitted_at", "updated_at", "is_test_data"},
+	}
+	for _, p := range participants {
+		row := []interface{}{p.Code, p.Name, p.Email, p.IsAdmin, false, "", "", false}
+		if r, ok := byCode[p.Code]; ok {
+			row[4] = true
+			row[5] = r.SubmittedAt.UTC().Format("2006-01-02T15:04:05Z")
+			row[6] = r.UpdatedAt.UTC().Format("2006-01-02T15:04:05Z")
+			row[7] = r.IsTestData
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t
+}
+
+// Cell normalises a decoded JSON answer value for tabular output.
+func Cell(v interface{}) interface{} {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		if val == float64(int64(val)) {
+			return int64(val)
+		}
+		return val
+	case string, bool, int, int64:
+		return val
+	case []interface{}:
+		parts := make([]string, 0, len(val))
+		for _, item := range val {
+			parts = append(parts, formatCell(Cell(item)))
+		}
+		return strings.Join(parts, "; ")
+	default:
+		return formatCell(val)
+	}
+}
+
+func sortedResponses(in []models.ResponseRecord) []models.ResponseRecord {
+	out := append([]models.ResponseRecord(nil), in...)
+	sort.Slice(out, func(i, j int) bool { return out[i].ParticipantCode < out[j].ParticipantCode })
+	return out
+}
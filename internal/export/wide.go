@@ -0,0 +1,332 @@
+package export
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/seed"
+)
+
+// Variable describes one column of the wide export for the codebook and
+// the SPSS syntax.
+type Variable struct {
+	Name        string
+	Label       string
+	QuestionID  string
+	Numeric     bool
+	Measure     string // nominal, ordinal or scale
+	Min, Max    int
+	ValueLabels []ValueLabel
+	width       int
+}
+
+// ValueLabel maps a numeric code to its meaning.
+type ValueLabel struct {
+	Value int
+	Label string
+}
+
+// Wide builds the one-row-per-rater table and its codebook. Columns follow
+// seed order: common questions, then every peer question per ratee, then the
+// ranking position and perception guess per criterion and ratee.
+func Wide(participants []models.Participant, responses []models.ResponseRecord) (Table, []Variable) {
+	var ratees []models.Participant
+	for _, p := range participants {
+		if !p.IsAdmin {
+			ratees = append(ratees, p)
+		}
+	}
+	names := map[string]string{}
+	for _, p := range participants {
+		names[p.Code] = p.Name
+	}
+
+	vars := []Variable{
+		{Name: "rater_code", Label: "Код оцінювача"},
+		{Name: "rater_name", Label: "Ім'я оцінювача"},
+		{Name: "is_test", Label: "Тестові дані", Numeric: true, Measure: "nominal", Min: 0, Max: 1,
+			ValueLabels: []ValueLabel{{0, "ні"}, {1, "так"}}},
+		{Name: "submitted_at", Label: "Час першої відправки (UTC)"},
+	}
+	// column maps a stored question ID (or ranking key) to its variable index.
+	column := map[string]int{}
+	addQuestion := func(id, label, typ string, scaleMax int, choice []string) {
+		v := Variable{Name: VariableName(id), Label: label, QuestionID: id}
+		switch typ {
+		case "scale":
+			v.Numeric, v.Measure, v.Min, v.Max = true, "scale", 1, scaleMax
+		case "choice":
+			v.Numeric, v.Measure, v.Min, v.Max = true, "nominal", 1, len(choice)
+			for i, c := range choice {
+				v.ValueLabels = append(v.ValueLabels, ValueLabel{i + 1, c})
+			}
+		}
+		column[id] = len(vars)
+		vars = append(vars, v)
+	}
+
+	for _, q := range seed.CommonQuestions() {
+		addQuestion(q.ID, q.Title, q.Type, q.ScaleMax, q.Choice)
+	}
+	for _, ratee := range ratees {
+		for _, t := range seed.PeerTemplates() {
+			addQuestion(t.ID+":"+ratee.Code, fmt.Sprintf(t.TitleFmt, ratee.Name), t.Type, t.ScaleMax, t.Choice)
+		}
+	}
+	for i, criterion := range seed.RankingCriteria() {
+		for _, ratee := range ratees {
+			rank := fmt.Sprintf("rank:c%d:%s", i+1, ratee.Code)
+			column[rank] = len(vars)
+			vars = append(vars, Variable{
+				Name: VariableName(rank), QuestionID: rank, Numeric: true, Measure: "ordinal", Min: 1, Max: len(ratees) - 1,
+				Label: fmt.Sprintf("%s: місце, яке оцінювач дав %s", criterion, ratee.Name),
+			})
+			guess := fmt.Sprintf("guess:c%d:%s", i+1, ratee.Code)
+			column[guess] = len(vars)
+			vars = append(vars, Variable{
+				Name: VariableName(guess), QuestionID: guess, Numeric: true, Measure: "ordinal", Min: 1, Max: len(ratees) - 1,
+				Label: fmt.Sprintf("%s: місце, яке, на думку оцінювача, йому дасть %s", criterion, ratee.Name),
+			})
+		}
+	}
+	criterionIndex := map[string]int{}
+	for i, c := range seed.RankingCriteria() {
+		criterionIndex[c] = i + 1
+	}
+
+	t := Table{Name: "data"}
+	for _, v := range vars {
+		t.Header = append(t.Header, v.Name)
+	}
+	for _, resp := range sortedResponses(responses) {
+		row := make([]interface{}, len(vars))
+		row[0], row[1] = resp.ParticipantCode, names[resp.ParticipantCode]
+		row[2] = 0
+		if resp.IsTestData {
+			row[2] = 1
+		}
+		row[3] = resp.SubmittedAt.UTC().Format(time.RFC3339)
+
+		for _, a := range resp.Answers {
+			meta := ParseQuestionID(a.QuestionID)
+			key := meta.Key
+			if meta.Scope == "peer" {
+				key = meta.Key + ":" + meta.Ratee
+			}
+			idx, ok := column[key]
+			if !ok {
+				continue
+			}
+			row[idx] = codeValue(vars[idx], a.Value)
+		}
+		for _, rk := range resp.Rankings {
+			c, ok := criterionIndex[rk.Criteria]
+			if !ok {
+				continue
+			}
+			for pos, ratee := range rk.Order {
+				if idx, ok := column[fmt.Sprintf("rank:c%d:%s", c, ratee)]; ok {
+					row[idx] = pos + 1
+				}
+			}
+			for ratee, pos := range rk.PeerRankings {
+				if idx, ok := column[fmt.Sprintf("guess:c%d:%s", c, ratee)]; ok {
+					row[idx] = pos
+				}
+			}
+		}
+
+		for i, cell := range row {
+			// SPSS string widths count bytes in UTF-8 mode.
+			if s, ok := cell.(string); ok && len(s) > vars[i].width {
+				vars[i].width = len(s)
+			}
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t, vars
+}
+
+// codeValue turns an answer into the cell stored for a variable: numbers for
+// scales, 1-based option codes for choices and single-line text otherwise.
+func codeValue(v Variable, value interface{}) interface{} {
+	if v.Numeric && len(v.ValueLabels) > 0 {
+		if s, ok := value.(string); ok {
+			for _, vl := range v.ValueLabels {
+				if vl.Label == s {
+					return vl.Value
+				}
+			}
+			return nil
+		}
+	}
+	if v.Numeric {
+		switch val := value.(type) {
+		case float64:
+			return Cell(val)
+		case string:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(val), 64); err == nil {
+				return Cell(f)
+			}
+		}
+		return nil
+	}
+	s := formatCell(Cell(value))
+	// SPSS reads one case per line, so embedded newlines would split a case.
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// VariableName derives a stable SPSS/R/Stata-safe name from a question ID:
+// "peer:trust-level:1122" → "peer_trust_level_1122". Names are lowercase,
+// start with a letter and stay within 64 characters.
+func VariableName(id string) string {
+	var b strings.Builder
+	lastUnderscore := false
+	for _, r := range strings.ToLower(id) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			lastUnderscore = false
+		} else if !lastUnderscore && b.Len() > 0 {
+			b.WriteByte('_')
+			lastUnderscore = true
+		}
+	}
+	name := strings.TrimSuffix(b.String(), "_")
+	if name == "" || name[0] < 'a' {
+		name = "v_" + name
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// CodebookTable lists every variable with its label, type and coding.
+func CodebookTable(vars []Variable) Table {
+	t := Table{
+		Name:   "codebook",
+		Header: []string{"variable", "label", "question_id", "type", "measure", "min", "max", "value_labels"},
+	}
+	for _, v := range vars {
+		typ, min, max := "string", interface{}(""), interface{}("")
+		if v.Numeric {
+			typ, min, max = "numeric", v.Min, v.Max
+		}
+		labels := make([]string, 0, len(v.ValueLabels))
+		for _, vl := range v.ValueLabels {
+			labels = append(labels, fmt.Sprintf("%d=%s", vl.Value, vl.Label))
+		}
+		t.Rows = append(t.Rows, []interface{}{v.Name, v.Label, v.QuestionID, typ, v.Measure, min, max, strings.Join(labels, "; ")})
+	}
+	return t
+}
+
+// WriteSPSSSyntax writes a .sps file that imports dataFile with variable
+// types, labels, value labels and measurement levels in one step.
+func WriteSPSSSyntax(w io.Writer, dataFile string, vars []Variable) error {
+	var b strings.Builder
+	b.WriteString("* Encoding: UTF-8.\n")
+	b.WriteString("* Generated by opslab-survey. Run with the data file in the working directory.\n\n")
+	b.WriteString("GET DATA\n  /TYPE=TXT\n")
+	fmt.Fprintf(&b, "  /FILE=%s\n", spssQuote(dataFile))
+	b.WriteString("  /ENCODING='UTF8'\n  /DELCASE=LINE\n  /DELIMITERS=\",\"\n  /QUALIFIER='\"'\n  /ARRANGEMENT=DELIMITED\n  /FIRSTCASE=2\n  /VARIABLES=\n")
+	for _, v := range vars {
+		if v.Numeric {
+			fmt.Fprintf(&b, "    %s F8.2\n", v.Name)
+			continue
+		}
+		width := v.width
+		if width < 8 {
+			width = 8
+		}
+		if width > 32767 {
+			width = 32767
+		}
+		fmt.Fprintf(&b, "    %s A%d\n", v.Name, width)
+	}
+	b.WriteString(".\n\nVARIABLE LABELS\n")
+	for i, v := range vars {
+		sep := "  /"
+		if i == 0 {
+			sep = "  "
+		}
+		fmt.Fprintf(&b, "%s%s %s\n", sep, v.Name, spssQuote(truncate(v.Label, 120)))
+	}
+	b.WriteString(".\n")
+
+	var labelled []Variable
+	for _, v := range vars {
+		if len(v.ValueLabels) > 0 {
+			labelled = append(labelled, v)
+		}
+	}
+	if len(labelled) > 0 {
+		b.WriteString("\nVALUE LABELS\n")
+		for _, v := range labelled {
+			fmt.Fprintf(&b, "  /%s", v.Name)
+			for _, vl := range v.ValueLabels {
+				fmt.Fprintf(&b, " %d %s", vl.Value, spssQuote(truncate(vl.Label, 120)))
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(".\n")
+	}
+
+	levels := map[string][]string{}
+	for _, v := range vars {
+		if v.Measure != "" {
+			levels[v.Measure] = append(levels[v.Measure], v.Name)
+		}
+	}
+	for _, level := range []string{"nominal", "ordinal", "scale"} {
+		if len(levels[level]) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\nVARIABLE LEVEL\n  %s (%s).\n", strings.Join(levels[level], "\n  "), strings.ToUpper(level))
+	}
+	b.WriteString("\nEXECUTE.\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteStatsZip writes the wide data file, the codebook and the SPSS import
+// syntax into one archive.
+func WriteStatsZip(w io.Writer, participants []models.Participant, responses []models.ResponseRecord) error {
+	data, vars := Wide(participants, responses)
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name  string
+		write func(io.Writer) error
+	}{
+		// SPSS does not expect a byte-order mark, so the data file goes without one.
+		{"data.csv", func(f io.Writer) error { return writeCSV(f, data, false) }},
+		{"codebook.csv", func(f io.Writer) error { return WriteCSV(f, CodebookTable(vars)) }},
+		{"import.sps", func(f io.Writer) error { return WriteSPSSSyntax(f, "data.csv", vars) }},
+	}
+	for _, file := range files {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		if err := file.write(f); err != nil {
+			return fmt.Errorf("%s: %w", file.name, err)
+		}
+	}
+	return zw.Close()
+}
+
+func spssQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,243 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WriteCSVZip writes every table as <name>.csv inside a zip archive. Files
+// start with a UTF-8 BOM so Excel opens Cyrillic text correctly.
+func WriteCSVZip(w io.Writer, tables []Table) error {
+	zw := zip.NewWriter(w)
+	for _, t := range tables {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: t.Name + ".csv", Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		if err := WriteCSV(f, t); err != nil {
+			return fmt.Errorf("%s.csv: %w", t.Name, err)
+		}
+	}
+	return zw.Close()
+}
+
+// WriteCSV writes a single table as CSV.
+func WriteCSV(w io.Writer, t Table) error {
+	return writeCSV(w, t, true)
+}
+
+func writeCSV(w io.Writer, t Table, bom bool) error {
+	if bom {
+		if _, err := io.WriteString(w, "\ufeff"); err != nil {
+			return err
+		}
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.Header); err != nil {
+		return err
+	}
+	record := make([]string, len(t.Header))
+	for _, row := range t.Rows {
+		for i := range record {
+			record[i] = ""
+			if i < len(row) {
+				record[i] = formatCell(row[i])
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatCell(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case bool:
+		if val {
+			return "true"
+		}
+		return "false"
+	case int:
+		return strconv.Itoa(val)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	default:
+		raw, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(raw)
+	}
+}
+
+// WriteXLSX writes the tables as sheets of a single Office Open XML workbook.
+// Only the parts Excel, LibreOffice and Google Sheets require are produced.
+func WriteXLSX(w io.Writer, tables []Table) error {
+	zw := zip.NewWriter(w)
+	add := func(name, body string) error {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(f, body)
+		return err
+	}
+
+	var contentTypes, workbookSheets, workbookRels strings.Builder
+	for i := range tables {
+		n := i + 1
+		fmt.Fprintf(&contentTypes, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n)
+		fmt.Fprintf(&workbookSheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(sheetName(tables[i].Name)), n, n)
+		fmt.Fprintf(&workbookRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n, n)
+	}
+	stylesID := len(tables) + 1
+
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", xmlHeader + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+			contentTypes.String() + `</Types>`},
+		{"_rels/.rels", xmlHeader + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", xmlHeader + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets>` + workbookSheets.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", xmlHeader + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			workbookRels.String() +
+			fmt.Sprintf(`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, stylesID) +
+			`</Relationships>`},
+		{"xl/styles.xml", xmlHeader + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+			`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+			`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+			`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+			`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+			`</styleSheet>`},
+	}
+	for _, p := range parts {
+		if err := add(p.name, p.body); err != nil {
+			return err
+		}
+	}
+	for i, t := range tables {
+		if err := add(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), sheetXML(t)); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+
+func sheetXML(t Table) string {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	// Freeze the header row.
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	b.WriteString(`<sheetData>`)
+	header := make([]interface{}, len(t.Header))
+	for i, h := range t.Header {
+		header[i] = h
+	}
+	writeRow(&b, 1, header, true)
+	for i, row := range t.Rows {
+		writeRow(&b, i+2, row, false)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+func writeRow(b *strings.Builder, rowNum int, cells []interface{}, bold bool) {
+	fmt.Fprintf(b, `<row r="%d">`, rowNum)
+	style := ""
+	if bold {
+		style = ` s="1"`
+	}
+	for i, v := range cells {
+		ref := columnName(i) + strconv.Itoa(rowNum)
+		switch val := v.(type) {
+		case int, int64, float64:
+			fmt.Fprintf(b, `<c r="%s"%s><v>%s</v></c>`, ref, style, formatCell(val))
+		case bool:
+			n := 0
+			if val {
+				n = 1
+			}
+			fmt.Fprintf(b, `<c r="%s" t="b"%s><v>%d</v></c>`, ref, style, n)
+		default:
+			s := formatCell(val)
+			if s == "" {
+				continue
+			}
+			fmt.Fprintf(b, `<c r="%s" t="inlineStr"%s><is><t xml:space="preserve">%s</t></is></c>`, ref, style, xmlEscape(s))
+		}
+	}
+	b.WriteString(`</row>`)
+}
+
+// columnName converts a zero-based column index to A, B, ..., Z, AA, AB, ...
+func columnName(i int) string {
+	name := ""
+	for i >= 0 {
+		name = string(rune('A'+i%26)) + name
+		i = i/26 - 1
+	}
+	return name
+}
+
+// sheetName trims a table name to Excel's 31-character limit and strips
+// characters sheet names cannot contain.
+func sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if r := []rune(name); len(r) > 31 {
+		name = string(r[:31])
+	}
+	return name
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '&':
+			b.WriteString("&amp;")
+		case '<':
+			b.WriteString("&lt;")
+		case '>':
+			b.WriteString("&gt;")
+		case '"':
+			b.WriteString("&quot;")
+		case '\t', '\n', '\r':
+			b.WriteRune(r)
+		default:
+			// Drop control characters XML 1.0 does not allow.
+			if r < 0x20 || r == 0xFFFE || r == 0xFFFF {
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,343 @@
+// Package gdpr handles data subject requests and retention: it collects
+// everything stored about a participant, pseudonymizes the answers that
+// refer to an erased participant, and anonymizes or purges old rounds.
+package gdpr
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	mrand "math/rand/v2"
+	"sort"
+	"strings"
+	"time"
+
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/scrub"
+	"opslab-survey/internal/seed"
+	"opslab-survey/internal/store"
+)
+
+// Export is everything stored about one participant: what they submitted,
+// what the survey keeps about them, and what colleagues answered about them
+// with the colleagues left out.
+type Export struct {
+	GeneratedAt        time.Time                  `json:"generatedAt"`
+	Participant        models.Participant         `json:"participant"`
+	Responses          []models.ResponseRevision  `json:"responses"`
+	Drafts             []models.Draft             `json:"drafts"`
+	DeadlineExtensions []models.DeadlineExtension `json:"deadlineExtensions"`
+	Reminders          []models.Reminder          `json:"reminders"`
+	AboutThem          []AboutAnswer              `json:"aboutThem"`
+	RankedBy           []AboutRanking             `json:"rankedBy"`
+	Consents           []models.Consent           `json:"consents"`
+	Activity           []models.AuditEntry        `json:"activity"`
+}
+
+// AboutAnswer is one colleague's current answer to a peer question about
+// the participant. QuestionID is the template ID, without codes.
+type AboutAnswer struct {
+	RoundID    int64       `json:"roundId"`
+	QuestionID string      `json:"questionId"`
+	Value      interface{} `json:"value"`
+}
+
+// AboutRanking is the place one colleague gave the participant in a ranking.
+type AboutRanking struct {
+	RoundID   int64  `json:"roundId"`
+	Criterion string `json:"criterion"`
+	Position  int    `json:"position"`
+}
+
+// Collect gathers the export for p. Answers and rankings about p come from
+// the current responses of the other participants, test data excluded, and
+// are sorted by value so their order does not hint at who gave them.
+func Collect(ctx context.Context, st store.Store, p models.Participant, now time.Time) (*Export, error) {
+	e := &Export{
+		GeneratedAt:        now.UTC(),
+		Participant:        p,
+		Drafts:             []models.Draft{},
+		DeadlineExtensions: []models.DeadlineExtension{},
+		Reminders:          []models.Reminder{},
+		AboutThem:          []AboutAnswer{},
+		RankedBy:           []AboutRanking{},
+		Consents:           []models.Consent{},
+	}
+	var err error
+	if e.Responses, err = st.ExportRevisions(ctx, models.RevisionFilter{ParticipantCode: p.Code}); err != nil {
+		return nil, fmt.Errorf("responses: %w", err)
+	}
+	if e.Responses == nil {
+		e.Responses = []models.ResponseRevision{}
+	}
+	rounds, err := st.ListRounds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("rounds: %w", err)
+	}
+	for _, round := range rounds {
+		draft, err := st.DraftFor(ctx, round.ID, p.Code)
+		if err != nil {
+			return nil, fmt.Errorf("draft: %w", err)
+		}
+		if draft != nil {
+			e.Drafts = append(e.Drafts, *draft)
+		}
+		extensions, err := st.ListDeadlineExtensions(ctx, round.ID)
+		if err != nil {
+			return nil, fmt.Errorf("extensions: %w", err)
+		}
+		for _, ext := range extensions {
+			if ext.ParticipantCode == p.Code {
+				e.DeadlineExtensions = append(e.DeadlineExtensions, ext)
+			}
+		}
+		reminders, err := st.ListReminders(ctx, round.ID)
+		if err != nil {
+			return nil, fmt.Errorf("reminders: %w", err)
+		}
+		for _, rem := range reminders {
+			if rem.ParticipantCode == p.Code {
+				e.Reminders = append(e.Reminders, rem)
+			}
+		}
+		responses, err := st.AllResponses(ctx, round.ID)
+		if err != nil {
+			return nil, fmt.Errorf("responses about: %w", err)
+		}
+		for _, r := range responses {
+			if r.ParticipantCode == p.Code || r.IsTestData {
+				continue
+			}
+			for _, a := range r.Answers {
+				if q, peer := seed.SplitQuestionID(a.QuestionID); peer == p.Code && a.Value != nil && a.Value != "" {
+					e.AboutThem = append(e.AboutThem, AboutAnswer{RoundID: round.ID, QuestionID: q, Value: a.Value})
+				}
+			}
+			for _, rk := range r.Rankings {
+				for i, code := range rk.Order {
+					if code == p.Code {
+						e.RankedBy = append(e.RankedBy, AboutRanking{RoundID: round.ID, Criterion: rk.Criteria, Position: i + 1})
+					}
+				}
+			}
+		}
+	}
+	sort.Slice(e.AboutThem, func(i, j int) bool {
+		a, b := e.AboutThem[i], e.AboutThem[j]
+		if a.RoundID != b.RoundID {
+			return a.RoundID < b.RoundID
+		}
+		if a.QuestionID != b.QuestionID {
+			return a.QuestionID < b.QuestionID
+		}
+		return fmt.Sprint(a.Value) < fmt.Sprint(b.Value)
+	})
+	sort.Slice(e.RankedBy, func(i, j int) bool {
+		a, b := e.RankedBy[i], e.RankedBy[j]
+		if a.RoundID != b.RoundID {
+			return a.RoundID < b.RoundID
+		}
+		if a.Criterion != b.Criterion {
+			return a.Criterion < b.Criterion
+		}
+		return a.Position < b.Position
+	})
+	consents, err := st.ConsentsOf(ctx, p.Code)
+	if err != nil {
+		return nil, fmt.Errorf("consents: %w", err)
+	}
+	e.Consents = append(e.Consents, consents...)
+	if e.Activity, err = st.ListAudit(ctx, models.AuditFilter{Actor: p.Code}); err != nil {
+		return nil, fmt.Errorf("audit: %w", err)
+	}
+	if e.Activity == nil {
+		e.Activity = []models.AuditEntry{}
+	}
+	return e, nil
+}
+
+// Pseudonym returns a new random pseudonym such as "anon-3f2a9c1e".
+func Pseudonym() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return "anon-" + hex.EncodeToString(b)
+}
+
+// Refers reports whether rev answers a peer question about code or ranks
+// them.
+func Refers(rev models.ResponseRevision, code string) bool {
+	for _, c := range referenced(rev) {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// referenced lists the colleague codes a revision mentions.
+func referenced(rev models.ResponseRevision) []string {
+	var codes []string
+	for _, a := range rev.Answers {
+		if _, peer := seed.SplitQuestionID(a.QuestionID); peer != "" {
+			codes = append(codes, peer)
+		}
+	}
+	for _, rk := range rev.Rankings {
+		codes = append(codes, rk.Order...)
+		for code := range rk.PeerRankings {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// Rename returns a copy of rev with the colleague codes in names replaced:
+// in peer question IDs, ranking orders and peer ranking guesses. The
+// respondent is left alone. changed reports whether anything was renamed.
+func Rename(rev models.ResponseRevision, names map[string]string) (renamed models.ResponseRevision, changed bool) {
+	renamed = rev
+	renamed.Answers = make([]models.AnswerPayload, len(rev.Answers))
+	for i, a := range rev.Answers {
+		if q, peer := seed.SplitQuestionID(a.QuestionID); peer != "" {
+			if name, ok := names[peer]; ok {
+				a.QuestionID = q + ":" + name + strings.TrimPrefix(a.QuestionID, q+":"+peer)
+				changed = true
+			}
+		}
+		renamed.Answers[i] = a
+	}
+	renamed.Rankings = make([]models.RankingPayload, len(rev.Rankings))
+	for i, rk := range rev.Rankings {
+		order := make([]string, len(rk.Order))
+		for j, code := range rk.Order {
+			if name, ok := names[code]; ok {
+				code = name
+				changed = true
+			}
+			order[j] = code
+		}
+		rk.Order = order
+		if rk.PeerRankings != nil {
+			guesses := make(map[string]int, len(rk.PeerRankings))
+			for code, pos := range rk.PeerRankings {
+				if name, ok := names[code]; ok {
+					code = name
+					changed = true
+				}
+				guesses[code] = pos
+			}
+			rk.PeerRankings = guesses
+		}
+		renamed.Rankings[i] = rk
+	}
+	return renamed, changed
+}
+
+// Unname returns a copy of rev with the name and email of the participant
+// code replaced by the colleague token in text answers and ranking comments.
+// sc must know every participant, so that a similar name of someone else is
+// left alone. changed reports whether any text was rewritten.
+func Unname(rev models.ResponseRevision, sc *scrub.Scrubber, code string) (unnamed models.ResponseRevision, changed bool) {
+	unname := func(text string) string {
+		var matches []scrub.Match
+		for _, m := range sc.Find(text) {
+			if m.Code == code && (m.Kind == scrub.KindName || m.Kind == scrub.KindEmail) {
+				matches = append(matches, m)
+			}
+		}
+		if len(matches) == 0 {
+			return text
+		}
+		changed = true
+		return scrub.Replace(text, matches, func(scrub.Match) string { return scrub.TokenColleague })
+	}
+	unnamed = rev
+	unnamed.Answers = make([]models.AnswerPayload, len(rev.Answers))
+	for i, a := range rev.Answers {
+		if text, ok := a.Value.(string); ok {
+			a.Value = unname(text)
+		}
+		unnamed.Answers[i] = a
+	}
+	unnamed.Rankings = make([]models.RankingPayload, len(rev.Rankings))
+	for i, rk := range rev.Rankings {
+		rk.Comment = unname(rk.Comment)
+		unnamed.Rankings[i] = rk
+	}
+	return unnamed, changed
+}
+
+// Anonymize turns the revisions of a round into anonymous responses: only
+// the latest real (non-test) revision of each respondent is kept, every
+// participant code gets a pseudonym that holds only within the result, free
+// text answers and ranking comments are dropped because they cannot be
+// anonymized reliably, and the responses are shuffled.
+func Anonymize(revisions []models.ResponseRevision) []models.AnonymousResponse {
+	latest := map[string]models.ResponseRevision{}
+	for _, rev := range revisions {
+		if rev.IsTestData {
+			continue
+		}
+		if prev, ok := latest[rev.ParticipantCode]; !ok || rev.ID > prev.ID {
+			latest[rev.ParticipantCode] = rev
+		}
+	}
+
+	names := map[string]string{}
+	taken := map[string]bool{}
+	name := func(code string) {
+		if _, ok := names[code]; ok {
+			return
+		}
+		for {
+			if p := Pseudonym(); !taken[p] {
+				names[code], taken[p] = p, true
+				return
+			}
+		}
+	}
+	for code, rev := range latest {
+		name(code)
+		for _, c := range referenced(rev) {
+			name(c)
+		}
+	}
+
+	types := questionTypes()
+	res := make([]models.AnonymousResponse, 0, len(latest))
+	for code, rev := range latest {
+		renamed, _ := Rename(rev, names)
+		a := models.AnonymousResponse{
+			RoundID:    rev.RoundID,
+			Respondent: names[code],
+			Answers:    []models.AnswerPayload{},
+			Rankings:   renamed.Rankings,
+		}
+		for _, answer := range renamed.Answers {
+			key, _ := seed.SplitQuestionID(answer.QuestionID)
+			if t := types[key]; t == "scale" || t == "choice" {
+				a.Answers = append(a.Answers, answer)
+			}
+		}
+		for i := range a.Rankings {
+			a.Rankings[i].Comment = ""
+		}
+		res = append(res, a)
+	}
+	mrand.Shuffle(len(res), func(i, j int) { res[i], res[j] = res[j], res[i] })
+	return res
+}
+
+func questionTypes() map[string]string {
+	types := map[string]string{}
+	for _, q := range seed.CommonQuestions() {
+		types[q.ID] = q.Type
+	}
+	for _, t := range seed.PeerTemplates() {
+		types[t.ID] = t.Type
+	}
+	return types
+}
@@ -0,0 +1,262 @@
+package gdpr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"opslab-survey/internal/audit"
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/store"
+)
+
+// Retention actions.
+const (
+	ActionAnonymize = "anonymize"
+	ActionPurge     = "purge"
+)
+
+// Policy says how long after a round closes its responses are anonymized
+// and the round is purged. A zero duration disables that step.
+type Policy struct {
+	AnonymizeAfter time.Duration
+	PurgeAfter     time.Duration
+	Interval       time.Duration
+}
+
+// MarshalJSON writes the ages the way they are configured, such as "365d"
+// or "off".
+func (p Policy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{
+		"anonymizeAfter": formatAge(p.AnonymizeAfter),
+		"purgeAfter":     formatAge(p.PurgeAfter),
+		"interval":       p.Interval.String(),
+	})
+}
+
+// PolicyFromEnv reads RETENTION_ANONYMIZE_AFTER and RETENTION_PURGE_AFTER
+// ("365d", "8760h"; empty or "off" disables) and RETENTION_INTERVAL.
+func PolicyFromEnv() (Policy, error) {
+	p := Policy{Interval: 6 * time.Hour}
+	var err error
+	if p.AnonymizeAfter, err = parseAge(os.Getenv("RETENTION_ANONYMIZE_AFTER")); err != nil {
+		return p, fmt.Errorf("RETENTION_ANONYMIZE_AFTER: %w", err)
+	}
+	if p.PurgeAfter, err = parseAge(os.Getenv("RETENTION_PURGE_AFTER")); err != nil {
+		return p, fmt.Errorf("RETENTION_PURGE_AFTER: %w", err)
+	}
+	if v := os.Getenv("RETENTION_INTERVAL"); v != "" {
+		if p.Interval, err = time.ParseDuration(v); err != nil {
+			return p, fmt.Errorf("RETENTION_INTERVAL: %w", err)
+		}
+	}
+	return p, p.Validate()
+}
+
+// Validate rejects a purge that would come before anonymization.
+func (p Policy) Validate() error {
+	if p.AnonymizeAfter > 0 && p.PurgeAfter > 0 && p.PurgeAfter <= p.AnonymizeAfter {
+		return errors.New("retention: purge must come after anonymization")
+	}
+	if p.Interval <= 0 {
+		return errors.New("retention: interval must be positive")
+	}
+	return nil
+}
+
+// Enabled reports whether the policy does anything.
+func (p Policy) Enabled() bool {
+	return p.AnonymizeAfter > 0 || p.PurgeAfter > 0
+}
+
+// snapshotAge is how old reset snapshots may get: they hold identified
+// revisions, so they go at the first step of the policy.
+func (p Policy) snapshotAge() time.Duration {
+	if p.AnonymizeAfter > 0 {
+		return p.AnonymizeAfter
+	}
+	return p.PurgeAfter
+}
+
+// parseAge accepts Go durations plus a "d" suffix for days.
+func parseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "off" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
+
+func formatAge(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return "off"
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	return d.String()
+}
+
+// Action is one step the policy takes on a round.
+type Action struct {
+	RoundID  int64     `json:"roundId"`
+	Title    string    `json:"title"`
+	Kind     string    `json:"kind"`
+	ClosedAt time.Time `json:"closedAt"`
+}
+
+// Report is what one pass of the policy did.
+type Report struct {
+	Actions   []Action `json:"actions"`
+	Snapshots int      `json:"snapshots"`
+}
+
+// Retention applies a Policy to closed rounds.
+type Retention struct {
+	store  store.Store
+	policy Policy
+	// Now returns the current time; tests may replace it.
+	Now func() time.Time
+}
+
+func NewRetention(st store.Store, p Policy) *Retention {
+	return &Retention{store: st, policy: p, Now: time.Now}
+}
+
+func (r *Retention) Policy() Policy {
+	return r.policy
+}
+
+// Run applies the policy every Interval until ctx is cancelled.
+func (r *Retention) Run(ctx context.Context) {
+	if !r.policy.Enabled() {
+		log.Println("retention: no retention policy configured")
+		return
+	}
+	ticker := time.NewTicker(r.policy.Interval)
+	defer ticker.Stop()
+	for {
+		if rep, err := r.Apply(ctx); err != nil {
+			log.Println("retention:", err)
+		} else if len(rep.Actions) > 0 || rep.Snapshots > 0 {
+			log.Printf("retention: %d rounds, %d snapshots", len(rep.Actions), rep.Snapshots)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Plan lists what the policy would do now. Only closed and archived rounds
+// are considered; a round counts as closed at its deadline, or when it was
+// last updated if it was closed by hand before the deadline. Rounds that
+// have no revisions left are not anonymized again.
+func (r *Retention) Plan(ctx context.Context) ([]Action, error) {
+	actions := []Action{}
+	if !r.policy.Enabled() {
+		return actions, nil
+	}
+	rounds, err := r.store.ListRounds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := r.Now()
+	for _, round := range rounds {
+		if state := round.StateAt(now); state != models.RoundClosed && state != models.RoundArchived {
+			continue
+		}
+		closedAt := round.UpdatedAt
+		if round.ClosesAt != nil && !round.ClosesAt.After(now) {
+			closedAt = *round.ClosesAt
+		}
+		age := now.Sub(closedAt)
+		action := Action{RoundID: round.ID, Title: round.Title, ClosedAt: closedAt}
+		switch {
+		case r.policy.PurgeAfter > 0 && age >= r.policy.PurgeAfter:
+			action.Kind = ActionPurge
+		case r.policy.AnonymizeAfter > 0 && age >= r.policy.AnonymizeAfter:
+			revisions, err := r.store.ExportRevisions(ctx, models.RevisionFilter{RoundID: round.ID})
+			if err != nil {
+				return nil, err
+			}
+			if len(revisions) == 0 {
+				continue
+			}
+			action.Kind = ActionAnonymize
+		default:
+			continue
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+// Apply carries out the plan and deletes reset snapshots older than the
+// first step of the policy. Every step is recorded in the audit log with
+// the actor "retention".
+func (r *Retention) Apply(ctx context.Context) (*Report, error) {
+	actions, err := r.Plan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rep := &Report{Actions: []Action{}}
+	for _, a := range actions {
+		target := fmt.Sprintf("round:%d", a.RoundID)
+		switch a.Kind {
+		case ActionPurge:
+			_, err = r.store.DeleteRound(ctx, a.RoundID)
+			r.record(ctx, audit.ActionRetentionPurge, target, err)
+		case ActionAnonymize:
+			var revisions []models.ResponseRevision
+			revisions, err = r.store.ExportRevisions(ctx, models.RevisionFilter{RoundID: a.RoundID})
+			if err == nil {
+				err = r.store.AnonymizeRound(ctx, a.RoundID, Anonymize(revisions))
+			}
+			r.record(ctx, audit.ActionRetentionAnonymize, target, err)
+		}
+		if err != nil {
+			return rep, fmt.Errorf("%s round %d: %w", a.Kind, a.RoundID, err)
+		}
+		rep.Actions = append(rep.Actions, a)
+	}
+	if r.policy.Enabled() {
+		n, err := r.store.DeleteSnapshots(ctx, r.Now().Add(-r.policy.snapshotAge()))
+		if err != nil {
+			return rep, fmt.Errorf("snapshots: %w", err)
+		}
+		if n > 0 {
+			r.record(ctx, audit.ActionRetentionPurge, fmt.Sprintf("snapshots:%d", n), nil)
+		}
+		rep.Snapshots = n
+	}
+	return rep, nil
+}
+
+func (r *Retention) record(ctx context.Context, action, target string, err error) {
+	outcome := models.AuditSuccess
+	if err != nil {
+		outcome = models.AuditFailure
+	}
+	if _, err := r.store.AppendAudit(ctx, models.AuditEntry{Actor: "retention", Action: action, Target: target, Outcome: outcome}); err != nil {
+		log.Println("retention audit:", err)
+	}
+}
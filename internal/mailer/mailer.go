@@ -0,0 +1,146 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Transport delivers messages. Implementations must be safe for concurrent use.
+type Transport interface {
+	Send(ctx context.Context, msg Message) error
+	Name() string
+}
+
+// FromEnv picks a transport from MAIL_TRANSPORT (smtp, file or stdout).
+// The default is stdout so the tool works offline without any mail setup.
+func FromEnv() (Transport, error) {
+	switch strings.ToLower(os.Getenv("MAIL_TRANSPORT")) {
+	case "", "stdout":
+		return NewWriterTransport("stdout", os.Stdout), nil
+	case "file":
+		path := os.Getenv("MAIL_FILE")
+		if path == "" {
+			path = "outbox.eml"
+		}
+		return NewFileTransport(path), nil
+	case "smtp":
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required for MAIL_TRANSPORT=smtp")
+		}
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return &SMTPTransport{
+			Addr:     net.JoinHostPort(host, port),
+			Host:     host,
+			Username: os.Getenv("SMTP_USER"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown MAIL_TRANSPORT %q", os.Getenv("MAIL_TRANSPORT"))
+	}
+}
+
+// SMTPTransport sends mail through an SMTP relay using PLAIN auth.
+type SMTPTransport struct {
+	Addr     string
+	Host     string
+	Username string
+	Password string
+	From     string
+}
+
+func (t *SMTPTransport) Name() string { return "smtp" }
+
+func (t *SMTPTransport) Send(ctx context.Context, msg Message) error {
+	var auth smtp.Auth
+	if t.Username != "" {
+		auth = smtp.PlainAuth("", t.Username, t.Password, t.Host)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(t.Addr, auth, t.From, []string{msg.To}, render(t.From, msg))
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WriterTransport writes messages to an io.Writer instead of delivering them.
+type WriterTransport struct {
+	name string
+	mu   sync.Mutex
+	w    io.Writer
+}
+
+func NewWriterTransport(name string, w io.Writer) *WriterTransport {
+	return &WriterTransport{name: name, w: w}
+}
+
+func (t *WriterTransport) Name() string { return t.name }
+
+func (t *WriterTransport) Send(_ context.Context, msg Message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, err := fmt.Fprintf(t.w, "%s\n", render("opslab-survey", msg))
+	return err
+}
+
+// FileTransport appends messages to a file, one RFC 822 message after another.
+type FileTransport struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileTransport(path string) *FileTransport {
+	return &FileTransport{path: path}
+}
+
+func (t *FileTransport) Name() string { return "file" }
+
+func (t *FileTransport) Send(_ context.Context, msg Message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f, err := os.OpenFile(t.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "%s\n", render("opslab-survey", msg))
+	return err
+}
+
+func render(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
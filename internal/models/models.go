@@ -1,6 +1,12 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -48,4 +54,548 @@ type ResponseRecord struct {
 	SubmittedAt     time.Time              `json:"submittedAt"`
 	UpdatedAt       time.Time              `json:"updatedAt"`
 	IsTestData      bool                   `json:"isTestData"`
+	RoundID         int64                  `json:"roundId"`
+}
+
+// ResponseRevision is one immutable snapshot of a participant's submission.
+type ResponseRevision struct {
+	ID              int64            `json:"id"`
+	ParticipantCode string           `json:"participantCode"`
+	Answers         []AnswerPayload  `json:"answers"`
+	Rankings        []RankingPayload `json:"rankings"`
+	IsTestData      bool             `json:"isTestData"`
+	SessionID       string           `json:"sessionId"`
+	SubmittedAt     time.Time        `json:"submittedAt"`
+	RoundID         int64            `json:"roundId"`
+}
+
+// Response listing sort orders.
+const (
+	SortNewest = "newest"
+	SortOldest = "oldest"
+)
+
+// ResponseFilter selects a page of current responses. Zero values mean no
+// restriction: RoundID 0 lists every round, nil IsTest includes test data.
+// From and To bound SubmittedAt, To being exclusive.
+type ResponseFilter struct {
+	RoundID int64
+	IsTest  *bool
+	From    *time.Time
+	To      *time.Time
+	Sort    string
+	Limit   int
+	After   *ResponseCursor
+}
+
+// ResponsePage is one page of a response listing. NextCursor is empty on
+// the last page.
+type ResponsePage struct {
+	Items      []ResponseRecord `json:"items"`
+	NextCursor string           `json:"nextCursor,omitempty"`
+}
+
+// ResponseCursor points at the last response of a page. Listings are keyed
+// by (SubmittedAt, ID), so pages stay stable while new responses arrive.
+type ResponseCursor struct {
+	SubmittedAt time.Time
+	ID          int64
+}
+
+// CursorAfter returns the cursor that continues a listing after r.
+func CursorAfter(r ResponseRecord) *ResponseCursor {
+	return &ResponseCursor{SubmittedAt: r.SubmittedAt, ID: r.ID}
+}
+
+// Encode returns the opaque string form handed to API clients.
+func (c ResponseCursor) Encode() string {
+	raw := strconv.FormatInt(c.SubmittedAt.UnixMicro(), 10) + "." + strconv.FormatInt(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseResponseCursor decodes a cursor produced by Encode.
+func ParseResponseCursor(s string) (*ResponseCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	at, id, ok := strings.Cut(string(raw), ".")
+	if !ok {
+		return nil, errors.New("invalid cursor")
+	}
+	micros, err := strconv.ParseInt(at, 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	c := ResponseCursor{SubmittedAt: time.UnixMicro(micros).UTC()}
+	if c.ID, err = strconv.ParseInt(id, 10, 64); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	return &c, nil
+}
+
+// AnswerRow is one answer in the normalized answers table. Peer questions
+// are keyed by their template ID with the colleague in PeerCode; exactly one
+// of the value fields is set, depending on the question type.
+type AnswerRow struct {
+	QuestionID   string   `json:"questionId"`
+	PeerCode     string   `json:"peerCode"`
+	NumericValue *float64 `json:"numericValue,omitempty"`
+	TextValue    *string  `json:"textValue,omitempty"`
+	ChoiceValue  *string  `json:"choiceValue,omitempty"`
+}
+
+// ValueCount is how many current responses of a round gave a numeric
+// answer Value to a question, about PeerCode for peer questions.
+type ValueCount struct {
+	QuestionID string
+	PeerCode   string
+	Value      float64
+	Count      int
+}
+
+// RankingPosition places one colleague within a ranking criterion, 1 being
+// the top of the list.
+type RankingPosition struct {
+	Criterion string `json:"criterion"`
+	Ratee     string `json:"ratee"`
+	Position  int    `json:"position"`
+}
+
+// Round states. A round moves draft -> open -> closed -> archived; the
+// schedule can open or close it without an explicit state change.
+const (
+	RoundDraft    = "draft"
+	RoundOpen     = "open"
+	RoundClosed   = "closed"
+	RoundArchived = "archived"
+)
+
+// Round is one survey cycle with its own schedule and set of responses.
+type Round struct {
+	ID        int64      `json:"id"`
+	Title     string     `json:"title"`
+	State     string     `json:"state"`
+	OpensAt   *time.Time `json:"opensAt"`
+	ClosesAt  *time.Time `json:"closesAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// StateAt resolves the stored state against the schedule at the given moment.
+func (r Round) StateAt(now time.Time) string {
+	switch r.State {
+	case RoundArchived, RoundClosed:
+		return r.State
+	case RoundDraft:
+		if r.OpensAt == nil || now.Before(*r.OpensAt) {
+			return RoundDraft
+		}
+	case RoundOpen:
+		if r.OpensAt != nil && now.Before(*r.OpensAt) {
+			return RoundDraft
+		}
+	}
+	if r.ClosesAt != nil && !now.Before(*r.ClosesAt) {
+		return RoundClosed
+	}
+	return RoundOpen
+}
+
+// WindowFor applies a participant's personal extension on top of the round
+// schedule. It returns the deadline that applies to them, whether the
+// extension was used and the effective state for that participant.
+func (r Round) WindowFor(extension *time.Time, now time.Time) (deadline *time.Time, extended bool, state string) {
+	state = r.StateAt(now)
+	deadline = r.ClosesAt
+	if extension != nil && (state == RoundClosed || (r.ClosesAt != nil && extension.After(*r.ClosesAt))) {
+		deadline = extension
+		extended = true
+		if state == RoundClosed && now.Before(*extension) {
+			state = RoundOpen
+		}
+	}
+	return deadline, extended, state
+}
+
+// CurrentRound picks the round participants and the dashboard work with
+// from rounds that are not archived: the one open by schedule now (the
+// latest to open if several are), otherwise the newest round that is no
+// longer a draft, so that preparing the next round does not take over the
+// current one. A draft is returned only when there is nothing else.
+func CurrentRound(rounds []Round, now time.Time) *Round {
+	var open, started, draft *Round
+	for i := range rounds {
+		r := &rounds[i]
+		switch r.StateAt(now) {
+		case RoundArchived:
+		case RoundOpen:
+			if open == nil || r.openedAt().After(open.openedAt()) || (r.openedAt().Equal(open.openedAt()) && r.ID > open.ID) {
+				open = r
+			}
+		case RoundDraft:
+			if draft == nil || r.ID > draft.ID {
+				draft = r
+			}
+		default:
+			if started == nil || r.ID > started.ID {
+				started = r
+			}
+		}
+	}
+	for _, r := range []*Round{open, started, draft} {
+		if r != nil {
+			out := *r
+			return &out
+		}
+	}
+	return nil
+}
+
+// openedAt is when the round opened: its scheduled opening, or its creation
+// when it was opened by hand.
+func (r Round) openedAt() time.Time {
+	if r.OpensAt != nil {
+		return *r.OpensAt
+	}
+	return r.CreatedAt
+}
+
+// ValidRoundState reports whether s is one of the known round states.
+func ValidRoundState(s string) bool {
+	switch s {
+	case RoundDraft, RoundOpen, RoundClosed, RoundArchived:
+		return true
+	}
+	return false
+}
+
+// DeadlineExtension lets a single participant submit after the round closes.
+type DeadlineExtension struct {
+	RoundID         int64     `json:"roundId"`
+	ParticipantCode string    `json:"participantCode"`
+	ClosesAt        time.Time `json:"closesAt"`
+	Reason          string    `json:"reason"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+// ReminderHistory sums up the attempts to deliver one reminder.
+type ReminderHistory struct {
+	Sent        bool
+	Failures    int
+	LastFailure time.Time
+}
+
+// Reminder records one reminder email sent (or attempted) to a participant.
+type Reminder struct {
+	ID              int64     `json:"id"`
+	RoundID         int64     `json:"roundId"`
+	ParticipantCode string    `json:"participantCode"`
+	Kind            string    `json:"kind"` // auto:<offset> or manual
+	Transport       string    `json:"transport"`
+	Status          string    `json:"status"` // sent or failed
+	Error           string    `json:"error,omitempty"`
+	SentAt          time.Time `json:"sentAt"`
+}
+
+// WebhookEndpoint is an outbound subscriber for survey events.
+type WebhookEndpoint struct {
+	ID        int64     `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events"` // empty means every event
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// WebhookJob is a pending outbox entry joined with its endpoint.
+type WebhookJob struct {
+	ID         int64
+	EndpointID int64
+	URL        string
+	Secret     string
+	Event      string
+	Payload    []byte
+	Attempts   int
+}
+
+// WebhookDelivery logs one HTTP attempt to deliver an outbox entry.
+type WebhookDelivery struct {
+	ID         int64     `json:"id"`
+	OutboxID   int64     `json:"outboxId"`
+	EndpointID int64     `json:"endpointId"`
+	Event      string    `json:"event"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"statusCode"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+	Outcome    string    `json:"outcome"` // delivered, retrying, failed
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// Draft is a participant's unsubmitted progress, autosaved from the UI.
+type Draft struct {
+	RoundID         int64            `json:"roundId"`
+	ParticipantCode string           `json:"participantCode"`
+	Answers         []AnswerPayload  `json:"answers"`
+	Rankings        []RankingPayload `json:"rankings"`
+	Progress        int              `json:"progress"` // percent of questions answered
+	UpdatedAt       time.Time        `json:"updatedAt"`
+}
+
+// Audit outcomes.
+const (
+	AuditSuccess = "success"
+	AuditDenied  = "denied"  // rejected by authentication or authorization
+	AuditFailure = "failure" // bad request or server error
+)
+
+// AuditEntry is one line of the append-only audit log. Hash covers the
+// entry's fields and PrevHash, chaining every entry to the one before it.
+type AuditEntry struct {
+	ID         int64     `json:"id"`
+	OccurredAt time.Time `json:"occurredAt"`
+	Actor      string    `json:"actor"` // participant code, empty when not signed in
+	Action     string    `json:"action"`
+	Target     string    `json:"target"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"userAgent"`
+	Outcome    string    `json:"outcome"`
+	Status     int       `json:"status"` // HTTP status of the response
+	PrevHash   string    `json:"prevHash"`
+	Hash       string    `json:"hash"`
+}
+
+// AuditFilter selects audit entries, newest first. Empty fields match
+// everything; an Action ending in "." matches every action with that prefix.
+// BeforeID continues a listing below the last ID of the previous page.
+type AuditFilter struct {
+	Actor    string
+	Action   string
+	Target   string
+	Outcome  string
+	From     *time.Time
+	To       *time.Time
+	BeforeID int64
+	Limit    int
+}
+
+// RevisionFilter selects stored revisions; zero values match everything.
+// UpToID keeps revisions with IDs at or below it.
+type RevisionFilter struct {
+	RoundID         int64
+	ParticipantCode string
+	TestOnly        bool
+	UpToID          int64
+}
+
+// Match reports whether rev passes the filter.
+func (f RevisionFilter) Match(rev ResponseRevision) bool {
+	switch {
+	case f.RoundID != 0 && rev.RoundID != f.RoundID,
+		f.ParticipantCode != "" && rev.ParticipantCode != f.ParticipantCode,
+		f.TestOnly && !rev.IsTestData,
+		f.UpToID != 0 && rev.ID > f.UpToID:
+		return false
+	}
+	return true
+}
+
+// Reset scopes.
+const (
+	ResetAll         = "all"
+	ResetTest        = "test"
+	ResetRound       = "round"
+	ResetParticipant = "participant"
+)
+
+// ResetScope says which submissions a reset deletes. RoundID is required for
+// the round scope and narrows the test and participant scopes to one round.
+type ResetScope struct {
+	Kind            string `json:"kind"`
+	RoundID         int64  `json:"roundId,omitempty"`
+	ParticipantCode string `json:"participantCode,omitempty"`
+}
+
+// Validate checks that the scope names everything its kind needs.
+func (s ResetScope) Validate() error {
+	switch s.Kind {
+	case ResetAll:
+		if s.RoundID != 0 || s.ParticipantCode != "" {
+			return errors.New("scope all takes no round or participant")
+		}
+	case ResetTest:
+		if s.ParticipantCode != "" {
+			return errors.New("scope test takes no participant")
+		}
+	case ResetRound:
+		if s.RoundID == 0 || s.ParticipantCode != "" {
+			return errors.New("scope round needs roundId and no participant")
+		}
+	case ResetParticipant:
+		if s.ParticipantCode == "" {
+			return errors.New("scope participant needs participantCode")
+		}
+	default:
+		return errors.New("scope kind must be all, test, round or participant")
+	}
+	return nil
+}
+
+// Filter returns the revisions the scope covers.
+func (s ResetScope) Filter() RevisionFilter {
+	return RevisionFilter{RoundID: s.RoundID, ParticipantCode: s.ParticipantCode, TestOnly: s.Kind == ResetTest}
+}
+
+// String describes the scope for logs, e.g. "participant:1425@round:2".
+func (s ResetScope) String() string {
+	var desc string
+	switch s.Kind {
+	case ResetParticipant:
+		desc = "participant:" + s.ParticipantCode
+	case ResetRound:
+		return "round:" + strconv.FormatInt(s.RoundID, 10)
+	default:
+		desc = "scope:" + s.Kind
+	}
+	if s.RoundID != 0 {
+		desc += "@round:" + strconv.FormatInt(s.RoundID, 10)
+	}
+	return desc
+}
+
+// Snapshot is a compressed copy of the revisions a reset deleted. Data holds
+// gzipped JSON of the revisions and is only loaded for restores.
+type Snapshot struct {
+	ID         int64      `json:"id"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	CreatedBy  string     `json:"createdBy"`
+	Scope      ResetScope `json:"scope"`
+	Revisions  int        `json:"revisions"`
+	Size       int        `json:"size"` // compressed bytes
+	RestoredAt *time.Time `json:"restoredAt"`
+	Data       []byte     `json:"-"`
+}
+
+// Dataset is the portable content of a backup: everything needed to
+// recreate the survey results in another environment.
+type Dataset struct {
+	Participants []Participant
+	Questions    []Question
+	Rounds       []Round
+	Revisions    []ResponseRevision
+	// Anonymous holds what retention reduced anonymized rounds to.
+	Anonymous []AnonymousResponse
+	// Consents record on what notice the answers were given.
+	Consents []Consent
+}
+
+// Restore modes.
+const (
+	// RestoreMerge adds the rows of a backup that are missing and keeps
+	// existing rows with the same ID untouched. A round or revision whose ID
+	// is taken by a different one fails the merge with ErrRestoreConflict.
+	RestoreMerge = "merge"
+	// RestoreReplace drops all rounds, and with them every response,
+	// draft, extension and reminder, before loading the backup.
+	RestoreReplace = "replace"
+)
+
+// ErrRestoreConflict is returned when a backup has a round or revision
+// whose ID is taken by a different one: a round created at another time, or
+// a revision of another round, participant or submission time. Merging it
+// would attach the backup's responses to the wrong round or drop them.
+var ErrRestoreConflict = errors.New("backup conflicts with stored data")
+
+// RestoreResult counts what a restore wrote.
+type RestoreResult struct {
+	Mode             string `json:"mode"`
+	Participants     int    `json:"participants"`
+	Rounds           int    `json:"rounds"`
+	Revisions        int    `json:"revisions"`
+	SkippedRounds    int    `json:"skippedRounds"`
+	SkippedRevisions int    `json:"skippedRevisions"`
+	// Anonymous counts the anonymous responses of the restored rounds;
+	// those of skipped rounds are kept as stored.
+	Anonymous int `json:"anonymous"`
+	// Consents counts the acceptances added; those already stored are kept.
+	Consents int `json:"consents"`
+}
+
+// RotationResult counts the values a key rotation re-encrypted.
+// Rankings counts the rankings of revisions and drafts, which are sealed
+// separately from their answers.
+type RotationResult struct {
+	KeyID     string `json:"keyId"`
+	Revisions int    `json:"revisions"`
+	Rankings  int    `json:"rankings"`
+	Drafts    int    `json:"drafts"`
+	Snapshots int    `json:"snapshots"`
+}
+
+// Erasure is what EraseParticipant writes: the participant whose authored
+// data is deleted, and the revisions and snapshots of others that referred
+// to them, already pseudonymized.
+type Erasure struct {
+	ParticipantCode string
+	Revisions       []ResponseRevision
+	// Snapshots carry new Data and Revisions counts.
+	Snapshots []Snapshot
+}
+
+// ErasureResult counts what an erasure deleted and rewrote.
+type ErasureResult struct {
+	Revisions     int `json:"revisions"`
+	Drafts        int `json:"drafts"`
+	Extensions    int `json:"extensions"`
+	Reminders     int `json:"reminders"`
+	Pseudonymized int `json:"pseudonymized"`
+	Snapshots     int `json:"snapshots"`
+}
+
+// AnonymousResponse is a response of an anonymized round. Respondent and
+// every colleague code are pseudonyms that only hold within the round, and
+// nothing links them back to participants.
+type AnonymousResponse struct {
+	RoundID    int64            `json:"roundId"`
+	Respondent string           `json:"respondent"`
+	Answers    []AnswerPayload  `json:"answers"`
+	Rankings   []RankingPayload `json:"rankings"`
+}
+
+// ConsentNotice is the privacy notice participants accept before they see
+// the survey. Version follows the text: a changed text gets the next version
+// (see store.Consents.NoticeVersion), which everyone is asked to accept again.
+type ConsentNotice struct {
+	Version  int              `json:"version"`
+	Title    string           `json:"title"`
+	Sections []ConsentSection `json:"sections"`
+}
+
+// Digest is the hex SHA-256 of the notice text, its version aside.
+func (n ConsentNotice) Digest() string {
+	h := sha256.New()
+	write := func(s string) {
+		h.Write([]byte(strconv.Itoa(len(s))))
+		h.Write([]byte{':'})
+		h.Write([]byte(s))
+	}
+	write(n.Title)
+	for _, s := range n.Sections {
+		write(s.Heading)
+		write(s.Text)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type ConsentSection struct {
+	Heading string `json:"heading"`
+	Text    string `json:"text"`
+}
+
+// Consent records that a participant accepted a version of the notice.
+type Consent struct {
+	ParticipantCode string    `json:"participantCode"`
+	Version         int       `json:"version"`
+	AcceptedAt      time.Time `json:"acceptedAt"`
 }
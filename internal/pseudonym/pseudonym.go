@@ -0,0 +1,164 @@
+// Package pseudonym replaces participant codes and names with identifiers
+// that are stable within a round but cannot be linked across rounds or back
+// to people without the key, for analysts who must not see who is who.
+package pseudonym
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/scrub"
+	"opslab-survey/internal/seed"
+)
+
+// KeySize is the minimum length of a pseudonym key in bytes.
+const KeySize = 32
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// KeyFromEnv reads PSEUDONYM_KEY (base64, at least 32 bytes). Without it the
+// key is derived from SESSION_SECRET, and without that a random key is used,
+// so pseudonyms change on every restart.
+func KeyFromEnv() ([]byte, error) {
+	if raw := os.Getenv("PSEUDONYM_KEY"); raw != "" {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("PSEUDONYM_KEY: %w", err)
+		}
+		if len(key) < KeySize {
+			return nil, fmt.Errorf("PSEUDONYM_KEY: need at least %d bytes, got %d", KeySize, len(key))
+		}
+		return key, nil
+	}
+	if secret := os.Getenv("SESSION_SECRET"); secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte("opslab pseudonyms"))
+		return mac.Sum(nil), nil
+	}
+	log.Println("pseudonym: no PSEUDONYM_KEY or SESSION_SECRET; pseudonyms change on restart")
+	return randomKey(), nil
+}
+
+func randomKey() []byte {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// Mapper hands out per-round pseudonymizers.
+type Mapper struct {
+	key []byte
+}
+
+// New returns a mapper keyed with key (see KeyFromEnv); a nil key picks a
+// random one.
+func New(key []byte) *Mapper {
+	if key == nil {
+		key = randomKey()
+	}
+	return &Mapper{key: key}
+}
+
+// Round returns the pseudonymizer of a round. participants are the people
+// whose names are scrubbed from free text.
+func (m *Mapper) Round(roundID int64, participants []models.Participant) *Round {
+	r := &Round{key: m.key, roundID: roundID, scrubber: scrub.New(participants)}
+	r.participants = make([]models.Participant, len(participants))
+	for i, p := range participants {
+		code := r.Code(p.Code)
+		r.participants[i] = models.Participant{Code: code, Name: code, IsAdmin: p.IsAdmin}
+	}
+	// Seed order would give the people away.
+	sort.Slice(r.participants, func(i, j int) bool { return r.participants[i].Code < r.participants[j].Code })
+	return r
+}
+
+// Round pseudonymizes the data of one round.
+type Round struct {
+	key          []byte
+	roundID      int64
+	participants []models.Participant
+	scrubber     *scrub.Scrubber
+}
+
+// Code returns the pseudonym of a participant code, such as "p-k3x7q2ma":
+// the keyed HMAC of the round and the code, so it is the same every time
+// within a round and different in every other round.
+func (r *Round) Code(code string) string {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(strconv.FormatInt(r.roundID, 10) + "/" + code))
+	return "p-" + strings.ToLower(encoding.EncodeToString(mac.Sum(nil))[:8])
+}
+
+// Participants returns the participants with pseudonyms for codes and names,
+// no emails, sorted by pseudonym.
+func (r *Round) Participants() []models.Participant {
+	return append([]models.Participant(nil), r.participants...)
+}
+
+// Responses returns pseudonymized copies of the responses: respondents,
+// colleagues in peer questions, rankings and ranking guesses get their
+// pseudonyms, names are scrubbed from text answers and ranking comments,
+// and revision IDs are dropped because they lead back to the originals.
+func (r *Round) Responses(responses []models.ResponseRecord) []models.ResponseRecord {
+	res := make([]models.ResponseRecord, len(responses))
+	for i, resp := range responses {
+		resp.ID = 0
+		resp.ParticipantCode = r.Code(resp.ParticipantCode)
+		answers := make([]models.AnswerPayload, len(resp.Answers))
+		for j, a := range resp.Answers {
+			if q, peer := seed.SplitQuestionID(a.QuestionID); peer != "" {
+				a.QuestionID = q + ":" + r.Code(peer) + strings.TrimPrefix(a.QuestionID, q+":"+peer)
+			}
+			if text, ok := a.Value.(string); ok {
+				a.Value = r.Text(text)
+			}
+			answers[j] = a
+		}
+		resp.Answers = answers
+		rankings := make([]models.RankingPayload, len(resp.Rankings))
+		for j, rk := range resp.Rankings {
+			order := make([]string, len(rk.Order))
+			for k, code := range rk.Order {
+				order[k] = r.Code(code)
+			}
+			rk.Order = order
+			if rk.PeerRankings != nil {
+				guesses := make(map[string]int, len(rk.PeerRankings))
+				for code, pos := range rk.PeerRankings {
+					guesses[r.Code(code)] = pos
+				}
+				rk.PeerRankings = guesses
+			}
+			rk.Comment = r.Text(rk.Comment)
+			rankings[j] = rk
+		}
+		resp.Rankings = rankings
+		res[i] = resp
+	}
+	return res
+}
+
+// Text replaces the names of participants, in any case form or
+// transliteration, with their pseudonyms, and emails and phone numbers with
+// the tokens of package scrub; a full name becomes a single pseudonym.
+func (r *Round) Text(s string) string {
+	return scrub.Replace(s, r.scrubber.Find(s), func(m scrub.Match) string {
+		if m.Kind == scrub.KindName {
+			return r.Code(m.Code)
+		}
+		return r.scrubber.Token(m, scrub.Context{})
+	})
+}
@@ -0,0 +1,286 @@
+package reminder
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"opslab-survey/internal/mailer"
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/store"
+)
+
+// Config controls when automatic reminders go out.
+type Config struct {
+	// Offsets before a participant's deadline at which a reminder is due,
+	// e.g. 72h and 24h. Empty disables automatic reminders.
+	Offsets  []time.Duration
+	Interval time.Duration
+	Lang     string // uk or en
+	BaseURL  string
+}
+
+// ConfigFromEnv reads REMINDER_OFFSETS ("3d,1d", "off"), REMINDER_INTERVAL,
+// REMINDER_LANG and APP_URL.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Interval: 15 * time.Minute,
+		Lang:     "uk",
+		BaseURL:  os.Getenv("APP_URL"),
+	}
+	raw := os.Getenv("REMINDER_OFFSETS")
+	if raw == "" {
+		raw = "3d,1d"
+	}
+	if raw != "off" {
+		for _, part := range strings.Split(raw, ",") {
+			d, err := parseOffset(strings.TrimSpace(part))
+			if err != nil {
+				return cfg, fmt.Errorf("REMINDER_OFFSETS: %w", err)
+			}
+			cfg.Offsets = append(cfg.Offsets, d)
+		}
+	}
+	if v := os.Getenv("REMINDER_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return cfg, fmt.Errorf("REMINDER_INTERVAL: %w", err)
+		}
+		cfg.Interval = d
+	}
+	if v := os.Getenv("REMINDER_LANG"); v != "" {
+		if _, ok := templates[v]; !ok {
+			return cfg, fmt.Errorf("REMINDER_LANG: unsupported language %q", v)
+		}
+		cfg.Lang = v
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "http://localhost:8080"
+	}
+	return cfg, nil
+}
+
+// parseOffset accepts Go durations plus a "d" suffix for days.
+func parseOffset(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid offset %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid offset %q", s)
+	}
+	return d, nil
+}
+
+// MaxAttempts is how many times an automatic reminder is tried before the
+// scheduler gives up on it; failed attempts are spaced out, see retryDelay.
+const MaxAttempts = 5
+
+// Scheduler emails participants who have not submitted in the current round.
+type Scheduler struct {
+	store     store.Store
+	transport mailer.Transport
+	cfg       Config
+	// Now returns the current time; tests may replace it.
+	Now func() time.Time
+}
+
+func NewScheduler(st store.Store, transport mailer.Transport, cfg Config) *Scheduler {
+	offsets := append([]time.Duration(nil), cfg.Offsets...)
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	cfg.Offsets = offsets
+	return &Scheduler{store: st, transport: transport, cfg: cfg, Now: time.Now}
+}
+
+// Run checks for due reminders every Interval until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	if len(s.cfg.Offsets) == 0 {
+		log.Println("reminders: automatic reminders disabled")
+		return
+	}
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.Tick(ctx); err != nil {
+			log.Println("reminders:", err)
+		} else if n > 0 {
+			log.Printf("reminders: sent %d", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick sends every automatic reminder that is due now and returns how many
+// were delivered. For each pending participant only the closest offset is
+// considered, so a scheduler that was down does not send a burst of stale
+// reminders. A reminder that failed is retried with growing delays, at most
+// MaxAttempts times, so a broken transport does not resend and log every
+// reminder on every tick.
+func (s *Scheduler) Tick(ctx context.Context) (int, error) {
+	round, err := s.store.CurrentRound(ctx)
+	if err != nil || round == nil {
+		return 0, err
+	}
+	pending, err := s.store.PendingParticipants(ctx, round.ID)
+	if err != nil {
+		return 0, err
+	}
+	now := s.Now()
+	sent := 0
+	for _, p := range pending {
+		extension, err := s.store.DeadlineExtension(ctx, round.ID, p.Code)
+		if err != nil {
+			return sent, err
+		}
+		deadline, _, state := round.WindowFor(extension, now)
+		if state != models.RoundOpen || deadline == nil {
+			continue
+		}
+		left := deadline.Sub(now)
+		due := -1
+		for i, offset := range s.cfg.Offsets {
+			if left <= offset {
+				due = i
+				break
+			}
+		}
+		if due < 0 {
+			continue
+		}
+		kind := "auto:" + formatOffset(s.cfg.Offsets[due])
+		history, err := s.store.ReminderHistory(ctx, round.ID, p.Code, kind)
+		if err != nil {
+			return sent, err
+		}
+		if history.Sent || history.Failures >= MaxAttempts ||
+			(history.Failures > 0 && now.Before(history.LastFailure.Add(s.retryDelay(history.Failures)))) {
+			continue
+		}
+		rem := s.deliver(ctx, *round, p, deadline, kind)
+		if rem.Status == "sent" {
+			sent++
+		} else if history.Failures+1 == MaxAttempts {
+			log.Printf("reminders: giving up on %s reminder to %s after %d attempts", kind, p.Code, MaxAttempts)
+		}
+	}
+	return sent, nil
+}
+
+// retryDelay is how long to wait after the given number of failed attempts:
+// one interval after the first, doubling after each next one.
+func (s *Scheduler) retryDelay(failures int) time.Duration {
+	return s.cfg.Interval << (failures - 1)
+}
+
+// Nudge immediately reminds pending participants, optionally limited to codes.
+func (s *Scheduler) Nudge(ctx context.Context, codes []string) ([]models.Reminder, error) {
+	round, err := s.store.CurrentRound(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if round == nil {
+		return nil, fmt.Errorf("no active round")
+	}
+	pending, err := s.store.PendingParticipants(ctx, round.ID)
+	if err != nil {
+		return nil, err
+	}
+	only := map[string]bool{}
+	for _, c := range codes {
+		only[c] = true
+	}
+	now := s.Now()
+	results := []models.Reminder{}
+	for _, p := range pending {
+		if len(only) > 0 && !only[p.Code] {
+			continue
+		}
+		extension, err := s.store.DeadlineExtension(ctx, round.ID, p.Code)
+		if err != nil {
+			return results, err
+		}
+		deadline, _, _ := round.WindowFor(extension, now)
+		results = append(results, s.deliver(ctx, *round, p, deadline, "manual"))
+	}
+	return results, nil
+}
+
+// deliver renders and sends one reminder and records the outcome.
+func (s *Scheduler) deliver(ctx context.Context, round models.Round, p models.Participant, deadline *time.Time, kind string) models.Reminder {
+	rem := models.Reminder{
+		RoundID:         round.ID,
+		ParticipantCode: p.Code,
+		Kind:            kind,
+		Transport:       s.transport.Name(),
+		Status:          "sent",
+		SentAt:          s.Now(),
+	}
+	data := messageData{
+		Name:       firstName(p.Name),
+		RoundTitle: round.Title,
+		URL:        s.cfg.BaseURL,
+		Manual:     kind == "manual",
+	}
+	if deadline != nil {
+		data.Deadline = deadline.Format("02.01.2006 15:04 MST")
+		data.TimeLeft = humanize(deadline.Sub(s.Now()), s.cfg.Lang)
+	}
+	subject, body, err := renderMessage(s.cfg.Lang, data)
+	if err == nil {
+		err = s.transport.Send(ctx, mailer.Message{To: p.Email, Subject: subject, Body: body})
+	}
+	if err != nil {
+		rem.Status = "failed"
+		rem.Error = err.Error()
+		log.Printf("reminder to %s: %v", p.Code, err)
+	}
+	if err := s.store.RecordReminder(ctx, rem); err != nil {
+		log.Printf("record reminder for %s: %v", p.Code, err)
+	}
+	return rem
+}
+
+func firstName(full string) string {
+	if first, _, ok := strings.Cut(strings.TrimSpace(full), " "); ok {
+		return first
+	}
+	return full
+}
+
+func formatOffset(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	return d.String()
+}
+
+func humanize(d time.Duration, lang string) string {
+	if d < 0 {
+		d = 0
+	}
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	if lang == "en" {
+		if days > 0 {
+			return fmt.Sprintf("%d d %d h", days, hours)
+		}
+		return fmt.Sprintf("%d h", hours)
+	}
+	if days > 0 {
+		return fmt.Sprintf("%d дн. %d год.", days, hours)
+	}
+	return fmt.Sprintf("%d год.", hours)
+}
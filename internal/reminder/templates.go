@@ -0,0 +1,71 @@
+package reminder
+
+import (
+	"strings"
+	"text/template"
+)
+
+// messageData is what reminder templates can reference.
+type messageData struct {
+	Name       string
+	RoundTitle string
+	Deadline   string
+	TimeLeft   string
+	URL        string
+	Manual     bool
+}
+
+type messageTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+var templates = map[string]messageTemplate{
+	"uk": {
+		subject: template.Must(template.New("subject").Parse(
+			`{{if .Deadline}}Нагадування: {{.RoundTitle}} — залишилось {{.TimeLeft}}{{else}}Нагадування: {{.RoundTitle}}{{end}}`)),
+		body: template.Must(template.New("body").Parse(`Вітаємо, {{.Name}}!
+
+{{if .Manual}}Нагадуємо, що ваша анкета у «{{.RoundTitle}}» ще не заповнена.{{else}}Ви ще не заповнили анкету «{{.RoundTitle}}».{{end}}
+{{- if .Deadline}}
+Відповіді приймаються до {{.Deadline}} (залишилось {{.TimeLeft}}).{{end}}
+
+Заповнити анкету: {{.URL}}
+Для входу використовуйте свою робочу пошту та персональний код.
+
+Дякуємо!
+Команда OPSLAB
+`)),
+	},
+	"en": {
+		subject: template.Must(template.New("subject").Parse(
+			`{{if .Deadline}}Reminder: {{.RoundTitle}} — {{.TimeLeft}} left{{else}}Reminder: {{.RoundTitle}}{{end}}`)),
+		body: template.Must(template.New("body").Parse(`Hi {{.Name}},
+
+{{if .Manual}}A friendly nudge: your "{{.RoundTitle}}" survey is still waiting for you.{{else}}You have not completed the "{{.RoundTitle}}" survey yet.{{end}}
+{{- if .Deadline}}
+Responses are accepted until {{.Deadline}} ({{.TimeLeft}} left).{{end}}
+
+Open the survey: {{.URL}}
+Sign in with your work email and personal code.
+
+Thank you!
+The OPSLAB team
+`)),
+	},
+}
+
+func renderMessage(lang string, data messageData) (subject, body string, err error) {
+	tpl, ok := templates[lang]
+	if !ok {
+		tpl = templates["uk"]
+	}
+	var sb, bb strings.Builder
+	if err := tpl.subject.Execute(&sb, data); err != nil {
+		return "", "", err
+	}
+	if err := tpl.body.Execute(&bb, data); err != nil {
+		return "", "", err
+	}
+	return sb.String(), bb.String(), nil
+}
@@ -0,0 +1,148 @@
+package scrub
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// nameEndings are the case endings a Ukrainian first name or surname can
+// take after its stem: Катерин-а/-и/-і/-у/-ою/-о, Марі-я/-ї/-ю/-єю/-є,
+// Михайл-о/-а/-ові/-ом/-е, Камінськ-ий/-ого/-ому/-им/-ім, Петухов-а/-ої/-ій.
+// The set is shared by all names: matching a form a name does not actually
+// take costs nothing, missing one leaks a name.
+var nameEndings = map[string]bool{
+	"": true, "а": true, "я": true, "и": true, "і": true, "ї": true, "у": true, "ю": true,
+	"о": true, "е": true, "є": true, "ою": true, "ею": true, "єю": true, "ої": true, "ій": true,
+	"ом": true, "ем": true, "єм": true, "ові": true, "еві": true, "єві": true, "ий": true,
+	"ого": true, "ому": true, "им": true, "ім": true, "ая": true, "ой": true,
+}
+
+// stemSuffixes are stripped from a name to get its stem, longest first.
+var stemSuffixes = []string{"ий", "ій", "а", "я", "о", "й", "ь", "е"}
+
+// alternations are the consonants that change before some endings:
+// Вероніка → Вероніці, Ольга → Ользі, Іващук → Іващуче, Олег → Олеже.
+var alternations = map[rune][]struct {
+	to      string
+	endings []string
+}{
+	'к': {{"ц", []string{"і"}}, {"ч", []string{"е"}}},
+	'г': {{"з", []string{"і"}}, {"ж", []string{"е"}}},
+	'х': {{"с", []string{"і"}}, {"ш", []string{"е"}}},
+}
+
+// minStem is the shortest stem matched with endings; shorter names are only
+// matched as written.
+const minStem = 3
+
+// form is a way of writing a name word: a stem and the endings it may take.
+// A nil endings set means the word is matched only as written.
+type form struct {
+	stem    string
+	endings map[string]bool
+}
+
+func (f form) matches(word string) bool {
+	rest, ok := strings.CutPrefix(word, f.stem)
+	if !ok {
+		return false
+	}
+	if f.endings == nil {
+		return rest == ""
+	}
+	return f.endings[rest]
+}
+
+// cyrillicForms returns the forms of a lower-cased Cyrillic name word.
+func cyrillicForms(word string) []form {
+	stem := word
+	for _, suffix := range stemSuffixes {
+		if s, ok := strings.CutSuffix(word, suffix); ok {
+			stem = s
+			break
+		}
+	}
+	if utf8.RuneCountInString(stem) < minStem {
+		return []form{{stem: word}}
+	}
+	forms := []form{{stem: stem, endings: nameEndings}}
+	last, size := utf8.DecodeLastRuneInString(stem)
+	for _, alt := range alternations[last] {
+		endings := map[string]bool{}
+		for _, e := range alt.endings {
+			endings[e] = true
+		}
+		forms = append(forms, form{stem: stem[:len(stem)-size] + alt.to, endings: endings})
+	}
+	return forms
+}
+
+// latinForms returns the forms of a lower-cased Latin name word, matched
+// as written or with a possessive "'s".
+func latinForms(word string) []form {
+	if utf8.RuneCountInString(word) < minStem {
+		return nil
+	}
+	return []form{{stem: word, endings: map[string]bool{"": true, "'s": true, "s": true}}}
+}
+
+// translit is the official Ukrainian romanization (KMU 2010); word-initial
+// forms of є, ї, й, ю, я are in translitInitial.
+var translit = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "h", 'ґ': "g", 'д': "d", 'е': "e", 'є': "ie",
+	'ж': "zh", 'з': "z", 'и': "y", 'і': "i", 'ї': "i", 'й': "i", 'к': "k", 'л': "l",
+	'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ь': "", 'ю': "iu",
+	'я': "ia", '\'': "",
+}
+
+var translitInitial = map[rune]string{'є': "ye", 'ї': "yi", 'й': "y", 'ю': "yu", 'я': "ya"}
+
+// spellings are the letters people romanize differently from the official
+// table: Russian-style i for и (Iryna → Irina), g for г (Oleh → Oleg) and
+// passport-style y in iotated vowels (Mariia → Mariya).
+var spellings = []map[rune]string{
+	nil,
+	{'и': "i"},
+	{'г': "g"},
+	{'и': "i", 'г': "g"},
+	{'є': "ye", 'ї': "yi", 'й': "y", 'ю': "yu", 'я': "ya"},
+}
+
+// transliterate romanizes a lower-cased Cyrillic word, taking the letters
+// in alt in place of the official ones; ok is false if the word has letters
+// outside the Ukrainian alphabet.
+func transliterate(word string, alt map[rune]string) (latin string, ok bool) {
+	var b strings.Builder
+	var prev rune
+	for i, c := range word {
+		s, found := translit[c]
+		if initial, ok := translitInitial[c]; ok && i == 0 {
+			s = initial
+		}
+		if c == 'г' && prev == 'з' {
+			// зг is zgh, so that it is not read as ж.
+			s = "gh"
+		}
+		if a, ok := alt[c]; ok {
+			s = a
+		}
+		if !found {
+			return "", false
+		}
+		b.WriteString(s)
+		prev = c
+	}
+	return b.String(), true
+}
+
+// isCyrillic reports whether word starts with a Cyrillic letter.
+func isCyrillic(word string) bool {
+	c, _ := utf8.DecodeRuneInString(word)
+	return c >= 0x400 && c <= 0x4ff
+}
+
+// normalizeApostrophes folds the apostrophes used in Ukrainian text into '.
+func normalizeApostrophes(s string) string {
+	return strings.NewReplacer("’", "'", "ʼ", "'", "`", "'").Replace(s)
+}
@@ -0,0 +1,325 @@
+// Package scrub finds the names of participants, emails and phone numbers in
+// free-text answers and replaces them with tokens that say only what role
+// the person played, so answers can be shown to the team.
+package scrub
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/seed"
+)
+
+// Kinds of redacted text.
+const (
+	KindName  = "name"
+	KindEmail = "email"
+	KindPhone = "phone"
+)
+
+// Tokens that replace redacted text.
+const (
+	TokenColleague = "[колега]"
+	TokenRatee     = "[оцінюваний]"
+	TokenAuthor    = "[автор]"
+	TokenAdmin     = "[адміністратор]"
+	TokenEmail     = "[email]"
+	TokenPhone     = "[телефон]"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[\p{L}0-9._%+-]+@[\p{L}0-9-]+(?:\.[\p{L}0-9-]+)*\.\p{L}{2,}`)
+	// phonePattern finds runs of digit groups such as +380 67 123 45 67,
+	// (067) 123-45-67 or 0671234567; phone checks how many digits they have.
+	phonePattern = regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{2,4}\)[ .-]?)?\d{2,4}(?:[ .-]?\d{2,4}){1,4}`)
+)
+
+// Phone numbers have 9 (local, no leading zero) to 13 digits; fewer are
+// dates, sums and counts.
+const (
+	minPhoneDigits = 9
+	maxPhoneDigits = 13
+)
+
+// Match is a piece of text to redact: bytes [Start, End) of it. Code is the
+// participant the text refers to, if known.
+type Match struct {
+	Kind  string
+	Start int
+	End   int
+	Code  string
+}
+
+// Redaction is a piece of text that was replaced, for reviewing.
+type Redaction struct {
+	Kind            string `json:"kind"`
+	Text            string `json:"text"`
+	Token           string `json:"token"`
+	ParticipantCode string `json:"participantCode,omitempty"`
+}
+
+// Context says who wrote a text and whom it is about, so that their names
+// get the matching token.
+type Context struct {
+	Author string
+	Ratee  string
+}
+
+type nameForm struct {
+	form
+	code string
+}
+
+// Scrubber recognises the participants it was made for.
+type Scrubber struct {
+	forms  []nameForm
+	emails map[string]string
+	admins map[string]bool
+}
+
+// New returns a scrubber for participants. Each word of a name is matched
+// on its own, in any case, in all Ukrainian case forms and in Latin
+// transliteration. Email addresses are matched whole; their local parts are
+// not taken as names, since they hold words such as "work" or "info" as
+// often as names. Case-insensitive matching also catches common words that
+// happen to be names (віра, надія); the admin preview is there to spot
+// those.
+func New(participants []models.Participant) *Scrubber {
+	s := &Scrubber{emails: map[string]string{}, admins: map[string]bool{}}
+	for _, p := range participants {
+		s.admins[p.Code] = p.IsAdmin
+		if p.Email != "" {
+			s.emails[strings.ToLower(p.Email)] = p.Code
+		}
+		var forms []form
+		for _, word := range nameWords(p.Name) {
+			word = normalizeApostrophes(strings.ToLower(word))
+			if !isCyrillic(word) {
+				forms = append(forms, latinForms(word)...)
+				continue
+			}
+			forms = append(forms, cyrillicForms(word)...)
+			for _, alt := range spellings {
+				if latin, ok := transliterate(word, alt); ok {
+					forms = append(forms, latinForms(latin)...)
+					if short := strings.ReplaceAll(latin, "iia", "ia"); short != latin {
+						// Mariia is usually written Maria.
+						forms = append(forms, latinForms(short)...)
+					}
+				}
+			}
+		}
+		for _, f := range forms {
+			s.forms = append(s.forms, nameForm{form: f, code: p.Code})
+		}
+	}
+	return s
+}
+
+// nameWords splits a display name into the words that identify the person,
+// leaving out notes in parentheses such as "(Адмін/тест)".
+func nameWords(name string) []string {
+	if i := strings.IndexByte(name, '('); i >= 0 {
+		name = name[:i]
+	}
+	return strings.Fields(name)
+}
+
+// Find returns what to redact in text, in order. A first name followed by
+// the surname of the same person is one match.
+func (s *Scrubber) Find(text string) []Match {
+	var matches []Match
+	for _, loc := range emailPattern.FindAllStringIndex(text, -1) {
+		matches = append(matches, Match{Kind: KindEmail, Start: loc[0], End: loc[1], Code: s.emails[strings.ToLower(text[loc[0]:loc[1]])]})
+	}
+	for _, loc := range phonePattern.FindAllStringIndex(text, -1) {
+		if phone(text, loc[0], loc[1]) && !overlaps(matches, loc[0], loc[1]) {
+			matches = append(matches, Match{Kind: KindPhone, Start: loc[0], End: loc[1]})
+		}
+	}
+	for i := 0; i < len(text); {
+		word := wordAt(text[i:])
+		if word == "" {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			i += size
+			continue
+		}
+		start, end := i, i+len(word)
+		i = end
+		if overlaps(matches, start, end) {
+			continue
+		}
+		if code := s.lookup(word); code != "" {
+			matches = append(matches, Match{Kind: KindName, Start: start, End: end, Code: code})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Start < matches[j].Start })
+	return mergeNames(text, matches)
+}
+
+// lookup returns the participant a word names. When several forms fit, the
+// one with the longest stem wins, so Іван does not take Іванна's forms.
+func (s *Scrubber) lookup(word string) string {
+	word = normalizeApostrophes(strings.ToLower(word))
+	code, best := "", -1
+	for _, f := range s.forms {
+		if len(f.stem) > best && f.matches(word) {
+			code, best = f.code, len(f.stem)
+		}
+	}
+	return code
+}
+
+// Token returns the token that replaces m in a text written in ctx.
+func (s *Scrubber) Token(m Match, ctx Context) string {
+	switch {
+	case m.Kind == KindEmail:
+		return TokenEmail
+	case m.Kind == KindPhone:
+		return TokenPhone
+	case m.Code != "" && m.Code == ctx.Ratee:
+		return TokenRatee
+	case m.Code != "" && m.Code == ctx.Author:
+		return TokenAuthor
+	case s.admins[m.Code]:
+		return TokenAdmin
+	}
+	return TokenColleague
+}
+
+// Scrub replaces names, emails and phone numbers in text with tokens and
+// lists what it replaced.
+func (s *Scrubber) Scrub(text string, ctx Context) (string, []Redaction) {
+	matches := s.Find(text)
+	if len(matches) == 0 {
+		return text, nil
+	}
+	redactions := make([]Redaction, len(matches))
+	for i, m := range matches {
+		redactions[i] = Redaction{Kind: m.Kind, Text: text[m.Start:m.End], Token: s.Token(m, ctx), ParticipantCode: m.Code}
+	}
+	return Replace(text, matches, func(m Match) string { return s.Token(m, ctx) }), redactions
+}
+
+// Replace writes text with every match replaced by token(match).
+func Replace(text string, matches []Match, token func(Match) string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(text[last:m.Start])
+		b.WriteString(token(m))
+		last = m.End
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// Redacted is a text answer or ranking comment the scrubber changed.
+// QuestionID is "ranking:<criterion>" for ranking comments.
+type Redacted struct {
+	ParticipantCode string      `json:"participantCode"`
+	QuestionID      string      `json:"questionId"`
+	Original        string      `json:"original"`
+	Scrubbed        string      `json:"scrubbed"`
+	Redactions      []Redaction `json:"redactions"`
+}
+
+// Responses returns copies of responses with text answers and ranking
+// comments scrubbed, and what was changed. The respondent is the author of
+// every text in a response; in peer questions the colleague is the ratee.
+func (s *Scrubber) Responses(responses []models.ResponseRecord) ([]models.ResponseRecord, []Redacted) {
+	res := make([]models.ResponseRecord, len(responses))
+	var redacted []Redacted
+	for i, resp := range responses {
+		answers := make([]models.AnswerPayload, len(resp.Answers))
+		for j, a := range resp.Answers {
+			if text, ok := a.Value.(string); ok {
+				_, peer := seed.SplitQuestionID(a.QuestionID)
+				scrubbed, redactions := s.Scrub(text, Context{Author: resp.ParticipantCode, Ratee: peer})
+				if len(redactions) > 0 {
+					a.Value = scrubbed
+					redacted = append(redacted, Redacted{resp.ParticipantCode, a.QuestionID, text, scrubbed, redactions})
+				}
+			}
+			answers[j] = a
+		}
+		resp.Answers = answers
+		rankings := make([]models.RankingPayload, len(resp.Rankings))
+		for j, rk := range resp.Rankings {
+			scrubbed, redactions := s.Scrub(rk.Comment, Context{Author: resp.ParticipantCode})
+			if len(redactions) > 0 {
+				redacted = append(redacted, Redacted{resp.ParticipantCode, "ranking:" + rk.Criteria, rk.Comment, scrubbed, redactions})
+				rk.Comment = scrubbed
+			}
+			rankings[j] = rk
+		}
+		resp.Rankings = rankings
+		res[i] = resp
+	}
+	return res, redacted
+}
+
+// phone reports whether text[start:end] has as many digits as a phone
+// number and is not part of a longer number or word.
+func phone(text string, start, end int) bool {
+	digits := 0
+	for _, c := range text[start:end] {
+		if c >= '0' && c <= '9' {
+			digits++
+		}
+	}
+	if digits < minPhoneDigits || digits > maxPhoneDigits {
+		return false
+	}
+	before, _ := utf8.DecodeLastRuneInString(text[:start])
+	after, _ := utf8.DecodeRuneInString(text[end:])
+	return !unicode.IsLetter(before) && !unicode.IsDigit(before) && !unicode.IsLetter(after) && !unicode.IsDigit(after)
+}
+
+func overlaps(matches []Match, start, end int) bool {
+	for _, m := range matches {
+		if start < m.End && m.Start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeNames joins name matches of the same person separated only by
+// whitespace, so "Катерина Петухова" becomes one token.
+func mergeNames(text string, matches []Match) []Match {
+	var merged []Match
+	for _, m := range matches {
+		if n := len(merged); n > 0 {
+			prev := &merged[n-1]
+			if prev.Kind == KindName && m.Kind == KindName && prev.Code == m.Code &&
+				strings.TrimSpace(text[prev.End:m.Start]) == "" {
+				prev.End = m.End
+				continue
+			}
+		}
+		merged = append(merged, m)
+	}
+	return merged
+}
+
+// wordAt returns the word at the start of s: letters and digits, with
+// apostrophes inside it as in "Мар'яна".
+func wordAt(s string) string {
+	end := 0
+	for i, c := range s {
+		switch {
+		case unicode.IsLetter(c) || unicode.IsDigit(c):
+			end = i + utf8.RuneLen(c)
+		case strings.ContainsRune("'’ʼ", c) && end == i && end > 0:
+			// Part of the word only if a letter follows.
+		default:
+			return s[:end]
+		}
+	}
+	return s[:end]
+}
@@ -0,0 +1,77 @@
+package scrub
+
+import (
+	"testing"
+
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/seed"
+)
+
+func TestScrub(t *testing.T) {
+	s := New(seed.Participants())
+	for _, tc := range []struct {
+		name, text, want string
+		ctx              Context
+	}{
+		{"full name in another case", "Катерину Петухову знову не попередили.", "[колега] знову не попередили.", Context{}},
+		{"alternating consonant", "Вероніці й Олеже варто домовитися.", "[колега] й [адміністратор] варто домовитися.", Context{}},
+		{"ratee", "Марія завжди допомагає, Марії можна довіряти.", "[оцінюваний] завжди допомагає, [оцінюваний] можна довіряти.", Context{Ratee: "1425"}},
+		{"author", "Я, Ірина, вважаю інакше.", "Я, [автор], вважаю інакше.", Context{Author: "3814"}},
+		{"official transliteration", "Kateryna and Mykhailo agreed.", "[колега] and [колега] agreed.", Context{}},
+		{"common spellings", "Irina, Oleg and Maria's team.", "[колега], [адміністратор] and [колега] team.", Context{}},
+		{"email", "Пишіть на work.olegkaminskyi@gmail.com.", "Пишіть на [email].", Context{}},
+		{"email local part is not a name", "Більше work-life balance і менше info-шуму.", "Більше work-life balance і менше info-шуму.", Context{}},
+		{"phones", "Дзвоніть +380 67 123 45 67 або (067) 123-45-67.", "Дзвоніть [телефон] або [телефон].", Context{}},
+		{"dates and sums", "До 12.05.2024 зібрали 1 500 грн.", "До 12.05.2024 зібрали 1 500 грн.", Context{}},
+		{"no names", "Команда працює злагоджено.", "Команда працює злагоджено.", Context{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got, _ := s.Scrub(tc.text, tc.ctx); got != tc.want {
+				t.Errorf("Scrub(%q) = %q, want %q", tc.text, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	s := New([]models.Participant{
+		{Code: "a", Name: "Іван Мельник"},
+		{Code: "b", Name: "Іванна Мельник"},
+	})
+	for word, want := range map[string]string{
+		"Івана":    "a",
+		"Іваном":   "a",
+		"Іванною":  "b",
+		"Іванни":   "b",
+		"Іваненко": "",
+	} {
+		matches := s.Find(word)
+		got := ""
+		if len(matches) == 1 {
+			got = matches[0].Code
+		}
+		if got != want || len(matches) > 1 {
+			t.Errorf("Find(%q) = %+v, want code %q", word, matches, want)
+		}
+	}
+}
+
+func TestRedactions(t *testing.T) {
+	s := New(seed.Participants())
+	text, redactions := s.Scrub("Оксана Клінчаян: 0671234567", Context{})
+	if text != "[колега]: [телефон]" {
+		t.Fatalf("text = %q", text)
+	}
+	want := []Redaction{
+		{Kind: KindName, Text: "Оксана Клінчаян", Token: TokenColleague, ParticipantCode: "8463"},
+		{Kind: KindPhone, Text: "0671234567", Token: TokenPhone},
+	}
+	if len(redactions) != len(want) {
+		t.Fatalf("redactions = %+v, want %+v", redactions, want)
+	}
+	for i := range want {
+		if redactions[i] != want[i] {
+			t.Errorf("redaction %d = %+v, want %+v", i, redactions[i], want[i])
+		}
+	}
+}
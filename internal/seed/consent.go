@@ -0,0 +1,69 @@
+package seed
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"opslab-survey/internal/models"
+)
+
+// ConsentNotice is the privacy notice shown before the survey. How long
+// answers are kept is told from the retention policy in effect (zero ages are
+// off). The version is left to the server, which takes it from the store for
+// the text (see models.ConsentNotice), so a changed text or policy is
+// accepted again before anyone can continue.
+func ConsentNotice(anonymizeAfter, purgeAfter time.Duration) models.ConsentNotice {
+	return models.ConsentNotice{
+		Title: "Як ми використовуємо ваші відповіді",
+		Sections: []models.ConsentSection{
+			{
+				Heading: "Хто бачить ваші відповіді",
+				Text:    "Окремі відповіді разом з вашим ім'ям бачить лише адміністратор опитування. Колеги не бачать, що саме ви відповіли про них чи про команду, і не бачать ваших рейтингів.",
+			},
+			{
+				Heading: "Що бачить команда",
+				Text:    "Команді показуються лише узагальнені результати: середні оцінки, соціограма та теми з текстових відповідей. Цитати з текстових відповідей можуть бути показані без підпису.",
+			},
+			{
+				Heading: "Що зберігається",
+				Text:    "Ваші відповіді та всі їхні версії, автозбережені чернетки, надіслані вам нагадування, а також журнал входів і дій (час, IP-адреса, браузер).",
+			},
+			{
+				Heading: "Скільки зберігається і ваші права",
+				Text:    retentionText(anonymizeAfter, purgeAfter) + " Ви можете попросити адміністратора надати копію ваших даних або стерти їх.",
+			},
+		},
+	}
+}
+
+// retentionText tells what the retention policy does with the answers.
+func retentionText(anonymizeAfter, purgeAfter time.Duration) string {
+	if anonymizeAfter <= 0 && purgeAfter <= 0 {
+		return "Відповіді зберігаються, доки адміністратор їх не видалить: автоматичного знеособлення чи видалення не налаштовано."
+	}
+	var text string
+	if anonymizeAfter > 0 {
+		text = fmt.Sprintf("Через %s після закриття раунду відповіді знеособлюються: зникають імена й коди, а текстові відповіді та коментарі видаляються.", days(anonymizeAfter))
+	}
+	if purgeAfter > 0 {
+		if text != "" {
+			text += " "
+		}
+		text += fmt.Sprintf("Через %s після закриття раунд видаляється повністю.", days(purgeAfter))
+	}
+	return text
+}
+
+// days writes d in whole days, rounded up, with the Ukrainian plural form:
+// "1 день", "3 дні", "30 днів".
+func days(d time.Duration) string {
+	n := int(math.Ceil(d.Hours() / 24))
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return fmt.Sprintf("%d день", n)
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return fmt.Sprintf("%d дні", n)
+	}
+	return fmt.Sprintf("%d днів", n)
+}
@@ -0,0 +1,29 @@
+package seed
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConsentNoticeRetention(t *testing.T) {
+	day := 24 * time.Hour
+	for _, tc := range []struct {
+		anonymize, purge time.Duration
+		want             []string
+	}{
+		{0, 0, []string{"не налаштовано"}},
+		{365 * day, 0, []string{"Через 365 днів після закриття раунду відповіді знеособлюються"}},
+		{0, 31 * day, []string{"Через 31 день після закриття раунд видаляється"}},
+		{22 * day, 36 * time.Hour, []string{"Через 22 дні", "Через 2 дні"}},
+		{11 * day, 14 * day, []string{"Через 11 днів", "Через 14 днів"}},
+	} {
+		notice := ConsentNotice(tc.anonymize, tc.purge)
+		text := notice.Sections[len(notice.Sections)-1].Text
+		for _, want := range tc.want {
+			if !strings.Contains(text, want) {
+				t.Errorf("notice for %v/%v = %q, want %q in it", tc.anonymize, tc.purge, text, want)
+			}
+		}
+	}
+}
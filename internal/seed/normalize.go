@@ -0,0 +1,93 @@
+package seed
+
+import (
+	"strconv"
+	"strings"
+
+	"opslab-survey/internal/models"
+)
+
+// Normalize flattens a submission into the rows of the answers and
+// ranking_positions tables. Unanswered questions are skipped, and a question
+// answered or a criterion ranked twice keeps its last value. Answers to
+// questions that are no longer in the survey are stored by value type.
+func Normalize(answers []models.AnswerPayload, rankings []models.RankingPayload) ([]models.AnswerRow, []models.RankingPosition) {
+	types := map[string]string{}
+	for _, q := range CommonQuestions() {
+		types[q.ID] = q.Type
+	}
+	for _, t := range PeerTemplates() {
+		types[t.ID] = t.Type
+	}
+
+	var rows []models.AnswerRow
+	seen := map[[2]string]int{}
+	for _, a := range answers {
+		questionID, peerCode := SplitQuestionID(a.QuestionID)
+		row := models.AnswerRow{QuestionID: questionID, PeerCode: peerCode}
+		switch v := a.Value.(type) {
+		case float64:
+			row.NumericValue = &v
+		case string:
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			switch types[questionID] {
+			case "scale":
+				f, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					row.TextValue = &v
+				} else {
+					row.NumericValue = &f
+				}
+			case "choice":
+				row.ChoiceValue = &v
+			default:
+				row.TextValue = &v
+			}
+		default:
+			continue
+		}
+		k := [2]string{questionID, peerCode}
+		if i, ok := seen[k]; ok {
+			rows[i] = row
+			continue
+		}
+		seen[k] = len(rows)
+		rows = append(rows, row)
+	}
+
+	last := map[string]int{}
+	for i, r := range rankings {
+		last[r.Criteria] = i
+	}
+	var positions []models.RankingPosition
+	for i, r := range rankings {
+		if last[r.Criteria] != i {
+			continue
+		}
+		ranked := map[string]bool{}
+		for i, code := range r.Order {
+			if code == "" || ranked[code] {
+				continue
+			}
+			ranked[code] = true
+			positions = append(positions, models.RankingPosition{Criterion: r.Criteria, Ratee: code, Position: i + 1})
+		}
+	}
+	return rows, positions
+}
+
+// SplitQuestionID returns the template ID and colleague of a peer question
+// ("peer:trust-level:1122:4" → "peer:trust-level", "1122"), or the ID itself
+// and an empty code for common questions.
+func SplitQuestionID(id string) (questionID, peerCode string) {
+	for _, t := range PeerTemplates() {
+		if rest, ok := strings.CutPrefix(id, t.ID+":"); ok {
+			peerCode, _, _ = strings.Cut(rest, ":")
+			return t.ID, peerCode
+		}
+	}
+	return id, ""
+}
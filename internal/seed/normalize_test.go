@@ -0,0 +1,90 @@
+package seed
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"opslab-survey/internal/models"
+)
+
+func TestNormalize(t *testing.T) {
+	num := func(v float64) *float64 { return &v }
+	str := func(v string) *string { return &v }
+	answers := []models.AnswerPayload{
+		{QuestionID: "common:trust-level", Value: 7.0},
+		{QuestionID: "common:communication-quality", Value: " 8 "},
+		{QuestionID: "common:ownership-gaps", Value: "  Онбординг  "},
+		{QuestionID: "common:decision-barriers", Value: "   "},
+		{QuestionID: "common:team-strength", Value: nil},
+		{QuestionID: "peer:reliability:1122:3", Value: 4.0},
+		{QuestionID: "peer:strengths:1122:4", Value: "Пояснює складне"},
+		{QuestionID: "peer:reliability:1122:3", Value: 5.0},
+		{QuestionID: "peer:reliability:3814:3", Value: "ніколи"},
+		{QuestionID: "common:retired", Value: 2.0},
+	}
+	rankings := []models.RankingPayload{
+		{Criteria: "Комунікація", Order: []string{"3814", "1122"}},
+		{Criteria: "Експертиза", Order: []string{"1122", "", "3814", "1122"}},
+		// A criterion ranked twice keeps its last order.
+		{Criteria: "Комунікація", Order: []string{"1122", "3814"}},
+	}
+	rows, positions := Normalize(answers, rankings)
+
+	wantRows := []models.AnswerRow{
+		{QuestionID: "common:trust-level", NumericValue: num(7)},
+		{QuestionID: "common:communication-quality", NumericValue: num(8)},
+		{QuestionID: "common:ownership-gaps", TextValue: str("Онбординг")},
+		// A question answered twice keeps its last value.
+		{QuestionID: "peer:reliability", PeerCode: "1122", NumericValue: num(5)},
+		{QuestionID: "peer:strengths", PeerCode: "1122", TextValue: str("Пояснює складне")},
+		// A scale answer that is not a number is kept as text.
+		{QuestionID: "peer:reliability", PeerCode: "3814", TextValue: str("ніколи")},
+		{QuestionID: "common:retired", NumericValue: num(2)},
+	}
+	if !reflect.DeepEqual(rows, wantRows) {
+		t.Errorf("rows:\n%s\nwant:\n%s", dump(rows), dump(wantRows))
+	}
+	// Empty places and repeated colleagues are skipped; positions follow
+	// the order as submitted.
+	wantPositions := []models.RankingPosition{
+		{Criterion: "Експертиза", Ratee: "1122", Position: 1},
+		{Criterion: "Експертиза", Ratee: "3814", Position: 3},
+		{Criterion: "Комунікація", Ratee: "1122", Position: 1},
+		{Criterion: "Комунікація", Ratee: "3814", Position: 2},
+	}
+	if !reflect.DeepEqual(positions, wantPositions) {
+		t.Errorf("positions = %+v, want %+v", positions, wantPositions)
+	}
+}
+
+func TestSplitQuestionID(t *testing.T) {
+	for id, want := range map[string][2]string{
+		"peer:trust-level:1122:4": {"peer:trust-level", "1122"},
+		"peer:trust-level:1122":   {"peer:trust-level", "1122"},
+		"common:trust-level":      {"common:trust-level", ""},
+		"peer:unknown:1122:4":     {"peer:unknown:1122:4", ""},
+	} {
+		if q, peer := SplitQuestionID(id); q != want[0] || peer != want[1] {
+			t.Errorf("SplitQuestionID(%q) = %q, %q, want %q, %q", id, q, peer, want[0], want[1])
+		}
+	}
+}
+
+func dump(rows []models.AnswerRow) string {
+	var s string
+	for _, r := range rows {
+		s += r.QuestionID + " " + r.PeerCode + ":"
+		if r.NumericValue != nil {
+			s += fmt.Sprint(" numeric ", *r.NumericValue)
+		}
+		if r.TextValue != nil {
+			s += " text " + *r.TextValue
+		}
+		if r.ChoiceValue != nil {
+			s += " choice " + *r.ChoiceValue
+		}
+		s += "\n"
+	}
+	return s
+}
@@ -138,6 +138,15 @@ func PeerTemplates() []PeerQuestionTemplate {
 	}
 }
 
+// RankingCriteria are the criteria participants rank their colleagues by.
+func RankingCriteria() []string {
+	return []string{
+		"Ініціативність та відповідальність",
+		"Лідерство та вплив",
+		"Розвиток бізнесу OPSLAB",
+	}
+}
+
 // BuildPeerQuestions returns expanded questions for concrete colleagues.
 func BuildPeerQuestions(peers []models.Participant) []models.Question {
 	var out []models.Question
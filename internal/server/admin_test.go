@@ -0,0 +1,1776 @@
+package server
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"opslab-survey/internal/backup"
+	"opslab-survey/internal/envelope"
+	"opslab-survey/internal/events"
+	"opslab-survey/internal/gdpr"
+	"opslab-survey/internal/mailer"
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/reminder"
+	"opslab-survey/internal/seed"
+	"opslab-survey/internal/store/memory"
+	"opslab-survey/internal/store/sqlite"
+	"opslab-survey/internal/webhook"
+)
+
+// zipFiles opens a zip response and returns its entries by name.
+func zipFiles(t *testing.T, rec *httptest.ResponseRecorder) map[string][]byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = data
+	}
+	return files
+}
+
+func fileNames(files map[string][]byte) []string {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func csvHeader(t *testing.T, data []byte) []string {
+	t.Helper()
+	header, err := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff")))).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return header
+}
+
+func TestAdminReports(t *testing.T) {
+	ts := newTestServer(t)
+	ts.submitFixture()
+	admin := ts.admin()
+
+	golden := []struct{ name, path string }{
+		{"admin_stats", "/api/admin/stats"},
+		{"admin_responses", "/api/admin/responses"},
+		{"admin_response_detail", "/api/admin/response/1425"},
+		{"admin_revisions", "/api/admin/revisions/1425"},
+		{"admin_export", "/api/admin/export"},
+		{"admin_analytics", "/api/admin/analytics"},
+		{"admin_text", "/api/admin/text?question=common:ownership-gaps"},
+		{"admin_reciprocity", "/api/admin/reciprocity"},
+		{"admin_reciprocity_ranking", "/api/admin/reciprocity?weight=ranking:1&limit=3"},
+		{"admin_communities", "/api/admin/communities"},
+		{"admin_sociogram_sources", "/api/admin/sociogram/sources"},
+		{"admin_rounds", "/api/admin/rounds"},
+		{"admin_reminders", "/api/admin/reminders"},
+		{"admin_extensions", "/api/admin/rounds/extend"},
+	}
+	for _, g := range golden {
+		t.Run(g.name, func(t *testing.T) {
+			rec := ts.do(http.MethodGet, g.path, admin, nil)
+			expectStatus(t, rec, http.StatusOK)
+			assertGolden(t, g.name, rec)
+		})
+	}
+
+	errors := []struct {
+		name, path string
+		status     int
+	}{
+		{"unknown response", "/api/admin/response/5555", http.StatusNotFound},
+		{"response of another round", "/api/admin/response/1425?round=42", http.StatusNotFound},
+		{"invalid limit", "/api/admin/responses?limit=0", http.StatusBadRequest},
+		{"invalid cursor", "/api/admin/responses?cursor=!!", http.StatusBadRequest},
+		{"invalid sort", "/api/admin/responses?sort=name", http.StatusBadRequest},
+		{"invalid test flag", "/api/admin/responses?isTest=maybe", http.StatusBadRequest},
+		{"invalid from", "/api/admin/responses?from=yesterday", http.StatusBadRequest},
+		{"missing response code", "/api/admin/response/", http.StatusBadRequest},
+		{"unknown round", "/api/admin/stats?round=42", http.StatusNotFound},
+		{"invalid round", "/api/admin/stats?round=first", http.StatusBadRequest},
+		{"unknown export format", "/api/admin/export?format=pdf", http.StatusBadRequest},
+		{"unknown network format", "/api/admin/network?format=png", http.StatusBadRequest},
+		{"unknown weight", "/api/admin/reciprocity?weight=salary", http.StatusBadRequest},
+		{"invalid threshold", "/api/admin/communities?high=x", http.StatusBadRequest},
+		{"unknown layout", "/api/admin/sociogram?layout=spiral", http.StatusBadRequest},
+		{"unknown text question", "/api/admin/text?question=common:trust-level", http.StatusBadRequest},
+		{"unknown text language", "/api/admin/text?lang=pl", http.StatusBadRequest},
+		{"diff of unknown revision", "/api/admin/revisions/1425/diff?from=999", http.StatusBadRequest},
+		{"diff without revisions", "/api/admin/revisions/0000/diff", http.StatusNotFound},
+	}
+	for _, e := range errors {
+		t.Run(e.name, func(t *testing.T) {
+			expectStatus(t, ts.do(http.MethodGet, e.path, admin, nil), e.status)
+		})
+	}
+}
+
+func TestAdminResponsesPagination(t *testing.T) {
+	ts := newTestServer(t)
+	ts.submitFixture()
+	admin := ts.admin()
+
+	type page struct {
+		Items []struct {
+			ParticipantCode string `json:"participantCode"`
+		} `json:"items"`
+		NextCursor string `json:"nextCursor"`
+	}
+	list := func(query string) page {
+		t.Helper()
+		rec := ts.do(http.MethodGet, "/api/admin/responses?"+query, admin, nil)
+		expectStatus(t, rec, http.StatusOK)
+		var p page
+		decodeJSON(t, rec, &p)
+		return p
+	}
+	codes := func(p page) []string {
+		var out []string
+		for _, item := range p.Items {
+			out = append(out, item.ParticipantCode)
+		}
+		return out
+	}
+
+	all := codes(list(""))
+	if len(all) != 8 {
+		t.Fatalf("responses = %d, want 8", len(all))
+	}
+	for _, sort := range []string{"newest", "oldest"} {
+		t.Run(sort, func(t *testing.T) {
+			var got []string
+			var sizes []int
+			for cursor := ""; ; {
+				p := list("limit=3&sort=" + sort + "&cursor=" + cursor)
+				got = append(got, codes(p)...)
+				sizes = append(sizes, len(p.Items))
+				if cursor = p.NextCursor; cursor == "" {
+					break
+				}
+			}
+			want := slices.Clone(all)
+			if sort == "oldest" {
+				slices.Reverse(want)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("paged = %v, want %v", got, want)
+			}
+			if !slices.Equal(sizes, []int{3, 3, 2}) {
+				t.Errorf("page sizes = %v, want [3 3 2]", sizes)
+			}
+		})
+	}
+
+	today := time.Now().UTC().Format(time.DateOnly)
+	filters := []struct {
+		query string
+		want  int
+	}{
+		{"isTest=false", 8},
+		{"isTest=true", 0},
+		{"to=" + today, 8},
+		{"from=" + time.Now().Add(time.Hour).UTC().Format(time.RFC3339), 0},
+		{"round=1&limit=8", 8},
+	}
+	for _, f := range filters {
+		p := list(f.query)
+		if len(p.Items) != f.want {
+			t.Errorf("%s: responses = %d, want %d", f.query, len(p.Items), f.want)
+		}
+		if p.NextCursor != "" {
+			t.Errorf("%s: unexpected next cursor on the last page", f.query)
+		}
+	}
+}
+
+func TestAdminRevisionDiff(t *testing.T) {
+	ts := newTestServer(t)
+	cookie := ts.participant("1425")
+	admin := ts.admin()
+	first := map[string]interface{}{"answers": []map[string]interface{}{
+		{"questionId": "common:trust-level", "value": 6},
+		{"questionId": "common:team-strength", "value": "Швидкість"},
+	}}
+	second := map[string]interface{}{"answers": []map[string]interface{}{
+		{"questionId": "common:trust-level", "value": 8},
+		{"questionId": "common:communication-quality", "value": 7},
+	}}
+	expectStatus(t, ts.do(http.MethodPost, "/api/response", cookie, first), http.StatusOK)
+	expectStatus(t, ts.do(http.MethodPost, "/api/response", cookie, second), http.StatusOK)
+
+	rec := ts.do(http.MethodGet, "/api/admin/revisions/1425/diff", admin, nil)
+	expectStatus(t, rec, http.StatusOK)
+	assertGolden(t, "admin_revision_diff", rec)
+}
+
+func TestAdminExportFormats(t *testing.T) {
+	ts := newTestServer(t)
+	ts.submitFixture()
+	admin := ts.admin()
+
+	t.Run("csv", func(t *testing.T) {
+		rec := ts.do(http.MethodGet, "/api/admin/export?format=csv", admin, nil)
+		expectStatus(t, rec, http.StatusOK)
+		if ct := rec.Header().Get("Content-Type"); ct != "application/zip" {
+			t.Fatalf("Content-Type = %q", ct)
+		}
+		if cd := rec.Header().Get("Content-Disposition"); !strings.HasSuffix(cd, `-csv.zip"`) {
+			t.Errorf("Content-Disposition = %q", cd)
+		}
+		files := zipFiles(t, rec)
+		want := []string{"answers.csv", "participants.csv", "perception_guesses.csv", "rankings.csv"}
+		if got := fileNames(files); !slices.Equal(got, want) {
+			t.Fatalf("files = %v, want %v", got, want)
+		}
+		if !bytes.HasPrefix(files["answers.csv"], []byte("\ufeff")) {
+			t.Error("answers.csv must start with a UTF-8 BOM for Excel")
+		}
+		header := csvHeader(t, files["answers.csv"])
+		wantHeader := []string{"rater_code", "rater_name", "ratee_code", "ratee_name", "question_id", "question_key", "type", "value"}
+		if !slices.Equal(header, wantHeader) {
+			t.Errorf("answers.csv header = %v, want %v", header, wantHeader)
+		}
+	})
+
+	t.Run("xlsx", func(t *testing.T) {
+		rec := ts.do(http.MethodGet, "/api/admin/export?format=xlsx", admin, nil)
+		expectStatus(t, rec, http.StatusOK)
+		files := zipFiles(t, rec)
+		for _, name := range []string{"[Content_Types].xml", "xl/workbook.xml", "xl/styles.xml", "xl/worksheets/sheet1.xml", "xl/worksheets/sheet4.xml"} {
+			if _, ok := files[name]; !ok {
+				t.Errorf("workbook is missing %s (have %v)", name, fileNames(files))
+			}
+		}
+		if !bytes.Contains(files["xl/workbook.xml"], []byte(`<sheet name="answers"`)) {
+			t.Error("workbook has no answers sheet")
+		}
+	})
+
+	t.Run("spss", func(t *testing.T) {
+		rec := ts.do(http.MethodGet, "/api/admin/export?format=spss", admin, nil)
+		expectStatus(t, rec, http.StatusOK)
+		files := zipFiles(t, rec)
+		if got, want := fileNames(files), []string{"codebook.csv", "data.csv", "import.sps"}; !slices.Equal(got, want) {
+			t.Fatalf("files = %v, want %v", got, want)
+		}
+		lines := bytes.Count(files["data.csv"], []byte("\n"))
+		if lines != 9 {
+			t.Errorf("data.csv has %d lines, want header + 8 raters", lines)
+		}
+		if !bytes.Contains(files["import.sps"], []byte("VARIABLE LABELS")) {
+			t.Error("import.sps has no variable labels")
+		}
+	})
+
+	t.Run("network", func(t *testing.T) {
+		for format, marker := range map[string]string{"graphml": "<graphml", "gexf": "<gexf", "dot": "digraph", "pajek": "*Vertices"} {
+			rec := ts.do(http.MethodGet, "/api/admin/network?format="+format, admin, nil)
+			expectStatus(t, rec, http.StatusOK)
+			if !strings.Contains(rec.Body.String(), marker) {
+				t.Errorf("%s export has no %q", format, marker)
+			}
+		}
+	})
+
+	t.Run("sociogram", func(t *testing.T) {
+		rec := ts.do(http.MethodGet, "/api/admin/sociogram?layout=circle", admin, nil)
+		expectStatus(t, rec, http.StatusOK)
+		if ct := rec.Header().Get("Content-Type"); ct != "image/svg+xml" {
+			t.Fatalf("Content-Type = %q", ct)
+		}
+		if !strings.Contains(rec.Body.String(), "<svg") {
+			t.Error("sociogram is not an SVG document")
+		}
+
+		// The edge filter thins the drawing but keeps everyone on it.
+		edges := func(query string) (int, int) {
+			t.Helper()
+			rec := ts.do(http.MethodGet, "/api/admin/sociogram?layout=circle"+query, admin, nil)
+			expectStatus(t, rec, http.StatusOK)
+			return strings.Count(rec.Body.String(), "marker-end="), strings.Count(rec.Body.String(), "<circle")
+		}
+		all, nodes := edges("")
+		strong, _ := edges("&min=8")
+		top, _ := edges("&top=1&weight=ranking:1")
+		none, left := edges("&min=11")
+		if all == 0 || strong == 0 || strong >= all || none != 0 || left != nodes {
+			t.Errorf("edges: all %d, min=8 %d, min=11 %d; nodes %d, then %d", all, strong, none, nodes, left)
+		}
+		if ranked, _ := edges("&weight=ranking:1"); top == 0 || top >= ranked {
+			t.Errorf("top=1 drew %d of %d ranking edges", top, ranked)
+		}
+		for _, query := range []string{"?min=x", "?top=0", "?top=x"} {
+			expectStatus(t, ts.do(http.MethodGet, "/api/admin/sociogram"+query, admin, nil), http.StatusBadRequest)
+		}
+	})
+}
+
+func TestAdminRounds(t *testing.T) {
+	ts := newTestServer(t)
+	admin := ts.admin()
+
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/rounds", admin, map[string]string{"title": " "}), http.StatusBadRequest)
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/rounds", admin, map[string]string{"title": "Q3", "state": "paused"}), http.StatusBadRequest)
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/rounds", admin, map[string]interface{}{
+		"title": "Q3", "opensAt": time.Now().Add(time.Hour), "closesAt": time.Now(),
+	}), http.StatusBadRequest)
+
+	rec := ts.do(http.MethodPost, "/api/admin/rounds", admin, map[string]string{"title": " Q3 "})
+	expectStatus(t, rec, http.StatusOK)
+	assertGolden(t, "admin_round_created", rec)
+
+	// Preparing the next round leaves the open one current.
+	currentRound := func() roundStatus {
+		t.Helper()
+		rec := ts.do(http.MethodGet, "/api/questions", ts.participant("1425"), nil)
+		expectStatus(t, rec, http.StatusOK)
+		var questions struct {
+			Round roundStatus `json:"round"`
+		}
+		decodeJSON(t, rec, &questions)
+		return questions.Round
+	}
+	if round := currentRound(); round.ID != 1 || !round.Accepting {
+		t.Fatalf("round status = %+v, want open round 1", round)
+	}
+	ts.submitFixture()
+	rec = ts.do(http.MethodGet, "/api/admin/stats", admin, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var stats struct {
+		Completed int `json:"completed"`
+	}
+	decodeJSON(t, rec, &stats)
+	if stats.Completed != 8 {
+		t.Errorf("stats completed = %d with a draft round, want 8", stats.Completed)
+	}
+
+	rec = ts.do(http.MethodPost, "/api/admin/rounds/update", admin, map[string]interface{}{"id": 2, "title": "Q3", "state": "open"})
+	expectStatus(t, rec, http.StatusOK)
+	assertGolden(t, "admin_round_updated", rec)
+	if round := currentRound(); round.ID != 2 || !round.Accepting {
+		t.Fatalf("round status = %+v, want open round 2", round)
+	}
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/rounds/update", admin, map[string]interface{}{"id": 42, "title": "x", "state": "open"}), http.StatusNotFound)
+	expectStatus(t, ts.do(http.MethodGet, "/api/admin/rounds/update", admin, nil), http.StatusMethodNotAllowed)
+
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/rounds/extend", admin, map[string]interface{}{
+		"participantCode": "5555", "closesAt": time.Now(),
+	}), http.StatusBadRequest)
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/rounds/extend", admin, map[string]interface{}{
+		"participantCode": "1425",
+	}), http.StatusBadRequest)
+
+	// Webhook subscribers hear about the new round and the state change.
+	jobs, err := ts.store.ClaimWebhookJobs(t.Context(), 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 0 {
+		t.Fatalf("jobs = %d without endpoints, want 0", len(jobs))
+	}
+}
+
+func TestAdminWebhooks(t *testing.T) {
+	ts := newTestServer(t)
+	admin := ts.admin()
+
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/webhooks", admin, map[string]string{"url": "ftp://example.com"}), http.StatusBadRequest)
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/webhooks", admin, map[string]interface{}{
+		"url": "https://example.com/hook", "events": []string{"response.deleted"},
+	}), http.StatusBadRequest)
+
+	rec := ts.do(http.MethodPost, "/api/admin/webhooks", admin, map[string]interface{}{
+		"url": "https://example.com/hook", "events": []string{"response.submitted"}, "secret": "s3cret",
+	})
+	expectStatus(t, rec, http.StatusOK)
+	assertGolden(t, "admin_webhook_created", rec)
+
+	rec = ts.do(http.MethodGet, "/api/admin/webhooks", admin, nil)
+	expectStatus(t, rec, http.StatusOK)
+	assertGolden(t, "admin_webhooks", rec)
+
+	// A submission lands in the outbox for the subscribed endpoint.
+	expectStatus(t, ts.do(http.MethodPost, "/api/response", ts.participant("1425"), map[string]interface{}{}), http.StatusOK)
+	jobs, err := ts.store.ClaimWebhookJobs(t.Context(), 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].Event != "response.submitted" || jobs[0].URL != "https://example.com/hook" {
+		t.Fatalf("jobs = %+v, want one response.submitted delivery", jobs)
+	}
+
+	rec = ts.do(http.MethodGet, "/api/admin/webhooks/deliveries?endpoint=1", admin, nil)
+	expectStatus(t, rec, http.StatusOK)
+	assertGolden(t, "admin_webhook_deliveries", rec)
+	expectStatus(t, ts.do(http.MethodGet, "/api/admin/webhooks/deliveries?limit=0", admin, nil), http.StatusBadRequest)
+
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/webhooks/delete", admin, map[string]int{"id": 1}), http.StatusOK)
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/webhooks/delete", admin, map[string]int{"id": 1}), http.StatusNotFound)
+}
+
+func TestRoundEventsOnce(t *testing.T) {
+	ts := newTestServer(t)
+	admin := ts.admin()
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/webhooks", admin, map[string]interface{}{
+		"url": "https://example.com/hook", "events": []string{"completion.reached", "round.opened", "round.closed"},
+	}), http.StatusOK)
+	events := func() []string {
+		t.Helper()
+		jobs, err := ts.store.ClaimWebhookJobs(t.Context(), 100, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := []string{}
+		for _, j := range jobs {
+			res = append(res, j.Event)
+		}
+		return res
+	}
+
+	// The last submission completes the round once; editing it afterwards
+	// does not complete it again.
+	ts.submitFixture()
+	ts.submitFixture()
+	if got := events(); !slices.Equal(got, []string{"completion.reached"}) {
+		t.Fatalf("events after submissions = %v, want one completion.reached", got)
+	}
+
+	// A scheduled round is opened and closed by the schedule, each once,
+	// however many watchers look.
+	start := time.Now()
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/rounds", admin, map[string]interface{}{
+		"title": "Q3", "opensAt": start.Add(time.Hour), "closesAt": start.Add(2 * time.Hour),
+	}), http.StatusOK)
+	watchers := []*webhook.RoundWatcher{webhook.NewRoundWatcher(ts.store, ts.srv.webhooks), webhook.NewRoundWatcher(ts.store, ts.srv.webhooks)}
+	for _, step := range []struct {
+		at   time.Duration
+		want []string
+	}{
+		{0, []string{}},
+		{90 * time.Minute, []string{"round.opened"}},
+		{100 * time.Minute, []string{}},
+		{3 * time.Hour, []string{"round.closed"}},
+		{4 * time.Hour, []string{}},
+	} {
+		for _, w := range watchers {
+			w.Now = func() time.Time { return start.Add(step.at) }
+			if _, err := w.Tick(t.Context()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := events(); !slices.Equal(got, step.want) {
+			t.Errorf("events at +%v = %v, want %v", step.at, got, step.want)
+		}
+	}
+
+	// Moving the dates into the past closes the round now, which the
+	// update announces; closing it by hand afterwards is not news, and the
+	// watcher leaves transitions made by a save to the save.
+	update := func(state string) {
+		t.Helper()
+		expectStatus(t, ts.do(http.MethodPost, "/api/admin/rounds/update", admin, map[string]interface{}{
+			"id": 2, "title": "Q3", "state": state, "opensAt": start.Add(-2 * time.Hour), "closesAt": start.Add(-time.Hour),
+		}), http.StatusOK)
+	}
+	update("open")
+	if got := events(); !slices.Equal(got, []string{"round.closed"}) {
+		t.Errorf("events after moving the dates = %v, want round.closed", got)
+	}
+	update("closed")
+	watchers[0].Now = time.Now
+	if _, err := watchers[0].Tick(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if got := events(); len(got) != 0 {
+		t.Errorf("events after closing a closed round = %v, want none", got)
+	}
+}
+
+func TestAdminReminders(t *testing.T) {
+	ts := newTestServer(t)
+	admin := ts.admin()
+	// No scheduler is configured in tests.
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/reminders/nudge", admin, nil), http.StatusServiceUnavailable)
+	expectStatus(t, ts.do(http.MethodGet, "/api/admin/reminders/nudge", admin, nil), http.StatusMethodNotAllowed)
+}
+
+// failingTransport refuses every message.
+type failingTransport struct{}
+
+func (failingTransport) Name() string { return "failing" }
+
+func (failingTransport) Send(ctx context.Context, msg mailer.Message) error {
+	return errors.New("relay unavailable")
+}
+
+func TestReminderRetries(t *testing.T) {
+	ts := newTestServer(t)
+	now := time.Now()
+	ts.store.Now = func() time.Time { return now }
+	round, err := ts.store.CurrentRound(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	closes := now.Add(12 * time.Hour)
+	round.State, round.ClosesAt = models.RoundOpen, &closes
+	if _, err := ts.store.UpdateRound(t.Context(), *round); err != nil {
+		t.Fatal(err)
+	}
+	sched := reminder.NewScheduler(ts.store, failingTransport{}, reminder.Config{Offsets: []time.Duration{24 * time.Hour}, Interval: 15 * time.Minute, Lang: "uk"})
+	sched.Now = func() time.Time { return now }
+	attempts := func() int {
+		t.Helper()
+		reminders, err := ts.store.ListReminders(t.Context(), round.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(reminders)
+	}
+
+	// A failed reminder waits one interval, then twice as long, and so on.
+	sched.Tick(t.Context())
+	sched.Tick(t.Context())
+	if n := attempts(); n != 8 {
+		t.Fatalf("attempts after two ticks = %d, want 8", n)
+	}
+	now = now.Add(15 * time.Minute)
+	sched.Tick(t.Context())
+	now = now.Add(15 * time.Minute)
+	sched.Tick(t.Context())
+	if n := attempts(); n != 16 {
+		t.Fatalf("attempts before the second retry = %d, want 16", n)
+	}
+
+	// After MaxAttempts the scheduler gives up.
+	for range 20 {
+		now = now.Add(time.Hour)
+		sched.Tick(t.Context())
+	}
+	if n := attempts(); n != 8*reminder.MaxAttempts {
+		t.Fatalf("attempts = %d, want %d", n, 8*reminder.MaxAttempts)
+	}
+}
+
+func TestAdminEvents(t *testing.T) {
+	ts := newTestServer(t)
+	admin := ts.admin()
+	expectStatus(t, ts.do(http.MethodGet, "/api/admin/events", admin, nil), http.StatusServiceUnavailable)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	ts.srv.events = events.NewBroker(nil)
+	go ts.srv.events.Run(ctx)
+	hs := httptest.NewServer(ts.srv.Routes())
+	defer hs.Close()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, hs.URL+"/api/admin/events", nil)
+	req.AddCookie(admin)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	lines := bufio.NewScanner(resp.Body)
+	next := func() string {
+		t.Helper()
+		for lines.Scan() {
+			if line := lines.Text(); strings.HasPrefix(line, "data: ") {
+				return line
+			}
+		}
+		t.Fatal("event stream ended")
+		return ""
+	}
+	if got := next(); got != `data: {"completed":0,"pending":8,"roundId":1,"total":8}` {
+		t.Fatalf("initial snapshot = %s", got)
+	}
+
+	expectStatus(t, ts.do(http.MethodPost, "/api/response", ts.participant("1425"), map[string]interface{}{}), http.StatusOK)
+	if got := next(); !strings.Contains(got, `"participantCode":"1425"`) {
+		t.Fatalf("submission event = %s", got)
+	}
+	if got := next(); got != `data: {"completed":1,"pending":7,"roundId":1,"total":8}` {
+		t.Fatalf("completion event = %s", got)
+	}
+
+	// A stream for one round skips what happens in the others.
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/rounds", admin, map[string]string{"title": "Q3", "state": "open"}), http.StatusOK)
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, hs.URL+"/api/admin/events?round=2", nil)
+	req.AddCookie(admin)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	lines = bufio.NewScanner(resp.Body)
+	if got := next(); got != `data: {"completed":0,"pending":8,"roundId":2,"total":8}` {
+		t.Fatalf("round 2 snapshot = %s", got)
+	}
+	rec := ts.do(http.MethodPost, "/api/admin/reset", admin, map[string]interface{}{"scope": map[string]interface{}{"kind": "round", "roundId": 1}})
+	expectStatus(t, rec, http.StatusOK)
+	var preview struct {
+		Token string `json:"token"`
+	}
+	decodeJSON(t, rec, &preview)
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/reset/confirm", admin, map[string]string{"token": preview.Token}), http.StatusOK)
+	expectStatus(t, ts.do(http.MethodPost, "/api/response", ts.participant("1122"), map[string]interface{}{}), http.StatusOK)
+	if got := next(); !strings.Contains(got, `"participantCode":"1122"`) || !strings.Contains(got, `"roundId":2`) {
+		t.Fatalf("first event of round 2 = %s, want the submission after the round 1 reset", got)
+	}
+}
+
+func TestRunTestData(t *testing.T) {
+	ts := newTestServer(t)
+	admin := ts.admin()
+	rec := ts.do(http.MethodPost, "/api/admin/run-test", admin, nil)
+	expectStatus(t, rec, http.StatusOK)
+
+	rec = ts.do(http.MethodGet, "/api/admin/responses", admin, nil)
+	var page struct {
+		Items []struct {
+			IsTestData bool `json:"isTestData"`
+		} `json:"items"`
+	}
+	decodeJSON(t, rec, &page)
+	if len(page.Items) != 8 {
+		t.Fatalf("responses = %d, want 8", len(page.Items))
+	}
+	for _, r := range page.Items {
+		if !r.IsTestData {
+			t.Fatal("run-test responses must be flagged as test data")
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	ts := newTestServer(t)
+	ts.submitFixture()
+	admin := ts.admin()
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/rounds", admin, map[string]string{"title": "Q3", "state": "open"}), http.StatusOK)
+	expectStatus(t, ts.do(http.MethodPost, "/api/response", ts.participant("1425"), map[string]interface{}{}), http.StatusOK)
+	expectStatus(t, ts.do(http.MethodPost, "/api/draft", ts.participant("1122"), map[string]interface{}{}), http.StatusOK)
+
+	type preview struct {
+		Token        string   `json:"token"`
+		Revisions    int      `json:"revisions"`
+		Participants []string `json:"participants"`
+	}
+	request := func(scope map[string]interface{}) preview {
+		t.Helper()
+		rec := ts.do(http.MethodPost, "/api/admin/reset", admin, map[string]interface{}{"scope": scope})
+		expectStatus(t, rec, http.StatusOK)
+		var p preview
+		decodeJSON(t, rec, &p)
+		return p
+	}
+	confirm := func(token string) int {
+		t.Helper()
+		rec := ts.do(http.MethodPost, "/api/admin/reset/confirm", admin, map[string]string{"token": token})
+		expectStatus(t, rec, http.StatusOK)
+		var res struct {
+			Status  string `json:"status"`
+			Deleted int    `json:"deleted"`
+		}
+		decodeJSON(t, rec, &res)
+		if res.Status != "cleared" {
+			t.Errorf("confirm status = %q", res.Status)
+		}
+		return res.Deleted
+	}
+	countResponses := func(round string) int {
+		t.Helper()
+		rec := ts.do(http.MethodGet, "/api/admin/responses?round="+round, admin, nil)
+		expectStatus(t, rec, http.StatusOK)
+		var page struct {
+			Items []interface{} `json:"items"`
+		}
+		decodeJSON(t, rec, &page)
+		return len(page.Items)
+	}
+
+	// Nothing is deleted without a valid scope and a confirmation.
+	expectStatus(t, ts.do(http.MethodGet, "/api/admin/reset", admin, nil), http.StatusMethodNotAllowed)
+	for _, scope := range []map[string]interface{}{
+		{"kind": "everything"},
+		{"kind": "round"},
+		{"kind": "all", "participantCode": "1425"},
+		{"kind": "participant", "participantCode": "nobody"},
+		{"kind": "round", "roundId": 99},
+	} {
+		expectStatus(t, ts.do(http.MethodPost, "/api/admin/reset", admin, map[string]interface{}{"scope": scope}), http.StatusBadRequest)
+	}
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/reset", admin, map[string]interface{}{"scope": map[string]string{"kind": "test"}}), http.StatusConflict)
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/reset/confirm", admin, map[string]string{"token": ts.admin().Value}), http.StatusBadRequest)
+	if n := countResponses("1"); n != 8 {
+		t.Fatalf("round 1 responses after previews = %d, want 8", n)
+	}
+
+	// One participant in one round.
+	p := request(map[string]interface{}{"kind": "participant", "participantCode": "1425", "roundId": 1})
+	if p.Revisions != 1 || len(p.Participants) != 1 || p.Participants[0] != "1425" {
+		t.Errorf("participant preview = %+v", p)
+	}
+	if n := confirm(p.Token); n != 1 {
+		t.Errorf("participant reset deleted %d, want 1", n)
+	}
+	if r1, r2 := countResponses("1"), countResponses("2"); r1 != 7 || r2 != 1 {
+		t.Errorf("responses after participant reset = %d/%d, want 7/1", r1, r2)
+	}
+	// A token is good for what it previewed only once.
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/reset/confirm", admin, map[string]string{"token": p.Token}), http.StatusConflict)
+
+	// Everything.
+	rec := ts.do(http.MethodPost, "/api/admin/reset", admin, map[string]interface{}{"scope": map[string]string{"kind": "all"}})
+	expectStatus(t, rec, http.StatusOK)
+	assertGolden(t, "admin_reset", rec)
+	decodeJSON(t, rec, &p)
+	if n := confirm(p.Token); n != 8 {
+		t.Errorf("full reset deleted %d, want 8", n)
+	}
+	for _, round := range []string{"1", "2"} {
+		if n := countResponses(round); n != 0 {
+			t.Errorf("round %s responses after reset = %d", round, n)
+		}
+	}
+	rec = ts.do(http.MethodGet, "/api/admin/revisions/1425?round=1", admin, nil)
+	expectStatus(t, rec, http.StatusOK)
+	assertGolden(t, "admin_revisions_after_reset", rec)
+	rec = ts.do(http.MethodGet, "/api/admin/stats?round=1", admin, nil)
+	expectStatus(t, rec, http.StatusOK)
+	assertGolden(t, "admin_stats_after_reset", rec)
+
+	// ...while participants, rounds and drafts stay.
+	expectStatus(t, ts.do(http.MethodGet, "/api/me", ts.participant("1425"), nil), http.StatusOK)
+	rec = ts.do(http.MethodGet, "/api/admin/rounds", admin, nil)
+	var rounds []interface{}
+	decodeJSON(t, rec, &rounds)
+	if len(rounds) != 2 {
+		t.Errorf("rounds after reset = %d, want 2", len(rounds))
+	}
+	rec = ts.do(http.MethodGet, "/api/draft", ts.participant("1122"), nil)
+	if !strings.Contains(rec.Body.String(), `"participantCode": "1122"`) {
+		t.Errorf("draft after reset = %s", rec.Body.String())
+	}
+
+	// Both resets left a snapshot that brings the submissions back.
+	rec = ts.do(http.MethodGet, "/api/admin/snapshots", admin, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var snapshots []models.Snapshot
+	decodeJSON(t, rec, &snapshots)
+	if len(snapshots) != 2 || snapshots[0].Scope.Kind != models.ResetAll || snapshots[0].Revisions != 8 || snapshots[1].Revisions != 1 {
+		t.Fatalf("snapshots = %+v", snapshots)
+	}
+	restore := func(id int64) int {
+		t.Helper()
+		rec := ts.do(http.MethodPost, "/api/admin/snapshots/restore", admin, map[string]int64{"id": id})
+		expectStatus(t, rec, http.StatusOK)
+		var res struct {
+			Restored int `json:"restored"`
+		}
+		decodeJSON(t, rec, &res)
+		return res.Restored
+	}
+	if n := restore(snapshots[1].ID); n != 1 {
+		t.Errorf("restored %d revisions, want 1", n)
+	}
+	if n := restore(snapshots[0].ID); n != 8 {
+		t.Errorf("restored %d revisions, want 8", n)
+	}
+	if n := restore(snapshots[0].ID); n != 0 {
+		t.Errorf("second restore added %d revisions, want 0", n)
+	}
+	if r1, r2 := countResponses("1"), countResponses("2"); r1 != 8 || r2 != 1 {
+		t.Errorf("responses after restore = %d/%d, want 8/1", r1, r2)
+	}
+	// The restored revisions still fall within what the used token
+	// previewed; replaying it must not delete them again.
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/reset/confirm", admin, map[string]string{"token": p.Token}), http.StatusConflict)
+	if r1, r2 := countResponses("1"), countResponses("2"); r1 != 8 || r2 != 1 {
+		t.Errorf("responses after replayed reset = %d/%d, want 8/1", r1, r2)
+	}
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/snapshots/restore", admin, map[string]int64{"id": 99}), http.StatusNotFound)
+
+	// The survey can be answered again.
+	expectStatus(t, ts.do(http.MethodPost, "/api/response", ts.participant("1425"), map[string]interface{}{}), http.StatusOK)
+}
+
+func TestAuditLog(t *testing.T) {
+	ts := newTestServer(t)
+	ts.submitFixture()
+	admin := ts.admin()
+	expectStatus(t, ts.do(http.MethodPost, "/api/login", nil, map[string]string{"email": "mariya.vasylyk@opslab.uk", "code": "9999"}), http.StatusUnauthorized)
+	expectStatus(t, ts.do(http.MethodGet, "/api/admin/response/1425", ts.participant("1122"), nil), http.StatusForbidden)
+	expectStatus(t, ts.do(http.MethodGet, "/api/admin/response/1425", admin, nil), http.StatusOK)
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/reset", admin, map[string]interface{}{"scope": map[string]string{"kind": "participant", "participantCode": "1425"}}), http.StatusOK)
+
+	type entry struct {
+		ID      int64  `json:"id"`
+		Actor   string `json:"actor"`
+		Action  string `json:"action"`
+		Target  string `json:"target"`
+		IP      string `json:"ip"`
+		Outcome string `json:"outcome"`
+		Status  int    `json:"status"`
+	}
+	list := func(query string) []entry {
+		t.Helper()
+		rec := ts.do(http.MethodGet, "/api/admin/audit"+query, admin, nil)
+		expectStatus(t, rec, http.StatusOK)
+		var page struct {
+			Items []entry `json:"items"`
+		}
+		decodeJSON(t, rec, &page)
+		return page.Items
+	}
+
+	got := list("?limit=4")
+	want := []entry{
+		{Actor: "0000", Action: "reset.request", Target: "participant:1425", Outcome: "success", Status: 200},
+		{Actor: "0000", Action: "response.view", Target: "participant:1425", Outcome: "success", Status: 200},
+		{Actor: "1122", Action: "response.view", Target: "path:/api/admin/response/1425", Outcome: "denied", Status: 403},
+		{Actor: "1122", Action: "auth.login", Target: "email:kateryna.petukhova@opslab.uk", Outcome: "success", Status: 200},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("entries = %+v", got)
+	}
+	for i := range want {
+		want[i].ID, want[i].IP = got[i].ID, "192.0.2.1"
+		if got[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	failed := list("?action=auth.&outcome=denied")
+	if len(failed) != 1 || failed[0].Actor != "" || failed[0].Target != "email:mariya.vasylyk@opslab.uk" {
+		t.Errorf("denied logins = %+v, want the one with a wrong code", failed)
+	}
+	if submits := list("?action=response.submit&actor=1425"); len(submits) != 1 || submits[0].Target != "round:1" {
+		t.Errorf("submissions of 1425 = %+v", submits)
+	}
+	older := list("?limit=2&before=" + strconv.FormatInt(got[1].ID, 10))
+	if len(older) != 2 || older[0].ID != got[2].ID {
+		t.Errorf("page before %d = %+v", got[1].ID, older)
+	}
+	expectStatus(t, ts.do(http.MethodGet, "/api/admin/audit?outcome=maybe", admin, nil), http.StatusBadRequest)
+
+	rec := ts.do(http.MethodGet, "/api/admin/audit/verify", admin, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var v struct {
+		Valid   bool   `json:"valid"`
+		Entries int    `json:"entries"`
+		Head    string `json:"head"`
+	}
+	decodeJSON(t, rec, &v)
+	if !v.Valid || v.Entries < 20 || len(v.Head) != 64 {
+		t.Errorf("verify = %+v, want an intact chain", v)
+	}
+}
+
+// rewriteArchive returns archive with every entry passed through edit.
+func rewriteArchive(t *testing.T, archive string, edit func(name string, data []byte) []byte) string {
+	t.Helper()
+	zr, err := gzip.NewReader(strings.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(zr)
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = edit(hdr.Name, data)
+		hdr.Size = int64(len(data))
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write(data)
+	}
+	tw.Close()
+	zw.Close()
+	return buf.String()
+}
+
+func TestBackup(t *testing.T) {
+	ts := newTestServer(t)
+	ts.submitFixture()
+	admin := ts.admin()
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/rounds", admin, map[string]string{"title": "Q3", "state": "open"}), http.StatusOK)
+	expectStatus(t, ts.do(http.MethodPost, "/api/response", ts.participant("1425"), map[string]interface{}{}), http.StatusOK)
+
+	rec := ts.do(http.MethodGet, "/api/admin/backup", admin, nil)
+	expectStatus(t, rec, http.StatusOK)
+	if ct := rec.Header().Get("Content-Type"); ct != "application/gzip" {
+		t.Errorf("content type = %q", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.Contains(cd, ".tar.gz") {
+		t.Errorf("content disposition = %q", cd)
+	}
+	archive := rec.Body.String()
+	d, m, err := backup.Read(strings.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.SchemaVersion != backup.SchemaVersion || len(d.Participants) != len(seed.Participants()) || len(d.Rounds) != 2 || len(d.Revisions) != 9 || len(d.Questions) == 0 {
+		t.Fatalf("backup = %+v with %d participants, %d rounds, %d responses, %d questions", m, len(d.Participants), len(d.Rounds), len(d.Revisions), len(d.Questions))
+	}
+
+	// A replace is previewed first and confirmed with the token it returns.
+	confirmReplace := func(ts *testServer, cookie *http.Cookie, archive string) string {
+		t.Helper()
+		rec := ts.do(http.MethodPost, "/api/admin/backup/restore?mode=replace", cookie, archive)
+		expectStatus(t, rec, http.StatusOK)
+		var preview struct {
+			Token string `json:"token"`
+		}
+		decodeJSON(t, rec, &preview)
+		if preview.Token == "" {
+			t.Fatalf("replace preview = %s, want a token", rec.Body.String())
+		}
+		return preview.Token
+	}
+	restore := func(ts *testServer, cookie *http.Cookie, mode, archive string) models.RestoreResult {
+		t.Helper()
+		path := "/api/admin/backup/restore?mode=" + mode
+		if mode == models.RestoreReplace {
+			path += "&token=" + url.QueryEscape(confirmReplace(ts, cookie, archive))
+		}
+		rec := ts.do(http.MethodPost, path, cookie, archive)
+		expectStatus(t, rec, http.StatusOK)
+		var res models.RestoreResult
+		decodeJSON(t, rec, &res)
+		return res
+	}
+	responses := func(ts *testServer, cookie *http.Cookie, round string) string {
+		t.Helper()
+		rec := ts.do(http.MethodGet, "/api/admin/responses?round="+round, cookie, nil)
+		expectStatus(t, rec, http.StatusOK)
+		return rec.Body.String()
+	}
+
+	// Replacing the data of another environment reproduces this one.
+	other := newTestServer(t)
+	otherAdmin := other.admin()
+	expectStatus(t, other.do(http.MethodPost, "/api/response", other.participant("1122"), map[string]interface{}{}), http.StatusOK)
+	before, err := other.store.ExportRevisions(t.Context(), models.RevisionFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Merging would put the archive's responses into other rounds.
+	rec = other.do(http.MethodPost, "/api/admin/backup/restore?mode=merge", otherAdmin, archive)
+	expectStatus(t, rec, http.StatusConflict)
+	// Nothing is replaced without a confirmation for this very archive.
+	if all, err := other.store.ExportRevisions(t.Context(), models.RevisionFilter{}); err != nil || !reflect.DeepEqual(all, before) {
+		t.Fatalf("revisions after a conflicting merge and a preview = %+v, %v", all, err)
+	}
+	expectStatus(t, other.do(http.MethodPost, "/api/admin/backup/restore?mode=replace&token=bogus", otherAdmin, archive), http.StatusBadRequest)
+	rec = other.do(http.MethodGet, "/api/admin/backup", otherAdmin, nil)
+	expectStatus(t, rec, http.StatusOK)
+	previous := rec.Body.String()
+	foreign := confirmReplace(other, otherAdmin, previous)
+	expectStatus(t, other.do(http.MethodPost, "/api/admin/backup/restore?mode=replace&token="+url.QueryEscape(foreign), otherAdmin, archive), http.StatusBadRequest)
+	if all, err := other.store.ExportRevisions(t.Context(), models.RevisionFilter{}); err != nil || !reflect.DeepEqual(all, before) {
+		t.Fatalf("revisions after unconfirmed replaces = %+v, %v", all, err)
+	}
+	replaceToken := confirmReplace(other, otherAdmin, archive)
+	replacePath := "/api/admin/backup/restore?mode=replace&token=" + url.QueryEscape(replaceToken)
+	rec = other.do(http.MethodPost, replacePath, otherAdmin, archive)
+	expectStatus(t, rec, http.StatusOK)
+	var res models.RestoreResult
+	decodeJSON(t, rec, &res)
+	if res.Rounds != 2 || res.Revisions != 9 || res.SkippedRevisions != 0 {
+		t.Errorf("replace = %+v", res)
+	}
+	// A reset snapshot could not bring back the replaced rounds, so none is
+	// left behind; the backup taken before is the way back (see below).
+	if snapshots, err := other.store.ListSnapshots(t.Context()); err != nil || len(snapshots) != 0 {
+		t.Errorf("snapshots after replace = %+v, %v", snapshots, err)
+	}
+	for _, round := range []string{"1", "2"} {
+		if got, want := responses(other, otherAdmin, round), responses(ts, admin, round); got != want {
+			t.Errorf("round %s after replace:\n%s\nwant:\n%s", round, got, want)
+		}
+	}
+	// New submissions continue after the restored IDs.
+	expectStatus(t, other.do(http.MethodPost, "/api/response", other.participant("1122"), map[string]interface{}{}), http.StatusOK)
+	revs, err := other.store.ListRevisions(t.Context(), 2, "1122")
+	if err != nil || len(revs) != 1 || revs[0].ID <= 9 {
+		t.Errorf("revision after replace = %+v, %v", revs, err)
+	}
+	// The confirmation is spent: replaying it would wipe that submission.
+	expectStatus(t, other.do(http.MethodPost, replacePath, otherAdmin, archive), http.StatusConflict)
+	if again, err := other.store.ListRevisions(t.Context(), 2, "1122"); err != nil || !reflect.DeepEqual(again, revs) {
+		t.Errorf("revisions after a replayed replace = %+v, %v", again, err)
+	}
+
+	// Merging keeps what is there and adds nothing twice.
+	res = restore(ts, admin, models.RestoreMerge, archive)
+	if res.Rounds != 0 || res.Revisions != 0 || res.SkippedRounds != 2 || res.SkippedRevisions != 9 {
+		t.Errorf("merge into the source = %+v", res)
+	}
+	res = restore(other, otherAdmin, "", archive)
+	if res.Mode != models.RestoreMerge || res.Revisions != 0 {
+		t.Errorf("default mode = %+v", res)
+	}
+	// Replacing with the backup taken before the replace undoes it.
+	restore(other, otherAdmin, models.RestoreReplace, previous)
+	all, err := other.store.ExportRevisions(t.Context(), models.RevisionFilter{})
+	if got, want := fmt.Sprintf("%+v", all), fmt.Sprintf("%+v", before); err != nil || got != want {
+		t.Errorf("revisions after going back = %s, %v\nwant %s", got, err, want)
+	}
+
+	// Broken or foreign archives are rejected before anything is written.
+	tampered := rewriteArchive(t, archive, func(name string, data []byte) []byte {
+		if name == backup.ResponsesFile {
+			return bytes.Replace(data, []byte(`"isTestData":false`), []byte(`"isTestData":true`), 1)
+		}
+		return data
+	})
+	future := rewriteArchive(t, archive, func(name string, data []byte) []byte {
+		if name == backup.ManifestName {
+			current := `"schemaVersion": ` + strconv.Itoa(backup.SchemaVersion)
+			return bytes.Replace(data, []byte(current), []byte(`"schemaVersion": `+strconv.Itoa(backup.SchemaVersion+1)), 1)
+		}
+		return data
+	})
+	for name, tc := range map[string]struct{ mode, body, want string }{
+		"mode":     {"wipe", archive, "mode must be"},
+		"garbage":  {"replace", "not an archive", "not a gzip archive"},
+		"checksum": {"replace", tampered, "checksum mismatch"},
+		"version":  {"replace", future, "unsupported backup schema version " + strconv.Itoa(backup.SchemaVersion+1)},
+	} {
+		rec := ts.do(http.MethodPost, "/api/admin/backup/restore?mode="+tc.mode, admin, tc.body)
+		expectStatus(t, rec, http.StatusBadRequest)
+		if !strings.Contains(rec.Body.String(), tc.want) {
+			t.Errorf("%s: body = %q, want %q", name, rec.Body.String(), tc.want)
+		}
+	}
+	expectStatus(t, ts.do(http.MethodGet, "/api/admin/backup/restore", admin, nil), http.StatusMethodNotAllowed)
+	if all, err := ts.store.ExportRevisions(t.Context(), models.RevisionFilter{}); err != nil || len(all) != 9 {
+		t.Errorf("revisions after rejected restores = %d, %v; want 9", len(all), err)
+	}
+
+	// A round that retention anonymized travels as its anonymous responses.
+	revs, err = ts.store.ExportRevisions(t.Context(), models.RevisionFilter{RoundID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.store.AnonymizeRound(t.Context(), 1, gdpr.Anonymize(revs)); err != nil {
+		t.Fatal(err)
+	}
+	anonymous, err := ts.store.ListAnonymousResponses(t.Context(), 1)
+	if err != nil || len(anonymous) == 0 {
+		t.Fatalf("anonymous responses = %d, %v", len(anonymous), err)
+	}
+	rec = ts.do(http.MethodGet, "/api/admin/backup", admin, nil)
+	expectStatus(t, rec, http.StatusOK)
+	res = restore(other, otherAdmin, models.RestoreReplace, rec.Body.String())
+	if res.Anonymous != len(anonymous) {
+		t.Errorf("replace with anonymous responses = %+v, want %d", res, len(anonymous))
+	}
+	if got, err := other.store.ListAnonymousResponses(t.Context(), 1); err != nil || !reflect.DeepEqual(got, anonymous) {
+		t.Errorf("restored anonymous responses = %+v, %v\nwant %+v", got, err, anonymous)
+	}
+
+	// Acceptances of the privacy notice travel with the answers.
+	d, _, err = backup.Read(strings.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh := memory.New()
+	if err := fresh.EnsureSchema(t.Context(), seed.Participants()); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := fresh.RestoreDataset(t.Context(), *d, models.RestoreReplace)
+	if err != nil || restored.Consents == 0 || restored.Consents != len(d.Consents) {
+		t.Fatalf("restore into a fresh store = %+v, %v; want %d consents", restored, err, len(d.Consents))
+	}
+	if c, err := fresh.ConsentFor(t.Context(), "1425", ts.srv.notice.Version); err != nil || c == nil {
+		t.Errorf("restored consent of 1425 = %+v, %v", c, err)
+	}
+}
+
+func TestEncryption(t *testing.T) {
+	keyring := func(keys ...byte) *envelope.Keyring {
+		t.Helper()
+		var previous [][]byte
+		for _, k := range keys[1:] {
+			previous = append(previous, bytes.Repeat([]byte{k}, envelope.KeySize))
+		}
+		k, err := envelope.New(bytes.Repeat([]byte{keys[0]}, envelope.KeySize), previous...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	answers := func(ts *testServer) [][]models.AnswerPayload {
+		t.Helper()
+		revs, err := ts.store.ExportRevisions(t.Context(), models.RevisionFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var res [][]models.AnswerPayload
+		for _, rev := range revs {
+			res = append(res, rev.Answers)
+		}
+		return res
+	}
+	draft := []models.AnswerPayload{{QuestionID: "common:ownership-gaps", Value: "чернетка"}}
+
+	plain := newTestServer(t)
+	plain.submitFixture()
+	want := answers(plain)
+
+	ts := newTestServer(t)
+	ts.store.SetKeyring(keyring(1))
+	ts.submitFixture()
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/rounds", ts.admin(), map[string]string{"title": "Q3", "state": "open"}), http.StatusOK)
+	expectStatus(t, ts.do(http.MethodPost, "/api/draft", ts.participant("1425"), map[string]interface{}{"answers": draft}), http.StatusOK)
+
+	// Reading is transparent with the key and impossible without it.
+	if got := answers(ts); !reflect.DeepEqual(got, want) {
+		t.Fatalf("decrypted answers differ:\n%v\nwant:\n%v", got, want)
+	}
+	expectStatus(t, ts.do(http.MethodGet, "/api/admin/responses?round=1", ts.admin(), nil), http.StatusOK)
+	ts.store.SetKeyring(nil)
+	if _, err := ts.store.ExportRevisions(t.Context(), models.RevisionFilter{}); !errors.Is(err, envelope.ErrNoKey) {
+		t.Errorf("export without key: %v, want ErrNoKey", err)
+	}
+	if _, err := ts.store.DraftFor(t.Context(), 2, "1425"); !errors.Is(err, envelope.ErrNoKey) {
+		t.Errorf("draft without key: %v, want ErrNoKey", err)
+	}
+	expectStatus(t, ts.do(http.MethodGet, "/api/admin/responses?round=1", ts.admin(), nil), http.StatusInternalServerError)
+
+	// Rotation re-seals everything under the new key, once.
+	ts.store.SetKeyring(keyring(2, 1))
+	res, err := ts.store.RotateKeys(t.Context())
+	if err != nil || res.Revisions != 8 || res.Rankings != 9 || res.Drafts != 1 || res.Snapshots != 0 || res.KeyID != keyring(2).KeyID() {
+		t.Fatalf("rotate = %+v, %v", res, err)
+	}
+	if res, err := ts.store.RotateKeys(t.Context()); err != nil || res.Revisions+res.Rankings+res.Drafts+res.Snapshots != 0 {
+		t.Errorf("second rotate = %+v, %v", res, err)
+	}
+	ts.store.SetKeyring(keyring(2))
+	if got := answers(ts); !reflect.DeepEqual(got, want) {
+		t.Errorf("answers after rotation differ")
+	}
+	if d, err := ts.store.DraftFor(t.Context(), 2, "1425"); err != nil || !reflect.DeepEqual(d.Answers, draft) {
+		t.Errorf("draft after rotation = %+v, %v", d, err)
+	}
+	ts.store.SetKeyring(keyring(1))
+	if _, err := ts.store.ExportRevisions(t.Context(), models.RevisionFilter{}); !errors.Is(err, envelope.ErrNoKey) {
+		t.Errorf("export with the retired key: %v, want ErrNoKey", err)
+	}
+
+	// Rotating a plaintext database turns encryption on.
+	plain.store.SetKeyring(keyring(3))
+	if res, err := plain.store.RotateKeys(t.Context()); err != nil || res.Revisions != 8 || res.Rankings != 8 {
+		t.Errorf("rotate plaintext = %+v, %v", res, err)
+	}
+	plain.store.SetKeyring(nil)
+	if _, err := plain.store.ExportRevisions(t.Context(), models.RevisionFilter{}); !errors.Is(err, envelope.ErrNoKey) {
+		t.Errorf("export after encrypting: %v, want ErrNoKey", err)
+	}
+	if _, err := plain.store.RotateKeys(t.Context()); err == nil {
+		t.Error("rotate without a key succeeded")
+	}
+}
+
+// Ranking comments are free text like the answers, so the rankings column
+// must not keep them readable either.
+func TestEncryptedRankingsAtRest(t *testing.T) {
+	ctx := t.Context()
+	path := filepath.Join(t.TempDir(), "survey.db")
+	st, err := sqlite.Open(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	if err := st.EnsureSchema(ctx, seed.Participants()); err != nil {
+		t.Fatal(err)
+	}
+	round, err := st.CurrentRound(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const comment = "Катерина мовчить на нарадах"
+	rankings := []models.RankingPayload{{Criteria: seed.RankingCriteria()[0], Order: []string{"1122", "3814"}, Comment: comment}}
+	// 3814 answered before encryption was turned on.
+	if err := st.UpsertResponse(ctx, round.ID, "3814", nil, rankings, false, "s0"); err != nil {
+		t.Fatal(err)
+	}
+	key, err := envelope.New(bytes.Repeat([]byte{7}, envelope.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.SetKeyring(key)
+	if err := st.UpsertResponse(ctx, round.ID, "1425", nil, rankings, false, "s1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SaveDraft(ctx, models.Draft{RoundID: round.ID, ParticipantCode: "4582", Rankings: rankings}); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	plaintext := func() []string {
+		t.Helper()
+		rows, err := db.QueryContext(ctx, `
+SELECT 'revision ' || participant_code, rankings FROM response_revisions
+UNION ALL SELECT 'draft ' || participant_code, rankings FROM drafts`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var res []string
+		for rows.Next() {
+			var row, raw string
+			if err := rows.Scan(&row, &raw); err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(raw, comment) || !envelope.IsSealed([]byte(raw)) {
+				res = append(res, row)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	if got := plaintext(); !slices.Equal(got, []string{"revision 3814"}) {
+		t.Fatalf("plaintext rankings = %v, want only the one written before encryption", got)
+	}
+	res, err := st.RotateKeys(ctx)
+	if err != nil || res.Rankings != 1 {
+		t.Fatalf("rotate = %+v, %v; want 1 ranking sealed", res, err)
+	}
+	if got := plaintext(); len(got) != 0 {
+		t.Errorf("plaintext rankings after rotation = %v", got)
+	}
+
+	revisions, err := st.ExportRevisions(ctx, models.RevisionFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rev := range revisions {
+		if !reflect.DeepEqual(rev.Rankings, rankings) {
+			t.Errorf("rankings of %s = %+v, want %+v", rev.ParticipantCode, rev.Rankings, rankings)
+		}
+	}
+	if d, err := st.DraftFor(ctx, round.ID, "4582"); err != nil || !reflect.DeepEqual(d.Rankings, rankings) {
+		t.Errorf("draft = %+v, %v", d, err)
+	}
+}
+
+func TestNormalizedAnswers(t *testing.T) {
+	ctx := t.Context()
+	path := filepath.Join(t.TempDir(), "survey.db")
+	st, err := sqlite.Open(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	ts := serve(t, st)
+	ts.submitFixture()
+	mem := newTestServer(t)
+	mem.submitFixture()
+	// The API used to accept a criterion ranked twice; such a revision keeps
+	// its last order.
+	for _, s := range []*testServer{ts, mem} {
+		rec, err := s.srv.store.ResponseByParticipant(ctx, 1, "1425")
+		if err != nil || rec == nil || len(rec.Rankings) == 0 {
+			t.Fatalf("response of 1425 = %+v, %v", rec, err)
+		}
+		again := rec.Rankings[0]
+		again.Order = slices.Clone(again.Order)
+		slices.Reverse(again.Order)
+		if err := s.srv.store.UpsertResponse(ctx, 1, "1425", rec.Answers, append(rec.Rankings, again), false, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	report := func(ts *testServer) string {
+		t.Helper()
+		rec := ts.do(http.MethodGet, "/api/admin/analytics", ts.admin(), nil)
+		expectStatus(t, rec, http.StatusOK)
+		return rec.Body.String()
+	}
+	want := report(mem)
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	type counts struct{ numeric, text, choice, positions int }
+	stored := func() counts {
+		t.Helper()
+		var c counts
+		err := db.QueryRowContext(ctx, `
+SELECT sum(numeric_value IS NOT NULL), sum(text_value IS NOT NULL), sum(choice_value IS NOT NULL),
+	(SELECT count(*) FROM ranking_positions)
+FROM answers`).Scan(&c.numeric, &c.text, &c.choice, &c.positions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	revisions, err := st.ExportRevisions(ctx, models.RevisionFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected counts
+	for _, rev := range revisions {
+		rows, positions := seed.Normalize(rev.Answers, rev.Rankings)
+		for _, a := range rows {
+			switch {
+			case a.NumericValue != nil:
+				expected.numeric++
+			case a.TextValue != nil:
+				expected.text++
+			case a.ChoiceValue != nil:
+				expected.choice++
+			}
+		}
+		expected.positions += len(positions)
+	}
+	if expected.numeric == 0 || expected.text == 0 || expected.positions == 0 {
+		t.Fatalf("fixture does not cover scales, texts and rankings: %+v", expected)
+	}
+
+	// Every submission is written to the normalized tables as well, and the
+	// analytics aggregated from them match the in-memory ones.
+	if got := stored(); got != expected {
+		t.Errorf("normalized rows = %+v, want %+v", got, expected)
+	}
+	if got := report(ts); got != want {
+		t.Errorf("analytics on sqlite:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Revisions stored before the tables existed are filled in on start.
+	for _, table := range []string{"answers", "ranking_positions"} {
+		if _, err := db.ExecContext(ctx, `DELETE FROM `+table); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := st.EnsureSchema(ctx, seed.Participants()); err != nil {
+		t.Fatal(err)
+	}
+	if got := stored(); got != expected {
+		t.Errorf("backfilled rows = %+v, want %+v", got, expected)
+	}
+	if got := report(ts); got != want {
+		t.Errorf("analytics after backfill:\n%s\nwant:\n%s", got, want)
+	}
+
+	// An admin who submits is neither a rater nor an expected one.
+	for _, s := range []*testServer{ts, mem} {
+		rec, err := s.srv.store.ResponseByParticipant(ctx, 1, "1122")
+		if err != nil || rec == nil {
+			t.Fatalf("response of 1122 = %+v, %v", rec, err)
+		}
+		if err := s.srv.store.UpsertResponse(ctx, 1, "0000", rec.Answers, nil, false, ""); err != nil {
+			t.Fatal(err)
+		}
+		if got := report(s); got != want {
+			t.Errorf("analytics with an admin response:\n%s\nwant:\n%s", got, want)
+		}
+	}
+}
+
+func TestGDPR(t *testing.T) {
+	ts := newTestServer(t)
+	ts.submitFixture()
+	admin := ts.admin()
+	revisions := func() []models.ResponseRevision {
+		t.Helper()
+		revs, err := ts.store.ExportRevisions(t.Context(), models.RevisionFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return revs
+	}
+
+	// The export holds the subject's own data and, without names, what
+	// colleagues said about them.
+	expectStatus(t, ts.do(http.MethodGet, "/api/admin/gdpr/export?participant=nobody", admin, nil), http.StatusBadRequest)
+	rec := ts.do(http.MethodGet, "/api/admin/gdpr/export?participant=1425", admin, nil)
+	expectStatus(t, rec, http.StatusOK)
+	if cd := rec.Header().Get("Content-Disposition"); !strings.Contains(cd, "opslab-subject-1425-") {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	var export gdpr.Export
+	decodeJSON(t, rec, &export)
+	if export.Participant.Code != "1425" || len(export.Responses) != 1 || len(export.AboutThem) == 0 || len(export.RankedBy) == 0 {
+		t.Fatalf("export = %d responses, %d answers about, %d rankings", len(export.Responses), len(export.AboutThem), len(export.RankedBy))
+	}
+	for _, a := range export.AboutThem {
+		if strings.Contains(a.QuestionID, ":1") {
+			t.Errorf("answer about the subject names a code: %q", a.QuestionID)
+		}
+	}
+
+	// A reset snapshot that holds mentions of 1425 is rewritten as well.
+	rec = ts.do(http.MethodPost, "/api/admin/reset", admin, map[string]interface{}{"scope": map[string]interface{}{"kind": "participant", "participantCode": "1122"}})
+	expectStatus(t, rec, http.StatusOK)
+	var reset struct {
+		Token string `json:"token"`
+	}
+	decodeJSON(t, rec, &reset)
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/reset/confirm", admin, map[string]string{"token": reset.Token}), http.StatusOK)
+
+	// A colleague who only names 1425 in free text is rewritten too.
+	expectStatus(t, ts.do(http.MethodPost, "/api/response", ts.participant("1122"), map[string]interface{}{"answers": []map[string]interface{}{
+		{"questionId": "common:ownership-gaps", "value": "Марії Василик дякую, Оксана теж молодець."},
+	}}), http.StatusOK)
+
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/gdpr/erase", admin, map[string]string{"participantCode": "nobody"}), http.StatusBadRequest)
+	rec = ts.do(http.MethodPost, "/api/admin/gdpr/erase", admin, map[string]string{"participantCode": "1425"})
+	expectStatus(t, rec, http.StatusOK)
+	var preview struct {
+		Token     string `json:"token"`
+		Revisions int    `json:"revisions"`
+		Mentions  int    `json:"mentions"`
+		Snapshots int    `json:"snapshots"`
+	}
+	decodeJSON(t, rec, &preview)
+	if preview.Revisions != 1 || preview.Mentions != 7 || preview.Snapshots != 1 {
+		t.Errorf("erase preview = %+v", preview)
+	}
+	if len(revisions()) != 8 {
+		t.Fatal("the preview changed data")
+	}
+
+	rec = ts.do(http.MethodPost, "/api/admin/gdpr/erase/confirm", admin, map[string]string{"token": preview.Token})
+	expectStatus(t, rec, http.StatusOK)
+	var erased struct {
+		Result models.ErasureResult `json:"result"`
+	}
+	decodeJSON(t, rec, &erased)
+	if r := erased.Result; r.Revisions != 1 || r.Pseudonymized != 7 || r.Snapshots != 1 {
+		t.Errorf("erasure = %+v", r)
+	}
+	for _, rev := range revisions() {
+		if rev.ParticipantCode == "1425" || gdpr.Refers(rev, "1425") {
+			t.Errorf("revision %d still refers to 1425", rev.ID)
+		}
+		if rev.ParticipantCode == "1122" {
+			if got := rev.Answers[0].Value; got != "[колега] дякую, Оксана теж молодець." {
+				t.Errorf("colleague's text after erasure = %q", got)
+			}
+		}
+	}
+	snapshots, err := ts.store.ListSnapshots(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := ts.store.SnapshotByID(t.Context(), snapshots[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	held, err := decodeSnapshot(snapshot.Data)
+	if err != nil || len(held) != 1 || gdpr.Refers(held[0], "1425") {
+		t.Errorf("snapshot after erasure = %+v, %v", held, err)
+	}
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/gdpr/erase/confirm", admin, map[string]string{"token": preview.Token}), http.StatusConflict)
+	rec = ts.do(http.MethodGet, "/api/admin/gdpr/export?participant=1425", admin, nil)
+	expectStatus(t, rec, http.StatusOK)
+	decodeJSON(t, rec, &export)
+	if len(export.Responses) != 0 || len(export.AboutThem) != 0 || len(export.RankedBy) != 0 {
+		t.Errorf("export after erasure = %+v", export)
+	}
+
+	// Retention anonymizes a closed round, then purges it.
+	expectStatus(t, ts.do(http.MethodGet, "/api/admin/gdpr/retention", admin, nil), http.StatusServiceUnavailable)
+	day := 24 * time.Hour
+	retention := gdpr.NewRetention(ts.store, gdpr.Policy{AnonymizeAfter: 30 * day, PurgeAfter: 90 * day, Interval: time.Hour})
+	ts.srv.retention = retention
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/rounds", admin, map[string]string{"title": "Q3", "state": "open"}), http.StatusOK)
+	expectStatus(t, ts.do(http.MethodPost, "/api/admin/rounds/update", admin, map[string]interface{}{"id": 1, "title": "Раунд 1", "state": "closed"}), http.StatusOK)
+
+	var plan struct {
+		Policy  map[string]string `json:"policy"`
+		Planned []gdpr.Action     `json:"planned"`
+	}
+	rec = ts.do(http.MethodGet, "/api/admin/gdpr/retention", admin, nil)
+	expectStatus(t, rec, http.StatusOK)
+	decodeJSON(t, rec, &plan)
+	if len(plan.Planned) != 0 || plan.Policy["anonymizeAfter"] != "30d" {
+		t.Errorf("retention today = %+v", plan)
+	}
+
+	start := time.Now()
+	retention.Now = func() time.Time { return start.Add(40 * day) }
+	rec = ts.do(http.MethodPost, "/api/admin/gdpr/retention", admin, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var report gdpr.Report
+	decodeJSON(t, rec, &report)
+	if len(report.Actions) != 1 || report.Actions[0].RoundID != 1 || report.Actions[0].Kind != gdpr.ActionAnonymize || report.Snapshots != 1 {
+		t.Errorf("anonymize report = %+v", report)
+	}
+	var anonymous []models.AnonymousResponse
+	rec = ts.do(http.MethodGet, "/api/admin/gdpr/anonymized?round=1", admin, nil)
+	expectStatus(t, rec, http.StatusOK)
+	decodeJSON(t, rec, &anonymous)
+	if len(anonymous) != 7 {
+		t.Fatalf("anonymous responses = %d, want 7", len(anonymous))
+	}
+	for _, a := range anonymous {
+		if !strings.HasPrefix(a.Respondent, "anon-") {
+			t.Errorf("respondent = %q", a.Respondent)
+		}
+		for _, rk := range a.Rankings {
+			for _, code := range rk.Order {
+				if _, ok := ts.srv.participantBy[code]; ok {
+					t.Errorf("ranking names %s", code)
+				}
+			}
+		}
+		for _, answer := range a.Answers {
+			if _, isText := answer.Value.(string); isText && strings.Contains(answer.QuestionID, "common:ownership") {
+				t.Errorf("free text kept: %s", answer.QuestionID)
+			}
+		}
+	}
+	if n := len(revisions()); n != 0 {
+		t.Errorf("revisions after anonymizing = %d", n)
+	}
+
+	retention.Now = func() time.Time { return start.Add(100 * day) }
+	rec = ts.do(http.MethodPost, "/api/admin/gdpr/retention", admin, nil)
+	expectStatus(t, rec, http.StatusOK)
+	decodeJSON(t, rec, &report)
+	if len(report.Actions) != 1 || report.Actions[0].Kind != gdpr.ActionPurge {
+		t.Errorf("purge report = %+v", report)
+	}
+	expectStatus(t, ts.do(http.MethodGet, "/api/admin/gdpr/anonymized?round=1", admin, nil), http.StatusNotFound)
+	entries, err := ts.store.ListAudit(t.Context(), models.AuditFilter{Actor: "retention"})
+	if err != nil || len(entries) != 3 {
+		t.Errorf("retention audit entries = %d, %v", len(entries), err)
+	}
+}
+
+func TestPseudonymizedAnalytics(t *testing.T) {
+	ts := newTestServer(t)
+	ts.submitFixture()
+	admin := ts.admin()
+	rec := ts.do(http.MethodPost, "/api/response", ts.participant("1122"), map[string]interface{}{"answers": []map[string]interface{}{
+		{"questionId": "common:ownership-gaps", "value": "Марія Василик і Оксана тягнуть онбординг, а Jane допомагає."},
+		{"questionId": "common:decision-barriers", "value": "З Марією Василик і Оксаною рішення швидші."},
+	}})
+	expectStatus(t, rec, http.StatusOK)
+	round := ts.srv.pseudonyms.Round(1, ts.srv.participants)
+	identifies := func(body string) string {
+		for _, p := range seed.Participants() {
+			for _, s := range []string{`"` + p.Code, ":" + p.Code, p.Name, p.Email, "Василик", "Оксан"} {
+				if strings.Contains(body, s) {
+					return s
+				}
+			}
+		}
+		return ""
+	}
+
+	expectStatus(t, ts.do(http.MethodGet, "/api/admin/export?anonymize=maybe", admin, nil), http.StatusBadRequest)
+	rec = ts.do(http.MethodGet, "/api/admin/export?anonymize=true", admin, nil)
+	expectStatus(t, rec, http.StatusOK)
+	if s := identifies(rec.Body.String()); s != "" {
+		t.Errorf("anonymized export contains %q", s)
+	}
+	var export struct {
+		Participants []models.Participant    `json:"participants"`
+		Responses    []models.ResponseRecord `json:"responses"`
+	}
+	decodeJSON(t, rec, &export)
+	if len(export.Participants) != len(seed.Participants()) || len(export.Responses) != 8 {
+		t.Fatalf("export = %d participants, %d responses", len(export.Participants), len(export.Responses))
+	}
+	texts := map[string]string{}
+	for _, resp := range export.Responses {
+		if resp.ParticipantCode != round.Code("1122") {
+			continue
+		}
+		for _, a := range resp.Answers {
+			texts[a.QuestionID], _ = a.Value.(string)
+		}
+	}
+	for question, want := range map[string]string{
+		"common:ownership-gaps":    round.Code("1425") + " і " + round.Code("8463") + " тягнуть онбординг, а " + round.Code("7139") + " допомагає.",
+		"common:decision-barriers": "З " + round.Code("1425") + " і " + round.Code("8463") + " рішення швидші.",
+	} {
+		if texts[question] != want {
+			t.Errorf("scrubbed %s = %q, want %q", question, texts[question], want)
+		}
+	}
+
+	// Pseudonyms hold within a round but not across rounds.
+	rec = ts.do(http.MethodGet, "/api/admin/export?anonymize=true", admin, nil)
+	var again struct {
+		Participants []models.Participant `json:"participants"`
+	}
+	decodeJSON(t, rec, &again)
+	if !reflect.DeepEqual(again.Participants, export.Participants) {
+		t.Error("pseudonyms changed between exports")
+	}
+	if ts.srv.pseudonyms.Round(2, ts.srv.participants).Code("1122") == round.Code("1122") {
+		t.Error("pseudonym is the same in another round")
+	}
+
+	for _, path := range []string{
+		"/api/admin/analytics?anonymize=true",
+		"/api/admin/text?anonymize=true",
+		"/api/admin/text?question=peer:strengths&ratee=" + round.Code("1425") + "&anonymize=true",
+		"/api/admin/sociogram?anonymize=true",
+		"/api/admin/reciprocity?anonymize=true",
+		"/api/admin/communities?anonymize=true",
+		"/api/admin/export?format=csv&anonymize=true",
+	} {
+		rec := ts.do(http.MethodGet, path, admin, nil)
+		expectStatus(t, rec, http.StatusOK)
+		if s := identifies(rec.Body.String()); s != "" && !strings.Contains(path, "csv") {
+			t.Errorf("%s contains %q", path, s)
+		}
+	}
+}
+
+func TestRedactions(t *testing.T) {
+	ts := newTestServer(t)
+	ts.submitFixture()
+	admin := ts.admin()
+	const (
+		common = "Поговоріть з Марією Василик або Олегом: oleg@example.com, +380 67 123 45 67. Катерину не питайте."
+		peer   = "Mariya завжди допоможе, як і Іващукові."
+	)
+	rec := ts.do(http.MethodPost, "/api/response", ts.participant("1122"), map[string]interface{}{
+		"answers": []map[string]interface{}{
+			{"questionId": "common:ownership-gaps", "value": common},
+			{"questionId": "peer:strengths:1425", "value": peer},
+			{"questionId": "common:decision-barriers", "value": "Рішення відкладаються до 15.01.2024."},
+		},
+		"rankings": []map[string]interface{}{
+			{"criteria": seed.RankingCriteria()[0], "order": []string{"1425", "3814", "4582", "6738", "7139", "8463", "9267"}, "comment": "Вероніці бракує часу"},
+		},
+	})
+	expectStatus(t, rec, http.StatusOK)
+
+	texts := func(query string) map[string]string {
+		t.Helper()
+		rec := ts.do(http.MethodGet, "/api/admin/export"+query, admin, nil)
+		expectStatus(t, rec, http.StatusOK)
+		var export struct {
+			Responses []models.ResponseRecord `json:"responses"`
+		}
+		decodeJSON(t, rec, &export)
+		texts := map[string]string{}
+		for _, resp := range export.Responses {
+			if resp.ParticipantCode != "1122" {
+				continue
+			}
+			for _, a := range resp.Answers {
+				if text, ok := a.Value.(string); ok {
+					texts[a.QuestionID] = text
+				}
+			}
+			for _, rk := range resp.Rankings {
+				texts["ranking"] += rk.Comment
+			}
+		}
+		return texts
+	}
+	want := map[string]string{
+		"common:ownership-gaps":    "Поговоріть з [колега] або [адміністратор]: [email], [телефон]. [автор] не питайте.",
+		"peer:strengths:1425":      "[оцінюваний] завжди допоможе, як і [колега].",
+		"common:decision-barriers": "Рішення відкладаються до 15.01.2024.",
+		"ranking":                  "[колега] бракує часу",
+	}
+	got := texts("")
+	for q, w := range want {
+		if got[q] != w {
+			t.Errorf("scrubbed %s = %q, want %q", q, got[q], w)
+		}
+	}
+	if got := texts("?scrub=false"); got["common:ownership-gaps"] != common || got["peer:strengths:1425"] != peer {
+		t.Errorf("unscrubbed export = %q", got)
+	}
+	expectStatus(t, ts.do(http.MethodGet, "/api/admin/export?scrub=nope", admin, nil), http.StatusBadRequest)
+
+	rec = ts.do(http.MethodGet, "/api/admin/redactions", admin, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var preview struct {
+		Counts map[string]int `json:"counts"`
+		Items  []struct {
+			ParticipantCode string `json:"participantCode"`
+			ParticipantName string `json:"participantName"`
+			QuestionID      string `json:"questionId"`
+			Original        string `json:"original"`
+			Scrubbed        string `json:"scrubbed"`
+			Redactions      []struct {
+				Kind            string `json:"kind"`
+				Text            string `json:"text"`
+				Token           string `json:"token"`
+				ParticipantCode string `json:"participantCode"`
+			} `json:"redactions"`
+		} `json:"items"`
+	}
+	decodeJSON(t, rec, &preview)
+	if c := preview.Counts; c["name"] != 6 || c["email"] != 1 || c["phone"] != 1 {
+		t.Errorf("counts = %v", c)
+	}
+	if len(preview.Items) != 3 {
+		t.Fatalf("items = %+v", preview.Items)
+	}
+	item := preview.Items[0]
+	if item.ParticipantName != "Катерина Петухова" || item.Original != common || item.Scrubbed != want["common:ownership-gaps"] {
+		t.Errorf("item = %+v", item)
+	}
+	if r := item.Redactions[0]; r.Text != "Марією Василик" || r.ParticipantCode != "1425" || r.Token != "[колега]" {
+		t.Errorf("first redaction = %+v", r)
+	}
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"opslab-survey/internal/analytics"
+)
+
+// handleAdminAnalytics returns descriptives for every scale question and
+// reliability of the peer scale set for a round. The descriptives are
+// aggregated by the store; with ?anonymize=true their colleague codes get the
+// same pseudonyms as the responses.
+func (s *Server) handleAdminAnalytics(w http.ResponseWriter, r *http.Request) {
+	round := s.requestRound(w, r)
+	if round == nil {
+		return
+	}
+	participants, responses, ok := s.roundResponses(w, r, round.ID)
+	if !ok {
+		return
+	}
+	counts, err := s.store.CountValues(r.Context(), round.ID)
+	if err != nil {
+		log.Println("analytics:", err)
+		http.Error(w, "cannot load analytics", http.StatusInternalServerError)
+		return
+	}
+	if anonymize, _ := strconv.ParseBool(r.URL.Query().Get("anonymize")); anonymize {
+		pseudonyms := s.pseudonyms.Round(round.ID, s.participants)
+		for i, c := range counts {
+			if c.PeerCode != "" {
+				counts[i].PeerCode = pseudonyms.Code(c.PeerCode)
+			}
+		}
+	}
+	writeJSON(w, analytics.Compute(participants, responses, counts))
+}
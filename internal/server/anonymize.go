@@ -0,0 +1,94 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/scrub"
+)
+
+// roundResponses loads the responses of a round together with the
+// participants to report them with. Names, emails and phone numbers in free
+// text are replaced with role tokens unless ?scrub=false; with
+// ?anonymize=true everything is pseudonymized for the round instead (see
+// package pseudonym). It writes an error response and returns false on
+// failure.
+func (s *Server) roundResponses(w http.ResponseWriter, r *http.Request, roundID int64) ([]models.Participant, []models.ResponseRecord, bool) {
+	anonymize, ok := queryBool(w, r, "anonymize", false)
+	if !ok {
+		return nil, nil, false
+	}
+	scrubbed, ok := queryBool(w, r, "scrub", true)
+	if !ok {
+		return nil, nil, false
+	}
+	responses, err := s.store.AllResponses(r.Context(), roundID)
+	if err != nil {
+		log.Println("round responses:", err)
+		http.Error(w, "cannot load responses", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	switch {
+	case anonymize:
+		noteAudit(r, "", fmt.Sprintf("round:%d anonymized", roundID))
+		round := s.pseudonyms.Round(roundID, s.participants)
+		return round.Participants(), round.Responses(responses), true
+	case scrubbed:
+		responses, _ = s.scrubber.Responses(responses)
+	default:
+		noteAudit(r, "", fmt.Sprintf("round:%d unscrubbed", roundID))
+	}
+	return s.participants, responses, true
+}
+
+// queryBool reads a boolean query parameter, def if it is absent.
+func queryBool(w http.ResponseWriter, r *http.Request, name string, def bool) (bool, bool) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return def, true
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		http.Error(w, "invalid "+name, http.StatusBadRequest)
+		return false, false
+	}
+	return v, true
+}
+
+// redactedAnswer is one row of the redaction preview.
+type redactedAnswer struct {
+	scrub.Redacted
+	ParticipantName string `json:"participantName"`
+}
+
+// handleAdminRedactions shows what scrubbing takes out of the text answers
+// and ranking comments of a round: each changed text before and after, and
+// what was replaced, so that missed names and false alarms can be spotted.
+func (s *Server) handleAdminRedactions(w http.ResponseWriter, r *http.Request) {
+	round := s.requestRound(w, r)
+	if round == nil {
+		return
+	}
+	responses, err := s.store.AllResponses(r.Context(), round.ID)
+	if err != nil {
+		log.Println("redactions:", err)
+		http.Error(w, "cannot load responses", http.StatusInternalServerError)
+		return
+	}
+	_, redacted := s.scrubber.Responses(responses)
+	counts := map[string]int{scrub.KindName: 0, scrub.KindEmail: 0, scrub.KindPhone: 0}
+	items := []redactedAnswer{}
+	for _, red := range redacted {
+		for _, rd := range red.Redactions {
+			counts[rd.Kind]++
+		}
+		items = append(items, redactedAnswer{Redacted: red, ParticipantName: s.participantBy[red.ParticipantCode].Name})
+	}
+	writeJSON(w, map[string]interface{}{
+		"counts": counts,
+		"items":  items,
+	})
+}
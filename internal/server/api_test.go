@@ -0,0 +1,379 @@
+package server
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"opslab-survey/internal/auth"
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/seed"
+	"opslab-survey/internal/store/sqlite"
+)
+
+func TestLogin(t *testing.T) {
+	ts := newTestServer(t)
+
+	t.Run("method", func(t *testing.T) {
+		expectStatus(t, ts.do(http.MethodGet, "/api/login", nil, nil), http.StatusMethodNotAllowed)
+	})
+	t.Run("malformed body", func(t *testing.T) {
+		expectStatus(t, ts.do(http.MethodPost, "/api/login", nil, "{"), http.StatusBadRequest)
+	})
+	t.Run("wrong code", func(t *testing.T) {
+		rec := ts.do(http.MethodPost, "/api/login", nil, map[string]string{"email": "mariya.vasylyk@opslab.uk", "code": "9999"})
+		expectStatus(t, rec, http.StatusUnauthorized)
+		if len(rec.Result().Cookies()) != 0 {
+			t.Error("failed login must not set a cookie")
+		}
+	})
+	t.Run("code of someone else", func(t *testing.T) {
+		rec := ts.do(http.MethodPost, "/api/login", nil, map[string]string{"email": "mariya.vasylyk@opslab.uk", "code": "1122"})
+		expectStatus(t, rec, http.StatusUnauthorized)
+	})
+	t.Run("normalises email", func(t *testing.T) {
+		rec := ts.do(http.MethodPost, "/api/login", nil, map[string]string{"email": "  Mariya.Vasylyk@OPSLAB.uk ", "code": " 1425 "})
+		expectStatus(t, rec, http.StatusOK)
+		assertGolden(t, "login", rec)
+		cookies := rec.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != "session" || !cookies[0].HttpOnly {
+			t.Fatalf("cookies = %+v, want one HttpOnly session cookie", cookies)
+		}
+	})
+}
+
+func TestSessionRequired(t *testing.T) {
+	ts := newTestServer(t)
+	forged := &http.Cookie{Name: "session", Value: "not-a-jwt"}
+	for _, route := range []string{"/api/me", "/api/consent", "/api/consent/accept", "/api/questions", "/api/response", "/api/draft", "/api/logout", "/api/admin/stats"} {
+		t.Run(route, func(t *testing.T) {
+			expectStatus(t, ts.do(http.MethodGet, route, nil, nil), http.StatusUnauthorized)
+			expectStatus(t, ts.do(http.MethodGet, route, forged, nil), http.StatusUnauthorized)
+		})
+	}
+
+	t.Run("token signed with another secret", func(t *testing.T) {
+		foreign := newTestServer(t)
+		foreign.srv.authManager = auth.NewManager("other-secret")
+		foreign.handler = foreign.srv.Routes()
+		cookie := foreign.participant("1425")
+		expectStatus(t, ts.do(http.MethodGet, "/api/me", cookie, nil), http.StatusUnauthorized)
+	})
+
+	t.Run("me", func(t *testing.T) {
+		rec := ts.do(http.MethodGet, "/api/me", ts.participant("1425"), nil)
+		expectStatus(t, rec, http.StatusOK)
+		assertGolden(t, "me", rec)
+	})
+
+	t.Run("logout clears cookie", func(t *testing.T) {
+		rec := ts.do(http.MethodPost, "/api/logout", ts.participant("1425"), nil)
+		expectStatus(t, rec, http.StatusOK)
+		cookies := rec.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Value != "" || cookies[0].Expires.After(time.Now()) {
+			t.Fatalf("cookies = %+v, want an expired empty session cookie", cookies)
+		}
+	})
+}
+
+// adminRoutes lists every /api/admin/* route with a request that would
+// succeed for an admin.
+var adminRoutes = []struct{ method, path string }{
+	{http.MethodGet, "/api/admin/stats"},
+	{http.MethodGet, "/api/admin/events"},
+	{http.MethodGet, "/api/admin/responses"},
+	{http.MethodGet, "/api/admin/response/1425"},
+	{http.MethodGet, "/api/admin/revisions/1425"},
+	{http.MethodGet, "/api/admin/export"},
+	{http.MethodGet, "/api/admin/analytics"},
+	{http.MethodGet, "/api/admin/text"},
+	{http.MethodGet, "/api/admin/redactions"},
+	{http.MethodGet, "/api/admin/network"},
+	{http.MethodGet, "/api/admin/reciprocity"},
+	{http.MethodGet, "/api/admin/communities"},
+	{http.MethodGet, "/api/admin/sociogram"},
+	{http.MethodGet, "/api/admin/sociogram/sources"},
+	{http.MethodPost, "/api/admin/run-test"},
+	{http.MethodPost, "/api/admin/reset"},
+	{http.MethodPost, "/api/admin/reset/confirm"},
+	{http.MethodGet, "/api/admin/snapshots"},
+	{http.MethodPost, "/api/admin/snapshots/restore"},
+	{http.MethodGet, "/api/admin/backup"},
+	{http.MethodPost, "/api/admin/backup/restore"},
+	{http.MethodGet, "/api/admin/rounds"},
+	{http.MethodPost, "/api/admin/rounds/update"},
+	{http.MethodGet, "/api/admin/rounds/extend"},
+	{http.MethodGet, "/api/admin/reminders"},
+	{http.MethodPost, "/api/admin/reminders/nudge"},
+	{http.MethodGet, "/api/admin/webhooks"},
+	{http.MethodPost, "/api/admin/webhooks/delete"},
+	{http.MethodGet, "/api/admin/webhooks/deliveries"},
+	{http.MethodGet, "/api/admin/audit"},
+	{http.MethodGet, "/api/admin/audit/verify"},
+	{http.MethodGet, "/api/admin/consent"},
+	{http.MethodGet, "/api/admin/gdpr/export?participant=1425"},
+	{http.MethodPost, "/api/admin/gdpr/erase"},
+	{http.MethodPost, "/api/admin/gdpr/erase/confirm"},
+	{http.MethodPost, "/api/admin/gdpr/retention"},
+	{http.MethodGet, "/api/admin/gdpr/anonymized"},
+}
+
+func TestAdminGating(t *testing.T) {
+	ts := newTestServer(t)
+	ts.submitFixture()
+	participant := ts.participant("1425")
+	for _, route := range adminRoutes {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			expectStatus(t, ts.do(route.method, route.path, nil, nil), http.StatusUnauthorized)
+			expectStatus(t, ts.do(route.method, route.path, participant, nil), http.StatusForbidden)
+		})
+	}
+	// Nothing a participant tried may have changed the data.
+	responses, err := ts.store.AllResponses(t.Context(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(responses) != 8 {
+		t.Fatalf("responses after gated calls = %d, want 8", len(responses))
+	}
+}
+
+func TestQuestions(t *testing.T) {
+	ts := newTestServer(t)
+	rec := ts.do(http.MethodGet, "/api/questions", ts.participant("1425"), nil)
+	expectStatus(t, rec, http.StatusOK)
+	assertGolden(t, "questions", rec)
+
+	var payload struct {
+		Rankable []models.Participant `json:"rankableParticipants"`
+	}
+	decodeJSON(t, rec, &payload)
+	for _, p := range payload.Rankable {
+		if p.Code == "1425" || p.IsAdmin {
+			t.Errorf("participant %s must not be rankable by 1425", p.Code)
+		}
+	}
+	if len(payload.Rankable) != 7 {
+		t.Errorf("rankable = %d, want 7", len(payload.Rankable))
+	}
+}
+
+func TestResponseValidation(t *testing.T) {
+	ts := newTestServer(t)
+	cookie := ts.participant("1425")
+	ranking := func(order ...string) map[string]interface{} {
+		return map[string]interface{}{
+			"answers":  []models.AnswerPayload{{QuestionID: "common:trust-level", Value: 7}},
+			"rankings": []models.RankingPayload{{Criteria: "Лідерство та вплив", Order: order}},
+		}
+	}
+
+	cases := []struct {
+		name   string
+		method string
+		body   interface{}
+		status int
+		msg    string
+	}{
+		{"get", http.MethodGet, nil, http.StatusMethodNotAllowed, "method not allowed"},
+		{"malformed", http.MethodPost, "{", http.StatusBadRequest, "bad request"},
+		{"unknown participant", http.MethodPost, ranking("1122", "5555"), http.StatusBadRequest, "unknown participant in ranking: 5555"},
+		{"ranks self", http.MethodPost, ranking("1122", "1425"), http.StatusBadRequest, "unknown participant in ranking: 1425"},
+		{"ranks admin", http.MethodPost, ranking("0000"), http.StatusBadRequest, "unknown participant in ranking: 0000"},
+		{"criterion ranked twice", http.MethodPost, map[string]interface{}{
+			"rankings": []models.RankingPayload{
+				{Criteria: "Лідерство та вплив", Order: []string{"1122"}},
+				{Criteria: "Лідерство та вплив", Order: []string{"3814"}},
+			},
+		}, http.StatusBadRequest, "criterion ranked twice: Лідерство та вплив"},
+		{"valid", http.MethodPost, ranking("1122", "3814"), http.StatusOK, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rec := ts.do(c.method, "/api/response", cookie, c.body)
+			expectStatus(t, rec, c.status)
+			if c.msg != "" && strings.TrimSpace(rec.Body.String()) != c.msg {
+				t.Errorf("body = %q, want %q", rec.Body.String(), c.msg)
+			}
+		})
+	}
+
+	revisions, err := ts.store.ListRevisions(t.Context(), 1, "1425")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 1 {
+		t.Fatalf("revisions = %d, want only the valid submission", len(revisions))
+	}
+}
+
+func TestResponseRoundWindow(t *testing.T) {
+	ts := newTestServer(t)
+	admin := ts.admin()
+	cookie := ts.participant("1425")
+	body := map[string]interface{}{"answers": []models.AnswerPayload{{QuestionID: "common:trust-level", Value: 5}}}
+
+	past := time.Now().Add(-time.Hour)
+	rec := ts.do(http.MethodPost, "/api/admin/rounds/update", admin, map[string]interface{}{
+		"id": 1, "title": "Раунд 1", "state": "open", "closesAt": past,
+	})
+	expectStatus(t, rec, http.StatusOK)
+
+	rec = ts.do(http.MethodPost, "/api/response", cookie, body)
+	expectStatus(t, rec, http.StatusForbidden)
+	if !strings.Contains(rec.Body.String(), "Термін подання відповідей минув") {
+		t.Errorf("closed round message = %q", rec.Body.String())
+	}
+
+	rec = ts.do(http.MethodPost, "/api/admin/rounds/extend", admin, map[string]interface{}{
+		"participantCode": "1425", "closesAt": time.Now().Add(time.Hour), "reason": "відпустка",
+	})
+	expectStatus(t, rec, http.StatusOK)
+	expectStatus(t, ts.do(http.MethodPost, "/api/response", cookie, body), http.StatusOK)
+	expectStatus(t, ts.do(http.MethodPost, "/api/response", ts.participant("1122"), body), http.StatusForbidden)
+}
+
+func TestDraft(t *testing.T) {
+	ts := newTestServer(t)
+	cookie := ts.participant("1425")
+
+	rec := ts.do(http.MethodGet, "/api/draft", cookie, nil)
+	expectStatus(t, rec, http.StatusOK)
+	assertGolden(t, "draft_empty", rec)
+
+	rec = ts.do(http.MethodPost, "/api/draft", cookie, map[string]interface{}{
+		"answers": []models.AnswerPayload{
+			{QuestionID: "common:trust-level", Value: 8},
+			{QuestionID: "common:ownership-gaps", Value: ""},
+		},
+	})
+	expectStatus(t, rec, http.StatusOK)
+	assertGolden(t, "draft_saved", rec)
+
+	rec = ts.do(http.MethodGet, "/api/draft", cookie, nil)
+	expectStatus(t, rec, http.StatusOK)
+	assertGolden(t, "draft", rec)
+
+	expectStatus(t, ts.do(http.MethodDelete, "/api/draft", cookie, nil), http.StatusMethodNotAllowed)
+}
+
+func TestConsent(t *testing.T) {
+	ts := newTestServer(t)
+	admin := ts.admin()
+	cookie := ts.participant("1425")
+	body := map[string]interface{}{"answers": []models.AnswerPayload{{QuestionID: "common:trust-level", Value: 5}}}
+	expectStatus(t, ts.do(http.MethodGet, "/api/questions", cookie, nil), http.StatusOK)
+
+	// A retention policy changes the text of the notice, which becomes a new
+	// version and locks the survey until it is accepted.
+	initial := ts.srv.notice
+	if err := ts.srv.useNotice(t.Context(), seed.ConsentNotice(365*24*time.Hour, 0)); err != nil {
+		t.Fatal(err)
+	}
+	version := ts.srv.notice.Version
+	if version != initial.Version+1 {
+		t.Fatalf("version after the policy changed = %d, want %d", version, initial.Version+1)
+	}
+	for _, path := range []string{"/api/questions", "/api/draft"} {
+		expectStatus(t, ts.do(http.MethodGet, path, cookie, nil), http.StatusPreconditionRequired)
+	}
+	expectStatus(t, ts.do(http.MethodPost, "/api/response", cookie, body), http.StatusPreconditionRequired)
+
+	rec := ts.do(http.MethodGet, "/api/consent", cookie, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var status struct {
+		Notice          models.ConsentNotice `json:"notice"`
+		Accepted        bool                 `json:"accepted"`
+		AcceptedVersion int                  `json:"acceptedVersion"`
+	}
+	decodeJSON(t, rec, &status)
+	if status.Accepted || status.AcceptedVersion != version-1 || status.Notice.Version != version || len(status.Notice.Sections) == 0 {
+		t.Errorf("consent before accepting = %+v", status)
+	}
+	expectStatus(t, ts.do(http.MethodGet, "/api/consent/accept", cookie, nil), http.StatusMethodNotAllowed)
+	expectStatus(t, ts.do(http.MethodPost, "/api/consent/accept", cookie, map[string]int{"version": version - 1}), http.StatusConflict)
+	expectStatus(t, ts.do(http.MethodPost, "/api/consent/accept", cookie, map[string]int{"version": version}), http.StatusOK)
+
+	rec = ts.do(http.MethodGet, "/api/consent", cookie, nil)
+	decodeJSON(t, rec, &status)
+	if !status.Accepted || status.AcceptedVersion != version {
+		t.Errorf("consent after accepting = %+v", status)
+	}
+	expectStatus(t, ts.do(http.MethodGet, "/api/questions", cookie, nil), http.StatusOK)
+	expectStatus(t, ts.do(http.MethodPost, "/api/response", cookie, body), http.StatusOK)
+	// Accepting twice keeps the first acceptance.
+	first, err := ts.store.ConsentFor(t.Context(), "1425", version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, ts.do(http.MethodPost, "/api/consent/accept", cookie, map[string]int{"version": version}), http.StatusOK)
+	if again, _ := ts.store.ConsentFor(t.Context(), "1425", version); !again.AcceptedAt.Equal(first.AcceptedAt) {
+		t.Errorf("second acceptance moved the timestamp")
+	}
+
+	// Admins are not surveyed and are never asked.
+	expectStatus(t, ts.do(http.MethodGet, "/api/questions", admin, nil), http.StatusOK)
+
+	rec = ts.do(http.MethodGet, "/api/admin/consent", admin, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var report struct {
+		Version      int            `json:"version"`
+		Counts       map[string]int `json:"counts"`
+		Participants []struct {
+			Code            string `json:"code"`
+			Status          string `json:"status"`
+			AcceptedVersion int    `json:"acceptedVersion"`
+		} `json:"participants"`
+	}
+	decodeJSON(t, rec, &report)
+	if report.Version != version || report.Counts["accepted"] != 1 || report.Counts["outdated"] != 7 || report.Counts["pending"] != 0 {
+		t.Errorf("consent report = %+v", report)
+	}
+	for _, p := range report.Participants {
+		if (p.Code == "1425") != (p.Status == "accepted") {
+			t.Errorf("participant %s: %s", p.Code, p.Status)
+		}
+	}
+
+	// A restart with the same text keeps the version; going back to an
+	// earlier text is a change like any other.
+	changed := ts.srv.notice
+	for _, step := range []struct {
+		notice models.ConsentNotice
+		want   int
+	}{{changed, version}, {initial, version + 1}} {
+		if err := ts.srv.useNotice(t.Context(), step.notice); err != nil {
+			t.Fatal(err)
+		}
+		if ts.srv.notice.Version != step.want {
+			t.Errorf("version = %d, want %d", ts.srv.notice.Version, step.want)
+		}
+	}
+}
+
+func TestConsentVersionPersisted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "survey.db")
+	open := func() *sqlite.Store {
+		t.Helper()
+		st, err := sqlite.Open(t.Context(), path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(st.Close)
+		return st
+	}
+	ts := serve(t, open())
+	if ts.srv.notice.Version != 1 {
+		t.Fatalf("first version = %d, want 1", ts.srv.notice.Version)
+	}
+	changed := seed.ConsentNotice(0, 30*24*time.Hour)
+	if err := ts.srv.useNotice(t.Context(), changed); err != nil {
+		t.Fatal(err)
+	}
+	// The next start finds the version of the text it shows.
+	if v, err := open().NoticeVersion(t.Context(), changed.Digest()); err != nil || v != 2 {
+		t.Errorf("version after a restart = %d, %v, want 2", v, err)
+	}
+}
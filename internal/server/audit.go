@@ -0,0 +1,199 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"opslab-survey/internal/audit"
+	"opslab-survey/internal/models"
+)
+
+const auditCtxKey ctxKey = "audit"
+
+// auditNote lets a handler refine what the audit middleware records.
+type auditNote struct {
+	actor  string
+	action string
+	target string
+}
+
+// noteAudit names the target of the current request and, when action is not
+// empty, replaces the action the route was registered with.
+func noteAudit(r *http.Request, action, target string) {
+	if n, ok := r.Context().Value(auditCtxKey).(*auditNote); ok {
+		if action != "" {
+			n.action = action
+		}
+		n.target = target
+	}
+}
+
+// noteAuditActor records who acted when the session does not say, as on login.
+func noteAuditActor(r *http.Request, code string) {
+	if n, ok := r.Context().Value(auditCtxKey).(*auditNote); ok {
+		n.actor = code
+	}
+}
+
+// statusRecorder remembers the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// audited records every request to next in the audit log under action, after
+// the handler has answered. It wraps the authentication middleware so that
+// rejected attempts are logged too. Write failures are logged and never
+// change the response.
+func (s *Server) audited(action string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		note := &auditNote{action: action}
+		if user, err := s.userFromRequest(r); err == nil {
+			note.actor = user.Participant.Code
+		}
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), auditCtxKey, note)))
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		outcome := audit.Outcome(rec.status)
+		// Rejected requests never reach the handler; keep what they asked for.
+		if note.target == "" && outcome == models.AuditDenied {
+			note.target = "path:" + r.URL.Path
+		}
+		_, err := s.store.AppendAudit(context.WithoutCancel(r.Context()), models.AuditEntry{
+			Actor:     note.actor,
+			Action:    note.action,
+			Target:    note.target,
+			IP:        clientIP(r),
+			UserAgent: truncate(r.UserAgent(), 512),
+			Outcome:   outcome,
+			Status:    rec.status,
+		})
+		if err != nil {
+			log.Println("audit:", err)
+		}
+	})
+}
+
+// clientIP is the address the request came from. Behind the platform proxy
+// that is the last X-Forwarded-For hop, which the proxy itself appends;
+// earlier hops are whatever the client claimed.
+func clientIP(r *http.Request) string {
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+		hops := strings.Split(fwd, ",")
+		if ip := strings.TrimSpace(hops[len(hops)-1]); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+// handleAdminAudit lists audit entries, newest first
+// (?actor=&action=&target=&outcome=&from=&to=&before=&limit=). An action
+// ending in "." matches the whole family, e.g. "round.".
+func (s *Server) handleAdminAudit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := models.AuditFilter{
+		Actor:   q.Get("actor"),
+		Action:  q.Get("action"),
+		Target:  q.Get("target"),
+		Outcome: q.Get("outcome"),
+		Limit:   100,
+	}
+	switch f.Outcome {
+	case "", models.AuditSuccess, models.AuditDenied, models.AuditFailure:
+	default:
+		http.Error(w, "invalid outcome", http.StatusBadRequest)
+		return
+	}
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > 1000 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		f.Limit = n
+	}
+	if raw := q.Get("before"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id <= 0 {
+			http.Error(w, "invalid before", http.StatusBadRequest)
+			return
+		}
+		f.BeforeID = id
+	}
+	var ok bool
+	if f.From, ok = queryTime(w, r, "from", false); !ok {
+		return
+	}
+	if f.To, ok = queryTime(w, r, "to", true); !ok {
+		return
+	}
+	entries, err := s.store.ListAudit(r.Context(), f)
+	if err != nil {
+		log.Println("audit log:", err)
+		http.Error(w, "cannot load audit log", http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []models.AuditEntry{}
+	}
+	payload := map[string]interface{}{"items": entries}
+	if len(entries) == f.Limit {
+		payload["nextBefore"] = entries[len(entries)-1].ID
+	}
+	writeJSON(w, payload)
+}
+
+// handleAdminAuditVerify walks the whole log and checks the hash chain.
+func (s *Server) handleAdminAuditVerify(w http.ResponseWriter, r *http.Request) {
+	v := audit.NewVerifier()
+	var after int64
+	for {
+		batch, err := s.store.AuditTrail(r.Context(), after, 1000)
+		if err != nil {
+			log.Println("audit verify:", err)
+			http.Error(w, "cannot verify audit log", http.StatusInternalServerError)
+			return
+		}
+		if len(batch) == 0 || !v.Add(batch) {
+			break
+		}
+		after = batch[len(batch)-1].ID
+	}
+	writeJSON(w, v.Result())
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"opslab-survey/internal/backup"
+	"opslab-survey/internal/events"
+	"opslab-survey/internal/models"
+)
+
+// maxBackupSize caps uploaded archives.
+const maxBackupSize = 64 << 20
+
+// handleAdminBackup downloads the whole dataset as a backup archive.
+func (s *Server) handleAdminBackup(w http.ResponseWriter, r *http.Request) {
+	d, err := backup.Collect(r.Context(), s.store)
+	if err != nil {
+		log.Println("backup:", err)
+		http.Error(w, "cannot collect backup", http.StatusInternalServerError)
+		return
+	}
+	now := time.Now()
+	var buf bytes.Buffer
+	if _, err := backup.Write(&buf, d, now); err != nil {
+		log.Println("backup:", err)
+		http.Error(w, "cannot write backup", http.StatusInternalServerError)
+		return
+	}
+	name := backup.FileName(now)
+	noteAudit(r, "", "file:"+name)
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	w.Write(buf.Bytes())
+}
+
+// replacePlan is what a confirmation token for a replacing restore commits
+// to: the archive that was previewed, by its SHA-256.
+type replacePlan struct {
+	Archive string `json:"archive"`
+}
+
+// handleAdminBackupRestore loads an archive sent as the request body
+// (?mode=merge|replace, merge by default). Replacing deletes everything, so
+// it takes two steps like a reset: without ?token= the archive is only
+// checked and a confirmation token for it is returned; the same archive sent
+// again with the token is restored. What it replaces is not kept: download a
+// backup first (the admin panel does) to be able to go back.
+func (s *Server) handleAdminBackupRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = models.RestoreMerge
+	}
+	noteAudit(r, "", "mode:"+mode)
+	if mode != models.RestoreMerge && mode != models.RestoreReplace {
+		http.Error(w, "mode must be merge or replace", http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBackupSize))
+	if err != nil {
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			http.Error(w, "backup is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "cannot read backup", http.StatusBadRequest)
+		return
+	}
+	d, _, err := backup.Read(bytes.NewReader(body))
+	if err != nil {
+		http.Error(w, "invalid backup: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	sum := sha256.Sum256(body)
+	plan := replacePlan{Archive: hex.EncodeToString(sum[:])}
+	user := r.Context().Value(userCtxKey).(*sessionUser)
+
+	if mode == models.RestoreReplace {
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			s.previewReplace(w, r, d, plan)
+			return
+		}
+		claims, err := s.authManager.ParseConfirmation(token, user.Participant.Code, "backup.replace")
+		var confirmed replacePlan
+		if err != nil || json.Unmarshal(claims.Payload, &confirmed) != nil {
+			http.Error(w, "invalid or expired confirmation token", http.StatusBadRequest)
+			return
+		}
+		if confirmed != plan {
+			http.Error(w, "the confirmation token is for another archive", http.StatusBadRequest)
+			return
+		}
+		if !s.useConfirmation(w, r, claims) {
+			return
+		}
+	}
+	res, err := s.store.RestoreDataset(r.Context(), *d, mode)
+	if errors.Is(err, models.ErrRestoreConflict) {
+		http.Error(w, "cannot merge: "+err.Error()+"; restore it into an empty database or with mode=replace", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Println("restore backup:", err)
+		http.Error(w, "cannot restore backup", http.StatusInternalServerError)
+		return
+	}
+
+	s.publish(r.Context(), events.TypeReset, map[string]interface{}{
+		"restoredBy": user.Participant.Code,
+		"mode":       mode,
+	})
+	if round, err := s.store.CurrentRound(r.Context()); err == nil && round != nil {
+		s.publishCompletion(r.Context(), round.ID)
+	}
+	writeJSON(w, res)
+}
+
+// previewReplace says what a replacing restore would delete and what it
+// would load, and issues the token that confirms it.
+func (s *Server) previewReplace(w http.ResponseWriter, r *http.Request, d *models.Dataset, plan replacePlan) {
+	rounds, err := s.store.ListRounds(r.Context())
+	if err != nil {
+		log.Println("restore preview:", err)
+		http.Error(w, "cannot preview restore", http.StatusInternalServerError)
+		return
+	}
+	revisions, err := s.store.ExportRevisions(r.Context(), models.RevisionFilter{})
+	if err != nil {
+		log.Println("restore preview:", err)
+		http.Error(w, "cannot preview restore", http.StatusInternalServerError)
+		return
+	}
+	user := r.Context().Value(userCtxKey).(*sessionUser)
+	token, expires, err := s.authManager.IssueConfirmation(user.Participant.Code, "backup.replace", plan, resetTokenTTL)
+	if err != nil {
+		log.Println("restore token:", err)
+		http.Error(w, "cannot issue token", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"token":     token,
+		"expiresAt": expires.UTC(),
+		"mode":      models.RestoreReplace,
+		"deletes":   map[string]int{"rounds": len(rounds), "revisions": len(revisions)},
+		"loads":     map[string]int{"rounds": len(d.Rounds), "revisions": len(d.Revisions), "participants": len(d.Participants)},
+	})
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"opslab-survey/internal/models"
+)
+
+// consentRequired is what participants get from the survey endpoints until
+// they accept the current notice.
+const consentRequired = "Щоб продовжити, прийміть повідомлення про конфіденційність"
+
+// useNotice makes n the notice participants accept, with the version the
+// store keeps for its text.
+func (s *Server) useNotice(ctx context.Context, n models.ConsentNotice) error {
+	version, err := s.store.NoticeVersion(ctx, n.Digest())
+	if err != nil {
+		return fmt.Errorf("privacy notice version: %w", err)
+	}
+	n.Version = version
+	s.notice = n
+	return nil
+}
+
+// handleConsent returns the current notice and whether the participant has
+// accepted it. acceptedVersion is the latest version they accepted, so the
+// page can say the notice changed.
+func (s *Server) handleConsent(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userCtxKey).(*sessionUser)
+	consents, err := s.store.ConsentsOf(r.Context(), user.Participant.Code)
+	if err != nil {
+		log.Println("consent:", err)
+		http.Error(w, "cannot load consent", http.StatusInternalServerError)
+		return
+	}
+	var latest *models.Consent
+	if n := len(consents); n > 0 {
+		latest = &consents[n-1]
+	}
+	payload := map[string]interface{}{
+		"notice":   s.notice,
+		"required": !user.Participant.IsAdmin,
+		"accepted": latest != nil && latest.Version == s.notice.Version,
+	}
+	if latest != nil {
+		payload["acceptedVersion"] = latest.Version
+		payload["acceptedAt"] = latest.AcceptedAt
+	}
+	writeJSON(w, payload)
+}
+
+// handleConsentAccept records that the participant accepted the notice. The
+// body names the version they read; a stale version is refused so nobody
+// accepts a text they have not seen.
+func (s *Server) handleConsentAccept(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var payload struct {
+		Version int `json:"version"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	noteAudit(r, "", fmt.Sprintf("version:%d", payload.Version))
+	if payload.Version != s.notice.Version {
+		http.Error(w, "the notice has changed; please read it again", http.StatusConflict)
+		return
+	}
+	user := r.Context().Value(userCtxKey).(*sessionUser)
+	consent, err := s.store.RecordConsent(r.Context(), user.Participant.Code, s.notice.Version)
+	if err != nil {
+		log.Println("record consent:", err)
+		http.Error(w, "cannot record consent", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, consent)
+}
+
+// consented lets a participant through only once they have accepted the
+// current notice. Admins do not take the survey and are not asked.
+func (s *Server) consented(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(userCtxKey).(*sessionUser)
+		if !user.Participant.IsAdmin {
+			consent, err := s.store.ConsentFor(r.Context(), user.Participant.Code, s.notice.Version)
+			if err != nil {
+				log.Println("consent check:", err)
+				http.Error(w, "cannot check consent", http.StatusInternalServerError)
+				return
+			}
+			if consent == nil {
+				http.Error(w, consentRequired, http.StatusPreconditionRequired)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// consentStatus is one row of the admin consent report.
+type consentStatus struct {
+	Code            string     `json:"code"`
+	Name            string     `json:"name"`
+	Email           string     `json:"email"`
+	Status          string     `json:"status"`
+	AcceptedVersion int        `json:"acceptedVersion,omitempty"`
+	AcceptedAt      *time.Time `json:"acceptedAt,omitempty"`
+}
+
+// Consent statuses in the admin report.
+const (
+	consentAccepted = "accepted"
+	consentOutdated = "outdated"
+	consentPending  = "pending"
+)
+
+// handleAdminConsent reports who accepted the current notice, who accepted
+// only an earlier version and who never accepted one.
+func (s *Server) handleAdminConsent(w http.ResponseWriter, r *http.Request) {
+	consents, err := s.store.ListConsents(r.Context())
+	if err != nil {
+		log.Println("list consents:", err)
+		http.Error(w, "cannot load consents", http.StatusInternalServerError)
+		return
+	}
+	latest := map[string]models.Consent{}
+	for _, c := range consents {
+		latest[c.ParticipantCode] = c
+	}
+	counts := map[string]int{consentAccepted: 0, consentOutdated: 0, consentPending: 0}
+	rows := []consentStatus{}
+	for _, p := range s.participants {
+		if p.IsAdmin {
+			continue
+		}
+		row := consentStatus{Code: p.Code, Name: p.Name, Email: p.Email, Status: consentPending}
+		if c, ok := latest[p.Code]; ok {
+			row.AcceptedVersion, row.AcceptedAt = c.Version, &c.AcceptedAt
+			row.Status = consentOutdated
+			if c.Version == s.notice.Version {
+				row.Status = consentAccepted
+			}
+		}
+		counts[row.Status]++
+		rows = append(rows, row)
+	}
+	writeJSON(w, map[string]interface{}{
+		"version":      s.notice.Version,
+		"counts":       counts,
+		"participants": rows,
+	})
+}
@@ -0,0 +1,287 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"opslab-survey/internal/audit"
+	"opslab-survey/internal/events"
+	"opslab-survey/internal/gdpr"
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/scrub"
+)
+
+// erasePlan is what an erasure confirmation token commits to.
+type erasePlan struct {
+	ParticipantCode string `json:"participantCode"`
+}
+
+// handleAdminSubjectExport hands out everything stored about a participant as
+// a JSON file.
+func (s *Server) handleAdminSubjectExport(w http.ResponseWriter, r *http.Request) {
+	code := r.URL.Query().Get("participant")
+	noteAudit(r, "", "participant:"+code)
+	p, ok := s.participantBy[code]
+	if !ok {
+		http.Error(w, "unknown participant", http.StatusBadRequest)
+		return
+	}
+	now := time.Now()
+	export, err := gdpr.Collect(r.Context(), s.store, p, now)
+	if err != nil {
+		log.Println("subject export:", err)
+		http.Error(w, "cannot export participant data", http.StatusInternalServerError)
+		return
+	}
+	// Colleagues' words about the subject must not give away who else they
+	// named, the colleague themselves included.
+	for i, a := range export.AboutThem {
+		if text, ok := a.Value.(string); ok {
+			export.AboutThem[i].Value, _ = s.scrubber.Scrub(text, scrub.Context{Ratee: p.Code})
+		}
+	}
+	filename := fmt.Sprintf("opslab-subject-%s-%s.json", p.Code, now.UTC().Format("20060102-150405"))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	writeJSON(w, export)
+}
+
+// handleAdminErase previews the erasure of a participant and issues the token
+// that confirms it. Nothing is changed here.
+func (s *Server) handleAdminErase(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var plan erasePlan
+	if err := json.NewDecoder(r.Body).Decode(&plan); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	noteAudit(r, "", "participant:"+plan.ParticipantCode)
+	if _, ok := s.participantBy[plan.ParticipantCode]; !ok {
+		http.Error(w, "unknown participant", http.StatusBadRequest)
+		return
+	}
+	erasure, err := s.planErasure(r, plan.ParticipantCode, "")
+	if err != nil {
+		log.Println("erase preview:", err)
+		http.Error(w, "cannot preview erasure", http.StatusInternalServerError)
+		return
+	}
+	if erasure == nil {
+		http.Error(w, "nothing to erase", http.StatusConflict)
+		return
+	}
+	user := r.Context().Value(userCtxKey).(*sessionUser)
+	token, expires, err := s.authManager.IssueConfirmation(user.Participant.Code, "erase", plan, resetTokenTTL)
+	if err != nil {
+		log.Println("erase token:", err)
+		http.Error(w, "cannot issue token", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"token":           token,
+		"expiresAt":       expires.UTC(),
+		"participantCode": plan.ParticipantCode,
+		"revisions":       len(erasure.authored),
+		"mentions":        len(erasure.Revisions),
+		"snapshots":       len(erasure.Snapshots),
+	})
+}
+
+// handleAdminEraseConfirm carries out a previewed erasure. The plan is worked
+// out again, so submissions made since the preview are covered too.
+func (s *Server) handleAdminEraseConfirm(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var payload struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Token == "" {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	user := r.Context().Value(userCtxKey).(*sessionUser)
+	claims, err := s.authManager.ParseConfirmation(payload.Token, user.Participant.Code, "erase")
+	if err != nil {
+		http.Error(w, "invalid or expired confirmation token", http.StatusBadRequest)
+		return
+	}
+	var plan erasePlan
+	if err := json.Unmarshal(claims.Payload, &plan); err != nil {
+		http.Error(w, "invalid confirmation token", http.StatusBadRequest)
+		return
+	}
+	noteAudit(r, "", "participant:"+plan.ParticipantCode)
+	if !s.useConfirmation(w, r, claims) {
+		return
+	}
+
+	erasure, err := s.planErasure(r, plan.ParticipantCode, gdpr.Pseudonym())
+	if err != nil {
+		log.Println("erase plan:", err)
+		http.Error(w, "cannot erase participant", http.StatusInternalServerError)
+		return
+	}
+	if erasure == nil {
+		http.Error(w, "nothing to erase; the erasure was already applied", http.StatusConflict)
+		return
+	}
+	result, err := s.store.EraseParticipant(r.Context(), erasure.Erasure)
+	if err != nil {
+		log.Println("erase:", err)
+		http.Error(w, "cannot erase participant", http.StatusInternalServerError)
+		return
+	}
+	s.publish(r.Context(), events.TypeReset, map[string]interface{}{
+		"erasedBy": user.Participant.Code,
+		"deleted":  result.Revisions,
+	})
+	s.publishAffectedRounds(r, erasure.authored)
+	writeJSON(w, map[string]interface{}{
+		"status": "erased",
+		"result": result,
+	})
+}
+
+// erasure is a store erasure plus the revisions it deletes.
+type erasure struct {
+	models.Erasure
+	authored []models.ResponseRevision
+}
+
+// planErasure works out what erasing code changes: the revisions they
+// authored, the revisions of colleagues that refer to them or name them in
+// free text, renamed to pseudonym and with the names taken out, and the
+// reset snapshots that hold either. It returns nil when
+// there is nothing to erase.
+func (s *Server) planErasure(r *http.Request, code, pseudonym string) (*erasure, error) {
+	revisions, err := s.store.ExportRevisions(r.Context(), models.RevisionFilter{})
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]string{code: pseudonym}
+	e := &erasure{Erasure: models.Erasure{ParticipantCode: code}}
+	for _, rev := range revisions {
+		if rev.ParticipantCode == code {
+			e.authored = append(e.authored, rev)
+		} else if renamed, changed := s.forget(rev, code, names); changed {
+			e.Revisions = append(e.Revisions, renamed)
+		}
+	}
+
+	snapshots, err := s.store.ListSnapshots(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	for _, listed := range snapshots {
+		snapshot, err := s.store.SnapshotByID(r.Context(), listed.ID)
+		if err != nil {
+			return nil, err
+		}
+		if snapshot == nil {
+			continue
+		}
+		held, err := decodeSnapshot(snapshot.Data)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot %d: %w", snapshot.ID, err)
+		}
+		var keep []models.ResponseRevision
+		changed := false
+		for _, rev := range held {
+			if rev.ParticipantCode == code {
+				changed = true
+				continue
+			}
+			renamed, mentions := s.forget(rev, code, names)
+			changed = changed || mentions
+			keep = append(keep, renamed)
+		}
+		if !changed {
+			continue
+		}
+		if keep == nil {
+			keep = []models.ResponseRevision{}
+		}
+		if snapshot.Data, err = encodeSnapshot(keep); err != nil {
+			return nil, err
+		}
+		snapshot.Revisions = len(keep)
+		e.Snapshots = append(e.Snapshots, *snapshot)
+	}
+
+	if len(e.authored) == 0 && len(e.Revisions) == 0 && len(e.Snapshots) == 0 {
+		return nil, nil
+	}
+	return e, nil
+}
+
+// forget renames code in rev to its pseudonym and takes their name out of
+// the colleague's free text.
+func (s *Server) forget(rev models.ResponseRevision, code string, names map[string]string) (models.ResponseRevision, bool) {
+	renamed, mentions := gdpr.Rename(rev, names)
+	unnamed, named := gdpr.Unname(renamed, s.scrubber, code)
+	return unnamed, mentions || named
+}
+
+// handleAdminRetention shows the retention policy and what it would do now;
+// POST applies it right away instead of waiting for the next pass.
+func (s *Server) handleAdminRetention(w http.ResponseWriter, r *http.Request) {
+	if s.retention == nil {
+		http.Error(w, "retention is not configured", http.StatusServiceUnavailable)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		planned, err := s.retention.Plan(r.Context())
+		if err != nil {
+			log.Println("retention plan:", err)
+			http.Error(w, "cannot plan retention", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"policy":  s.retention.Policy(),
+			"enabled": s.retention.Policy().Enabled(),
+			"planned": planned,
+		})
+	case http.MethodPost:
+		noteAudit(r, audit.ActionRetentionRun, "")
+		report, err := s.retention.Apply(r.Context())
+		if err != nil {
+			log.Println("retention:", err)
+			http.Error(w, "cannot apply retention", http.StatusInternalServerError)
+			return
+		}
+		for _, a := range report.Actions {
+			if a.Kind == gdpr.ActionAnonymize {
+				s.publishCompletion(r.Context(), a.RoundID)
+			}
+		}
+		writeJSON(w, report)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAdminAnonymized lists the anonymous responses a round was reduced to.
+func (s *Server) handleAdminAnonymized(w http.ResponseWriter, r *http.Request) {
+	round := s.requestRound(w, r)
+	if round == nil {
+		return
+	}
+	responses, err := s.store.ListAnonymousResponses(r.Context(), round.ID)
+	if err != nil {
+		log.Println("anonymous responses:", err)
+		http.Error(w, "cannot load anonymous responses", http.StatusInternalServerError)
+		return
+	}
+	if responses == nil {
+		responses = []models.AnonymousResponse{}
+	}
+	writeJSON(w, responses)
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"opslab-survey/internal/events"
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/seed"
+)
+
+// publish pushes a dashboard event if live updates are enabled.
+func (s *Server) publish(ctx context.Context, typ string, data interface{}) {
+	if s.events == nil {
+		return
+	}
+	s.events.Publish(context.WithoutCancel(ctx), typ, data)
+}
+
+// publishCompletion pushes the current completed/pending counts for a round.
+func (s *Server) publishCompletion(ctx context.Context, roundID int64) {
+	if s.events == nil {
+		return
+	}
+	snapshot, err := s.completionSnapshot(ctx, roundID)
+	if err != nil {
+		log.Println("completion snapshot:", err)
+		return
+	}
+	s.publish(ctx, events.TypeCompletion, snapshot)
+}
+
+func (s *Server) completionSnapshot(ctx context.Context, roundID int64) (map[string]interface{}, error) {
+	pending, err := s.store.PendingParticipants(ctx, roundID)
+	if err != nil {
+		return nil, err
+	}
+	total := 0
+	for _, p := range s.participants {
+		if !p.IsAdmin {
+			total++
+		}
+	}
+	return map[string]interface{}{
+		"roundId":   roundID,
+		"total":     total,
+		"completed": total - len(pending),
+		"pending":   len(pending),
+	}, nil
+}
+
+// draftProgress returns the percentage of the participant's questions that
+// have a non-empty answer.
+func (s *Server) draftProgress(code string, answers []models.AnswerPayload) int {
+	questions := map[string]bool{}
+	for _, q := range seed.CommonQuestions() {
+		questions[q.ID] = true
+	}
+	for _, q := range seed.BuildPeerQuestions(s.peerListFor(code)) {
+		questions[q.ID] = true
+	}
+	if len(questions) == 0 {
+		return 0
+	}
+	answered := 0
+	for _, a := range answers {
+		if !questions[a.QuestionID] || a.Value == nil || a.Value == "" {
+			continue
+		}
+		answered++
+		delete(questions, a.QuestionID)
+	}
+	return answered * 100 / (answered + len(questions))
+}
+
+// handleDraft returns (GET) or autosaves (POST) the participant's unsubmitted answers.
+func (s *Server) handleDraft(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userCtxKey).(*sessionUser)
+	round, status, err := s.participantRoundStatus(r.Context(), user.Participant.Code)
+	if err != nil {
+		log.Println("draft round:", err)
+		http.Error(w, "cannot load round", http.StatusInternalServerError)
+		return
+	}
+	if round == nil {
+		http.Error(w, status.Message, http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		draft, err := s.store.DraftFor(r.Context(), round.ID, user.Participant.Code)
+		if err != nil {
+			log.Println("load draft:", err)
+			http.Error(w, "cannot load draft", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"draft": draft})
+	case http.MethodPost:
+		if !status.Accepting {
+			http.Error(w, status.Message, http.StatusForbidden)
+			return
+		}
+		var payload struct {
+			Answers  []models.AnswerPayload  `json:"answers"`
+			Rankings []models.RankingPayload `json:"rankings"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		draft := models.Draft{
+			RoundID:         round.ID,
+			ParticipantCode: user.Participant.Code,
+			Answers:         payload.Answers,
+			Rankings:        payload.Rankings,
+			Progress:        s.draftProgress(user.Participant.Code, payload.Answers),
+		}
+		if err := s.store.SaveDraft(r.Context(), draft); err != nil {
+			log.Println("save draft:", err)
+			http.Error(w, "cannot save draft", http.StatusInternalServerError)
+			return
+		}
+		s.publish(r.Context(), events.TypeDraft, map[string]interface{}{
+			"roundId":         round.ID,
+			"participantCode": user.Participant.Code,
+			"participantName": user.Participant.Name,
+			"progress":        draft.Progress,
+		})
+		writeJSON(w, map[string]interface{}{"status": "draft saved", "progress": draft.Progress})
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAdminEvents streams dashboard updates as Server-Sent Events. With
+// ?round= only that round's events and those about every round go out;
+// without it the stream follows every round, so one opened meanwhile shows up.
+func (s *Server) handleAdminEvents(w http.ResponseWriter, r *http.Request) {
+	if s.events == nil {
+		http.Error(w, "live updates are not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	round := s.requestRound(w, r)
+	if round == nil {
+		return
+	}
+	only := int64(0)
+	if r.URL.Query().Get("round") != "" {
+		only = round.ID
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	// The server-wide WriteTimeout would cut the stream; lift it for this response.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	sub, cancel := s.events.Subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	if snapshot, err := s.completionSnapshot(r.Context(), round.ID); err == nil {
+		writeEvent(w, events.TypeCompletion, snapshot)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(25 * time.Second)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case ev, ok := <-sub:
+			if !ok {
+				return
+			}
+			if only != 0 {
+				if id := eventRound(ev); id != 0 && id != only {
+					continue
+				}
+			}
+			writeEvent(w, ev.Type, ev.Data)
+			flusher.Flush()
+		}
+	}
+}
+
+// eventRound returns the round an event is about: its roundId or, for a
+// reset, the round of its scope. Zero means it may touch every round.
+func eventRound(ev events.Event) int64 {
+	var data struct {
+		RoundID int64 `json:"roundId"`
+		Scope   struct {
+			RoundID int64 `json:"roundId"`
+		} `json:"scope"`
+	}
+	if err := json.Unmarshal(ev.Data, &data); err != nil {
+		return 0
+	}
+	if data.RoundID != 0 {
+		return data.RoundID
+	}
+	return data.Scope.RoundID
+}
+
+func writeEvent(w http.ResponseWriter, typ string, data interface{}) {
+	raw, ok := data.(json.RawMessage)
+	if !ok {
+		var err error
+		raw, err = json.Marshal(data)
+		if err != nil {
+			log.Println("sse marshal:", err)
+			return
+		}
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typ, raw)
+}
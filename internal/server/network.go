@@ -0,0 +1,195 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/sociogram"
+)
+
+// handleAdminNetwork exports the rater→ratee network for Gephi, yEd,
+// Graphviz or Pajek: ?format=graphml|gexf|dot|pajek&weight=trust|peer:<id>|ranking:<criterion>.
+func (s *Server) handleAdminNetwork(w http.ResponseWriter, r *http.Request) {
+	round := s.requestRound(w, r)
+	if round == nil {
+		return
+	}
+	graph, ok := s.buildNetwork(w, r, round.ID)
+	if !ok {
+		return
+	}
+
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		name = "graphml"
+	}
+	format, ok := sociogram.Formats[name]
+	if !ok {
+		http.Error(w, "unknown format: "+name, http.StatusBadRequest)
+		return
+	}
+	filename := fmt.Sprintf("opslab-sociogram-%s.%s", time.Now().UTC().Format("20060102-150405"), format.Ext)
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	if err := format.Write(w, graph); err != nil {
+		log.Println("network export:", err)
+	}
+}
+
+// buildNetwork loads a round's responses and builds the graph for the
+// requested ?weight= source, writing an error response on failure.
+func (s *Server) buildNetwork(w http.ResponseWriter, r *http.Request, roundID int64) (sociogram.Graph, bool) {
+	weight, err := sociogram.ParseWeight(r.URL.Query().Get("weight"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return sociogram.Graph{}, false
+	}
+	participants, responses, ok := s.roundResponses(w, r, roundID)
+	if !ok {
+		return sociogram.Graph{}, false
+	}
+	var members []models.Participant
+	for _, p := range participants {
+		if !p.IsAdmin {
+			members = append(members, p)
+		}
+	}
+	return sociogram.Build(members, responses, weight), true
+}
+
+// handleAdminSociogram renders the network as SVG for the admin panel:
+// ?layout=force|rings|circle&weight=…&min=6&top=3.
+func (s *Server) handleAdminSociogram(w http.ResponseWriter, r *http.Request) {
+	round := s.requestRound(w, r)
+	if round == nil {
+		return
+	}
+	filter, ok := edgeFilterFromQuery(w, r)
+	if !ok {
+		return
+	}
+	graph, ok := s.buildNetwork(w, r, round.ID)
+	if !ok {
+		return
+	}
+	var buf bytes.Buffer
+	if err := sociogram.WriteSVG(&buf, graph.Filter(filter), r.URL.Query().Get("layout")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(buf.Bytes())
+}
+
+// handleAdminSociogramSources lists the layouts and weight sources the
+// sociogram filters offer.
+func (s *Server) handleAdminSociogramSources(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"layouts": sociogram.Layouts,
+		"weights": sociogram.Sources(),
+	})
+}
+
+// handleAdminReciprocity returns the mutuality matrix for a weight source:
+// ?weight=…&high=8&low=4&top=3&limit=10.
+func (s *Server) handleAdminReciprocity(w http.ResponseWriter, r *http.Request) {
+	round := s.requestRound(w, r)
+	if round == nil {
+		return
+	}
+	t, ok := thresholdsFromQuery(w, r)
+	if !ok {
+		return
+	}
+	limit := 10
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = v
+	}
+	graph, ok := s.buildNetwork(w, r, round.ID)
+	if !ok {
+		return
+	}
+	writeJSON(w, sociogram.ComputeMutuality(graph, t, limit))
+}
+
+// handleAdminCommunities returns subgroups, cliques and bridge persons:
+// ?weight=…&high=8&low=4&top=3.
+func (s *Server) handleAdminCommunities(w http.ResponseWriter, r *http.Request) {
+	round := s.requestRound(w, r)
+	if round == nil {
+		return
+	}
+	t, ok := thresholdsFromQuery(w, r)
+	if !ok {
+		return
+	}
+	graph, ok := s.buildNetwork(w, r, round.ID)
+	if !ok {
+		return
+	}
+	writeJSON(w, sociogram.DetectCommunities(graph, t))
+}
+
+// thresholdsFromQuery overrides the default positive/low choice thresholds
+// from ?high=, ?low= and ?top=, writing an error response on bad input.
+func thresholdsFromQuery(w http.ResponseWriter, r *http.Request) (sociogram.Thresholds, bool) {
+	q := r.URL.Query()
+	t := sociogram.DefaultThresholds
+	for _, p := range []struct {
+		name string
+		dst  *float64
+	}{{"high", &t.High}, {"low", &t.Low}} {
+		if raw := q.Get(p.name); raw != "" {
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				http.Error(w, "invalid "+p.name, http.StatusBadRequest)
+				return t, false
+			}
+			*p.dst = v
+		}
+	}
+	if raw := q.Get("top"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 {
+			http.Error(w, "invalid top", http.StatusBadRequest)
+			return t, false
+		}
+		t.TopK = v
+	}
+	return t, true
+}
+
+// edgeFilterFromQuery reads the sociogram's ?min= weight and ?top= ties per
+// rater, writing an error response on bad input.
+func edgeFilterFromQuery(w http.ResponseWriter, r *http.Request) (sociogram.EdgeFilter, bool) {
+	q := r.URL.Query()
+	var f sociogram.EdgeFilter
+	if raw := q.Get("min"); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			http.Error(w, "invalid min", http.StatusBadRequest)
+			return f, false
+		}
+		f.MinWeight = v
+	}
+	if raw := q.Get("top"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 {
+			http.Error(w, "invalid top", http.StatusBadRequest)
+			return f, false
+		}
+		f.TopK = v
+	}
+	return f, true
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+
+	"opslab-survey/internal/models"
+)
+
+// handleAdminReminders lists every reminder sent in a round.
+func (s *Server) handleAdminReminders(w http.ResponseWriter, r *http.Request) {
+	round := s.requestRound(w, r)
+	if round == nil {
+		return
+	}
+	reminders, err := s.store.ListReminders(r.Context(), round.ID)
+	if err != nil {
+		log.Println("list reminders:", err)
+		http.Error(w, "cannot load reminders", http.StatusInternalServerError)
+		return
+	}
+	if reminders == nil {
+		reminders = []models.Reminder{}
+	}
+	writeJSON(w, reminders)
+}
+
+// handleAdminNudge emails pending participants right away. An optional
+// {"codes": [...]} body limits the nudge to specific people.
+func (s *Server) handleAdminNudge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.reminders == nil {
+		http.Error(w, "reminders are not configured", http.StatusServiceUnavailable)
+		return
+	}
+	var payload struct {
+		Codes []string `json:"codes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && err != io.EOF {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	sent, err := s.reminders.Nudge(r.Context(), payload.Codes)
+	if err != nil {
+		log.Println("nudge:", err)
+		http.Error(w, "cannot send reminders", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"status":    "sent",
+		"reminders": sent,
+	})
+}
@@ -0,0 +1,320 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"opslab-survey/internal/auth"
+	"opslab-survey/internal/events"
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/webhook"
+)
+
+// resetTokenTTL is how long an admin has to confirm a previewed reset.
+const resetTokenTTL = 5 * time.Minute
+
+// useConfirmation lets a confirmation token confirm its action once: a
+// replayed token is refused with 409 even before it expires. It writes the
+// error response and returns false when the token cannot be used.
+func (s *Server) useConfirmation(w http.ResponseWriter, r *http.Request, claims *auth.ConfirmClaims) bool {
+	first, err := s.store.UseConfirmation(r.Context(), claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		log.Println("use confirmation:", err)
+		http.Error(w, "cannot check the confirmation token", http.StatusInternalServerError)
+		return false
+	}
+	if !first {
+		http.Error(w, "this confirmation token was already used", http.StatusConflict)
+		return false
+	}
+	return true
+}
+
+// resetPlan is what a confirmation token commits to: the scope and the newest
+// revision that existed when it was previewed, so submissions that arrive
+// in between are never deleted by surprise.
+type resetPlan struct {
+	Scope models.ResetScope `json:"scope"`
+	UpTo  int64             `json:"upTo"`
+}
+
+// handleReset previews a reset and issues the token that confirms it. Nothing
+// is deleted here.
+func (s *Server) handleReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var payload struct {
+		Scope models.ResetScope `json:"scope"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	scope := payload.Scope
+	noteAudit(r, "", scope.String())
+	if err := scope.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if scope.RoundID != 0 {
+		if _, err := s.store.RoundByID(r.Context(), scope.RoundID); err != nil {
+			http.Error(w, "unknown round", http.StatusBadRequest)
+			return
+		}
+	}
+	if scope.ParticipantCode != "" {
+		if _, ok := s.participantBy[scope.ParticipantCode]; !ok {
+			http.Error(w, "unknown participant", http.StatusBadRequest)
+			return
+		}
+	}
+
+	revisions, err := s.store.ExportRevisions(r.Context(), scope.Filter())
+	if err != nil {
+		log.Println("reset preview:", err)
+		http.Error(w, "cannot preview reset", http.StatusInternalServerError)
+		return
+	}
+	if len(revisions) == 0 {
+		http.Error(w, "nothing to delete", http.StatusConflict)
+		return
+	}
+	plan := resetPlan{Scope: scope, UpTo: revisions[len(revisions)-1].ID}
+	user := r.Context().Value(userCtxKey).(*sessionUser)
+	token, expires, err := s.authManager.IssueConfirmation(user.Participant.Code, "reset", plan, resetTokenTTL)
+	if err != nil {
+		log.Println("reset token:", err)
+		http.Error(w, "cannot issue token", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"token":        token,
+		"expiresAt":    expires.UTC(),
+		"scope":        scope,
+		"revisions":    len(revisions),
+		"participants": revisionParticipants(revisions),
+	})
+}
+
+// handleResetConfirm carries out a previewed reset. The deleted revisions are
+// kept as a compressed snapshot first, in the same transaction.
+func (s *Server) handleResetConfirm(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var payload struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Token == "" {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	user := r.Context().Value(userCtxKey).(*sessionUser)
+	claims, err := s.authManager.ParseConfirmation(payload.Token, user.Participant.Code, "reset")
+	if err != nil {
+		http.Error(w, "invalid or expired confirmation token", http.StatusBadRequest)
+		return
+	}
+	var plan resetPlan
+	if err := json.Unmarshal(claims.Payload, &plan); err != nil {
+		http.Error(w, "invalid confirmation token", http.StatusBadRequest)
+		return
+	}
+	noteAudit(r, "", plan.Scope.String())
+	if !s.useConfirmation(w, r, claims) {
+		return
+	}
+
+	filter := plan.Scope.Filter()
+	filter.UpToID = plan.UpTo
+	revisions, err := s.store.ExportRevisions(r.Context(), filter)
+	if err != nil {
+		log.Println("reset export:", err)
+		http.Error(w, "cannot reset", http.StatusInternalServerError)
+		return
+	}
+	if len(revisions) == 0 {
+		http.Error(w, "nothing to delete; the reset was already applied", http.StatusConflict)
+		return
+	}
+	data, err := encodeSnapshot(revisions)
+	if err != nil {
+		log.Println("reset snapshot:", err)
+		http.Error(w, "cannot reset", http.StatusInternalServerError)
+		return
+	}
+	ids := make([]int64, len(revisions))
+	for i, rev := range revisions {
+		ids[i] = rev.ID
+	}
+	snapshot, err := s.store.ResetResponses(r.Context(), models.Snapshot{
+		CreatedBy: user.Participant.Code,
+		Scope:     plan.Scope,
+		Revisions: len(revisions),
+		Data:      data,
+	}, ids)
+	if err != nil {
+		log.Println("reset:", err)
+		http.Error(w, "cannot reset", http.StatusInternalServerError)
+		return
+	}
+
+	event := map[string]interface{}{
+		"resetBy":    user.Participant.Code,
+		"scope":      plan.Scope,
+		"deleted":    len(revisions),
+		"snapshotId": snapshot.ID,
+	}
+	s.emit(r.Context(), webhook.EventResponsesReset, event)
+	s.publish(r.Context(), events.TypeReset, event)
+	s.publishAffectedRounds(r, revisions)
+	writeJSON(w, map[string]interface{}{
+		"status":   "cleared",
+		"deleted":  len(revisions),
+		"snapshot": snapshot,
+	})
+}
+
+// handleAdminSnapshots lists reset snapshots, newest first.
+func (s *Server) handleAdminSnapshots(w http.ResponseWriter, r *http.Request) {
+	snapshots, err := s.store.ListSnapshots(r.Context())
+	if err != nil {
+		log.Println("list snapshots:", err)
+		http.Error(w, "cannot load snapshots", http.StatusInternalServerError)
+		return
+	}
+	if snapshots == nil {
+		snapshots = []models.Snapshot{}
+	}
+	writeJSON(w, snapshots)
+}
+
+// handleAdminSnapshotRestore puts the revisions of a snapshot back. Revisions
+// that are stored again already are left alone, so restoring twice is
+// harmless; revisions of rounds that no longer exist are skipped.
+func (s *Server) handleAdminSnapshotRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var payload struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	noteAudit(r, "", fmt.Sprintf("snapshot:%d", payload.ID))
+	snapshot, err := s.store.SnapshotByID(r.Context(), payload.ID)
+	if err != nil {
+		log.Println("load snapshot:", err)
+		http.Error(w, "cannot load snapshot", http.StatusInternalServerError)
+		return
+	}
+	if snapshot == nil {
+		http.Error(w, "snapshot not found", http.StatusNotFound)
+		return
+	}
+	revisions, err := decodeSnapshot(snapshot.Data)
+	if err != nil {
+		log.Println("decode snapshot:", err)
+		http.Error(w, "snapshot is corrupt", http.StatusInternalServerError)
+		return
+	}
+	rounds, err := s.store.ListRounds(r.Context())
+	if err != nil {
+		log.Println("restore rounds:", err)
+		http.Error(w, "cannot load rounds", http.StatusInternalServerError)
+		return
+	}
+	known := make(map[int64]bool, len(rounds))
+	for _, round := range rounds {
+		known[round.ID] = true
+	}
+	var keep []models.ResponseRevision
+	for _, rev := range revisions {
+		if known[rev.RoundID] {
+			if _, ok := s.participantBy[rev.ParticipantCode]; ok {
+				keep = append(keep, rev)
+			}
+		}
+	}
+	restored, err := s.store.RestoreSnapshot(r.Context(), snapshot.ID, keep)
+	if err != nil {
+		log.Println("restore snapshot:", err)
+		http.Error(w, "cannot restore snapshot", http.StatusInternalServerError)
+		return
+	}
+	s.publishAffectedRounds(r, keep)
+	writeJSON(w, map[string]interface{}{
+		"status":   "restored",
+		"restored": restored,
+		"skipped":  len(revisions) - len(keep),
+	})
+}
+
+// publishAffectedRounds refreshes completion counts for every round the
+// revisions belong to.
+func (s *Server) publishAffectedRounds(r *http.Request, revisions []models.ResponseRevision) {
+	seen := map[int64]bool{}
+	for _, rev := range revisions {
+		if !seen[rev.RoundID] {
+			seen[rev.RoundID] = true
+			s.publishCompletion(r.Context(), rev.RoundID)
+		}
+	}
+}
+
+// revisionParticipants returns the distinct participant codes, sorted.
+func revisionParticipants(revisions []models.ResponseRevision) []string {
+	seen := map[string]bool{}
+	codes := []string{}
+	for _, rev := range revisions {
+		if !seen[rev.ParticipantCode] {
+			seen[rev.ParticipantCode] = true
+			codes = append(codes, rev.ParticipantCode)
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// encodeSnapshot gzips the revisions as a JSON array.
+func encodeSnapshot(revisions []models.ResponseRevision) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(revisions); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeSnapshot(data []byte) ([]models.ResponseRevision, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+	var revisions []models.ResponseRevision
+	if err := json.Unmarshal(raw, &revisions); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
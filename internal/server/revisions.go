@@ -0,0 +1,178 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"opslab-survey/internal/models"
+)
+
+// answerChange describes how a single question's answer moved between two revisions.
+type answerChange struct {
+	QuestionID string      `json:"questionId"`
+	Change     string      `json:"change"` // added, removed, changed
+	Before     interface{} `json:"before,omitempty"`
+	After      interface{} `json:"after,omitempty"`
+}
+
+// rankingChange describes how one criterion's ranking moved between two revisions.
+type rankingChange struct {
+	Criteria string                 `json:"criteria"`
+	Change   string                 `json:"change"`
+	Before   *models.RankingPayload `json:"before,omitempty"`
+	After    *models.RankingPayload `json:"after,omitempty"`
+}
+
+type revisionDiff struct {
+	From      int64           `json:"from"`
+	To        int64           `json:"to"`
+	Unchanged int             `json:"unchanged"`
+	Answers   []answerChange  `json:"answers"`
+	Rankings  []rankingChange `json:"rankings"`
+}
+
+// handleAdminRevisions serves /api/admin/revisions/{code} (history) and
+// /api/admin/revisions/{code}/diff?from=&to= (question-by-question diff).
+func (s *Server) handleAdminRevisions(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/api/admin/revisions/")
+	code, action, _ := strings.Cut(rest, "/")
+	if code == "" {
+		http.Error(w, "participant code required", http.StatusBadRequest)
+		return
+	}
+	noteAudit(r, "", "participant:"+code)
+
+	round := s.requestRound(w, r)
+	if round == nil {
+		return
+	}
+	revisions, err := s.store.ListRevisions(r.Context(), round.ID, code)
+	if err != nil {
+		log.Println("admin revisions:", err)
+		http.Error(w, "cannot load revisions", http.StatusInternalServerError)
+		return
+	}
+
+	switch action {
+	case "":
+		items := []map[string]interface{}{}
+		for _, rev := range revisions {
+			items = append(items, map[string]interface{}{
+				"id":            rev.ID,
+				"submittedAt":   rev.SubmittedAt,
+				"sessionId":     rev.SessionID,
+				"isTestData":    rev.IsTestData,
+				"answersCount":  len(rev.Answers),
+				"rankingsCount": len(rev.Rankings),
+			})
+		}
+		writeJSON(w, map[string]interface{}{
+			"participantCode": code,
+			"roundId":         round.ID,
+			"revisions":       items,
+		})
+	case "diff":
+		if len(revisions) == 0 {
+			http.Error(w, "no revisions", http.StatusNotFound)
+			return
+		}
+		from, to, err := pickRevisions(revisions, r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, diffRevisions(from, to))
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+// pickRevisions resolves the from/to query parameters against a participant's
+// history. Missing values default to the previous and the latest revision.
+func pickRevisions(revisions []models.ResponseRevision, fromParam, toParam string) (models.ResponseRevision, models.ResponseRevision, error) {
+	byID := make(map[int64]models.ResponseRevision, len(revisions))
+	for _, rev := range revisions {
+		byID[rev.ID] = rev
+	}
+	lookup := func(param string, fallback models.ResponseRevision) (models.ResponseRevision, error) {
+		if param == "" {
+			return fallback, nil
+		}
+		id, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			return models.ResponseRevision{}, fmt.Errorf("unknown revision for participant: %s", param)
+		}
+		rev, ok := byID[id]
+		if !ok {
+			return models.ResponseRevision{}, fmt.Errorf("unknown revision for participant: %s", param)
+		}
+		return rev, nil
+	}
+
+	latest := revisions[len(revisions)-1]
+	previous := latest
+	if len(revisions) > 1 {
+		previous = revisions[len(revisions)-2]
+	}
+	from, err := lookup(fromParam, previous)
+	if err != nil {
+		return from, from, err
+	}
+	to, err := lookup(toParam, latest)
+	return from, to, err
+}
+
+func diffRevisions(from, to models.ResponseRevision) revisionDiff {
+	diff := revisionDiff{From: from.ID, To: to.ID, Answers: []answerChange{}, Rankings: []rankingChange{}}
+
+	before := make(map[string]interface{}, len(from.Answers))
+	for _, a := range from.Answers {
+		before[a.QuestionID] = a.Value
+	}
+	seen := make(map[string]bool, len(to.Answers))
+	for _, a := range to.Answers {
+		seen[a.QuestionID] = true
+		old, ok := before[a.QuestionID]
+		switch {
+		case !ok:
+			diff.Answers = append(diff.Answers, answerChange{QuestionID: a.QuestionID, Change: "added", After: a.Value})
+		case !reflect.DeepEqual(old, a.Value):
+			diff.Answers = append(diff.Answers, answerChange{QuestionID: a.QuestionID, Change: "changed", Before: old, After: a.Value})
+		default:
+			diff.Unchanged++
+		}
+	}
+	for _, a := range from.Answers {
+		if !seen[a.QuestionID] {
+			diff.Answers = append(diff.Answers, answerChange{QuestionID: a.QuestionID, Change: "removed", Before: a.Value})
+		}
+	}
+
+	oldRankings := make(map[string]models.RankingPayload, len(from.Rankings))
+	for _, rk := range from.Rankings {
+		oldRankings[rk.Criteria] = rk
+	}
+	seenCriteria := make(map[string]bool, len(to.Rankings))
+	for i := range to.Rankings {
+		after := to.Rankings[i]
+		seenCriteria[after.Criteria] = true
+		old, ok := oldRankings[after.Criteria]
+		switch {
+		case !ok:
+			diff.Rankings = append(diff.Rankings, rankingChange{Criteria: after.Criteria, Change: "added", After: &after})
+		case !reflect.DeepEqual(old, after):
+			diff.Rankings = append(diff.Rankings, rankingChange{Criteria: after.Criteria, Change: "changed", Before: &old, After: &after})
+		}
+	}
+	for i := range from.Rankings {
+		old := from.Rankings[i]
+		if !seenCriteria[old.Criteria] {
+			diff.Rankings = append(diff.Rankings, rankingChange{Criteria: old.Criteria, Change: "removed", Before: &old})
+		}
+	}
+	return diff
+}
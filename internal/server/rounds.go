@@ -0,0 +1,290 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"opslab-survey/internal/audit"
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/webhook"
+)
+
+// roundStatus is what a participant (and the UI countdown) needs to know about
+// the round they are answering.
+type roundStatus struct {
+	ID         int64      `json:"id"`
+	Title      string     `json:"title"`
+	State      string     `json:"state"`
+	OpensAt    *time.Time `json:"opensAt"`
+	ClosesAt   *time.Time `json:"closesAt"`
+	Deadline   *time.Time `json:"deadline"`
+	Extended   bool       `json:"extended"`
+	Accepting  bool       `json:"acceptingSubmissions"`
+	ServerTime time.Time  `json:"serverTime"`
+	Message    string     `json:"message,omitempty"`
+}
+
+// statusFor resolves the round schedule and any personal extension into the
+// window a participant may submit in.
+func statusFor(round *models.Round, extension *time.Time, now time.Time) roundStatus {
+	if round == nil {
+		return roundStatus{State: models.RoundArchived, ServerTime: now, Message: "Зараз немає активного опитування."}
+	}
+	deadline, extended, state := round.WindowFor(extension, now)
+	st := roundStatus{
+		ID:         round.ID,
+		Title:      round.Title,
+		State:      state,
+		OpensAt:    round.OpensAt,
+		ClosesAt:   round.ClosesAt,
+		Deadline:   deadline,
+		Extended:   extended,
+		ServerTime: now,
+	}
+	switch st.State {
+	case models.RoundOpen:
+		st.Accepting = true
+	case models.RoundDraft:
+		if st.OpensAt != nil {
+			st.Message = fmt.Sprintf("Опитування відкриється %s.", st.OpensAt.Format("02.01.2006 15:04 MST"))
+		} else {
+			st.Message = "Опитування ще не відкрите."
+		}
+	case models.RoundClosed:
+		if st.Deadline != nil {
+			st.Message = fmt.Sprintf("Термін подання відповідей минув %s.", st.Deadline.Format("02.01.2006 15:04 MST"))
+		} else {
+			st.Message = "Опитування закрите, відповіді більше не приймаються."
+		}
+	case models.RoundArchived:
+		st.Message = "Опитування архівоване."
+	}
+	return st
+}
+
+// participantRoundStatus loads the current round and the participant's extension.
+func (s *Server) participantRoundStatus(ctx context.Context, code string) (*models.Round, roundStatus, error) {
+	round, err := s.store.CurrentRound(ctx)
+	if err != nil {
+		return nil, roundStatus{}, err
+	}
+	var extension *time.Time
+	if round != nil {
+		extension, err = s.store.DeadlineExtension(ctx, round.ID, code)
+		if err != nil {
+			return nil, roundStatus{}, err
+		}
+	}
+	return round, statusFor(round, extension, time.Now()), nil
+}
+
+// requestRound resolves ?round=<id> on admin requests, falling back to the
+// current round. It writes the error response itself and returns nil on failure.
+func (s *Server) requestRound(w http.ResponseWriter, r *http.Request) *models.Round {
+	if raw := r.URL.Query().Get("round"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid round", http.StatusBadRequest)
+			return nil
+		}
+		round, err := s.store.RoundByID(r.Context(), id)
+		if err != nil {
+			http.Error(w, "round not found", http.StatusNotFound)
+			return nil
+		}
+		return round
+	}
+	round, err := s.store.CurrentRound(r.Context())
+	if err != nil {
+		log.Println("current round:", err)
+		http.Error(w, "cannot load round", http.StatusInternalServerError)
+		return nil
+	}
+	if round == nil {
+		http.Error(w, "no active round", http.StatusNotFound)
+		return nil
+	}
+	return round
+}
+
+type roundPayload struct {
+	Title    string     `json:"title"`
+	State    string     `json:"state"`
+	OpensAt  *time.Time `json:"opensAt"`
+	ClosesAt *time.Time `json:"closesAt"`
+}
+
+func (p roundPayload) validate() error {
+	if strings.TrimSpace(p.Title) == "" {
+		return fmt.Errorf("title is required")
+	}
+	if !models.ValidRoundState(p.State) {
+		return fmt.Errorf("unknown state: %s", p.State)
+	}
+	if p.OpensAt != nil && p.ClosesAt != nil && !p.ClosesAt.After(*p.OpensAt) {
+		return fmt.Errorf("closesAt must be after opensAt")
+	}
+	return nil
+}
+
+// handleAdminRounds lists rounds (GET) or creates a new one (POST).
+func (s *Server) handleAdminRounds(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		rounds, err := s.store.ListRounds(r.Context())
+		if err != nil {
+			log.Println("list rounds:", err)
+			http.Error(w, "cannot load rounds", http.StatusInternalServerError)
+			return
+		}
+		now := time.Now()
+		items := []map[string]interface{}{}
+		for _, round := range rounds {
+			items = append(items, map[string]interface{}{
+				"round":          round,
+				"effectiveState": round.StateAt(now),
+			})
+		}
+		writeJSON(w, items)
+	case http.MethodPost:
+		noteAudit(r, audit.ActionRoundCreate, "")
+		var payload roundPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if payload.State == "" {
+			payload.State = models.RoundDraft
+		}
+		if err := payload.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		round, err := s.store.CreateRound(r.Context(), models.Round{
+			Title:    strings.TrimSpace(payload.Title),
+			State:    payload.State,
+			OpensAt:  payload.OpensAt,
+			ClosesAt: payload.ClosesAt,
+		})
+		if err != nil {
+			log.Println("create round:", err)
+			http.Error(w, "cannot create round", http.StatusInternalServerError)
+			return
+		}
+		noteAudit(r, "", fmt.Sprintf("round:%d", round.ID))
+		s.emit(r.Context(), webhook.EventRoundCreated, round)
+		writeJSON(w, round)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAdminRoundUpdate replaces a round's title, state and schedule.
+func (s *Server) handleAdminRoundUpdate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var payload struct {
+		ID int64 `json:"id"`
+		roundPayload
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	noteAudit(r, "", fmt.Sprintf("round:%d", payload.ID))
+	if err := payload.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	prev, err := s.store.RoundByID(r.Context(), payload.ID)
+	if err != nil {
+		http.Error(w, "round not found", http.StatusNotFound)
+		return
+	}
+	round, err := s.store.UpdateRound(r.Context(), models.Round{
+		ID:       payload.ID,
+		Title:    strings.TrimSpace(payload.Title),
+		State:    payload.State,
+		OpensAt:  payload.OpensAt,
+		ClosesAt: payload.ClosesAt,
+	})
+	if err != nil {
+		log.Println("update round:", err)
+		http.Error(w, "cannot update round", http.StatusInternalServerError)
+		return
+	}
+	// Only changes that take effect now are emitted here: a round set to
+	// open before its opening date is announced by the round watcher when
+	// the schedule opens it, and closing a round the schedule has already
+	// closed is not news.
+	now := time.Now()
+	if before, after := prev.StateAt(now), round.StateAt(now); before != after {
+		s.emit(r.Context(), webhook.RoundStateEvent(after), map[string]interface{}{
+			"round":         round,
+			"previousState": before,
+		})
+	}
+	writeJSON(w, round)
+}
+
+// handleAdminRoundExtend grants a personal deadline (GET lists existing ones).
+func (s *Server) handleAdminRoundExtend(w http.ResponseWriter, r *http.Request) {
+	round := s.requestRound(w, r)
+	if round == nil {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		extensions, err := s.store.ListDeadlineExtensions(r.Context(), round.ID)
+		if err != nil {
+			log.Println("list extensions:", err)
+			http.Error(w, "cannot load extensions", http.StatusInternalServerError)
+			return
+		}
+		if extensions == nil {
+			extensions = []models.DeadlineExtension{}
+		}
+		writeJSON(w, extensions)
+	case http.MethodPost:
+		var payload struct {
+			ParticipantCode string    `json:"participantCode"`
+			ClosesAt        time.Time `json:"closesAt"`
+			Reason          string    `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		noteAudit(r, audit.ActionExtensionGrant, "participant:"+payload.ParticipantCode)
+		if _, ok := s.participantBy[payload.ParticipantCode]; !ok {
+			http.Error(w, "unknown participant", http.StatusBadRequest)
+			return
+		}
+		if payload.ClosesAt.IsZero() {
+			http.Error(w, "closesAt is required", http.StatusBadRequest)
+			return
+		}
+		ext := models.DeadlineExtension{
+			RoundID:         round.ID,
+			ParticipantCode: payload.ParticipantCode,
+			ClosesAt:        payload.ClosesAt,
+			Reason:          strings.TrimSpace(payload.Reason),
+		}
+		if err := s.store.ExtendDeadline(r.Context(), ext); err != nil {
+			log.Println("extend deadline:", err)
+			http.Error(w, "cannot extend deadline", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, ext)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -10,22 +10,39 @@ import (
 	"net/http"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"opslab-survey/internal/audit"
 	"opslab-survey/internal/auth"
+	"opslab-survey/internal/events"
+	"opslab-survey/internal/export"
+	"opslab-survey/internal/gdpr"
+	"opslab-survey/internal/mailer"
 	"opslab-survey/internal/models"
+	"opslab-survey/internal/pseudonym"
+	"opslab-survey/internal/reminder"
+	"opslab-survey/internal/scrub"
 	"opslab-survey/internal/seed"
 	"opslab-survey/internal/store"
+	"opslab-survey/internal/webhook"
 	"opslab-survey/web"
 )
 
 type Server struct {
-	store         *store.Store
+	store         store.Store
 	authManager   *auth.Manager
 	participants  []models.Participant
 	participantBy map[string]models.Participant
 	staticFS      http.Handler
+	notice        models.ConsentNotice
+	pseudonyms    *pseudonym.Mapper
+	scrubber      *scrub.Scrubber
+	reminders     *reminder.Scheduler
+	retention     *gdpr.Retention
+	webhooks      *webhook.Dispatcher
+	events        *events.Broker
 }
 
 type ctxKey string
@@ -34,9 +51,10 @@ const userCtxKey ctxKey = "user"
 
 type sessionUser struct {
 	Participant models.Participant
+	SessionID   string
 }
 
-func New(store *store.Store, authManager *auth.Manager, participants []models.Participant) *Server {
+func New(store store.Store, authManager *auth.Manager, participants []models.Participant) *Server {
 	staticSub, err := fs.Sub(web.Static, "static")
 	if err != nil {
 		log.Fatal("failed to get static subdir:", err)
@@ -52,25 +70,65 @@ func New(store *store.Store, authManager *auth.Manager, participants []models.Pa
 		participants:  participants,
 		participantBy: participantBy,
 		staticFS:      http.StripPrefix("/static/", handler),
+		notice:        seed.ConsentNotice(0, 0),
+		pseudonyms:    pseudonym.New(nil),
+		scrubber:      scrub.New(participants),
 	}
 }
 
 func (s *Server) Routes() http.Handler {
 	mux := http.NewServeMux()
 	mux.Handle("/static/", s.cacheControl(s.staticFS))
-	mux.HandleFunc("/api/login", s.handleLogin)
-	mux.Handle("/api/logout", s.authenticated(s.handleLogout))
+	mux.Handle("/api/login", s.audited(audit.ActionLogin, http.HandlerFunc(s.handleLogin)))
+	mux.Handle("/api/logout", s.audited(audit.ActionLogout, s.authenticated(s.handleLogout)))
 	mux.Handle("/api/me", s.authenticated(s.handleMe))
-	mux.Handle("/api/questions", s.authenticated(s.handleQuestions))
-	mux.Handle("/api/response", s.authenticated(s.handleResponse))
+	mux.Handle("/api/consent", s.authenticated(s.handleConsent))
+	mux.Handle("/api/consent/accept", s.audited(audit.ActionConsentAccept, s.authenticated(s.handleConsentAccept)))
+	mux.Handle("/api/questions", s.authenticated(s.consented(s.handleQuestions)))
+	mux.Handle("/api/response", s.audited(audit.ActionResponseSubmit, s.authenticated(s.consented(s.handleResponse))))
+	mux.Handle("/api/draft", s.authenticated(s.consented(s.handleDraft)))
 
 	// Admin
-	mux.Handle("/api/admin/stats", s.adminOnly(s.handleStats))
-	mux.Handle("/api/admin/responses", s.adminOnly(s.handleAdminResponses))
-	mux.Handle("/api/admin/response/", s.adminOnly(s.handleAdminResponseDetail))
-	mux.Handle("/api/admin/export", s.adminOnly(s.handleExport))
-	mux.Handle("/api/admin/run-test", s.adminOnly(s.handleRunTestData))
-	mux.Handle("/api/admin/reset", s.adminOnly(s.handleReset))
+	admin := func(path, action string, h http.HandlerFunc) {
+		mux.Handle(path, s.audited(action, s.adminOnly(h)))
+	}
+	mux.Handle("/api/admin/events", s.adminOnly(s.handleAdminEvents))
+	admin("/api/admin/stats", audit.ActionStatsView, s.handleStats)
+	admin("/api/admin/responses", audit.ActionResponsesList, s.handleAdminResponses)
+	admin("/api/admin/response/", audit.ActionResponseView, s.handleAdminResponseDetail)
+	admin("/api/admin/revisions/", audit.ActionRevisionsView, s.handleAdminRevisions)
+	admin("/api/admin/export", audit.ActionExport, s.handleExport)
+	admin("/api/admin/analytics", audit.ActionAnalyticsView, s.handleAdminAnalytics)
+	admin("/api/admin/text", audit.ActionTextView, s.handleAdminText)
+	admin("/api/admin/redactions", audit.ActionRedactionsView, s.handleAdminRedactions)
+	admin("/api/admin/network", audit.ActionNetworkExport, s.handleAdminNetwork)
+	admin("/api/admin/reciprocity", audit.ActionReciprocityView, s.handleAdminReciprocity)
+	admin("/api/admin/communities", audit.ActionCommunitiesView, s.handleAdminCommunities)
+	admin("/api/admin/sociogram", audit.ActionSociogramView, s.handleAdminSociogram)
+	mux.Handle("/api/admin/sociogram/sources", s.adminOnly(s.handleAdminSociogramSources))
+	admin("/api/admin/run-test", audit.ActionTestData, s.handleRunTestData)
+	admin("/api/admin/reset", audit.ActionResetRequest, s.handleReset)
+	admin("/api/admin/reset/confirm", audit.ActionReset, s.handleResetConfirm)
+	admin("/api/admin/snapshots", audit.ActionSnapshotsList, s.handleAdminSnapshots)
+	admin("/api/admin/snapshots/restore", audit.ActionSnapshotRestore, s.handleAdminSnapshotRestore)
+	admin("/api/admin/backup", audit.ActionBackupDownload, s.handleAdminBackup)
+	admin("/api/admin/backup/restore", audit.ActionBackupRestore, s.handleAdminBackupRestore)
+	admin("/api/admin/rounds", audit.ActionRoundsList, s.handleAdminRounds)
+	admin("/api/admin/rounds/update", audit.ActionRoundUpdate, s.handleAdminRoundUpdate)
+	admin("/api/admin/rounds/extend", audit.ActionExtensionsList, s.handleAdminRoundExtend)
+	admin("/api/admin/reminders", audit.ActionRemindersList, s.handleAdminReminders)
+	admin("/api/admin/reminders/nudge", audit.ActionRemindersNudge, s.handleAdminNudge)
+	admin("/api/admin/webhooks", audit.ActionWebhooksList, s.handleAdminWebhooks)
+	admin("/api/admin/webhooks/delete", audit.ActionWebhookDelete, s.handleAdminWebhookDelete)
+	admin("/api/admin/webhooks/deliveries", audit.ActionDeliveriesList, s.handleAdminWebhookDeliveries)
+	admin("/api/admin/audit", audit.ActionAuditView, s.handleAdminAudit)
+	admin("/api/admin/audit/verify", audit.ActionAuditVerify, s.handleAdminAuditVerify)
+	admin("/api/admin/consent", audit.ActionConsentView, s.handleAdminConsent)
+	admin("/api/admin/gdpr/export", audit.ActionSubjectExport, s.handleAdminSubjectExport)
+	admin("/api/admin/gdpr/erase", audit.ActionEraseRequest, s.handleAdminErase)
+	admin("/api/admin/gdpr/erase/confirm", audit.ActionErase, s.handleAdminEraseConfirm)
+	admin("/api/admin/gdpr/retention", audit.ActionRetentionView, s.handleAdminRetention)
+	admin("/api/admin/gdpr/anonymized", audit.ActionAnonymizedView, s.handleAdminAnonymized)
 
 	// SPA fallback
 	mux.HandleFunc("/", s.handleIndex)
@@ -116,11 +174,13 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 	payload.Email = strings.TrimSpace(strings.ToLower(payload.Email))
 	payload.Code = strings.TrimSpace(payload.Code)
+	noteAudit(r, "", "email:"+payload.Email)
 	p, err := s.store.ParticipantByEmailAndCode(r.Context(), payload.Email, payload.Code)
 	if err != nil {
 		http.Error(w, "неправильний код або email", http.StatusUnauthorized)
 		return
 	}
+	noteAuditActor(r, p.Code)
 	token, err := s.authManager.Issue(p.Code, p.IsAdmin)
 	if err != nil {
 		http.Error(w, "cannot issue session", http.StatusInternalServerError)
@@ -160,6 +220,12 @@ func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleQuestions(w http.ResponseWriter, r *http.Request) {
 	user := r.Context().Value(userCtxKey).(*sessionUser)
+	_, status, err := s.participantRoundStatus(r.Context(), user.Participant.Code)
+	if err != nil {
+		log.Println("questions round:", err)
+		http.Error(w, "cannot load round", http.StatusInternalServerError)
+		return
+	}
 	peers := s.peerListFor(user.Participant.Code)
 	common := seed.CommonQuestions()
 	peerQuestions := seed.BuildPeerQuestions(peers)
@@ -167,11 +233,8 @@ func (s *Server) handleQuestions(w http.ResponseWriter, r *http.Request) {
 		"common":              common,
 		"peer":                peerQuestions,
 		"rankableParticipants": peers,
-		"criteria": []string{
-			"Ініціативність та відповідальність",
-			"Лідерство та вплив",
-			"Розвиток бізнесу OPSLAB",
-		},
+		"round":               status,
+		"criteria":             seed.RankingCriteria(),
 	}
 	writeJSON(w, payload)
 }
@@ -208,11 +271,56 @@ func (s *Server) handleResponse(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	if err := s.store.UpsertResponse(r.Context(), user.Participant.Code, payload.Answers, payload.Rankings, false); err != nil {
+	round, status, err := s.participantRoundStatus(r.Context(), user.Participant.Code)
+	if round != nil {
+		noteAudit(r, "", fmt.Sprintf("round:%d", round.ID))
+	}
+	if err != nil {
+		log.Println("response round:", err)
+		http.Error(w, "cannot load round", http.StatusInternalServerError)
+		return
+	}
+	if !status.Accepting {
+		http.Error(w, status.Message, http.StatusForbidden)
+		return
+	}
+	pendingBefore, err := s.store.PendingParticipants(r.Context(), round.ID)
+	if err != nil {
+		log.Println("pending participants:", err)
+		http.Error(w, "cannot save", http.StatusInternalServerError)
+		return
+	}
+	if err := s.store.UpsertResponse(r.Context(), round.ID, user.Participant.Code, payload.Answers, payload.Rankings, false, user.SessionID); err != nil {
 		log.Println("save response:", err)
 		http.Error(w, "cannot save", http.StatusInternalServerError)
 		return
 	}
+	firstSubmission := false
+	for _, p := range pendingBefore {
+		if p.Code == user.Participant.Code {
+			firstSubmission = true
+		}
+	}
+	s.emit(r.Context(), webhook.EventResponseSubmitted, map[string]interface{}{
+		"roundId":         round.ID,
+		"participantCode": user.Participant.Code,
+		"participantName": user.Participant.Name,
+		"firstSubmission": firstSubmission,
+		"answersCount":    len(payload.Answers),
+		"rankingsCount":   len(payload.Rankings),
+	})
+	if firstSubmission {
+		s.emitCompletion(r.Context(), round)
+	}
+	s.publish(r.Context(), events.TypeSubmission, map[string]interface{}{
+		"roundId":         round.ID,
+		"participantCode": user.Participant.Code,
+		"participantName": user.Participant.Name,
+		"firstSubmission": firstSubmission,
+	})
+	if firstSubmission {
+		s.publishCompletion(r.Context(), round.ID)
+	}
 	writeJSON(w, map[string]string{"status": "saved"})
 }
 
@@ -221,7 +329,12 @@ func (s *Server) validateRankings(selfCode string, rankings []models.RankingPayl
 	for _, p := range s.peerListFor(selfCode) {
 		allowed[p.Code] = true
 	}
+	criteria := map[string]bool{}
 	for _, r := range rankings {
+		if criteria[r.Criteria] {
+			return fmt.Errorf("criterion ranked twice: %s", r.Criteria)
+		}
+		criteria[r.Criteria] = true
 		for _, c := range r.Order {
 			if !allowed[c] {
 				return fmt.Errorf("unknown participant in ranking: %s", c)
@@ -232,7 +345,11 @@ func (s *Server) validateRankings(selfCode string, rankings []models.RankingPayl
 }
 
 func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
-	responses, err := s.store.AllResponses(r.Context())
+	round := s.requestRound(w, r)
+	if round == nil {
+		return
+	}
+	responses, err := s.store.AllResponses(r.Context(), round.ID)
 	if err != nil {
 		log.Println("stats:", err)
 		http.Error(w, "cannot load stats", http.StatusInternalServerError)
@@ -252,6 +369,13 @@ func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	drafts, err := s.store.DraftProgress(r.Context(), round.ID)
+	if err != nil {
+		log.Println("stats drafts:", err)
+		http.Error(w, "cannot load stats", http.StatusInternalServerError)
+		return
+	}
+
 	completedList := []map[string]interface{}{}
 	pendingList := []map[string]interface{}{}
 
@@ -264,11 +388,17 @@ func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 		if respondedCodes[p.Code] {
 			completedList = append(completedList, info)
 		} else {
+			if d, ok := drafts[p.Code]; ok {
+				info["progress"] = d.Progress
+				info["draftUpdatedAt"] = d.UpdatedAt
+			}
 			pendingList = append(pendingList, info)
 		}
 	}
 
 	payload := map[string]interface{}{
+		"round":         round,
+		"roundState":    round.StateAt(time.Now()),
 		"total":         len(nonAdminParticipants),
 		"completed":     len(completedList),
 		"pending":       len(pendingList),
@@ -278,8 +408,19 @@ func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, payload)
 }
 
+// handleAdminResponses lists current responses a page at a time
+// (?limit=&cursor=&isTest=&sort=newest|oldest&from=&to=&round=).
 func (s *Server) handleAdminResponses(w http.ResponseWriter, r *http.Request) {
-	responses, err := s.store.AllResponses(r.Context())
+	round := s.requestRound(w, r)
+	if round == nil {
+		return
+	}
+	filter, ok := responseFilterFromQuery(w, r)
+	if !ok {
+		return
+	}
+	filter.RoundID = round.ID
+	page, err := s.store.ListResponses(r.Context(), filter)
 	if err != nil {
 		log.Println("admin responses:", err)
 		http.Error(w, "cannot load responses", http.StatusInternalServerError)
@@ -288,7 +429,7 @@ func (s *Server) handleAdminResponses(w http.ResponseWriter, r *http.Request) {
 
 	// Enrich with participant names
 	enriched := []map[string]interface{}{}
-	for _, resp := range responses {
+	for _, resp := range page.Items {
 		p, ok := s.participantBy[resp.ParticipantCode]
 		item := map[string]interface{}{
 			"id":              resp.ID,
@@ -305,7 +446,82 @@ func (s *Server) handleAdminResponses(w http.ResponseWriter, r *http.Request) {
 		enriched = append(enriched, item)
 	}
 
-	writeJSON(w, enriched)
+	payload := map[string]interface{}{"items": enriched}
+	if page.NextCursor != "" {
+		payload["nextCursor"] = page.NextCursor
+	}
+	writeJSON(w, payload)
+}
+
+// responseFilterFromQuery reads the listing parameters of
+// /api/admin/responses. from and to take RFC 3339 timestamps or dates; a
+// date in to includes the whole day.
+func responseFilterFromQuery(w http.ResponseWriter, r *http.Request) (models.ResponseFilter, bool) {
+	q := r.URL.Query()
+	f := models.ResponseFilter{Sort: models.SortNewest, Limit: 50}
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > 500 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return f, false
+		}
+		f.Limit = n
+	}
+	if raw := q.Get("cursor"); raw != "" {
+		c, err := models.ParseResponseCursor(raw)
+		if err != nil {
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return f, false
+		}
+		f.After = c
+	}
+	if raw := q.Get("isTest"); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			http.Error(w, "invalid isTest", http.StatusBadRequest)
+			return f, false
+		}
+		f.IsTest = &v
+	}
+	switch raw := q.Get("sort"); raw {
+	case "":
+	case models.SortNewest, models.SortOldest:
+		f.Sort = raw
+	default:
+		http.Error(w, "invalid sort", http.StatusBadRequest)
+		return f, false
+	}
+	var ok bool
+	if f.From, ok = queryTime(w, r, "from", false); !ok {
+		return f, false
+	}
+	if f.To, ok = queryTime(w, r, "to", true); !ok {
+		return f, false
+	}
+	return f, true
+}
+
+// queryTime reads an RFC 3339 timestamp or a date from the query parameter
+// name. With endOfDay a date stands for the start of the next day, so that an
+// exclusive upper bound includes the whole day.
+func queryTime(w http.ResponseWriter, r *http.Request, name string, endOfDay bool) (*time.Time, bool) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return nil, true
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		day, dayErr := time.Parse(time.DateOnly, raw)
+		if dayErr != nil {
+			http.Error(w, "invalid "+name, http.StatusBadRequest)
+			return nil, false
+		}
+		t = day
+		if endOfDay {
+			t = day.AddDate(0, 0, 1)
+		}
+	}
+	return &t, true
 }
 
 func (s *Server) handleAdminResponseDetail(w http.ResponseWriter, r *http.Request) {
@@ -315,23 +531,18 @@ func (s *Server) handleAdminResponseDetail(w http.ResponseWriter, r *http.Reques
 		http.Error(w, "participant code required", http.StatusBadRequest)
 		return
 	}
+	noteAudit(r, "", "participant:"+code)
 
-	responses, err := s.store.AllResponses(r.Context())
+	round := s.requestRound(w, r)
+	if round == nil {
+		return
+	}
+	targetResp, err := s.store.ResponseByParticipant(r.Context(), round.ID, code)
 	if err != nil {
 		log.Println("admin response detail:", err)
 		http.Error(w, "cannot load response", http.StatusInternalServerError)
 		return
 	}
-
-	// Find response for this participant
-	var targetResp *models.ResponseRecord
-	for _, resp := range responses {
-		if resp.ParticipantCode == code {
-			targetResp = &resp
-			break
-		}
-	}
-
 	if targetResp == nil {
 		http.Error(w, "response not found", http.StatusNotFound)
 		return
@@ -356,31 +567,53 @@ func (s *Server) handleAdminResponseDetail(w http.ResponseWriter, r *http.Reques
 }
 
 func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
-	responses, err := s.store.AllResponses(r.Context())
-	if err != nil {
-		log.Println("export:", err)
-		http.Error(w, "cannot load export", http.StatusInternalServerError)
+	round := s.requestRound(w, r)
+	if round == nil {
 		return
 	}
-	payload := map[string]interface{}{
-		"exportedAt":  time.Now(),
-		"participants": s.participants,
-		"responses":   responses,
-	}
-	writeJSON(w, payload)
-}
-
-func (s *Server) handleReset(w http.ResponseWriter, r *http.Request) {
-	if err := s.store.ResetResponses(r.Context()); err != nil {
-		log.Println("reset:", err)
-		http.Error(w, "cannot reset", http.StatusInternalServerError)
+	participants, responses, ok := s.roundResponses(w, r, round.ID)
+	if !ok {
 		return
 	}
-	writeJSON(w, map[string]string{"status": "cleared"})
+	filename := fmt.Sprintf("opslab-survey-export-%s", time.Now().UTC().Format("20060102-150405"))
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		payload := map[string]interface{}{
+			"exportedAt":   time.Now(),
+			"round":        round,
+			"participants": participants,
+			"responses":    responses,
+		}
+		writeJSON(w, payload)
+	case "csv":
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-csv.zip"`, filename))
+		if err := export.WriteCSVZip(w, export.Tables(participants, responses)); err != nil {
+			log.Println("export csv:", err)
+		}
+	case "xlsx":
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.xlsx"`, filename))
+		if err := export.WriteXLSX(w, export.Tables(participants, responses)); err != nil {
+			log.Println("export xlsx:", err)
+		}
+	case "spss":
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-spss.zip"`, filename))
+		if err := export.WriteStatsZip(w, participants, responses); err != nil {
+			log.Println("export spss:", err)
+		}
+	default:
+		http.Error(w, "unknown format: "+format, http.StatusBadRequest)
+	}
 }
 
 func (s *Server) handleRunTestData(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	round := s.requestRound(w, r)
+	if round == nil {
+		return
+	}
 	participants := s.participants
 	peersByCode := map[string][]models.Participant{}
 	for _, p := range participants {
@@ -396,10 +629,11 @@ func (s *Server) handleRunTestData(w http.ResponseWriter, r *http.Request) {
 		}
 		answers := buildSyntheticAnswers(peersByCode[p.Code])
 		rankings := buildSyntheticRankings(peersByCode[p.Code])
-		if err := s.store.UpsertResponse(ctx, p.Code, answers, rankings, true); err != nil {
+		if err := s.store.UpsertResponse(ctx, round.ID, p.Code, answers, rankings, true, "run-test"); err != nil {
 			log.Println("testdata for", p.Code, ":", err)
 		}
 	}
+	s.publishCompletion(ctx, round.ID)
 	writeJSON(w, map[string]string{"status": "test data loaded"})
 }
 
@@ -500,7 +734,7 @@ func (s *Server) userFromRequest(r *http.Request) (*sessionUser, error) {
 	if !ok {
 		return nil, errors.New("unknown participant")
 	}
-	return &sessionUser{Participant: p}, nil
+	return &sessionUser{Participant: p, SessionID: claims.ID}, nil
 }
 
 func writeJSON(w http.ResponseWriter, payload interface{}) {
@@ -513,22 +747,60 @@ func writeJSON(w http.ResponseWriter, payload interface{}) {
 // Start starts the HTTP server.
 func Start() error {
 	port := envOrDefault("PORT", "8080")
-	dbURL := os.Getenv("DATABASE_URL")
 	sessionSecret := os.Getenv("SESSION_SECRET")
 
 	ctx := context.Background()
-	st, err := store.New(ctx, dbURL)
+	st, err := store.OpenEnv(ctx)
 	if err != nil {
 		return err
 	}
 	defer st.Close()
 
 	participants := seed.Participants()
-	if err := st.EnsureSchema(ctx, participants); err != nil {
+
+	srv := New(st, auth.NewManager(sessionSecret), participants)
+
+	pseudonymKey, err := pseudonym.KeyFromEnv()
+	if err != nil {
 		return err
 	}
+	srv.pseudonyms = pseudonym.New(pseudonymKey)
+
+	transport, err := mailer.FromEnv()
+	if err != nil {
+		return err
+	}
+	reminderCfg, err := reminder.ConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	srv.reminders = reminder.NewScheduler(st, transport, reminderCfg)
+	go srv.reminders.Run(ctx)
+
+	srv.webhooks = webhook.NewDispatcher(st)
+	go srv.webhooks.Run(ctx)
+	go webhook.NewRoundWatcher(st, srv.webhooks).Run(ctx)
+
+	retentionPolicy, err := gdpr.PolicyFromEnv()
+	if err != nil {
+		return err
+	}
+	srv.retention = gdpr.NewRetention(st, retentionPolicy)
+	if err := srv.useNotice(ctx, seed.ConsentNotice(retentionPolicy.AnonymizeAfter, retentionPolicy.PurgeAfter)); err != nil {
+		return err
+	}
+	go srv.retention.Run(ctx)
+
+	// LIVE_EVENTS=local keeps dashboard events in-process; the default relays
+	// them through Postgres LISTEN/NOTIFY so every instance sees them. Stores
+	// that cannot relay (SQLite, memory) serve a single instance anyway.
+	if relay, ok := st.(events.Relay); ok && os.Getenv("LIVE_EVENTS") != "local" {
+		srv.events = events.NewBroker(relay)
+	} else {
+		srv.events = events.NewBroker(nil)
+	}
+	go srv.events.Run(ctx)
 
-	srv := New(st, auth.NewManager(sessionSecret), participants)
 	server := &http.Server{
 		Addr:         ":" + port,
 		Handler:      srv.Routes(),
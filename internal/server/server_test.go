@@ -0,0 +1,277 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"opslab-survey/internal/auth"
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/seed"
+	"opslab-survey/internal/store"
+	"opslab-survey/internal/store/memory"
+	"opslab-survey/internal/webhook"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata/golden")
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+const (
+	adminEmail = "work.olegkaminskyi@gmail.com"
+	adminCode  = "0000"
+)
+
+// testServer wires a Server to an in-memory store. store is nil when the
+// server runs on another backend (see serve).
+type testServer struct {
+	t       *testing.T
+	srv     *Server
+	store   *memory.Store
+	handler http.Handler
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	st := memory.New()
+	ts := serve(t, st)
+	ts.store = st
+	return ts
+}
+
+// serve wires a Server to st, which must be empty.
+func serve(t *testing.T, st store.Store) *testServer {
+	t.Helper()
+	participants := seed.Participants()
+	if err := st.EnsureSchema(context.Background(), participants); err != nil {
+		t.Fatal(err)
+	}
+	srv := New(st, auth.NewManager("test-secret"), participants)
+	srv.webhooks = webhook.NewDispatcher(st)
+	if err := srv.useNotice(context.Background(), srv.notice); err != nil {
+		t.Fatal(err)
+	}
+	// Everyone has accepted the privacy notice; TestConsent covers the rest.
+	for _, p := range participants {
+		if p.IsAdmin {
+			continue
+		}
+		if _, err := st.RecordConsent(context.Background(), p.Code, srv.notice.Version); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &testServer{t: t, srv: srv, handler: srv.Routes()}
+}
+
+// do sends a request through the full router. body may be nil, a string or
+// a value to encode as JSON.
+func (ts *testServer) do(method, path string, cookie *http.Cookie, body interface{}) *httptest.ResponseRecorder {
+	ts.t.Helper()
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = strings.NewReader(b)
+	default:
+		raw, err := json.Marshal(b)
+		if err != nil {
+			ts.t.Fatal(err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	ts.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// login signs in and returns the session cookie.
+func (ts *testServer) login(email, code string) *http.Cookie {
+	ts.t.Helper()
+	rec := ts.do(http.MethodPost, "/api/login", nil, map[string]string{"email": email, "code": code})
+	if rec.Code != http.StatusOK {
+		ts.t.Fatalf("login %s: %d %s", code, rec.Code, rec.Body)
+	}
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "session" {
+			return c
+		}
+	}
+	ts.t.Fatalf("login %s: no session cookie", code)
+	return nil
+}
+
+func (ts *testServer) admin() *http.Cookie {
+	return ts.login(adminEmail, adminCode)
+}
+
+func (ts *testServer) participant(code string) *http.Cookie {
+	for _, p := range seed.Participants() {
+		if p.Code == code {
+			return ts.login(p.Email, p.Code)
+		}
+	}
+	ts.t.Fatalf("unknown participant %s", code)
+	return nil
+}
+
+// submitFixture has every non-admin participant answer the survey through
+// /api/response. Values vary by participant so analytics have something to
+// chew on, but are fully deterministic.
+func (ts *testServer) submitFixture() {
+	ts.t.Helper()
+	texts := []string{
+		"Потрібні чіткі кордони між продажами та аналітикою, бо задачі губляться.",
+		"Регулярні зустрічі команди допомагають, але рішення часто не фіксуються.",
+		"Clear ownership of client onboarding would help the whole team.",
+		"Більше довіри та прозорості у рішеннях щодо пріоритетів.",
+	}
+	for i, p := range seed.Participants() {
+		if p.IsAdmin {
+			continue
+		}
+		peers := ts.srv.peerListFor(p.Code)
+		var answers []models.AnswerPayload
+		for j, q := range append(seed.CommonQuestions(), seed.BuildPeerQuestions(peers)...) {
+			switch q.Type {
+			case "text":
+				answers = append(answers, models.AnswerPayload{QuestionID: q.ID, Value: texts[(i+j)%len(texts)]})
+			case "scale":
+				answers = append(answers, models.AnswerPayload{QuestionID: q.ID, Value: (i*3+j)%q.ScaleMax + 1})
+			case "choice":
+				answers = append(answers, models.AnswerPayload{QuestionID: q.ID, Value: q.Choice[(i+j)%len(q.Choice)]})
+			}
+		}
+		var rankings []models.RankingPayload
+		for c, criteria := range seed.RankingCriteria() {
+			order := make([]string, len(peers))
+			for k := range peers {
+				order[k] = peers[(k+i+c)%len(peers)].Code
+			}
+			rankings = append(rankings, models.RankingPayload{Criteria: criteria, Order: order})
+		}
+		rec := ts.do(http.MethodPost, "/api/response", ts.participant(p.Code), map[string]interface{}{
+			"answers":  answers,
+			"rankings": rankings,
+		})
+		if rec.Code != http.StatusOK {
+			ts.t.Fatalf("submit %s: %d %s", p.Code, rec.Code, rec.Body)
+		}
+	}
+}
+
+// expectStatus fails unless the response has the given status code.
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if rec.Code != want {
+		t.Fatalf("status = %d, want %d (body %q)", rec.Code, want, rec.Body.String())
+	}
+}
+
+func decodeJSON(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Content-Type = %q, want application/json", ct)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+}
+
+var timestampRe = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})$`)
+
+// volatileKeys hold values that differ between runs even with a fixed store.
+var volatileKeys = map[string]bool{"sessionId": true, "token": true}
+
+// normalize replaces timestamps and per-login values with placeholders so
+// golden files only change when the API shape or computed values do.
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			if volatileKeys[k] {
+				v[k] = "<" + k + ">"
+				continue
+			}
+			v[k] = normalize(val)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = normalize(v[i])
+		}
+	case string:
+		if timestampRe.MatchString(v) {
+			return "<time>"
+		}
+	}
+	return v
+}
+
+// assertGolden compares a JSON response with testdata/golden/<name>.json.
+// Run `go test ./internal/server -update` to accept intended changes.
+func assertGolden(t *testing.T, name string, rec *httptest.ResponseRecorder) {
+	t.Helper()
+	var body interface{}
+	decodeJSON(t, rec, &body)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(normalize(body)); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.Bytes()
+
+	path := filepath.Join("testdata", "golden", name+".json")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s does not match golden file %s:\n%s", name, path, firstDiff(string(want), string(got)))
+	}
+}
+
+func firstDiff(want, got string) string {
+	wl, gl := strings.Split(want, "\n"), strings.Split(got, "\n")
+	for i := 0; i < len(wl) || i < len(gl); i++ {
+		var w, g string
+		if i < len(wl) {
+			w = wl[i]
+		}
+		if i < len(gl) {
+			g = gl[i]
+		}
+		if w != g {
+			return fmt.Sprintf("line %d:\n  want: %s\n  got:  %s", i+1, w, g)
+		}
+	}
+	return "(no line difference)"
+}
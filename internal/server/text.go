@@ -0,0 +1,92 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"opslab-survey/internal/export"
+	"opslab-survey/internal/seed"
+	"opslab-survey/internal/textanalytics"
+)
+
+type textQuestionAnalysis struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Scope string `json:"scope"`
+	textanalytics.Analysis
+}
+
+// handleAdminText extracts keywords, n-grams and themes from open answers:
+// ?question=<id>&ratee=<code>&lang=uk|en&themes=<k>. Without question every
+// text question is analysed; peer questions are pooled across colleagues
+// unless ratee is given.
+func (s *Server) handleAdminText(w http.ResponseWriter, r *http.Request) {
+	round := s.requestRound(w, r)
+	if round == nil {
+		return
+	}
+	q := r.URL.Query()
+	opts := textanalytics.Options{Lang: q.Get("lang")}
+	if opts.Lang != "" && opts.Lang != textanalytics.LangUK && opts.Lang != textanalytics.LangEN {
+		http.Error(w, "lang must be uk or en", http.StatusBadRequest)
+		return
+	}
+	if raw := q.Get("themes"); raw != "" {
+		k, err := strconv.Atoi(raw)
+		if err != nil || k < 1 || k > 20 {
+			http.Error(w, "themes must be between 1 and 20", http.StatusBadRequest)
+			return
+		}
+		opts.Themes = k
+	}
+
+	var questions []textQuestionAnalysis
+	for _, cq := range seed.CommonQuestions() {
+		if cq.Type == "text" {
+			questions = append(questions, textQuestionAnalysis{ID: cq.ID, Title: cq.Title, Scope: "common"})
+		}
+	}
+	for _, t := range seed.PeerTemplates() {
+		if t.Type == "text" {
+			questions = append(questions, textQuestionAnalysis{ID: t.ID, Title: fmt.Sprintf(t.TitleFmt, "…"), Scope: "peer"})
+		}
+	}
+	if only := q.Get("question"); only != "" {
+		var filtered []textQuestionAnalysis
+		for _, tq := range questions {
+			if tq.ID == only {
+				filtered = append(filtered, tq)
+			}
+		}
+		if len(filtered) == 0 {
+			http.Error(w, "unknown text question", http.StatusBadRequest)
+			return
+		}
+		questions = filtered
+	}
+
+	_, responses, ok := s.roundResponses(w, r, round.ID)
+	if !ok {
+		return
+	}
+	ratee := q.Get("ratee")
+	texts := map[string][]string{}
+	for _, resp := range responses {
+		for _, a := range resp.Answers {
+			text, ok := a.Value.(string)
+			if !ok || text == "" {
+				continue
+			}
+			meta := export.ParseQuestionID(a.QuestionID)
+			if ratee != "" && meta.Scope == "peer" && meta.Ratee != ratee {
+				continue
+			}
+			texts[meta.Key] = append(texts[meta.Key], text)
+		}
+	}
+	for i := range questions {
+		questions[i].Analysis = textanalytics.Analyze(texts[questions[i].ID], opts)
+	}
+	writeJSON(w, questions)
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"opslab-survey/internal/audit"
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/webhook"
+)
+
+// emit queues a webhook event. Failures are logged rather than surfaced so a
+// broken subscriber never blocks a participant's submission.
+func (s *Server) emit(ctx context.Context, event string, data interface{}) {
+	if s.webhooks == nil {
+		return
+	}
+	if err := s.webhooks.Emit(context.WithoutCancel(ctx), event, data); err != nil {
+		log.Println("webhooks:", err)
+	}
+}
+
+// emitCompletion sends completion.reached once everyone has submitted. It
+// counts after the submission is stored, and the event is keyed by the
+// round and its latest reset, so concurrent last submissions send it once
+// and a round that is reset and filled in again sends it again.
+func (s *Server) emitCompletion(ctx context.Context, round *models.Round) {
+	if s.webhooks == nil {
+		return
+	}
+	pending, err := s.store.PendingParticipants(ctx, round.ID)
+	if err != nil {
+		log.Println("webhooks: pending participants:", err)
+		return
+	}
+	if len(pending) > 0 {
+		return
+	}
+	snapshots, err := s.store.ListSnapshots(ctx)
+	if err != nil {
+		log.Println("webhooks: snapshots:", err)
+		return
+	}
+	var lastReset int64
+	for _, snap := range snapshots {
+		if (snap.Scope.RoundID == 0 || snap.Scope.RoundID == round.ID) && snap.ID > lastReset {
+			lastReset = snap.ID
+		}
+	}
+	key := fmt.Sprintf("round:%d:%s@reset:%d", round.ID, webhook.EventCompletionReached, lastReset)
+	_, err = s.webhooks.EmitOnce(context.WithoutCancel(ctx), key, webhook.EventCompletionReached, map[string]interface{}{
+		"roundId":    round.ID,
+		"roundTitle": round.Title,
+	})
+	if err != nil {
+		log.Println("webhooks:", err)
+	}
+}
+
+// handleAdminWebhooks lists endpoints (GET) or registers a new one (POST).
+func (s *Server) handleAdminWebhooks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		endpoints, err := s.store.ListWebhookEndpoints(r.Context())
+		if err != nil {
+			log.Println("list webhooks:", err)
+			http.Error(w, "cannot load webhooks", http.StatusInternalServerError)
+			return
+		}
+		// Secrets are only shown once, when the endpoint is created.
+		for i := range endpoints {
+			endpoints[i].Secret = ""
+		}
+		if endpoints == nil {
+			endpoints = []models.WebhookEndpoint{}
+		}
+		writeJSON(w, map[string]interface{}{
+			"endpoints": endpoints,
+			"events":    webhook.Events,
+		})
+	case http.MethodPost:
+		noteAudit(r, audit.ActionWebhookCreate, "")
+		var payload struct {
+			URL    string   `json:"url"`
+			Events []string `json:"events"`
+			Secret string   `json:"secret"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		u, err := url.Parse(strings.TrimSpace(payload.URL))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			http.Error(w, "url must be an absolute http(s) URL", http.StatusBadRequest)
+			return
+		}
+		for _, e := range payload.Events {
+			if !webhook.KnownEvent(e) {
+				http.Error(w, "unknown event: "+e, http.StatusBadRequest)
+				return
+			}
+		}
+		if payload.Secret == "" {
+			payload.Secret, err = webhook.NewSecret()
+			if err != nil {
+				http.Error(w, "cannot generate secret", http.StatusInternalServerError)
+				return
+			}
+		}
+		endpoint, err := s.store.CreateWebhookEndpoint(r.Context(), models.WebhookEndpoint{
+			URL:    u.String(),
+			Secret: payload.Secret,
+			Events: payload.Events,
+			Active: true,
+		})
+		if err != nil {
+			log.Println("create webhook:", err)
+			http.Error(w, "cannot create webhook", http.StatusInternalServerError)
+			return
+		}
+		noteAudit(r, "", fmt.Sprintf("webhook:%d", endpoint.ID))
+		writeJSON(w, endpoint)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleAdminWebhookDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var payload struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	noteAudit(r, "", fmt.Sprintf("webhook:%d", payload.ID))
+	found, err := s.store.DeleteWebhookEndpoint(r.Context(), payload.ID)
+	if err != nil {
+		log.Println("delete webhook:", err)
+		http.Error(w, "cannot delete webhook", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "webhook not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, map[string]string{"status": "deleted"})
+}
+
+// handleAdminWebhookDeliveries returns the delivery log (?endpoint=<id>&limit=<n>).
+func (s *Server) handleAdminWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	var endpointID int64
+	if raw := r.URL.Query().Get("endpoint"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid endpoint", http.StatusBadRequest)
+			return
+		}
+		endpointID = id
+	}
+	limit := 100
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > 1000 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	deliveries, err := s.store.ListWebhookDeliveries(r.Context(), endpointID, limit)
+	if err != nil {
+		log.Println("webhook deliveries:", err)
+		http.Error(w, "cannot load deliveries", http.StatusInternalServerError)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+	writeJSON(w, deliveries)
+}
@@ -0,0 +1,333 @@
+package sociogram
+
+import (
+	"math"
+	"sort"
+)
+
+// Member is a participant inside a group, clique or bridge listing.
+type Member struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// Group is one detected community.
+type Group struct {
+	ID      int      `json:"id"`
+	Members []Member `json:"members"`
+}
+
+// Bridge is a person whose positive ties spread across several groups.
+type Bridge struct {
+	Member
+	Group int `json:"group"`
+	// Participation is 1 − Σ(share of tie weight per group)²: 0 when all ties
+	// stay inside one group, approaching 1 when they are spread evenly.
+	Participation float64 `json:"participation"`
+	Groups        []int   `json:"groups"`
+}
+
+// Communities is the subgroup report for one weight source.
+type Communities struct {
+	WeightLabel string         `json:"weightLabel"`
+	Thresholds  Thresholds     `json:"thresholds"`
+	Modularity  *float64       `json:"modularity"`
+	Groups      []Group        `json:"groups"`
+	Membership  map[string]int `json:"membership"`
+	Cliques     [][]Member     `json:"cliques"`
+	Bridges     []Bridge       `json:"bridges"`
+}
+
+// minBridgeParticipation filters out people with only a stray outside tie.
+const minBridgeParticipation = 0.3
+
+// DetectCommunities finds subgroups with Louvain on the undirected graph of
+// positive choices (tie weight = sum of positive weights in both directions),
+// enumerates maximal cliques of size three or more among mutual positive
+// ties, and lists bridge persons between groups.
+func DetectCommunities(g Graph, t Thresholds) Communities {
+	n := len(g.Nodes)
+	index := map[string]int{}
+	members := make([]Member, n)
+	for i, node := range g.Nodes {
+		index[node.Code] = i
+		members[i] = Member{Code: node.Code, Name: node.Name}
+	}
+
+	adj := make([][]float64, n)
+	positive := make([][]bool, n)
+	for i := range adj {
+		adj[i] = make([]float64, n)
+		positive[i] = make([]bool, n)
+	}
+	for _, e := range g.Edges {
+		a, okA := index[e.Source]
+		b, okB := index[e.Target]
+		if !okA || !okB || a == b || t.classify(e) != choicePositive {
+			continue
+		}
+		positive[a][b] = true
+		adj[a][b] += e.Weight
+		adj[b][a] += e.Weight
+	}
+
+	membership := louvain(adj)
+	out := Communities{
+		WeightLabel: g.WeightLabel,
+		Thresholds:  t,
+		Membership:  map[string]int{},
+		Cliques:     [][]Member{},
+		Bridges:     []Bridge{},
+	}
+	if q, ok := modularity(adj, membership); ok {
+		out.Modularity = &q
+	}
+
+	groups := map[int][]Member{}
+	for i, c := range membership {
+		groups[c] = append(groups[c], members[i])
+		out.Membership[members[i].Code] = c
+	}
+	for id := 0; id < len(groups); id++ {
+		out.Groups = append(out.Groups, Group{ID: id, Members: groups[id]})
+	}
+
+	mutual := make([][]bool, n)
+	for a := range mutual {
+		mutual[a] = make([]bool, n)
+		for b := range mutual[a] {
+			mutual[a][b] = positive[a][b] && positive[b][a]
+		}
+	}
+	for _, clique := range maximalCliques(mutual) {
+		if len(clique) < 3 {
+			continue
+		}
+		sort.Ints(clique)
+		var c []Member
+		for _, i := range clique {
+			c = append(c, members[i])
+		}
+		out.Cliques = append(out.Cliques, c)
+	}
+	sort.SliceStable(out.Cliques, func(i, j int) bool { return len(out.Cliques[i]) > len(out.Cliques[j]) })
+
+	for i := 0; i < n; i++ {
+		byGroup := map[int]float64{}
+		total := 0.0
+		for j := 0; j < n; j++ {
+			if adj[i][j] > 0 {
+				byGroup[membership[j]] += adj[i][j]
+				total += adj[i][j]
+			}
+		}
+		if total == 0 || len(byGroup) < 2 {
+			continue
+		}
+		p := 1.0
+		var touched []int
+		for c, w := range byGroup {
+			p -= (w / total) * (w / total)
+			touched = append(touched, c)
+		}
+		if p < minBridgeParticipation {
+			continue
+		}
+		sort.Ints(touched)
+		out.Bridges = append(out.Bridges, Bridge{
+			Member: members[i], Group: membership[i],
+			Participation: math.Round(p*1000) / 1000, Groups: touched,
+		})
+	}
+	sort.SliceStable(out.Bridges, func(i, j int) bool { return out.Bridges[i].Participation > out.Bridges[j].Participation })
+	return out
+}
+
+// louvain returns a community index per node for the symmetric weighted
+// adjacency matrix adj. Nodes are visited in order, so results are
+// deterministic. Community IDs are renumbered 0..k-1 by first member.
+func louvain(adj [][]float64) []int {
+	n := len(adj)
+	membership := make([]int, n)
+	for i := range membership {
+		membership[i] = i
+	}
+	level := adj
+	for {
+		local, moved := louvainPass(level)
+		if !moved {
+			break
+		}
+		// Lift the moves to the original nodes and collapse each community
+		// into a single node for the next level.
+		k := renumber(local)
+		for i := range membership {
+			membership[i] = local[membership[i]]
+		}
+		next := make([][]float64, k)
+		for i := range next {
+			next[i] = make([]float64, k)
+		}
+		for i := range level {
+			for j, w := range level[i] {
+				next[local[i]][local[j]] += w
+			}
+		}
+		level = next
+	}
+	renumber(membership)
+	return membership
+}
+
+// louvainPass moves single nodes between communities while modularity
+// improves and reports whether any node moved.
+func louvainPass(adj [][]float64) ([]int, bool) {
+	n := len(adj)
+	comm := make([]int, n)
+	degree := make([]float64, n)
+	tot := make([]float64, n)
+	m2 := 0.0
+	for i := range adj {
+		comm[i] = i
+		for _, w := range adj[i] {
+			degree[i] += w
+		}
+		tot[i] = degree[i]
+		m2 += degree[i]
+	}
+	if m2 == 0 {
+		return comm, false
+	}
+
+	moved := false
+	for improved := true; improved; {
+		improved = false
+		for i := 0; i < n; i++ {
+			current := comm[i]
+			tot[current] -= degree[i]
+			links := map[int]float64{}
+			for j, w := range adj[i] {
+				if j != i && w > 0 {
+					links[comm[j]] += w
+				}
+			}
+			best, bestGain := current, links[current]-tot[current]*degree[i]/m2
+			candidates := make([]int, 0, len(links))
+			for c := range links {
+				candidates = append(candidates, c)
+			}
+			sort.Ints(candidates)
+			for _, c := range candidates {
+				if gain := links[c] - tot[c]*degree[i]/m2; gain > bestGain+1e-12 {
+					best, bestGain = c, gain
+				}
+			}
+			tot[best] += degree[i]
+			if best != current {
+				comm[i] = best
+				improved, moved = true, true
+			}
+		}
+	}
+	return comm, moved
+}
+
+// renumber rewrites community IDs to 0..k-1 in order of first appearance and
+// returns k.
+func renumber(comm []int) int {
+	ids := map[int]int{}
+	for i, c := range comm {
+		id, ok := ids[c]
+		if !ok {
+			id = len(ids)
+			ids[c] = id
+		}
+		comm[i] = id
+	}
+	return len(ids)
+}
+
+// modularity is Newman's Q for a partition of the weighted graph.
+func modularity(adj [][]float64, comm []int) (float64, bool) {
+	degree := make([]float64, len(adj))
+	m2 := 0.0
+	for i := range adj {
+		for _, w := range adj[i] {
+			degree[i] += w
+		}
+		m2 += degree[i]
+	}
+	if m2 == 0 {
+		return 0, false
+	}
+	q := 0.0
+	for i := range adj {
+		for j := range adj {
+			if comm[i] == comm[j] {
+				q += adj[i][j] - degree[i]*degree[j]/m2
+			}
+		}
+	}
+	return math.Round(q/m2*10000) / 10000, true
+}
+
+// maximalCliques runs Bron–Kerbosch with pivoting over an undirected
+// adjacency matrix.
+func maximalCliques(adj [][]bool) [][]int {
+	var out [][]int
+	var expand func(r, p, x []int)
+	expand = func(r, p, x []int) {
+		if len(p) == 0 && len(x) == 0 {
+			out = append(out, append([]int(nil), r...))
+			return
+		}
+		// Pick the pivot with most neighbours in P to prune branches.
+		pivot, most := -1, -1
+		for _, u := range append(append([]int(nil), p...), x...) {
+			count := 0
+			for _, v := range p {
+				if adj[u][v] {
+					count++
+				}
+			}
+			if count > most {
+				pivot, most = u, count
+			}
+		}
+		for _, v := range append([]int(nil), p...) {
+			if pivot >= 0 && adj[pivot][v] {
+				continue
+			}
+			var np, nx []int
+			for _, u := range p {
+				if adj[v][u] {
+					np = append(np, u)
+				}
+			}
+			for _, u := range x {
+				if adj[v][u] {
+					nx = append(nx, u)
+				}
+			}
+			expand(append(r, v), np, nx)
+			p = remove(p, v)
+			x = append(x, v)
+		}
+	}
+	all := make([]int, len(adj))
+	for i := range all {
+		all[i] = i
+	}
+	expand(nil, all, nil)
+	return out
+}
+
+func remove(xs []int, v int) []int {
+	out := xs[:0:0]
+	for _, x := range xs {
+		if x != v {
+			out = append(out, x)
+		}
+	}
+	return out
+}
@@ -0,0 +1,120 @@
+package sociogram
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Format describes one network file format.
+type Format struct {
+	Ext         string
+	ContentType string
+	Write       func(w io.Writer, g Graph) error
+}
+
+// Formats maps the ?format= value to its writer.
+var Formats = map[string]Format{
+	"graphml": {Ext: "graphml", ContentType: "application/graphml+xml", Write: WriteGraphML},
+	"gexf":    {Ext: "gexf", ContentType: "application/gexf+xml", Write: WriteGEXF},
+	"dot":     {Ext: "dot", ContentType: "text/vnd.graphviz; charset=utf-8", Write: WriteDOT},
+	"pajek":   {Ext: "net", ContentType: "text/plain; charset=utf-8", Write: WritePajek},
+}
+
+// WriteGraphML writes the graph for yEd, Gephi and most graph libraries.
+func WriteGraphML(w io.Writer, g Graph) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header)
+	bw.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	bw.WriteString(`  <key id="name" for="node" attr.name="name" attr.type="string"/>` + "\n")
+	bw.WriteString(`  <key id="completed" for="node" attr.name="completed" attr.type="boolean"/>` + "\n")
+	fmt.Fprintf(bw, `  <key id="weight" for="edge" attr.name="weight" attr.type="double"><desc>%s</desc></key>`+"\n", esc(g.WeightLabel))
+	bw.WriteString(`  <graph id="sociogram" edgedefault="directed">` + "\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(bw, `    <node id="%s"><data key="name">%s</data><data key="completed">%t</data></node>`+"\n",
+			esc(n.Code), esc(n.Name), n.Completed)
+	}
+	for i, e := range g.Edges {
+		fmt.Fprintf(bw, `    <edge id="e%d" source="%s" target="%s"><data key="weight">%s</data></edge>`+"\n",
+			i, esc(e.Source), esc(e.Target), num(e.Weight))
+	}
+	bw.WriteString("  </graph>\n</graphml>\n")
+	return bw.Flush()
+}
+
+// WriteGEXF writes the graph in Gephi's native format.
+func WriteGEXF(w io.Writer, g Graph) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header)
+	bw.WriteString(`<gexf xmlns="http://gexf.net/1.3" version="1.3">` + "\n")
+	fmt.Fprintf(bw, "  <meta><creator>opslab-survey</creator><description>weight: %s</description></meta>\n", esc(g.WeightLabel))
+	bw.WriteString(`  <graph mode="static" defaultedgetype="directed">` + "\n")
+	bw.WriteString(`    <attributes class="node"><attribute id="completed" title="completed" type="boolean"/></attributes>` + "\n")
+	bw.WriteString("    <nodes>\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(bw, `      <node id="%s" label="%s"><attvalues><attvalue for="completed" value="%t"/></attvalues></node>`+"\n",
+			esc(n.Code), esc(n.Name), n.Completed)
+	}
+	bw.WriteString("    </nodes>\n    <edges>\n")
+	for i, e := range g.Edges {
+		fmt.Fprintf(bw, `      <edge id="%d" source="%s" target="%s" weight="%s"/>`+"\n",
+			i, esc(e.Source), esc(e.Target), num(e.Weight))
+	}
+	bw.WriteString("    </edges>\n  </graph>\n</gexf>\n")
+	return bw.Flush()
+}
+
+// WriteDOT writes a Graphviz digraph. Participants who have not submitted
+// are drawn dashed.
+func WriteDOT(w io.Writer, g Graph) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "// weight: %s\n", g.WeightLabel)
+	bw.WriteString("digraph sociogram {\n  node [shape=ellipse];\n")
+	for _, n := range g.Nodes {
+		style := "solid"
+		if !n.Completed {
+			style = "dashed"
+		}
+		fmt.Fprintf(bw, "  %s [label=%s, completed=%t, style=%s];\n", quote(n.Code), quote(n.Name), n.Completed, style)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(bw, "  %s -> %s [weight=%s, label=%s];\n", quote(e.Source), quote(e.Target), num(e.Weight), quote(num(e.Weight)))
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// WritePajek writes a Pajek .net file. Pajek has no node attributes, so
+// completion status is not included; vertex labels are participant names.
+func WritePajek(w io.Writer, g Graph) error {
+	bw := bufio.NewWriter(w)
+	index := map[string]int{}
+	fmt.Fprintf(bw, "*Vertices %d\n", len(g.Nodes))
+	for i, n := range g.Nodes {
+		index[n.Code] = i + 1
+		fmt.Fprintf(bw, "%d %s\n", i+1, quote(n.Name))
+	}
+	bw.WriteString("*Arcs\n")
+	for _, e := range g.Edges {
+		fmt.Fprintf(bw, "%d %d %s\n", index[e.Source], index[e.Target], num(e.Weight))
+	}
+	return bw.Flush()
+}
+
+func esc(s string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func num(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
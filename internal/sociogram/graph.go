@@ -0,0 +1,231 @@
+package sociogram
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/seed"
+)
+
+// Node is a participant in the network.
+type Node struct {
+	Code      string
+	Name      string
+	Completed bool
+}
+
+// Edge is a directed rater→ratee tie. For ranking sources Rank is the
+// 1-based position the rater gave the ratee out of Of colleagues.
+type Edge struct {
+	Source string
+	Target string
+	Weight float64
+	Rank   int
+	Of     int
+}
+
+// Graph is the directed rater→ratee network for one weight source.
+type Graph struct {
+	WeightLabel string
+	Nodes       []Node
+	Edges       []Edge
+}
+
+// Weight selects where edge weights come from.
+type Weight struct {
+	// Kind is "scale" for a peer:* scale question or "ranking" for a
+	// ranking criterion.
+	Kind string
+	// Key is the peer template ID (peer:reliability) or the criterion name.
+	Key string
+}
+
+// Label is a human-readable name for the weight source.
+func (w Weight) Label() string {
+	if w.Kind == "ranking" {
+		return "ranking: " + w.Key
+	}
+	return w.Key
+}
+
+// ParseWeight understands:
+//
+//	trust                    alias for peer:trust-level (the default)
+//	peer:<template>          a peer scale question, e.g. peer:reliability
+//	ranking:<criterion>      a ranking criterion by name or 1-based index;
+//	                         the weight is the inverted position (1st of n → n)
+func ParseWeight(raw string) (Weight, error) {
+	raw = strings.TrimSpace(raw)
+	switch {
+	case raw == "" || raw == "trust":
+		return Weight{Kind: "scale", Key: "peer:trust-level"}, nil
+	case strings.HasPrefix(raw, "peer:"):
+		for _, t := range seed.PeerTemplates() {
+			if t.ID == raw {
+				if t.Type != "scale" {
+					return Weight{}, fmt.Errorf("%s is not a scale question", raw)
+				}
+				return Weight{Kind: "scale", Key: raw}, nil
+			}
+		}
+		return Weight{}, fmt.Errorf("unknown peer question %s", raw)
+	case strings.HasPrefix(raw, "ranking:"):
+		name := strings.TrimPrefix(raw, "ranking:")
+		criteria := seed.RankingCriteria()
+		if idx, err := strconv.Atoi(name); err == nil {
+			if idx < 1 || idx > len(criteria) {
+				return Weight{}, fmt.Errorf("criterion index %d out of range 1..%d", idx, len(criteria))
+			}
+			return Weight{Kind: "ranking", Key: criteria[idx-1]}, nil
+		}
+		for _, c := range criteria {
+			if c == name {
+				return Weight{Kind: "ranking", Key: c}, nil
+			}
+		}
+		return Weight{}, fmt.Errorf("unknown ranking criterion %q", name)
+	default:
+		return Weight{}, fmt.Errorf("unknown weight source %q", raw)
+	}
+}
+
+// Build turns responses into a network over the given participants. Ties to
+// or from people outside participants are dropped.
+func Build(participants []models.Participant, responses []models.ResponseRecord, weight Weight) Graph {
+	g := Graph{WeightLabel: weight.Label()}
+	known := map[string]bool{}
+	completed := map[string]bool{}
+	for _, r := range responses {
+		completed[r.ParticipantCode] = true
+	}
+	for _, p := range participants {
+		known[p.Code] = true
+		g.Nodes = append(g.Nodes, Node{Code: p.Code, Name: p.Name, Completed: completed[p.Code]})
+	}
+
+	for _, r := range responses {
+		rater := r.ParticipantCode
+		if !known[rater] {
+			continue
+		}
+		switch weight.Kind {
+		case "scale":
+			prefix := weight.Key + ":"
+			for _, a := range r.Answers {
+				rest, ok := strings.CutPrefix(a.QuestionID, prefix)
+				if !ok {
+					continue
+				}
+				ratee, _, _ := strings.Cut(rest, ":")
+				value, ok := number(a.Value)
+				if !ok || !known[ratee] || ratee == rater {
+					continue
+				}
+				g.Edges = append(g.Edges, Edge{Source: rater, Target: ratee, Weight: value})
+			}
+		case "ranking":
+			for _, rk := range r.Rankings {
+				if rk.Criteria != weight.Key {
+					continue
+				}
+				n := len(rk.Order)
+				for i, ratee := range rk.Order {
+					if !known[ratee] || ratee == rater {
+						continue
+					}
+					g.Edges = append(g.Edges, Edge{Source: rater, Target: ratee, Weight: float64(n - i), Rank: i + 1, Of: n})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(g.Edges, func(i, j int) bool {
+		if g.Edges[i].Source != g.Edges[j].Source {
+			return g.Edges[i].Source < g.Edges[j].Source
+		}
+		return g.Edges[i].Target < g.Edges[j].Target
+	})
+	return g
+}
+
+// EdgeFilter thins a graph to its stronger ties before it is drawn.
+type EdgeFilter struct {
+	// MinWeight drops ties lighter than it; zero keeps every tie.
+	MinWeight float64 `json:"minWeight"`
+	// TopK keeps only the k heaviest ties of each rater (for rankings, the
+	// top k positions); zero keeps them all.
+	TopK int `json:"topK"`
+}
+
+// Filter returns a copy of g with only the ties f keeps. Nodes stay, so
+// people left without ties are still drawn.
+func (g Graph) Filter(f EdgeFilter) Graph {
+	var kept []Edge
+	for _, e := range g.Edges {
+		if e.Weight >= f.MinWeight {
+			kept = append(kept, e)
+		}
+	}
+	if f.TopK > 0 {
+		byRater := map[string][]Edge{}
+		for _, e := range kept {
+			byRater[e.Source] = append(byRater[e.Source], e)
+		}
+		top := map[Edge]bool{}
+		for _, edges := range byRater {
+			// Edges arrive ordered by target, so equal weights keep that order.
+			sort.SliceStable(edges, func(i, j int) bool { return edges[i].Weight > edges[j].Weight })
+			for i := 0; i < len(edges) && i < f.TopK; i++ {
+				top[edges[i]] = true
+			}
+		}
+		var res []Edge
+		for _, e := range kept {
+			if top[e] {
+				res = append(res, e)
+			}
+		}
+		kept = res
+	}
+	g.Edges = kept
+	return g
+}
+
+func number(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case int:
+		return float64(val), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// Source is a selectable weight source for the admin UI.
+type Source struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+}
+
+// Sources lists every weight source ParseWeight accepts: the peer scale
+// questions followed by the ranking criteria.
+func Sources() []Source {
+	var out []Source
+	for _, t := range seed.PeerTemplates() {
+		if t.Type != "scale" {
+			continue
+		}
+		out = append(out, Source{Value: t.ID, Label: fmt.Sprintf(t.TitleFmt, "…")})
+	}
+	for i, c := range seed.RankingCriteria() {
+		out = append(out, Source{Value: fmt.Sprintf("ranking:%d", i+1), Label: "Рейтинг: " + c})
+	}
+	return out
+}
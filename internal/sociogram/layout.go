@@ -0,0 +1,183 @@
+package sociogram
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Point is a node position in layout space, centred on (0, 0).
+type Point struct{ X, Y float64 }
+
+// Layouts are the supported ?layout= values.
+var Layouts = []string{"force", "rings", "circle"}
+
+// Layout positions every node by code. Positions fit inside a circle of
+// radius 1.
+func Layout(g Graph, name string) (map[string]Point, error) {
+	switch name {
+	case "", "force":
+		return forceLayout(g), nil
+	case "rings":
+		return ringLayout(g), nil
+	case "circle":
+		return circleLayout(g), nil
+	default:
+		return nil, fmt.Errorf("unknown layout %q", name)
+	}
+}
+
+// InDegree counts incoming edges per node.
+func (g Graph) InDegree() map[string]int {
+	in := map[string]int{}
+	for _, e := range g.Edges {
+		in[e.Target]++
+	}
+	return in
+}
+
+// Reciprocal reports whether the tie source→target is returned.
+func (g Graph) Reciprocal() func(source, target string) bool {
+	ties := map[[2]string]bool{}
+	for _, e := range g.Edges {
+		ties[[2]string{e.Source, e.Target}] = true
+	}
+	return func(source, target string) bool {
+		return ties[[2]string{target, source}]
+	}
+}
+
+func circleLayout(g Graph) map[string]Point {
+	pos := map[string]Point{}
+	n := len(g.Nodes)
+	for i, node := range g.Nodes {
+		pos[node.Code] = onCircle(i, n, 1, 0)
+	}
+	return pos
+}
+
+// ringLayout places nodes on concentric rings by in-degree: the most chosen
+// people sit in the centre, the least chosen on the outer ring.
+func ringLayout(g Graph) map[string]Point {
+	in := g.InDegree()
+	var levels []int
+	seen := map[int]bool{}
+	for _, node := range g.Nodes {
+		if d := in[node.Code]; !seen[d] {
+			seen[d] = true
+			levels = append(levels, d)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(levels)))
+	ring := map[int]int{}
+	for i, d := range levels {
+		ring[d] = i
+	}
+
+	members := make([][]string, len(levels))
+	for _, node := range g.Nodes {
+		r := ring[in[node.Code]]
+		members[r] = append(members[r], node.Code)
+	}
+	pos := map[string]Point{}
+	for r, codes := range members {
+		radius := float64(r+1) / float64(len(levels))
+		if r == 0 && len(codes) == 1 {
+			radius = 0
+		}
+		// Offset alternate rings so labels on neighbouring rings do not line up.
+		offset := float64(r) * 0.5
+		for i, code := range codes {
+			pos[code] = onCircle(i, len(codes), radius, offset)
+		}
+	}
+	return pos
+}
+
+// forceLayout runs a deterministic Fruchterman–Reingold simulation starting
+// from the circle layout, so the same data always yields the same picture.
+func forceLayout(g Graph) map[string]Point {
+	n := len(g.Nodes)
+	pos := circleLayout(g)
+	if n < 3 {
+		return pos
+	}
+	k := math.Sqrt(4.0 / float64(n)) // ideal edge length in a 2×2 frame
+	temp := 0.2
+	const iterations = 300
+
+	for it := 0; it < iterations; it++ {
+		disp := map[string]Point{}
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				a, b := g.Nodes[i].Code, g.Nodes[j].Code
+				dx, dy, d := delta(pos[a], pos[b])
+				f := k * k / d
+				disp[a] = Point{disp[a].X + dx/d*f, disp[a].Y + dy/d*f}
+				disp[b] = Point{disp[b].X - dx/d*f, disp[b].Y - dy/d*f}
+			}
+		}
+		for _, e := range g.Edges {
+			dx, dy, d := delta(pos[e.Source], pos[e.Target])
+			f := d * d / k
+			disp[e.Source] = Point{disp[e.Source].X - dx/d*f, disp[e.Source].Y - dy/d*f}
+			disp[e.Target] = Point{disp[e.Target].X + dx/d*f, disp[e.Target].Y + dy/d*f}
+		}
+		for _, node := range g.Nodes {
+			p, v := pos[node.Code], disp[node.Code]
+			length := math.Hypot(v.X, v.Y)
+			if length > 0 {
+				step := math.Min(length, temp)
+				p.X += v.X / length * step
+				p.Y += v.Y / length * step
+			}
+			// Gentle gravity keeps disconnected people from drifting off.
+			p.X *= 0.98
+			p.Y *= 0.98
+			pos[node.Code] = p
+		}
+		temp *= 0.985
+	}
+	return normalise(pos)
+}
+
+func delta(a, b Point) (dx, dy, d float64) {
+	dx, dy = a.X-b.X, a.Y-b.Y
+	d = math.Hypot(dx, dy)
+	if d < 1e-6 {
+		// Nudge coincident nodes apart deterministically.
+		dx, dy, d = 1e-3, 0, 1e-3
+	}
+	return dx, dy, d
+}
+
+// normalise recentres positions and scales them into the unit circle.
+func normalise(pos map[string]Point) map[string]Point {
+	var cx, cy float64
+	for _, p := range pos {
+		cx += p.X
+		cy += p.Y
+	}
+	cx /= float64(len(pos))
+	cy /= float64(len(pos))
+	maxR := 0.0
+	for _, p := range pos {
+		maxR = math.Max(maxR, math.Hypot(p.X-cx, p.Y-cy))
+	}
+	if maxR == 0 {
+		maxR = 1
+	}
+	out := make(map[string]Point, len(pos))
+	for code, p := range pos {
+		out[code] = Point{(p.X - cx) / maxR, (p.Y - cy) / maxR}
+	}
+	return out
+}
+
+func onCircle(i, n int, radius, offset float64) Point {
+	if n == 0 {
+		return Point{}
+	}
+	angle := 2*math.Pi*(float64(i)+offset)/float64(n) - math.Pi/2
+	return Point{radius * math.Cos(angle), radius * math.Sin(angle)}
+}
@@ -0,0 +1,223 @@
+package sociogram
+
+import (
+	"math"
+	"sort"
+)
+
+// Pair categories in the mutuality matrix.
+const (
+	PairMutualPositive = "mutual_positive"
+	PairOneSided       = "one_sided"
+	PairMutualLow      = "mutual_low"
+	PairNeutral        = "neutral"
+	PairIncomplete     = "incomplete"
+)
+
+// Choice levels for one direction of a pair.
+const (
+	choiceMissing = iota
+	choiceLow
+	choiceNeutral
+	choicePositive
+)
+
+// Thresholds decide when a tie counts as a positive or a low choice.
+type Thresholds struct {
+	// High and Low apply to scale weights: >= High is positive, <= Low is low.
+	High float64 `json:"high"`
+	Low  float64 `json:"low"`
+	// TopK applies to rankings: the top k positions are positive choices and
+	// the bottom k are low ones.
+	TopK int `json:"topK"`
+}
+
+// DefaultThresholds suit the 1–10 peer scales and a team of about ten.
+var DefaultThresholds = Thresholds{High: 8, Low: 4, TopK: 3}
+
+func (t Thresholds) classify(e Edge) int {
+	if e.Rank > 0 {
+		switch {
+		case e.Rank <= t.TopK:
+			return choicePositive
+		case e.Rank > e.Of-t.TopK:
+			return choiceLow
+		default:
+			return choiceNeutral
+		}
+	}
+	switch {
+	case e.Weight >= t.High:
+		return choicePositive
+	case e.Weight <= t.Low:
+		return choiceLow
+	default:
+		return choiceNeutral
+	}
+}
+
+// PairSummary describes one unordered pair of colleagues.
+type PairSummary struct {
+	A         string   `json:"a"`
+	AName     string   `json:"aName"`
+	B         string   `json:"b"`
+	BName     string   `json:"bName"`
+	AtoB      *float64 `json:"aToB"`
+	BtoA      *float64 `json:"bToA"`
+	Category  string   `json:"category"`
+	Asymmetry float64  `json:"asymmetry"`
+}
+
+// Mutuality is the reciprocity report. Matrix rows are raters and columns
+// ratees in Codes order; Categories is symmetric.
+type Mutuality struct {
+	WeightLabel string         `json:"weightLabel"`
+	Thresholds  Thresholds     `json:"thresholds"`
+	Codes       []string       `json:"codes"`
+	Names       []string       `json:"names"`
+	Matrix      [][]*float64   `json:"matrix"`
+	Categories  [][]string     `json:"categories"`
+	Counts      map[string]int `json:"counts"`
+	// Reciprocity is the share of positive choices that are returned.
+	Reciprocity *float64 `json:"reciprocity"`
+	// WeightCorrelation is Pearson's r between A→B and B→A over complete pairs.
+	WeightCorrelation *float64      `json:"weightCorrelation"`
+	MostAsymmetric    []PairSummary `json:"mostAsymmetric"`
+}
+
+// ComputeMutuality classifies every pair of nodes in g and lists the limit
+// most asymmetric complete pairs.
+func ComputeMutuality(g Graph, t Thresholds, limit int) Mutuality {
+	n := len(g.Nodes)
+	m := Mutuality{
+		WeightLabel:    g.WeightLabel,
+		Thresholds:     t,
+		Matrix:         make([][]*float64, n),
+		Categories:     make([][]string, n),
+		Counts:         map[string]int{},
+		MostAsymmetric: []PairSummary{},
+	}
+	index := map[string]int{}
+	for i, node := range g.Nodes {
+		index[node.Code] = i
+		m.Codes = append(m.Codes, node.Code)
+		m.Names = append(m.Names, node.Name)
+		m.Matrix[i] = make([]*float64, n)
+		m.Categories[i] = make([]string, n)
+	}
+	edges := make([][]*Edge, n)
+	for i := range edges {
+		edges[i] = make([]*Edge, n)
+	}
+	for i := range g.Edges {
+		e := &g.Edges[i]
+		a, okA := index[e.Source]
+		b, okB := index[e.Target]
+		if !okA || !okB {
+			continue
+		}
+		edges[a][b] = e
+		w := e.Weight
+		m.Matrix[a][b] = &w
+	}
+
+	positive, returned := 0, 0
+	var xs, ys []float64
+	var pairs []PairSummary
+	for a := 0; a < n; a++ {
+		for b := a + 1; b < n; b++ {
+			ab, ba := choiceMissing, choiceMissing
+			if edges[a][b] != nil {
+				ab = t.classify(*edges[a][b])
+			}
+			if edges[b][a] != nil {
+				ba = t.classify(*edges[b][a])
+			}
+			if ab == choicePositive {
+				positive++
+				if ba == choicePositive {
+					returned++
+				}
+			}
+			if ba == choicePositive {
+				positive++
+				if ab == choicePositive {
+					returned++
+				}
+			}
+
+			category := pairCategory(ab, ba)
+			m.Categories[a][b], m.Categories[b][a] = category, category
+			m.Counts[category]++
+
+			if ab == choiceMissing || ba == choiceMissing {
+				continue
+			}
+			wab, wba := edges[a][b].Weight, edges[b][a].Weight
+			xs = append(xs, wab)
+			ys = append(ys, wba)
+			pairs = append(pairs, PairSummary{
+				A: g.Nodes[a].Code, AName: g.Nodes[a].Name,
+				B: g.Nodes[b].Code, BName: g.Nodes[b].Name,
+				AtoB: m.Matrix[a][b], BtoA: m.Matrix[b][a],
+				Category: category, Asymmetry: math.Abs(wab - wba),
+			})
+		}
+	}
+	if positive > 0 {
+		r := float64(returned) / float64(positive)
+		m.Reciprocity = &r
+	}
+	if r, ok := pearson(xs, ys); ok {
+		m.WeightCorrelation = &r
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].Asymmetry > pairs[j].Asymmetry })
+	for _, p := range pairs {
+		if len(m.MostAsymmetric) >= limit || p.Asymmetry == 0 {
+			break
+		}
+		m.MostAsymmetric = append(m.MostAsymmetric, p)
+	}
+	return m
+}
+
+func pairCategory(ab, ba int) string {
+	switch {
+	case ab == choiceMissing || ba == choiceMissing:
+		return PairIncomplete
+	case ab == choicePositive && ba == choicePositive:
+		return PairMutualPositive
+	case ab == choicePositive || ba == choicePositive:
+		return PairOneSided
+	case ab == choiceLow && ba == choiceLow:
+		return PairMutualLow
+	default:
+		return PairNeutral
+	}
+}
+
+func pearson(xs, ys []float64) (float64, bool) {
+	n := float64(len(xs))
+	if n < 3 {
+		return 0, false
+	}
+	var mx, my float64
+	for i := range xs {
+		mx += xs[i]
+		my += ys[i]
+	}
+	mx /= n
+	my /= n
+	var sxy, sxx, syy float64
+	for i := range xs {
+		dx, dy := xs[i]-mx, ys[i]-my
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	if sxx == 0 || syy == 0 {
+		return 0, false
+	}
+	return sxy / math.Sqrt(sxx*syy), true
+}
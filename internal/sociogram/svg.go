@@ -0,0 +1,117 @@
+package sociogram
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+)
+
+// SVG drawing constants.
+const (
+	svgSize     = 800.0
+	svgMargin   = 90.0
+	minRadius   = 8.0
+	maxRadius   = 22.0
+	minStroke   = 0.8
+	maxStroke   = 5.0
+	curveOffset = 6.0
+)
+
+// WriteSVG renders the graph. Edge thickness follows weight, reciprocal ties
+// are drawn in the accent colour, node size follows in-degree, and people who
+// have not submitted yet get a dashed outline.
+func WriteSVG(w io.Writer, g Graph, layout string) error {
+	pos, err := Layout(g, layout)
+	if err != nil {
+		return err
+	}
+	in := g.InDegree()
+	reciprocal := g.Reciprocal()
+
+	maxIn := 0
+	for _, d := range in {
+		if d > maxIn {
+			maxIn = d
+		}
+	}
+	minW, maxW := math.Inf(1), math.Inf(-1)
+	for _, e := range g.Edges {
+		minW = math.Min(minW, e.Weight)
+		maxW = math.Max(maxW, e.Weight)
+	}
+
+	half := svgSize / 2
+	scale := half - svgMargin
+	at := func(code string) (float64, float64) {
+		p := pos[code]
+		return half + p.X*scale, half + p.Y*scale
+	}
+	radius := func(code string) float64 {
+		if maxIn == 0 {
+			return minRadius
+		}
+		return minRadius + (maxRadius-minRadius)*float64(in[code])/float64(maxIn)
+	}
+	stroke := func(weight float64) float64 {
+		if maxW <= minW {
+			return (minStroke + maxStroke) / 2
+		}
+		return minStroke + (maxStroke-minStroke)*(weight-minW)/(maxW-minW)
+	}
+	names := map[string]string{}
+	for _, n := range g.Nodes {
+		names[n.Code] = n.Name
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %[1]g %[1]g" width="%[1]g" height="%[1]g" font-family="Inter, Arial, sans-serif">`+"\n", svgSize)
+	bw.WriteString(`<defs>` +
+		`<marker id="arrow" viewBox="0 0 10 10" refX="9" refY="5" markerWidth="6" markerHeight="6" orient="auto-start-reverse"><path d="M0,0 L10,5 L0,10 z" fill="#94a3b8"/></marker>` +
+		`<marker id="arrow-mutual" viewBox="0 0 10 10" refX="9" refY="5" markerWidth="6" markerHeight="6" orient="auto-start-reverse"><path d="M0,0 L10,5 L0,10 z" fill="#f97316"/></marker>` +
+		"</defs>\n")
+	fmt.Fprintf(bw, `<rect width="100%%" height="100%%" fill="#ffffff"/>`+"\n")
+	fmt.Fprintf(bw, `<text x="16" y="24" font-size="13" fill="#64748b">%s</text>`+"\n", esc(g.WeightLabel))
+
+	bw.WriteString(`<g class="edges" fill="none">` + "\n")
+	for _, e := range g.Edges {
+		x1, y1 := at(e.Source)
+		x2, y2 := at(e.Target)
+		dx, dy := x2-x1, y2-y1
+		d := math.Hypot(dx, dy)
+		if d == 0 {
+			continue
+		}
+		ux, uy := dx/d, dy/d
+		// Start and end on the node outlines rather than the centres.
+		sx, sy := x1+ux*radius(e.Source), y1+uy*radius(e.Source)
+		tx, ty := x2-ux*(radius(e.Target)+2), y2-uy*(radius(e.Target)+2)
+
+		colour, marker := "#94a3b8", "arrow"
+		mutual := reciprocal(e.Source, e.Target)
+		if mutual {
+			colour, marker = "#f97316", "arrow-mutual"
+		}
+		// Bend every edge slightly to its right so the two directions of a
+		// reciprocal pair do not overlap.
+		mx, my := (sx+tx)/2-uy*curveOffset, (sy+ty)/2+ux*curveOffset
+		fmt.Fprintf(bw, `<path d="M%.1f,%.1f Q%.1f,%.1f %.1f,%.1f" stroke="%s" stroke-width="%.2f" stroke-opacity="0.8" marker-end="url(#%s)"><title>%s → %s: %s</title></path>`+"\n",
+			sx, sy, mx, my, tx, ty, colour, stroke(e.Weight), marker, esc(names[e.Source]), esc(names[e.Target]), num(e.Weight))
+	}
+	bw.WriteString("</g>\n")
+
+	bw.WriteString(`<g class="nodes">` + "\n")
+	for _, n := range g.Nodes {
+		x, y := at(n.Code)
+		r := radius(n.Code)
+		dash := ""
+		if !n.Completed {
+			dash = ` stroke-dasharray="3,2"`
+		}
+		fmt.Fprintf(bw, `<g><circle cx="%.1f" cy="%.1f" r="%.1f" fill="#6366f1" fill-opacity="0.85" stroke="#312e81" stroke-width="1.5"%s/>`, x, y, r, dash)
+		fmt.Fprintf(bw, `<text x="%.1f" y="%.1f" font-size="12" text-anchor="middle" fill="#0f172a">%s</text>`, x, y+r+14, esc(n.Name))
+		fmt.Fprintf(bw, `<title>%s — вхідних виборів: %d</title></g>`+"\n", esc(n.Name), in[n.Code])
+	}
+	bw.WriteString("</g>\n</svg>\n")
+	return bw.Flush()
+}
@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"context"
+
+	"opslab-survey/internal/audit"
+	"opslab-survey/internal/models"
+)
+
+// AppendAudit stamps e with the current time, chains it to the last entry
+// and stores it.
+func (s *Store) AppendAudit(ctx context.Context, e models.AuditEntry) (*models.AuditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.ID = s.nextID("audit_log")
+	e.OccurredAt = s.now()
+	e.PrevHash = ""
+	if n := len(s.audit); n > 0 {
+		e.PrevHash = s.audit[n-1].Hash
+	}
+	e.Hash = audit.Hash(e.PrevHash, e)
+	s.audit = append(s.audit, e)
+	return &e, nil
+}
+
+func (s *Store) ListAudit(ctx context.Context, f models.AuditFilter) ([]models.AuditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []models.AuditEntry
+	for i := len(s.audit) - 1; i >= 0; i-- {
+		if f.Limit > 0 && len(res) == f.Limit {
+			break
+		}
+		if audit.Matches(f, s.audit[i]) {
+			res = append(res, s.audit[i])
+		}
+	}
+	return res, nil
+}
+
+// AuditTrail returns up to limit entries with IDs above afterID, oldest first.
+func (s *Store) AuditTrail(ctx context.Context, afterID int64, limit int) ([]models.AuditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []models.AuditEntry
+	for _, e := range s.audit {
+		if len(res) == limit {
+			break
+		}
+		if e.ID > afterID {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,138 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+
+	"opslab-survey/internal/envelope"
+	"opslab-survey/internal/models"
+)
+
+// RestoreDataset writes d into copies of the tables and swaps them in only
+// when everything succeeded, which is what a transaction gives the SQL
+// backends.
+func (s *Store) RestoreDataset(ctx context.Context, d models.Dataset, mode string) (*models.RestoreResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := &models.RestoreResult{Mode: mode}
+	participants := maps.Clone(s.participants)
+	rounds := slices.Clone(s.rounds)
+	revisions := slices.Clone(s.revisions)
+	extensions, drafts, reminders := s.extensions, s.drafts, s.reminders
+	switch mode {
+	case models.RestoreMerge:
+	case models.RestoreReplace:
+		rounds, revisions = nil, nil
+		extensions, drafts, reminders = map[key]models.DeadlineExtension{}, map[key]draft{}, nil
+	default:
+		return nil, fmt.Errorf("unknown restore mode %q", mode)
+	}
+
+	for _, p := range d.Participants {
+		if _, ok := participants[p.Code]; ok && mode == models.RestoreMerge {
+			continue
+		}
+		for code, other := range participants {
+			if other.Email == p.Email && code != p.Code {
+				return nil, fmt.Errorf("participant %s: email %s is taken by %s", p.Code, p.Email, code)
+			}
+		}
+		participants[p.Code] = p
+		res.Participants++
+	}
+
+	inserted := map[int64]bool{}
+	roundIDs := map[int64]bool{}
+	for _, r := range rounds {
+		roundIDs[r.ID] = true
+	}
+	for _, r := range d.Rounds {
+		if roundIDs[r.ID] {
+			i := slices.IndexFunc(rounds, func(other models.Round) bool { return other.ID == r.ID })
+			if i < 0 || !rounds[i].CreatedAt.Equal(r.CreatedAt) {
+				return nil, fmt.Errorf("round %d: %w", r.ID, models.ErrRestoreConflict)
+			}
+			res.SkippedRounds++
+			continue
+		}
+		if !models.ValidRoundState(r.State) {
+			return nil, fmt.Errorf("round %d: invalid state %q", r.ID, r.State)
+		}
+		roundIDs[r.ID], inserted[r.ID] = true, true
+		rounds = append(rounds, r)
+		res.Rounds++
+	}
+	sort.Slice(rounds, func(i, j int) bool { return rounds[i].ID < rounds[j].ID })
+
+	revisionIDs := map[int64]bool{}
+	for _, r := range revisions {
+		revisionIDs[r.id] = true
+	}
+	for _, rev := range d.Revisions {
+		if revisionIDs[rev.ID] {
+			if !slices.ContainsFunc(revisions, func(r revision) bool { return r.same(rev) }) {
+				return nil, fmt.Errorf("revision %d: %w", rev.ID, models.ErrRestoreConflict)
+			}
+			res.SkippedRevisions++
+			continue
+		}
+		if !roundIDs[rev.RoundID] {
+			return nil, fmt.Errorf("revision %d: round %d: %w", rev.ID, rev.RoundID, ErrNotFound)
+		}
+		if _, ok := participants[rev.ParticipantCode]; !ok {
+			return nil, fmt.Errorf("revision %d: participant %s: %w", rev.ID, rev.ParticipantCode, ErrNotFound)
+		}
+		answersJSON, rankingsJSON, err := s.encode(rev.Answers, rev.Rankings, envelope.LabelAnswers)
+		if err != nil {
+			return nil, err
+		}
+		revisionIDs[rev.ID] = true
+		revisions = append(revisions, revision{
+			id: rev.ID, roundID: rev.RoundID, code: rev.ParticipantCode,
+			answers: answersJSON, rankings: rankingsJSON, isTest: rev.IsTestData,
+			sessionID: rev.SessionID, at: rev.SubmittedAt,
+		})
+		res.Revisions++
+	}
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].id < revisions[j].id })
+
+	anonymous := s.anonymous
+	if mode == models.RestoreReplace {
+		anonymous = nil
+	}
+	anonymous = slices.Clone(anonymous)
+	for _, a := range d.Anonymous {
+		if inserted[a.RoundID] {
+			anonymous = append(anonymous, a)
+			res.Anonymous++
+		}
+	}
+
+	s.participants, s.rounds, s.revisions = participants, rounds, revisions
+	s.extensions, s.drafts, s.reminders = extensions, drafts, reminders
+	s.anonymous = anonymous
+	for _, c := range d.Consents {
+		if _, ok := participants[c.ParticipantCode]; ok && s.consent(c.ParticipantCode, c.Version) == nil {
+			s.consents = append(s.consents, c)
+			res.Consents++
+		}
+	}
+	// Keep handing out IDs above the restored ones, like setval does.
+	for _, r := range rounds {
+		s.seq["rounds"] = max(s.seq["rounds"], r.ID)
+	}
+	for _, r := range revisions {
+		s.seq["revisions"] = max(s.seq["revisions"], r.id)
+	}
+	return res, nil
+}
+
+// same reports whether r is the stored copy of rev: the same ID, round,
+// participant and submission time.
+func (r revision) same(rev models.ResponseRevision) bool {
+	return r.id == rev.ID && r.roundID == rev.RoundID && r.code == rev.ParticipantCode && r.at.Equal(rev.SubmittedAt)
+}
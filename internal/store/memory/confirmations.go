@@ -0,0 +1,19 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"time"
+)
+
+func (s *Store) UseConfirmation(ctx context.Context, jti string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	maps.DeleteFunc(s.usedTokens, func(_ string, expires time.Time) bool { return expires.Before(now) })
+	if _, ok := s.usedTokens[jti]; ok {
+		return false, nil
+	}
+	s.usedTokens[jti] = expiresAt
+	return true, nil
+}
@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+	"slices"
+
+	"opslab-survey/internal/models"
+)
+
+func (s *Store) RecordConsent(ctx context.Context, participantCode string, version int) (*models.Consent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.participants[participantCode]; !ok {
+		return nil, ErrNotFound
+	}
+	if c := s.consent(participantCode, version); c != nil {
+		return c, nil
+	}
+	c := models.Consent{ParticipantCode: participantCode, Version: version, AcceptedAt: s.now()}
+	s.consents = append(s.consents, c)
+	return &c, nil
+}
+
+func (s *Store) ConsentFor(ctx context.Context, participantCode string, version int) (*models.Consent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.consent(participantCode, version), nil
+}
+
+func (s *Store) consent(participantCode string, version int) *models.Consent {
+	for _, c := range s.consents {
+		if c.ParticipantCode == participantCode && c.Version == version {
+			return &c
+		}
+	}
+	return nil
+}
+
+func (s *Store) ListConsents(ctx context.Context) ([]models.Consent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := slices.Clone(s.consents)
+	slices.SortFunc(res, func(a, b models.Consent) int {
+		if a.ParticipantCode != b.ParticipantCode {
+			if a.ParticipantCode < b.ParticipantCode {
+				return -1
+			}
+			return 1
+		}
+		return a.Version - b.Version
+	})
+	return res, nil
+}
+
+func (s *Store) ConsentsOf(ctx context.Context, participantCode string) ([]models.Consent, error) {
+	all, err := s.ListConsents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(all, func(c models.Consent) bool { return c.ParticipantCode != participantCode }), nil
+}
+
+func (s *Store) NoticeVersion(ctx context.Context, digest string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n := len(s.notices); n == 0 || s.notices[n-1] != digest {
+		s.notices = append(s.notices, digest)
+	}
+	return len(s.notices), nil
+}
@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"context"
+
+	"opslab-survey/internal/envelope"
+	"opslab-survey/internal/models"
+)
+
+func (s *Store) SaveDraft(ctx context.Context, d models.Draft) error {
+	answersJSON, rankingsJSON, err := s.encode(d.Answers, d.Rankings, envelope.LabelDraft)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drafts[key{d.RoundID, d.ParticipantCode}] = draft{answers: answersJSON, rankings: rankingsJSON, progress: d.Progress, updatedAt: s.now()}
+	return nil
+}
+
+func (s *Store) DraftFor(ctx context.Context, roundID int64, participantCode string) (*models.Draft, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.drafts[key{roundID, participantCode}]
+	if !ok {
+		return nil, nil
+	}
+	d := models.Draft{RoundID: roundID, ParticipantCode: participantCode, Progress: stored.progress, UpdatedAt: stored.updatedAt}
+	if err := s.decode(stored.answers, stored.rankings, envelope.LabelDraft, &d.Answers, &d.Rankings); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (s *Store) DraftProgress(ctx context.Context, roundID int64) (map[string]models.Draft, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := map[string]models.Draft{}
+	for k, d := range s.drafts {
+		if k.round == roundID {
+			res[k.code] = models.Draft{RoundID: roundID, ParticipantCode: k.code, Progress: d.progress, UpdatedAt: d.updatedAt}
+		}
+	}
+	return res, nil
+}
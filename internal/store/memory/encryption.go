@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+
+	"opslab-survey/internal/envelope"
+	"opslab-survey/internal/models"
+)
+
+// SetKeyring makes the store seal answer payloads with k.
+func (s *Store) SetKeyring(k *envelope.Keyring) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = k
+}
+
+// RotateKeys re-seals every stale value into copies of the tables and swaps
+// them in only when all of them succeeded.
+func (s *Store) RotateKeys(ctx context.Context) (*models.RotationResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keys == nil {
+		return nil, errors.New("rotate keys: no encryption key configured")
+	}
+	res := &models.RotationResult{KeyID: s.keys.KeyID()}
+
+	revisions := slices.Clone(s.revisions)
+	for i, r := range revisions {
+		sealed, changed, err := s.reseal(r.answers, envelope.LabelAnswers)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			revisions[i].answers = sealed
+			res.Revisions++
+		}
+		sealed, changed, err = s.reseal(r.rankings, envelope.LabelRankings)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			revisions[i].rankings = sealed
+			res.Rankings++
+		}
+	}
+	drafts := maps.Clone(s.drafts)
+	for k, d := range drafts {
+		sealed, changed, err := s.reseal(d.answers, envelope.LabelDraft)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			d.answers = sealed
+			drafts[k] = d
+			res.Drafts++
+		}
+		sealed, changed, err = s.reseal(d.rankings, envelope.LabelDraftRankings)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			d.rankings = sealed
+			drafts[k] = d
+			res.Rankings++
+		}
+	}
+	snapshots := slices.Clone(s.snapshots)
+	for i, snap := range snapshots {
+		sealed, changed, err := s.reseal(snap.Data, envelope.LabelSnapshot)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			snapshots[i].Data = sealed
+			snapshots[i].Size = len(sealed)
+			res.Snapshots++
+		}
+	}
+	s.revisions, s.drafts, s.snapshots = revisions, drafts, snapshots
+	return res, nil
+}
+
+// reseal seals stored with the current master key unless it already is.
+func (s *Store) reseal(stored []byte, label string) ([]byte, bool, error) {
+	if s.keys.Current(stored) {
+		return stored, false, nil
+	}
+	plain, err := s.keys.Open(stored, label)
+	if err != nil {
+		return nil, false, err
+	}
+	sealed, err := s.keys.Seal(plain, label)
+	return sealed, err == nil, err
+}
@@ -0,0 +1,432 @@
+// Package memory is an in-process store for tests and quick demos. It keeps
+// the same semantics as the SQL backends, including JSON round-tripping of
+// answers, but forgets everything when the process exits.
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"opslab-survey/internal/envelope"
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/seed"
+)
+
+// ErrNotFound is returned when a looked-up row does not exist.
+var ErrNotFound = errors.New("not found")
+
+type key struct {
+	round int64
+	code  string
+}
+
+type revision struct {
+	id        int64
+	roundID   int64
+	code      string
+	answers   []byte
+	rankings  []byte
+	isTest    bool
+	sessionID string
+	at        time.Time
+}
+
+type draft struct {
+	answers, rankings []byte
+	progress          int
+	updatedAt         time.Time
+}
+
+type outboxEntry struct {
+	id          int64
+	endpointID  int64
+	event       string
+	payload     []byte
+	status      string
+	attempts    int
+	nextAttempt time.Time
+	lastError   string
+	deliveredAt *time.Time
+}
+
+// Store keeps survey data in maps guarded by one mutex.
+type Store struct {
+	// Now returns the current time; tests may replace it for stable timestamps.
+	Now func() time.Time
+
+	mu           sync.Mutex
+	seq          map[string]int64
+	participants map[string]models.Participant
+	rounds       []models.Round
+	extensions   map[key]models.DeadlineExtension
+	revisions    []revision
+	drafts       map[key]draft
+	reminders    []models.Reminder
+	endpoints    []models.WebhookEndpoint
+	outbox       []*outboxEntry
+	eventKeys    map[string]bool
+	deliveries   []models.WebhookDelivery
+	audit        []models.AuditEntry
+	snapshots    []models.Snapshot
+	anonymous    []models.AnonymousResponse
+	consents     []models.Consent
+	notices      []string // digests of the notice texts; version i+1 at i
+	keys         *envelope.Keyring
+	usedTokens   map[string]time.Time // used confirmation token IDs and their expiry
+}
+
+func New() *Store {
+	return &Store{
+		Now:          time.Now,
+		seq:          map[string]int64{},
+		participants: map[string]models.Participant{},
+		extensions:   map[key]models.DeadlineExtension{},
+		drafts:       map[key]draft{},
+		eventKeys:    map[string]bool{},
+		usedTokens:   map[string]time.Time{},
+	}
+}
+
+func (s *Store) Close() {}
+
+// nextID hands out identifiers per table, like a serial column.
+func (s *Store) nextID(table string) int64 {
+	s.seq[table]++
+	return s.seq[table]
+}
+
+// now matches the microsecond precision of the SQL backends.
+func (s *Store) now() time.Time {
+	return s.Now().Truncate(time.Microsecond)
+}
+
+// EnsureSchema creates the first round and seeds known participants.
+func (s *Store) EnsureSchema(ctx context.Context, participants []models.Participant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.rounds) == 0 {
+		now := s.now()
+		s.rounds = append(s.rounds, models.Round{ID: s.nextID("rounds"), Title: "Раунд 1", State: models.RoundOpen, CreatedAt: now, UpdatedAt: now})
+	}
+	for _, p := range participants {
+		for code, other := range s.participants {
+			if other.Email == p.Email && code != p.Code {
+				return fmt.Errorf("seed participant %s: email %s is taken by %s", p.Code, p.Email, code)
+			}
+		}
+		s.participants[p.Code] = p
+	}
+	return nil
+}
+
+func (s *Store) ParticipantByEmailAndCode(ctx context.Context, email, code string) (*models.Participant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.participants[code]
+	if !ok || p.Email != email {
+		return nil, ErrNotFound
+	}
+	return &p, nil
+}
+
+func (s *Store) ListParticipants(ctx context.Context) ([]models.Participant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sortedParticipants(func(models.Participant) bool { return true }), nil
+}
+
+func (s *Store) PendingParticipants(ctx context.Context, roundID int64) ([]models.Participant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	submitted := map[string]bool{}
+	for _, r := range s.revisions {
+		if r.roundID == roundID {
+			submitted[r.code] = true
+		}
+	}
+	return s.sortedParticipants(func(p models.Participant) bool { return !p.IsAdmin && !submitted[p.Code] }), nil
+}
+
+func (s *Store) sortedParticipants(keep func(models.Participant) bool) []models.Participant {
+	var res []models.Participant
+	for _, p := range s.participants {
+		if keep(p) {
+			res = append(res, p)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// UpsertResponse appends a new revision; AllResponses exposes the latest one.
+func (s *Store) UpsertResponse(ctx context.Context, roundID int64, participantCode string, answers []models.AnswerPayload, rankings []models.RankingPayload, isTest bool, sessionID string) error {
+	answersJSON, rankingsJSON, err := s.encode(answers, rankings, envelope.LabelAnswers)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.round(roundID) == nil {
+		return fmt.Errorf("round %d: %w", roundID, ErrNotFound)
+	}
+	if _, ok := s.participants[participantCode]; !ok {
+		return fmt.Errorf("participant %s: %w", participantCode, ErrNotFound)
+	}
+	s.revisions = append(s.revisions, revision{
+		id: s.nextID("revisions"), roundID: roundID, code: participantCode,
+		answers: answersJSON, rankings: rankingsJSON, isTest: isTest, sessionID: sessionID, at: s.now(),
+	})
+	return nil
+}
+
+func (s *Store) AllResponses(ctx context.Context, roundID int64) ([]models.ResponseRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res, err := s.current(roundID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(res, newestFirst(res))
+	return res, nil
+}
+
+// CountValues counts what seed.Normalize makes of the current non-admin
+// responses, as the SQL backends do with their answers table.
+func (s *Store) CountValues(ctx context.Context, roundID int64) ([]models.ValueCount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	responses, err := s.current(roundID)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[models.ValueCount]int{}
+	for _, r := range responses {
+		if s.participants[r.ParticipantCode].IsAdmin {
+			continue
+		}
+		rows, _ := seed.Normalize(r.Answers, nil)
+		for _, a := range rows {
+			if a.NumericValue != nil {
+				counts[models.ValueCount{QuestionID: a.QuestionID, PeerCode: a.PeerCode, Value: *a.NumericValue}]++
+			}
+		}
+	}
+	res := make([]models.ValueCount, 0, len(counts))
+	for c, n := range counts {
+		c.Count = n
+		res = append(res, c)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		a, b := res[i], res[j]
+		if a.QuestionID != b.QuestionID {
+			return a.QuestionID < b.QuestionID
+		}
+		if a.PeerCode != b.PeerCode {
+			return a.PeerCode < b.PeerCode
+		}
+		return a.Value < b.Value
+	})
+	return res, nil
+}
+
+func (s *Store) ResponseByParticipant(ctx context.Context, roundID int64, participantCode string) (*models.ResponseRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var rec *models.ResponseRecord
+	for _, r := range s.revisions {
+		if r.roundID != roundID || r.code != participantCode {
+			continue
+		}
+		if rec == nil {
+			rec = &models.ResponseRecord{ParticipantCode: r.code, SubmittedAt: r.at, RoundID: r.roundID}
+		}
+		rec.ID, rec.IsTestData, rec.UpdatedAt = r.id, r.isTest, r.at
+		if err := s.decode(r.answers, r.rankings, envelope.LabelAnswers, &rec.Answers, &rec.Rankings); err != nil {
+			return nil, err
+		}
+	}
+	return rec, nil
+}
+
+func (s *Store) ListResponses(ctx context.Context, f models.ResponseFilter) (models.ResponsePage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all, err := s.current(f.RoundID)
+	if err != nil {
+		return models.ResponsePage{}, err
+	}
+	sort.Slice(all, newestFirst(all))
+	if f.Sort == models.SortOldest {
+		slices.Reverse(all)
+	}
+
+	page := models.ResponsePage{Items: []models.ResponseRecord{}}
+	for _, rec := range all {
+		switch {
+		case f.IsTest != nil && rec.IsTestData != *f.IsTest,
+			f.From != nil && rec.SubmittedAt.Before(*f.From),
+			f.To != nil && !rec.SubmittedAt.Before(*f.To),
+			f.After != nil && !pastCursor(rec, f.After, f.Sort):
+			continue
+		}
+		if f.Limit > 0 && len(page.Items) == f.Limit {
+			page.NextCursor = models.CursorAfter(page.Items[len(page.Items)-1]).Encode()
+			break
+		}
+		page.Items = append(page.Items, rec)
+	}
+	return page, nil
+}
+
+// current returns the latest revision of every participant in a round, or
+// in every round when roundID is 0, like the responses view of the SQL
+// backends.
+func (s *Store) current(roundID int64) ([]models.ResponseRecord, error) {
+	latest := map[key]revision{}
+	first := map[key]time.Time{}
+	for _, r := range s.revisions {
+		if roundID != 0 && r.roundID != roundID {
+			continue
+		}
+		k := key{r.roundID, r.code}
+		if _, ok := first[k]; !ok {
+			first[k] = r.at
+		}
+		latest[k] = r
+	}
+	var res []models.ResponseRecord
+	for k, r := range latest {
+		rec := models.ResponseRecord{
+			ID: r.id, ParticipantCode: r.code, IsTestData: r.isTest,
+			SubmittedAt: first[k], UpdatedAt: r.at, RoundID: r.roundID,
+		}
+		if err := s.decode(r.answers, r.rankings, envelope.LabelAnswers, &rec.Answers, &rec.Rankings); err != nil {
+			return nil, err
+		}
+		res = append(res, rec)
+	}
+	return res, nil
+}
+
+func newestFirst(res []models.ResponseRecord) func(i, j int) bool {
+	return func(i, j int) bool {
+		if !res[i].SubmittedAt.Equal(res[j].SubmittedAt) {
+			return res[i].SubmittedAt.After(res[j].SubmittedAt)
+		}
+		return res[i].ID > res[j].ID
+	}
+}
+
+// pastCursor reports whether rec comes after the cursor in the given order.
+func pastCursor(rec models.ResponseRecord, c *models.ResponseCursor, order string) bool {
+	if order == models.SortOldest {
+		return rec.SubmittedAt.After(c.SubmittedAt) || rec.SubmittedAt.Equal(c.SubmittedAt) && rec.ID > c.ID
+	}
+	return rec.SubmittedAt.Before(c.SubmittedAt) || rec.SubmittedAt.Equal(c.SubmittedAt) && rec.ID < c.ID
+}
+
+func (s *Store) ListRevisions(ctx context.Context, roundID int64, participantCode string) ([]models.ResponseRevision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []models.ResponseRevision
+	for _, r := range s.revisions {
+		if r.roundID != roundID || r.code != participantCode {
+			continue
+		}
+		rev, err := s.revisionModel(r)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, *rev)
+	}
+	return res, nil
+}
+
+func (s *Store) revisionModel(r revision) (*models.ResponseRevision, error) {
+	rev := models.ResponseRevision{
+		ID: r.id, ParticipantCode: r.code, IsTestData: r.isTest,
+		SessionID: r.sessionID, SubmittedAt: r.at, RoundID: r.roundID,
+	}
+	if err := s.decode(r.answers, r.rankings, envelope.LabelAnswers, &rev.Answers, &rev.Rankings); err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// ExportRevisions returns the revisions matching f, oldest first.
+func (s *Store) ExportRevisions(ctx context.Context, f models.RevisionFilter) ([]models.ResponseRevision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []models.ResponseRevision
+	for _, r := range s.revisions {
+		rev, err := s.revisionModel(r)
+		if err != nil {
+			return nil, err
+		}
+		if f.Match(*rev) {
+			res = append(res, *rev)
+		}
+	}
+	return res, nil
+}
+
+// ResetResponses stores the snapshot and deletes the listed revisions.
+func (s *Store) ResetResponses(ctx context.Context, snapshot models.Snapshot, revisionIDs []int64) (*models.Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := s.keys.Seal(snapshot.Data, envelope.LabelSnapshot)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.ID = s.nextID("reset_snapshots")
+	snapshot.CreatedAt = s.now()
+	snapshot.Data = slices.Clone(data)
+	snapshot.Size = len(snapshot.Data)
+	s.snapshots = append(s.snapshots, snapshot)
+	s.revisions = slices.DeleteFunc(s.revisions, func(r revision) bool { return slices.Contains(revisionIDs, r.id) })
+	snapshot.Data = nil
+	return &snapshot, nil
+}
+
+// encode marshals a payload, sealing the answers and the rankings, whose
+// comments are free text too, when a keyring is set.
+func (s *Store) encode(answers []models.AnswerPayload, rankings []models.RankingPayload, label string) ([]byte, []byte, error) {
+	answersJSON, err := json.Marshal(answers)
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshal answers: %w", err)
+	}
+	rankingsJSON, err := json.Marshal(rankings)
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshal rankings: %w", err)
+	}
+	if answersJSON, err = s.keys.Seal(answersJSON, label); err != nil {
+		return nil, nil, err
+	}
+	if rankingsJSON, err = s.keys.Seal(rankingsJSON, envelope.RankingsLabel(label)); err != nil {
+		return nil, nil, err
+	}
+	return answersJSON, rankingsJSON, nil
+}
+
+func (s *Store) decode(answersJSON, rankingsJSON []byte, label string, answers *[]models.AnswerPayload, rankings *[]models.RankingPayload) error {
+	answersJSON, err := s.keys.Open(answersJSON, label)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(answersJSON, answers); err != nil {
+		return fmt.Errorf("unmarshal answers: %w", err)
+	}
+	if rankingsJSON, err = s.keys.Open(rankingsJSON, envelope.RankingsLabel(label)); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(rankingsJSON, rankings); err != nil {
+		return fmt.Errorf("unmarshal rankings: %w", err)
+	}
+	return nil
+}
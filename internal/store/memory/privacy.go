@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"opslab-survey/internal/envelope"
+	"opslab-survey/internal/models"
+)
+
+// EraseParticipant works on copies of the tables and swaps them in only when
+// every rewrite succeeded.
+func (s *Store) EraseParticipant(ctx context.Context, e models.Erasure) (*models.ErasureResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	code := e.ParticipantCode
+	res := &models.ErasureResult{}
+
+	revisions := slices.DeleteFunc(slices.Clone(s.revisions), func(r revision) bool { return r.code == code })
+	res.Revisions = len(s.revisions) - len(revisions)
+	for _, rev := range e.Revisions {
+		i := slices.IndexFunc(revisions, func(r revision) bool { return r.id == rev.ID })
+		if i < 0 {
+			continue
+		}
+		answersJSON, rankingsJSON, err := s.encode(rev.Answers, rev.Rankings, envelope.LabelAnswers)
+		if err != nil {
+			return nil, err
+		}
+		revisions[i].answers, revisions[i].rankings = answersJSON, rankingsJSON
+		res.Pseudonymized++
+	}
+	snapshots := slices.Clone(s.snapshots)
+	for _, snap := range e.Snapshots {
+		i := slices.IndexFunc(snapshots, func(other models.Snapshot) bool { return other.ID == snap.ID })
+		if i < 0 {
+			continue
+		}
+		data, err := s.keys.Seal(snap.Data, envelope.LabelSnapshot)
+		if err != nil {
+			return nil, err
+		}
+		snapshots[i].Data, snapshots[i].Size, snapshots[i].Revisions = slices.Clone(data), len(data), snap.Revisions
+		res.Snapshots++
+	}
+
+	drafts := maps.Clone(s.drafts)
+	maps.DeleteFunc(drafts, func(k key, _ draft) bool { return k.code == code })
+	res.Drafts = len(s.drafts) - len(drafts)
+	extensions := maps.Clone(s.extensions)
+	maps.DeleteFunc(extensions, func(k key, _ models.DeadlineExtension) bool { return k.code == code })
+	res.Extensions = len(s.extensions) - len(extensions)
+	reminders := slices.DeleteFunc(slices.Clone(s.reminders), func(r models.Reminder) bool { return r.ParticipantCode == code })
+	res.Reminders = len(s.reminders) - len(reminders)
+
+	s.revisions, s.snapshots = revisions, snapshots
+	s.drafts, s.extensions, s.reminders = drafts, extensions, reminders
+	return res, nil
+}
+
+func (s *Store) AnonymizeRound(ctx context.Context, roundID int64, responses []models.AnonymousResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.round(roundID) == nil {
+		return ErrNotFound
+	}
+	s.dropRound(roundID)
+	for _, a := range responses {
+		a.RoundID = roundID
+		s.anonymous = append(s.anonymous, a)
+	}
+	return nil
+}
+
+func (s *Store) ListAnonymousResponses(ctx context.Context, roundID int64) ([]models.AnonymousResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []models.AnonymousResponse
+	for _, a := range s.anonymous {
+		if a.RoundID == roundID {
+			res = append(res, a)
+		}
+	}
+	return res, nil
+}
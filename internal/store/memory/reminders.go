@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"opslab-survey/internal/models"
+)
+
+func (s *Store) RecordReminder(ctx context.Context, r models.Reminder) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r.ID = s.nextID("reminders")
+	r.SentAt = s.now()
+	s.reminders = append(s.reminders, r)
+	return nil
+}
+
+func (s *Store) ReminderHistory(ctx context.Context, roundID int64, participantCode, kind string) (models.ReminderHistory, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var h models.ReminderHistory
+	for _, r := range s.reminders {
+		if r.RoundID != roundID || r.ParticipantCode != participantCode || r.Kind != kind {
+			continue
+		}
+		switch r.Status {
+		case "sent":
+			h.Sent = true
+		case "failed":
+			h.Failures++
+			if r.SentAt.After(h.LastFailure) {
+				h.LastFailure = r.SentAt
+			}
+		}
+	}
+	return h, nil
+}
+
+func (s *Store) ListReminders(ctx context.Context, roundID int64) ([]models.Reminder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []models.Reminder
+	for _, r := range s.reminders {
+		if r.RoundID == roundID {
+			res = append(res, r)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].SentAt.After(res[j].SentAt) })
+	return res, nil
+}
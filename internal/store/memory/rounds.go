@@ -0,0 +1,141 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"time"
+
+	"opslab-survey/internal/models"
+)
+
+func (s *Store) round(id int64) *models.Round {
+	for i := range s.rounds {
+		if s.rounds[i].ID == id {
+			return &s.rounds[i]
+		}
+	}
+	return nil
+}
+
+// CurrentRound returns the round picked by models.CurrentRound, or nil when
+// every round is archived.
+func (s *Store) CurrentRound(ctx context.Context) (*models.Round, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return models.CurrentRound(slices.Clone(s.rounds), s.now()), nil
+}
+
+func (s *Store) RoundByID(ctx context.Context, id int64) (*models.Round, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.round(id)
+	if r == nil {
+		return nil, ErrNotFound
+	}
+	out := *r
+	return &out, nil
+}
+
+func (s *Store) ListRounds(ctx context.Context) ([]models.Round, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]models.Round, 0, len(s.rounds))
+	for i := len(s.rounds) - 1; i >= 0; i-- {
+		res = append(res, s.rounds[i])
+	}
+	return res, nil
+}
+
+func (s *Store) CreateRound(ctx context.Context, round models.Round) (*models.Round, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !models.ValidRoundState(round.State) {
+		return nil, fmt.Errorf("invalid round state %q", round.State)
+	}
+	now := s.now()
+	round.ID = s.nextID("rounds")
+	round.CreatedAt, round.UpdatedAt = now, now
+	s.rounds = append(s.rounds, round)
+	return &round, nil
+}
+
+func (s *Store) UpdateRound(ctx context.Context, round models.Round) (*models.Round, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !models.ValidRoundState(round.State) {
+		return nil, fmt.Errorf("invalid round state %q", round.State)
+	}
+	r := s.round(round.ID)
+	if r == nil {
+		return nil, ErrNotFound
+	}
+	r.Title, r.State, r.OpensAt, r.ClosesAt = round.Title, round.State, round.OpensAt, round.ClosesAt
+	r.UpdatedAt = s.now()
+	out := *r
+	return &out, nil
+}
+
+func (s *Store) ExtendDeadline(ctx context.Context, ext models.DeadlineExtension) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.round(ext.RoundID) == nil {
+		return fmt.Errorf("round %d: %w", ext.RoundID, ErrNotFound)
+	}
+	if _, ok := s.participants[ext.ParticipantCode]; !ok {
+		return fmt.Errorf("participant %s: %w", ext.ParticipantCode, ErrNotFound)
+	}
+	ext.ClosesAt = ext.ClosesAt.Truncate(time.Microsecond)
+	ext.CreatedAt = s.now()
+	s.extensions[key{ext.RoundID, ext.ParticipantCode}] = ext
+	return nil
+}
+
+func (s *Store) DeadlineExtension(ctx context.Context, roundID int64, participantCode string) (*time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ext, ok := s.extensions[key{roundID, participantCode}]
+	if !ok {
+		return nil, nil
+	}
+	return &ext.ClosesAt, nil
+}
+
+func (s *Store) ListDeadlineExtensions(ctx context.Context, roundID int64) ([]models.DeadlineExtension, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []models.DeadlineExtension
+	for k, ext := range s.extensions {
+		if k.round == roundID {
+			res = append(res, ext)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ParticipantCode < res[j].ParticipantCode })
+	return res, nil
+}
+
+// DeleteRound removes a round with its revisions, drafts, extensions,
+// reminders and anonymous responses.
+func (s *Store) DeleteRound(ctx context.Context, id int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.rounds)
+	s.rounds = slices.DeleteFunc(s.rounds, func(r models.Round) bool { return r.ID == id })
+	if len(s.rounds) == n {
+		return false, nil
+	}
+	s.dropRound(id)
+	s.anonymous = slices.DeleteFunc(s.anonymous, func(a models.AnonymousResponse) bool { return a.RoundID == id })
+	return true, nil
+}
+
+// dropRound deletes the participant data of a round: revisions, drafts,
+// extensions and reminders.
+func (s *Store) dropRound(id int64) {
+	s.revisions = slices.DeleteFunc(s.revisions, func(r revision) bool { return r.roundID == id })
+	maps.DeleteFunc(s.drafts, func(k key, _ draft) bool { return k.round == id })
+	maps.DeleteFunc(s.extensions, func(k key, _ models.DeadlineExtension) bool { return k.round == id })
+	s.reminders = slices.DeleteFunc(s.reminders, func(r models.Reminder) bool { return r.RoundID == id })
+}
@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"opslab-survey/internal/envelope"
+	"opslab-survey/internal/models"
+)
+
+// ListSnapshots returns every snapshot without its data, newest first.
+func (s *Store) ListSnapshots(ctx context.Context) ([]models.Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []models.Snapshot
+	for i := len(s.snapshots) - 1; i >= 0; i-- {
+		snap := s.snapshots[i]
+		snap.Data = nil
+		res = append(res, snap)
+	}
+	return res, nil
+}
+
+func (s *Store) SnapshotByID(ctx context.Context, id int64) (*models.Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, snap := range s.snapshots {
+		if snap.ID == id {
+			data, err := s.keys.Open(snap.Data, envelope.LabelSnapshot)
+			if err != nil {
+				return nil, err
+			}
+			snap.Data = slices.Clone(data)
+			return &snap, nil
+		}
+	}
+	return nil, nil
+}
+
+// RestoreSnapshot inserts the revisions that are not stored any more,
+// keeping their IDs, and marks the snapshot restored.
+func (s *Store) RestoreSnapshot(ctx context.Context, id int64, revisions []models.ResponseRevision) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.snapshots, func(snap models.Snapshot) bool { return snap.ID == id })
+	if i < 0 {
+		return 0, ErrNotFound
+	}
+	var restored []revision
+	for _, rev := range revisions {
+		if slices.ContainsFunc(s.revisions, func(r revision) bool { return r.id == rev.ID }) {
+			if !slices.ContainsFunc(s.revisions, func(r revision) bool { return r.same(rev) }) {
+				return 0, fmt.Errorf("revision %d: %w", rev.ID, models.ErrRestoreConflict)
+			}
+			continue
+		}
+		if s.round(rev.RoundID) == nil {
+			return 0, fmt.Errorf("round %d: %w", rev.RoundID, ErrNotFound)
+		}
+		if _, ok := s.participants[rev.ParticipantCode]; !ok {
+			return 0, fmt.Errorf("participant %s: %w", rev.ParticipantCode, ErrNotFound)
+		}
+		answersJSON, rankingsJSON, err := s.encode(rev.Answers, rev.Rankings, envelope.LabelAnswers)
+		if err != nil {
+			return 0, err
+		}
+		restored = append(restored, revision{
+			id: rev.ID, roundID: rev.RoundID, code: rev.ParticipantCode,
+			answers: answersJSON, rankings: rankingsJSON, isTest: rev.IsTestData,
+			sessionID: rev.SessionID, at: rev.SubmittedAt,
+		})
+	}
+	s.revisions = append(s.revisions, restored...)
+	sort.Slice(s.revisions, func(i, j int) bool { return s.revisions[i].id < s.revisions[j].id })
+	now := s.now()
+	s.snapshots[i].RestoredAt = &now
+	return len(restored), nil
+}
+
+// DeleteSnapshots removes snapshots created before the given time.
+func (s *Store) DeleteSnapshots(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.snapshots)
+	s.snapshots = slices.DeleteFunc(s.snapshots, func(snap models.Snapshot) bool { return snap.CreatedAt.Before(before) })
+	return n - len(s.snapshots), nil
+}
@@ -0,0 +1,147 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"opslab-survey/internal/models"
+)
+
+func (s *Store) CreateWebhookEndpoint(ctx context.Context, e models.WebhookEndpoint) (*models.WebhookEndpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.Events = slices.Clone(e.Events)
+	if e.Events == nil {
+		e.Events = []string{}
+	}
+	e.ID = s.nextID("webhook_endpoints")
+	e.CreatedAt = s.now()
+	s.endpoints = append(s.endpoints, e)
+	return &e, nil
+}
+
+func (s *Store) ListWebhookEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []models.WebhookEndpoint
+	for _, e := range s.endpoints {
+		e.Events = slices.Clone(e.Events)
+		res = append(res, e)
+	}
+	return res, nil
+}
+
+// DeleteWebhookEndpoint removes an endpoint together with its outbox and delivery log.
+func (s *Store) DeleteWebhookEndpoint(ctx context.Context, id int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.endpoints)
+	s.endpoints = slices.DeleteFunc(s.endpoints, func(e models.WebhookEndpoint) bool { return e.ID == id })
+	if len(s.endpoints) == n {
+		return false, nil
+	}
+	s.outbox = slices.DeleteFunc(s.outbox, func(o *outboxEntry) bool { return o.endpointID == id })
+	s.deliveries = slices.DeleteFunc(s.deliveries, func(d models.WebhookDelivery) bool { return d.EndpointID == id })
+	return true, nil
+}
+
+// EnqueueWebhook adds an outbox entry for every active endpoint subscribed to
+// the event. An endpoint with no event filter receives everything.
+func (s *Store) EnqueueWebhook(ctx context.Context, event string, payload []byte) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enqueueWebhook(event, payload), nil
+}
+
+// EnqueueWebhookOnce enqueues the event unless key was recorded before.
+func (s *Store) EnqueueWebhookOnce(ctx context.Context, key, event string, payload []byte) (int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.eventKeys[key] {
+		return 0, false, nil
+	}
+	s.eventKeys[key] = true
+	return s.enqueueWebhook(event, payload), true, nil
+}
+
+func (s *Store) enqueueWebhook(event string, payload []byte) int64 {
+	var n int64
+	for _, e := range s.endpoints {
+		if !e.Active || !(len(e.Events) == 0 || slices.Contains(e.Events, event) || slices.Contains(e.Events, "*")) {
+			continue
+		}
+		s.outbox = append(s.outbox, &outboxEntry{
+			id: s.nextID("webhook_outbox"), endpointID: e.ID, event: event, payload: slices.Clone(payload),
+			status: "pending", nextAttempt: s.now(),
+		})
+		n++
+	}
+	return n
+}
+
+// ClaimWebhookJobs leases up to limit due outbox entries by pushing their
+// next attempt past the lease.
+func (s *Store) ClaimWebhookJobs(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	var res []models.WebhookJob
+	for _, o := range s.outbox {
+		if len(res) == limit {
+			break
+		}
+		if o.status != "pending" || o.nextAttempt.After(now) {
+			continue
+		}
+		i := slices.IndexFunc(s.endpoints, func(e models.WebhookEndpoint) bool { return e.ID == o.endpointID })
+		if i < 0 {
+			continue
+		}
+		o.nextAttempt = now.Add(lease.Truncate(time.Second))
+		res = append(res, models.WebhookJob{
+			ID: o.id, EndpointID: o.endpointID, URL: s.endpoints[i].URL, Secret: s.endpoints[i].Secret,
+			Event: o.event, Payload: slices.Clone(o.payload), Attempts: o.attempts,
+		})
+	}
+	return res, nil
+}
+
+// RecordWebhookAttempt logs a delivery attempt and moves the outbox entry to
+// its next state: delivered, failed, or pending again at retryAt.
+func (s *Store) RecordWebhookAttempt(ctx context.Context, d models.WebhookDelivery, retryAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.outbox, func(o *outboxEntry) bool { return o.id == d.OutboxID })
+	if i < 0 {
+		return ErrNotFound
+	}
+	d.ID = s.nextID("webhook_deliveries")
+	d.CreatedAt = s.now()
+	s.deliveries = append(s.deliveries, d)
+
+	o := s.outbox[i]
+	o.attempts = d.Attempt
+	switch d.Outcome {
+	case "delivered":
+		o.status, o.lastError, o.deliveredAt = "delivered", "", &d.CreatedAt
+	case "failed":
+		o.status, o.lastError = "failed", d.Error
+	default:
+		o.lastError, o.nextAttempt = d.Error, retryAt
+	}
+	return nil
+}
+
+// ListWebhookDeliveries returns the newest delivery attempts, optionally for one endpoint.
+func (s *Store) ListWebhookDeliveries(ctx context.Context, endpointID int64, limit int) ([]models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []models.WebhookDelivery
+	for i := len(s.deliveries) - 1; i >= 0 && len(res) < limit; i-- {
+		if endpointID == 0 || s.deliveries[i].EndpointID == endpointID {
+			res = append(res, s.deliveries[i])
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"opslab-survey/internal/audit"
+	"opslab-survey/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const auditColumns = `id, occurred_at, actor, action, target, ip, user_agent, outcome, status, prev_hash, hash`
+
+// AppendAudit stamps e with the current time, chains it to the last entry
+// and stores it. An advisory lock serialises appends across instances so
+// the chain never forks.
+func (s *Store) AppendAudit(ctx context.Context, e models.AuditEntry) (*models.AuditEntry, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('audit_log'))`); err != nil {
+		return nil, err
+	}
+	err = tx.QueryRow(ctx, `SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1`).Scan(&e.PrevHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		e.PrevHash = ""
+	} else if err != nil {
+		return nil, err
+	}
+	e.OccurredAt = time.Now().Truncate(time.Microsecond)
+	e.Hash = audit.Hash(e.PrevHash, e)
+	err = tx.QueryRow(ctx, `
+INSERT INTO audit_log (occurred_at, actor, action, target, ip, user_agent, outcome, status, prev_hash, hash)
+VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+RETURNING id`, e.OccurredAt, e.Actor, e.Action, e.Target, e.IP, e.UserAgent, e.Outcome, e.Status, e.PrevHash, e.Hash).Scan(&e.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &e, tx.Commit(ctx)
+}
+
+func (s *Store) ListAudit(ctx context.Context, f models.AuditFilter) ([]models.AuditEntry, error) {
+	var where []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	for _, c := range []struct{ column, value string }{
+		{"actor", f.Actor}, {"target", f.Target}, {"outcome", f.Outcome},
+	} {
+		if c.value != "" {
+			where = append(where, c.column+" = "+arg(c.value))
+		}
+	}
+	if f.Action != "" {
+		if strings.HasSuffix(f.Action, ".") {
+			where = append(where, "starts_with(action, "+arg(f.Action)+")")
+		} else {
+			where = append(where, "action = "+arg(f.Action))
+		}
+	}
+	if f.From != nil {
+		where = append(where, "occurred_at >= "+arg(*f.From))
+	}
+	if f.To != nil {
+		where = append(where, "occurred_at < "+arg(*f.To))
+	}
+	if f.BeforeID != 0 {
+		where = append(where, "id < "+arg(f.BeforeID))
+	}
+	query := `SELECT ` + auditColumns + ` FROM audit_log`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY id DESC`
+	if f.Limit > 0 {
+		query += ` LIMIT ` + arg(f.Limit)
+	}
+	return s.queryAudit(ctx, query, args...)
+}
+
+// AuditTrail returns up to limit entries with IDs above afterID, oldest first.
+func (s *Store) AuditTrail(ctx context.Context, afterID int64, limit int) ([]models.AuditEntry, error) {
+	return s.queryAudit(ctx, `SELECT `+auditColumns+` FROM audit_log WHERE id > $1 ORDER BY id LIMIT $2`, afterID, limit)
+}
+
+func (s *Store) queryAudit(ctx context.Context, query string, args ...any) ([]models.AuditEntry, error) {
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(&e.ID, &e.OccurredAt, &e.Actor, &e.Action, &e.Target, &e.IP, &e.UserAgent, &e.Outcome, &e.Status, &e.PrevHash, &e.Hash); err != nil {
+			return nil, err
+		}
+		res = append(res, e)
+	}
+	return res, rows.Err()
+}
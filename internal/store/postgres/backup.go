@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"opslab-survey/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RestoreDataset writes d in one transaction, keeping the IDs from the
+// backup. Rows skipped in merge mode must be the ones already stored.
+// Deleting the rounds in replace mode cascades to everything that belongs
+// to them. Anonymous responses are written for the rounds inserted here;
+// consents are added to those stored in either mode.
+func (s *Store) RestoreDataset(ctx context.Context, d models.Dataset, mode string) (*models.RestoreResult, error) {
+	participantConflict := `DO NOTHING`
+	switch mode {
+	case models.RestoreMerge:
+	case models.RestoreReplace:
+		participantConflict = `DO UPDATE SET name=EXCLUDED.name, email=EXCLUDED.email, is_admin=EXCLUDED.is_admin`
+	default:
+		return nil, fmt.Errorf("unknown restore mode %q", mode)
+	}
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if mode == models.RestoreReplace {
+		if _, err := tx.Exec(ctx, `DELETE FROM rounds`); err != nil {
+			return nil, fmt.Errorf("clear rounds: %w", err)
+		}
+	}
+	res := &models.RestoreResult{Mode: mode}
+	inserted := map[int64]bool{}
+	for _, p := range d.Participants {
+		tag, err := tx.Exec(ctx, `
+INSERT INTO participants (code, name, email, is_admin)
+VALUES ($1,$2,$3,$4)
+ON CONFLICT (code) `+participantConflict, p.Code, p.Name, p.Email, p.IsAdmin)
+		if err != nil {
+			return nil, fmt.Errorf("participant %s: %w", p.Code, err)
+		}
+		res.Participants += int(tag.RowsAffected())
+	}
+	for _, r := range d.Rounds {
+		tag, err := tx.Exec(ctx, `
+INSERT INTO rounds (`+roundColumns+`)
+VALUES ($1,$2,$3,$4,$5,$6,$7)
+ON CONFLICT (id) DO NOTHING`, r.ID, r.Title, r.State, r.OpensAt, r.ClosesAt, r.CreatedAt, r.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("round %d: %w", r.ID, err)
+		}
+		if tag.RowsAffected() == 0 {
+			if err := sameRow(ctx, tx, `SELECT 1 FROM rounds WHERE id=$1 AND created_at=$2`, r.ID, r.CreatedAt); err != nil {
+				return nil, fmt.Errorf("round %d: %w", r.ID, err)
+			}
+			res.SkippedRounds++
+			continue
+		}
+		inserted[r.ID] = true
+		res.Rounds++
+	}
+	_, err = tx.Exec(ctx, `
+SELECT setval(pg_get_serial_sequence('rounds', 'id'), max(id))
+FROM rounds
+HAVING max(id) > (SELECT last_value FROM rounds_id_seq)`)
+	if err != nil {
+		return nil, err
+	}
+	if res.Revisions, err = s.insertRevisions(ctx, tx, d.Revisions); err != nil {
+		return nil, err
+	}
+	res.SkippedRevisions = len(d.Revisions) - res.Revisions
+	for _, a := range d.Anonymous {
+		if !inserted[a.RoundID] {
+			continue
+		}
+		if err := insertAnonymous(ctx, tx, a.RoundID, a); err != nil {
+			return nil, fmt.Errorf("anonymous response of round %d: %w", a.RoundID, err)
+		}
+		res.Anonymous++
+	}
+	for _, c := range d.Consents {
+		tag, err := tx.Exec(ctx, `
+INSERT INTO consents (participant_code, version, accepted_at) VALUES ($1,$2,$3)
+ON CONFLICT (participant_code, version) DO NOTHING`, c.ParticipantCode, c.Version, c.AcceptedAt)
+		if err != nil {
+			return nil, fmt.Errorf("consent of %s: %w", c.ParticipantCode, err)
+		}
+		res.Consents += int(tag.RowsAffected())
+	}
+	return res, tx.Commit(ctx)
+}
+
+// sameRow checks that the row a skipped insert collided with is the one
+// being inserted: query selects it by ID and identifying columns.
+func sameRow(ctx context.Context, tx pgx.Tx, query string, args ...any) error {
+	var one int
+	err := tx.QueryRow(ctx, query, args...).Scan(&one)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrRestoreConflict
+	}
+	return err
+}
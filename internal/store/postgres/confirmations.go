@@ -0,0 +1,24 @@
+package postgres
+
+import (
+	"context"
+	"time"
+)
+
+func (s *Store) UseConfirmation(ctx context.Context, jti string, expiresAt time.Time) (bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `DELETE FROM used_confirmations WHERE expires_at < now()`); err != nil {
+		return false, err
+	}
+	tag, err := tx.Exec(ctx, `
+INSERT INTO used_confirmations (jti, expires_at) VALUES ($1,$2)
+ON CONFLICT (jti) DO NOTHING`, jti, expiresAt)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, tx.Commit(ctx)
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"opslab-survey/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RecordConsent stores an acceptance; accepting the same version again keeps
+// the first one.
+func (s *Store) RecordConsent(ctx context.Context, participantCode string, version int) (*models.Consent, error) {
+	_, err := s.pool.Exec(ctx, `
+INSERT INTO consents (participant_code, version) VALUES ($1,$2)
+ON CONFLICT (participant_code, version) DO NOTHING`, participantCode, version)
+	if err != nil {
+		return nil, err
+	}
+	return s.ConsentFor(ctx, participantCode, version)
+}
+
+func (s *Store) ConsentFor(ctx context.Context, participantCode string, version int) (*models.Consent, error) {
+	c := models.Consent{ParticipantCode: participantCode, Version: version}
+	err := s.pool.QueryRow(ctx, `
+SELECT accepted_at FROM consents WHERE participant_code=$1 AND version=$2`, participantCode, version).Scan(&c.AcceptedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (s *Store) ListConsents(ctx context.Context) ([]models.Consent, error) {
+	return s.queryConsents(ctx, `
+SELECT participant_code, version, accepted_at FROM consents ORDER BY participant_code, version`)
+}
+
+func (s *Store) ConsentsOf(ctx context.Context, participantCode string) ([]models.Consent, error) {
+	return s.queryConsents(ctx, `
+SELECT participant_code, version, accepted_at FROM consents WHERE participant_code=$1 ORDER BY version`, participantCode)
+}
+
+func (s *Store) queryConsents(ctx context.Context, query string, args ...any) ([]models.Consent, error) {
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.Consent
+	for rows.Next() {
+		var c models.Consent
+		if err := rows.Scan(&c.ParticipantCode, &c.Version, &c.AcceptedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+// NoticeVersion holds an advisory lock so that instances starting together
+// with the same new text agree on its version.
+func (s *Store) NoticeVersion(ctx context.Context, digest string) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('consent_notices'))`); err != nil {
+		return 0, err
+	}
+	var (
+		version int
+		latest  string
+	)
+	err = tx.QueryRow(ctx, `SELECT version, digest FROM consent_notices ORDER BY version DESC LIMIT 1`).Scan(&version, &latest)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, err
+	}
+	if version > 0 && latest == digest {
+		return version, nil
+	}
+	version++
+	if _, err := tx.Exec(ctx, `INSERT INTO consent_notices (version, digest) VALUES ($1,$2)`, version, digest); err != nil {
+		return 0, err
+	}
+	return version, tx.Commit(ctx)
+}
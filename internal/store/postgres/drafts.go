@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"opslab-survey/internal/envelope"
+	"opslab-survey/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SaveDraft stores the participant's in-progress answers, replacing the previous draft.
+func (s *Store) SaveDraft(ctx context.Context, d models.Draft) error {
+	answersJSON, rankingsJSON, err := s.encode(d.Answers, d.Rankings, envelope.LabelDraft)
+	if err != nil {
+		return err
+	}
+	_, err = s.pool.Exec(ctx, `
+INSERT INTO drafts (round_id, participant_code, answers, rankings, progress, updated_at)
+VALUES ($1,$2,$3,$4,$5, now())
+ON CONFLICT (round_id, participant_code)
+DO UPDATE SET answers=EXCLUDED.answers, rankings=EXCLUDED.rankings, progress=EXCLUDED.progress, updated_at=now();`,
+		d.RoundID, d.ParticipantCode, answersJSON, rankingsJSON, d.Progress)
+	return err
+}
+
+// DraftFor returns the participant's draft, or nil when there is none.
+func (s *Store) DraftFor(ctx context.Context, roundID int64, participantCode string) (*models.Draft, error) {
+	var d models.Draft
+	var answersJSON, rankingsJSON []byte
+	err := s.pool.QueryRow(ctx, `
+SELECT round_id, participant_code, answers, rankings, progress, updated_at
+FROM drafts WHERE round_id=$1 AND participant_code=$2`, roundID, participantCode).
+		Scan(&d.RoundID, &d.ParticipantCode, &answersJSON, &rankingsJSON, &d.Progress, &d.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.decode(answersJSON, rankingsJSON, envelope.LabelDraft, &d.Answers, &d.Rankings); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// DraftProgress returns the progress of every draft in a round, keyed by
+// participant code. Payloads are not loaded.
+func (s *Store) DraftProgress(ctx context.Context, roundID int64) (map[string]models.Draft, error) {
+	rows, err := s.pool.Query(ctx, `SELECT participant_code, progress, updated_at FROM drafts WHERE round_id=$1`, roundID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := map[string]models.Draft{}
+	for rows.Next() {
+		d := models.Draft{RoundID: roundID}
+		if err := rows.Scan(&d.ParticipantCode, &d.Progress, &d.UpdatedAt); err != nil {
+			return nil, err
+		}
+		res[d.ParticipantCode] = d
+	}
+	return res, rows.Err()
+}
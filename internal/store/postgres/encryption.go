@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"opslab-survey/internal/envelope"
+	"opslab-survey/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SetKeyring makes the store seal answer payloads with k.
+func (s *Store) SetKeyring(k *envelope.Keyring) {
+	s.keys = k
+}
+
+// RotateKeys re-seals every stale value in one transaction and drops the
+// plaintext copies of text answers from the answers table. Rows are locked
+// while they are rewritten, so concurrent saves wait instead of being lost.
+func (s *Store) RotateKeys(ctx context.Context) (*models.RotationResult, error) {
+	if s.keys == nil {
+		return nil, errors.New("rotate keys: no encryption key configured")
+	}
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	res := &models.RotationResult{KeyID: s.keys.KeyID()}
+	for _, c := range []struct {
+		table, key, column, label string
+		count                     *int
+	}{
+		{"response_revisions", "id::text", "answers", envelope.LabelAnswers, &res.Revisions},
+		{"response_revisions", "id::text", "rankings", envelope.LabelRankings, &res.Rankings},
+		{"drafts", "round_id || '/' || participant_code", "answers", envelope.LabelDraft, &res.Drafts},
+		{"drafts", "round_id || '/' || participant_code", "rankings", envelope.LabelDraftRankings, &res.Rankings},
+		{"reset_snapshots", "id::text", "data", envelope.LabelSnapshot, &res.Snapshots},
+	} {
+		n, err := s.reseal(ctx, tx, c.table, c.key, c.column, c.label)
+		if err != nil {
+			return nil, err
+		}
+		*c.count += n
+	}
+	if _, err := tx.Exec(ctx, `UPDATE answers SET text_value=NULL WHERE text_value IS NOT NULL`); err != nil {
+		return nil, err
+	}
+	return res, tx.Commit(ctx)
+}
+
+// reseal seals every value of column that is not sealed with the current
+// master key yet. key is an expression identifying a row of table.
+func (s *Store) reseal(ctx context.Context, tx pgx.Tx, table, key, column, label string) (int, error) {
+	rows, err := tx.Query(ctx, `SELECT `+key+`, `+column+` FROM `+table+` FOR UPDATE`)
+	if err != nil {
+		return 0, err
+	}
+	type stale struct {
+		key   string
+		value []byte
+	}
+	var todo []stale
+	for rows.Next() {
+		var v stale
+		if err := rows.Scan(&v.key, &v.value); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if !s.keys.Current(v.value) {
+			todo = append(todo, v)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for _, v := range todo {
+		plain, err := s.keys.Open(v.value, label)
+		if err != nil {
+			return 0, fmt.Errorf("%s %s: %w", table, v.key, err)
+		}
+		sealed, err := s.keys.Seal(plain, label)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(ctx, `UPDATE `+table+` SET `+column+`=$1 WHERE `+key+`=$2`, sealed, v.key); err != nil {
+			return 0, fmt.Errorf("%s %s: %w", table, v.key, err)
+		}
+	}
+	return len(todo), nil
+}
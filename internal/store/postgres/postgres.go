@@ -0,0 +1,699 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"opslab-survey/internal/envelope"
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/seed"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Store keeps survey data in Postgres.
+type Store struct {
+	pool *pgxpool.Pool
+	keys *envelope.Keyring
+}
+
+func New(ctx context.Context, url string) (*Store, error) {
+	if url == "" {
+		return nil, errors.New("DATABASE_URL is required")
+	}
+	cfg, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		return nil, fmt.Errorf("parse DATABASE_URL: %w", err)
+	}
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("connect db: %w", err)
+	}
+	return &Store{pool: pool}, nil
+}
+
+func (s *Store) Close() {
+	s.pool.Close()
+}
+
+// EnsureSchema sets up tables and seeds known participants.
+func (s *Store) EnsureSchema(ctx context.Context, participants []models.Participant) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+CREATE TABLE IF NOT EXISTS participants (
+	code text primary key,
+	name text not null,
+	email text not null unique,
+	is_admin boolean default false
+);
+
+CREATE TABLE IF NOT EXISTS rounds (
+	id bigserial primary key,
+	title text not null,
+	state text not null default 'draft' check (state in ('draft','open','closed','archived')),
+	opens_at timestamptz,
+	closes_at timestamptz,
+	created_at timestamptz not null default now(),
+	updated_at timestamptz not null default now()
+);
+
+INSERT INTO rounds (title, state) SELECT 'Раунд 1', 'open' WHERE NOT EXISTS (SELECT 1 FROM rounds);
+
+CREATE TABLE IF NOT EXISTS deadline_extensions (
+	round_id bigint not null references rounds(id) on delete cascade,
+	participant_code text not null references participants(code) on delete cascade,
+	closes_at timestamptz not null,
+	reason text not null default '',
+	created_at timestamptz not null default now(),
+	primary key (round_id, participant_code)
+);
+
+CREATE TABLE IF NOT EXISTS response_revisions (
+	id bigserial primary key,
+	round_id bigint not null references rounds(id) on delete cascade,
+	participant_code text not null references participants(code) on delete cascade,
+	answers jsonb not null,
+	rankings jsonb not null,
+	is_test_data boolean not null default false,
+	session_id text not null default '',
+	submitted_at timestamptz not null default now()
+);
+
+-- Revisions recorded before rounds existed belong to the first round.
+ALTER TABLE response_revisions ADD COLUMN IF NOT EXISTS round_id bigint references rounds(id) on delete cascade;
+UPDATE response_revisions SET round_id = (SELECT min(id) FROM rounds) WHERE round_id IS NULL;
+ALTER TABLE response_revisions ALTER COLUMN round_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS response_revisions_participant_idx ON response_revisions(participant_code, id);
+CREATE INDEX IF NOT EXISTS response_revisions_round_idx ON response_revisions(round_id, participant_code, id);
+
+-- Older deployments kept one mutable row per participant in a responses
+-- table. Carry those rows over as first revisions at their original
+-- submission time, plus a second revision at updated_at for rows edited
+-- since, and keep the original table around as responses_legacy.
+DO $$
+BEGIN
+	IF EXISTS (
+		SELECT 1 FROM information_schema.tables
+		WHERE table_schema = current_schema() AND table_name = 'responses' AND table_type = 'BASE TABLE'
+	) THEN
+		INSERT INTO response_revisions (round_id, participant_code, answers, rankings, is_test_data, session_id, submitted_at)
+		SELECT (SELECT min(id) FROM rounds), participant_code, answers, rankings, coalesce(is_test_data, false), 'legacy', submitted_at FROM responses;
+		INSERT INTO response_revisions (round_id, participant_code, answers, rankings, is_test_data, session_id, submitted_at)
+		SELECT (SELECT min(id) FROM rounds), participant_code, answers, rankings, coalesce(is_test_data, false), 'legacy', updated_at FROM responses
+		WHERE updated_at > submitted_at;
+		ALTER TABLE responses RENAME TO responses_legacy;
+	END IF;
+END $$;
+
+-- Answers and ranking positions in queryable form, one row per answered
+-- question or ranked colleague. The JSON columns of response_revisions stay
+-- the source of truth; these rows are written alongside them.
+CREATE TABLE IF NOT EXISTS answers (
+	response_id bigint not null references response_revisions(id) on delete cascade,
+	question_id text not null,
+	peer_code text not null default '',
+	numeric_value double precision,
+	text_value text,
+	choice_value text,
+	primary key (response_id, question_id, peer_code)
+);
+
+CREATE INDEX IF NOT EXISTS answers_question_idx ON answers(question_id, peer_code);
+
+CREATE TABLE IF NOT EXISTS ranking_positions (
+	response_id bigint not null references response_revisions(id) on delete cascade,
+	criterion text not null,
+	ratee text not null,
+	position int not null,
+	primary key (response_id, criterion, ratee)
+);
+
+CREATE INDEX IF NOT EXISTS ranking_positions_ratee_idx ON ranking_positions(criterion, ratee);
+
+CREATE TABLE IF NOT EXISTS drafts (
+	round_id bigint not null references rounds(id) on delete cascade,
+	participant_code text not null references participants(code) on delete cascade,
+	answers jsonb not null,
+	rankings jsonb not null,
+	progress int not null default 0,
+	updated_at timestamptz not null default now(),
+	primary key (round_id, participant_code)
+);
+
+CREATE TABLE IF NOT EXISTS reminders (
+	id bigserial primary key,
+	round_id bigint not null references rounds(id) on delete cascade,
+	participant_code text not null references participants(code) on delete cascade,
+	kind text not null,
+	transport text not null,
+	status text not null,
+	error text not null default '',
+	sent_at timestamptz not null default now()
+);
+
+CREATE INDEX IF NOT EXISTS reminders_round_idx ON reminders(round_id, participant_code, kind);
+
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+	id bigserial primary key,
+	url text not null,
+	secret text not null,
+	events text[] not null default '{}',
+	active boolean not null default true,
+	created_at timestamptz not null default now()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_outbox (
+	id bigserial primary key,
+	endpoint_id bigint not null references webhook_endpoints(id) on delete cascade,
+	event text not null,
+	payload jsonb not null,
+	status text not null default 'pending',
+	attempts int not null default 0,
+	next_attempt_at timestamptz not null default now(),
+	last_error text not null default '',
+	created_at timestamptz not null default now(),
+	delivered_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS webhook_outbox_due_idx ON webhook_outbox(next_attempt_at) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id bigserial primary key,
+	outbox_id bigint not null references webhook_outbox(id) on delete cascade,
+	endpoint_id bigint not null references webhook_endpoints(id) on delete cascade,
+	event text not null,
+	attempt int not null,
+	status_code int not null default 0,
+	error text not null default '',
+	duration_ms bigint not null default 0,
+	outcome text not null,
+	created_at timestamptz not null default now()
+);
+
+-- Events that go out at most once, such as a round closing by schedule.
+CREATE TABLE IF NOT EXISTS webhook_event_keys (
+	key text primary key,
+	created_at timestamptz not null default now()
+);
+
+-- Confirmation tokens that were used, kept until they expire.
+CREATE TABLE IF NOT EXISTS used_confirmations (
+	jti text primary key,
+	expires_at timestamptz not null
+);
+
+-- Append-only audit log. Each row carries the hash of the previous one;
+-- the trigger rejects edits and deletions from the application role.
+CREATE TABLE IF NOT EXISTS audit_log (
+	id bigserial primary key,
+	occurred_at timestamptz not null,
+	actor text not null default '',
+	action text not null,
+	target text not null default '',
+	ip text not null default '',
+	user_agent text not null default '',
+	outcome text not null,
+	status int not null default 0,
+	prev_hash text not null,
+	hash text not null
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON audit_log(actor, id);
+CREATE INDEX IF NOT EXISTS audit_log_action_idx ON audit_log(action, id);
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_log is append-only';
+END $$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_no_change ON audit_log;
+CREATE TRIGGER audit_log_no_change BEFORE UPDATE OR DELETE ON audit_log
+	FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log;
+CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON audit_log
+	FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+
+-- Gzipped JSON copies of the revisions deleted by each reset.
+CREATE TABLE IF NOT EXISTS reset_snapshots (
+	id bigserial primary key,
+	created_at timestamptz not null default now(),
+	created_by text not null,
+	scope jsonb not null,
+	revisions int not null,
+	data bytea not null,
+	restored_at timestamptz
+);
+
+-- Responses of anonymized rounds, detached from participants.
+CREATE TABLE IF NOT EXISTS anonymous_responses (
+	id bigserial primary key,
+	round_id bigint not null references rounds(id) on delete cascade,
+	respondent text not null,
+	answers jsonb not null,
+	rankings jsonb not null
+);
+
+CREATE INDEX IF NOT EXISTS anonymous_responses_round_idx ON anonymous_responses(round_id, id);
+
+-- Acceptances of the privacy notice, one row per participant and version.
+CREATE TABLE IF NOT EXISTS consents (
+	participant_code text not null references participants(code) on delete cascade,
+	version int not null,
+	accepted_at timestamptz not null default now(),
+	primary key (participant_code, version)
+);
+
+-- The texts of the privacy notice by version, as a SHA-256 digest.
+CREATE TABLE IF NOT EXISTS consent_notices (
+	version int primary key,
+	digest text not null,
+	created_at timestamptz not null default now()
+);
+
+CREATE OR REPLACE VIEW responses AS
+SELECT DISTINCT ON (round_id, participant_code)
+	id,
+	participant_code,
+	answers,
+	rankings,
+	is_test_data,
+	session_id,
+	min(submitted_at) OVER (PARTITION BY round_id, participant_code) AS submitted_at,
+	submitted_at AS updated_at,
+	round_id
+FROM response_revisions
+ORDER BY round_id, participant_code, id DESC;
+`)
+	if err != nil {
+		return fmt.Errorf("create tables: %w", err)
+	}
+
+	if err := s.backfillNormalized(ctx, tx); err != nil {
+		return fmt.Errorf("backfill answers: %w", err)
+	}
+
+	for _, p := range participants {
+		_, err = tx.Exec(ctx, `
+INSERT INTO participants (code, name, email, is_admin)
+VALUES ($1,$2,$3,$4)
+ON CONFLICT (code) DO UPDATE SET name=EXCLUDED.name, email=EXCLUDED.email, is_admin=EXCLUDED.is_admin;
+`, p.Code, p.Name, p.Email, p.IsAdmin)
+		if err != nil {
+			return fmt.Errorf("seed participant %s: %w", p.Code, err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ParticipantByEmailAndCode finds a participant.
+func (s *Store) ParticipantByEmailAndCode(ctx context.Context, email, code string) (*models.Participant, error) {
+	var p models.Participant
+	err := s.pool.QueryRow(ctx, `SELECT code, name, email, is_admin FROM participants WHERE email=$1 AND code=$2`, email, code).
+		Scan(&p.Code, &p.Name, &p.Email, &p.IsAdmin)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *Store) ListParticipants(ctx context.Context) ([]models.Participant, error) {
+	rows, err := s.pool.Query(ctx, `SELECT code, name, email, is_admin FROM participants ORDER BY name asc`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.Participant
+	for rows.Next() {
+		var p models.Participant
+		if err := rows.Scan(&p.Code, &p.Name, &p.Email, &p.IsAdmin); err != nil {
+			return nil, err
+		}
+		res = append(res, p)
+	}
+	return res, rows.Err()
+}
+
+// UpsertResponse appends a new revision for the participant in the given
+// round. The responses view always exposes the latest revision, so
+// resubmitting never loses history.
+func (s *Store) UpsertResponse(ctx context.Context, roundID int64, participantCode string, answers []models.AnswerPayload, rankings []models.RankingPayload, isTest bool, sessionID string) error {
+	answersJSON, rankingsJSON, err := s.encode(answers, rankings, envelope.LabelAnswers)
+	if err != nil {
+		return err
+	}
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var id int64
+	err = tx.QueryRow(ctx, `
+INSERT INTO response_revisions (round_id, participant_code, answers, rankings, is_test_data, session_id, submitted_at)
+VALUES ($1,$2,$3,$4,$5,$6, now()) RETURNING id;`,
+		roundID, participantCode, answersJSON, rankingsJSON, isTest, sessionID).Scan(&id)
+	if err != nil {
+		return err
+	}
+	if err := s.insertNormalized(ctx, tx, id, answers, rankings); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// insertNormalized writes the answers and ranking_positions rows of one
+// revision. With encryption on, text answers are left out of the answers
+// table.
+func (s *Store) insertNormalized(ctx context.Context, tx pgx.Tx, responseID int64, answers []models.AnswerPayload, rankings []models.RankingPayload) error {
+	rows, positions := seed.Normalize(answers, rankings)
+	batch := &pgx.Batch{}
+	for _, a := range rows {
+		if s.keys != nil {
+			// Free text stays only in the sealed payload.
+			a.TextValue = nil
+		}
+		batch.Queue(`
+INSERT INTO answers (response_id, question_id, peer_code, numeric_value, text_value, choice_value)
+VALUES ($1,$2,$3,$4,$5,$6)`, responseID, a.QuestionID, a.PeerCode, a.NumericValue, a.TextValue, a.ChoiceValue)
+	}
+	for _, p := range positions {
+		batch.Queue(`
+INSERT INTO ranking_positions (response_id, criterion, ratee, position)
+VALUES ($1,$2,$3,$4)`, responseID, p.Criterion, p.Ratee, p.Position)
+	}
+	if batch.Len() == 0 {
+		return nil
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("insert normalized answers: %w", err)
+	}
+	return nil
+}
+
+// backfillNormalized fills the answers and ranking_positions tables for
+// revisions stored before they existed. Revisions without any rows are
+// revisited on every start, which only costs anything for empty submissions.
+func (s *Store) backfillNormalized(ctx context.Context, tx pgx.Tx) error {
+	rows, err := tx.Query(ctx, `
+SELECT id, answers, rankings FROM response_revisions r
+WHERE NOT EXISTS (SELECT 1 FROM answers a WHERE a.response_id = r.id)
+  AND NOT EXISTS (SELECT 1 FROM ranking_positions p WHERE p.response_id = r.id)
+ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	type pending struct {
+		id       int64
+		answers  []models.AnswerPayload
+		rankings []models.RankingPayload
+	}
+	var todo []pending
+	for rows.Next() {
+		var p pending
+		var answersJSON, rankingsJSON []byte
+		if err := rows.Scan(&p.id, &answersJSON, &rankingsJSON); err != nil {
+			rows.Close()
+			return err
+		}
+		if err := s.decode(answersJSON, rankingsJSON, envelope.LabelAnswers, &p.answers, &p.rankings); err != nil {
+			rows.Close()
+			return fmt.Errorf("revision %d: %w", p.id, err)
+		}
+		todo = append(todo, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, p := range todo {
+		if err := s.insertNormalized(ctx, tx, p.id, p.answers, p.rankings); err != nil {
+			return fmt.Errorf("revision %d: %w", p.id, err)
+		}
+	}
+	return nil
+}
+
+const responseColumns = `id, participant_code, answers, rankings, is_test_data, submitted_at, updated_at, round_id`
+
+// AllResponses returns the current response of every participant in a round.
+func (s *Store) AllResponses(ctx context.Context, roundID int64) ([]models.ResponseRecord, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+responseColumns+` FROM responses WHERE round_id=$1 ORDER BY submitted_at desc`, roundID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.ResponseRecord
+	for rows.Next() {
+		r, err := s.scanResponse(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, *r)
+	}
+	return res, rows.Err()
+}
+
+// CountValues aggregates the answers table of the current non-admin responses.
+func (s *Store) CountValues(ctx context.Context, roundID int64) ([]models.ValueCount, error) {
+	rows, err := s.pool.Query(ctx, `
+SELECT a.question_id, a.peer_code, a.numeric_value, count(*)
+FROM responses r
+JOIN answers a ON a.response_id = r.id
+JOIN participants p ON p.code = r.participant_code
+WHERE r.round_id=$1 AND a.numeric_value IS NOT NULL AND NOT p.is_admin
+GROUP BY a.question_id, a.peer_code, a.numeric_value
+ORDER BY a.question_id, a.peer_code, a.numeric_value`, roundID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.ValueCount
+	for rows.Next() {
+		var c models.ValueCount
+		if err := rows.Scan(&c.QuestionID, &c.PeerCode, &c.Value, &c.Count); err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+// ResponseByParticipant returns the current response of one participant, or
+// nil when they have not submitted in the round.
+func (s *Store) ResponseByParticipant(ctx context.Context, roundID int64, participantCode string) (*models.ResponseRecord, error) {
+	r, err := s.scanResponse(s.pool.QueryRow(ctx, `SELECT `+responseColumns+` FROM responses WHERE round_id=$1 AND participant_code=$2`, roundID, participantCode))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return r, err
+}
+
+// ListResponses returns one page of current responses, keyed by
+// (submitted_at, id) so a cursor stays valid while new responses arrive.
+func (s *Store) ListResponses(ctx context.Context, f models.ResponseFilter) (models.ResponsePage, error) {
+	var where []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if f.RoundID != 0 {
+		where = append(where, "round_id = "+arg(f.RoundID))
+	}
+	if f.IsTest != nil {
+		where = append(where, "is_test_data = "+arg(*f.IsTest))
+	}
+	if f.From != nil {
+		where = append(where, "submitted_at >= "+arg(*f.From))
+	}
+	if f.To != nil {
+		where = append(where, "submitted_at < "+arg(*f.To))
+	}
+	order, cmp := "desc", "<"
+	if f.Sort == models.SortOldest {
+		order, cmp = "asc", ">"
+	}
+	if f.After != nil {
+		where = append(where, fmt.Sprintf("(submitted_at, id) %s (%s, %s)", cmp, arg(f.After.SubmittedAt), arg(f.After.ID)))
+	}
+	query := `SELECT ` + responseColumns + ` FROM responses`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY submitted_at ` + order + `, id ` + order
+	if f.Limit > 0 {
+		query += ` LIMIT ` + arg(f.Limit+1)
+	}
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return models.ResponsePage{}, err
+	}
+	defer rows.Close()
+	page := models.ResponsePage{Items: []models.ResponseRecord{}}
+	for rows.Next() {
+		r, err := s.scanResponse(rows)
+		if err != nil {
+			return models.ResponsePage{}, err
+		}
+		page.Items = append(page.Items, *r)
+	}
+	if err := rows.Err(); err != nil {
+		return models.ResponsePage{}, err
+	}
+	if f.Limit > 0 && len(page.Items) > f.Limit {
+		page.Items = page.Items[:f.Limit]
+		page.NextCursor = models.CursorAfter(page.Items[f.Limit-1]).Encode()
+	}
+	return page, nil
+}
+
+func (s *Store) scanResponse(row pgx.Row) (*models.ResponseRecord, error) {
+	var r models.ResponseRecord
+	var answersJSON, rankingsJSON []byte
+	if err := row.Scan(&r.ID, &r.ParticipantCode, &answersJSON, &rankingsJSON, &r.IsTestData, &r.SubmittedAt, &r.UpdatedAt, &r.RoundID); err != nil {
+		return nil, err
+	}
+	if err := s.decode(answersJSON, rankingsJSON, envelope.LabelAnswers, &r.Answers, &r.Rankings); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ListRevisions returns every revision a participant submitted in a round, oldest first.
+func (s *Store) ListRevisions(ctx context.Context, roundID int64, participantCode string) ([]models.ResponseRevision, error) {
+	rows, err := s.pool.Query(ctx, `
+SELECT `+revisionColumns+`
+FROM response_revisions WHERE round_id=$1 AND participant_code=$2 ORDER BY id asc`, roundID, participantCode)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.ResponseRevision
+	for rows.Next() {
+		rev, err := s.scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, *rev)
+	}
+	return res, rows.Err()
+}
+
+func (s *Store) scanRevision(row pgx.Row) (*models.ResponseRevision, error) {
+	var r models.ResponseRevision
+	var answersJSON, rankingsJSON []byte
+	if err := row.Scan(&r.ID, &r.ParticipantCode, &answersJSON, &rankingsJSON, &r.IsTestData, &r.SessionID, &r.SubmittedAt, &r.RoundID); err != nil {
+		return nil, err
+	}
+	if err := s.decode(answersJSON, rankingsJSON, envelope.LabelAnswers, &r.Answers, &r.Rankings); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+const revisionColumns = `id, participant_code, answers, rankings, is_test_data, session_id, submitted_at, round_id`
+
+// ExportRevisions returns the revisions matching f, oldest first.
+func (s *Store) ExportRevisions(ctx context.Context, f models.RevisionFilter) ([]models.ResponseRevision, error) {
+	rows, err := s.pool.Query(ctx, `
+SELECT `+revisionColumns+` FROM response_revisions
+WHERE ($1::bigint = 0 OR round_id = $1)
+  AND ($2::text = '' OR participant_code = $2)
+  AND (NOT $3::boolean OR is_test_data)
+  AND ($4::bigint = 0 OR id <= $4)
+ORDER BY id`, f.RoundID, f.ParticipantCode, f.TestOnly, f.UpToID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.ResponseRevision
+	for rows.Next() {
+		rev, err := s.scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, *rev)
+	}
+	return res, rows.Err()
+}
+
+// ResetResponses stores the snapshot and deletes the listed revisions in one
+// transaction; their answers and ranking positions go with them.
+func (s *Store) ResetResponses(ctx context.Context, snapshot models.Snapshot, revisionIDs []int64) (*models.Snapshot, error) {
+	scope, err := json.Marshal(snapshot.Scope)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.keys.Seal(snapshot.Data, envelope.LabelSnapshot)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+INSERT INTO reset_snapshots (created_by, scope, revisions, data)
+VALUES ($1,$2,$3,$4)
+RETURNING id, created_at`, snapshot.CreatedBy, scope, snapshot.Revisions, data).Scan(&snapshot.ID, &snapshot.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM response_revisions WHERE id = ANY($1)`, revisionIDs); err != nil {
+		return nil, err
+	}
+	snapshot.Size = len(data)
+	snapshot.Data = nil
+	return &snapshot, tx.Commit(ctx)
+}
+
+// encode marshals a payload, sealing the answers and the rankings, whose
+// comments are free text too, when a keyring is set.
+func (s *Store) encode(answers []models.AnswerPayload, rankings []models.RankingPayload, label string) ([]byte, []byte, error) {
+	answersJSON, err := json.Marshal(answers)
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshal answers: %w", err)
+	}
+	rankingsJSON, err := json.Marshal(rankings)
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshal rankings: %w", err)
+	}
+	if answersJSON, err = s.keys.Seal(answersJSON, label); err != nil {
+		return nil, nil, err
+	}
+	if rankingsJSON, err = s.keys.Seal(rankingsJSON, envelope.RankingsLabel(label)); err != nil {
+		return nil, nil, err
+	}
+	return answersJSON, rankingsJSON, nil
+}
+
+func (s *Store) decode(answersJSON, rankingsJSON []byte, label string, answers *[]models.AnswerPayload, rankings *[]models.RankingPayload) error {
+	answersJSON, err := s.keys.Open(answersJSON, label)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(answersJSON, answers); err != nil {
+		return fmt.Errorf("unmarshal answers: %w", err)
+	}
+	if rankingsJSON, err = s.keys.Open(rankingsJSON, envelope.RankingsLabel(label)); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(rankingsJSON, rankings); err != nil {
+		return fmt.Errorf("unmarshal rankings: %w", err)
+	}
+	return nil
+}
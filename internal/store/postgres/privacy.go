@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"opslab-survey/internal/envelope"
+	"opslab-survey/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// EraseParticipant deletes what the participant authored and rewrites the
+// revisions and snapshots that referred to them in one transaction.
+// Rewritten revisions get their answers and ranking positions rebuilt.
+func (s *Store) EraseParticipant(ctx context.Context, e models.Erasure) (*models.ErasureResult, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	res := &models.ErasureResult{}
+	for _, d := range []struct {
+		table string
+		count *int
+	}{
+		{"response_revisions", &res.Revisions},
+		{"drafts", &res.Drafts},
+		{"deadline_extensions", &res.Extensions},
+		{"reminders", &res.Reminders},
+	} {
+		tag, err := tx.Exec(ctx, `DELETE FROM `+d.table+` WHERE participant_code=$1`, e.ParticipantCode)
+		if err != nil {
+			return nil, fmt.Errorf("erase %s: %w", d.table, err)
+		}
+		*d.count = int(tag.RowsAffected())
+	}
+
+	for _, rev := range e.Revisions {
+		answersJSON, rankingsJSON, err := s.encode(rev.Answers, rev.Rankings, envelope.LabelAnswers)
+		if err != nil {
+			return nil, err
+		}
+		tag, err := tx.Exec(ctx, `UPDATE response_revisions SET answers=$1, rankings=$2 WHERE id=$3`, answersJSON, rankingsJSON, rev.ID)
+		if err != nil {
+			return nil, fmt.Errorf("revision %d: %w", rev.ID, err)
+		}
+		if tag.RowsAffected() == 0 {
+			continue
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM answers WHERE response_id=$1`, rev.ID); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM ranking_positions WHERE response_id=$1`, rev.ID); err != nil {
+			return nil, err
+		}
+		if err := s.insertNormalized(ctx, tx, rev.ID, rev.Answers, rev.Rankings); err != nil {
+			return nil, fmt.Errorf("revision %d: %w", rev.ID, err)
+		}
+		res.Pseudonymized++
+	}
+
+	for _, snap := range e.Snapshots {
+		data, err := s.keys.Seal(snap.Data, envelope.LabelSnapshot)
+		if err != nil {
+			return nil, err
+		}
+		tag, err := tx.Exec(ctx, `UPDATE reset_snapshots SET data=$1, revisions=$2 WHERE id=$3`, data, snap.Revisions, snap.ID)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot %d: %w", snap.ID, err)
+		}
+		res.Snapshots += int(tag.RowsAffected())
+	}
+	return res, tx.Commit(ctx)
+}
+
+// AnonymizeRound replaces the participant data of a round with anonymous
+// responses in one transaction.
+func (s *Store) AnonymizeRound(ctx context.Context, roundID int64, responses []models.AnonymousResponse) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var exists int
+	if err := tx.QueryRow(ctx, `SELECT 1 FROM rounds WHERE id=$1 FOR UPDATE`, roundID).Scan(&exists); err != nil {
+		return err
+	}
+	for _, table := range []string{"response_revisions", "drafts", "deadline_extensions", "reminders"} {
+		if _, err := tx.Exec(ctx, `DELETE FROM `+table+` WHERE round_id=$1`, roundID); err != nil {
+			return fmt.Errorf("anonymize %s: %w", table, err)
+		}
+	}
+	for _, a := range responses {
+		if err := insertAnonymous(ctx, tx, roundID, a); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func insertAnonymous(ctx context.Context, tx pgx.Tx, roundID int64, a models.AnonymousResponse) error {
+	answersJSON, err := json.Marshal(a.Answers)
+	if err != nil {
+		return fmt.Errorf("marshal answers: %w", err)
+	}
+	rankingsJSON, err := json.Marshal(a.Rankings)
+	if err != nil {
+		return fmt.Errorf("marshal rankings: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+INSERT INTO anonymous_responses (round_id, respondent, answers, rankings)
+VALUES ($1,$2,$3,$4)`, roundID, a.Respondent, answersJSON, rankingsJSON)
+	return err
+}
+
+// ListAnonymousResponses returns the anonymous responses of a round in the
+// order they were stored.
+func (s *Store) ListAnonymousResponses(ctx context.Context, roundID int64) ([]models.AnonymousResponse, error) {
+	rows, err := s.pool.Query(ctx, `
+SELECT round_id, respondent, answers, rankings FROM anonymous_responses
+WHERE round_id=$1 ORDER BY id`, roundID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.AnonymousResponse
+	for rows.Next() {
+		var a models.AnonymousResponse
+		var answersJSON, rankingsJSON []byte
+		if err := rows.Scan(&a.RoundID, &a.Respondent, &answersJSON, &rankingsJSON); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(answersJSON, &a.Answers); err != nil {
+			return nil, fmt.Errorf("unmarshal answers: %w", err)
+		}
+		if err := json.Unmarshal(rankingsJSON, &a.Rankings); err != nil {
+			return nil, fmt.Errorf("unmarshal rankings: %w", err)
+		}
+		res = append(res, a)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"opslab-survey/internal/models"
+)
+
+// PendingParticipants lists non-admin participants without any submission in the round.
+func (s *Store) PendingParticipants(ctx context.Context, roundID int64) ([]models.Participant, error) {
+	rows, err := s.pool.Query(ctx, `
+SELECT code, name, email, is_admin FROM participants p
+WHERE NOT p.is_admin
+  AND NOT EXISTS (SELECT 1 FROM response_revisions r WHERE r.round_id=$1 AND r.participant_code=p.code)
+ORDER BY name asc`, roundID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.Participant
+	for rows.Next() {
+		var p models.Participant
+		if err := rows.Scan(&p.Code, &p.Name, &p.Email, &p.IsAdmin); err != nil {
+			return nil, err
+		}
+		res = append(res, p)
+	}
+	return res, rows.Err()
+}
+
+// RecordReminder stores the outcome of a reminder delivery.
+func (s *Store) RecordReminder(ctx context.Context, r models.Reminder) error {
+	_, err := s.pool.Exec(ctx, `
+INSERT INTO reminders (round_id, participant_code, kind, transport, status, error)
+VALUES ($1,$2,$3,$4,$5,$6)`, r.RoundID, r.ParticipantCode, r.Kind, r.Transport, r.Status, r.Error)
+	return err
+}
+
+// ReminderHistory sums up the earlier attempts at a reminder of one kind.
+func (s *Store) ReminderHistory(ctx context.Context, roundID int64, participantCode, kind string) (models.ReminderHistory, error) {
+	var h models.ReminderHistory
+	var lastFailure *time.Time
+	err := s.pool.QueryRow(ctx, `
+SELECT coalesce(bool_or(status='sent'), false), count(*) FILTER (WHERE status='failed'), max(sent_at) FILTER (WHERE status='failed')
+FROM reminders WHERE round_id=$1 AND participant_code=$2 AND kind=$3`,
+		roundID, participantCode, kind).Scan(&h.Sent, &h.Failures, &lastFailure)
+	if lastFailure != nil {
+		h.LastFailure = *lastFailure
+	}
+	return h, err
+}
+
+func (s *Store) ListReminders(ctx context.Context, roundID int64) ([]models.Reminder, error) {
+	rows, err := s.pool.Query(ctx, `
+SELECT id, round_id, participant_code, kind, transport, status, error, sent_at
+FROM reminders WHERE round_id=$1 ORDER BY sent_at desc`, roundID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.Reminder
+	for rows.Next() {
+		var r models.Reminder
+		if err := rows.Scan(&r.ID, &r.RoundID, &r.ParticipantCode, &r.Kind, &r.Transport, &r.Status, &r.Error, &r.SentAt); err != nil {
+			return nil, err
+		}
+		res = append(res, r)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"opslab-survey/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const roundColumns = `id, title, state, opens_at, closes_at, created_at, updated_at`
+
+func scanRound(row pgx.Row) (*models.Round, error) {
+	var r models.Round
+	if err := row.Scan(&r.ID, &r.Title, &r.State, &r.OpensAt, &r.ClosesAt, &r.CreatedAt, &r.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// CurrentRound returns the round picked by models.CurrentRound, or nil when
+// every round is archived.
+func (s *Store) CurrentRound(ctx context.Context) (*models.Round, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+roundColumns+` FROM rounds WHERE state <> 'archived'`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var rounds []models.Round
+	for rows.Next() {
+		r, err := scanRound(rows)
+		if err != nil {
+			return nil, err
+		}
+		rounds = append(rounds, *r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return models.CurrentRound(rounds, time.Now()), nil
+}
+
+func (s *Store) RoundByID(ctx context.Context, id int64) (*models.Round, error) {
+	return scanRound(s.pool.QueryRow(ctx, `SELECT `+roundColumns+` FROM rounds WHERE id=$1`, id))
+}
+
+func (s *Store) ListRounds(ctx context.Context) ([]models.Round, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+roundColumns+` FROM rounds ORDER BY id desc`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.Round
+	for rows.Next() {
+		r, err := scanRound(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, *r)
+	}
+	return res, rows.Err()
+}
+
+// CreateRound inserts a new round and returns it with its generated fields.
+func (s *Store) CreateRound(ctx context.Context, round models.Round) (*models.Round, error) {
+	return scanRound(s.pool.QueryRow(ctx, `
+INSERT INTO rounds (title, state, opens_at, closes_at)
+VALUES ($1,$2,$3,$4)
+RETURNING `+roundColumns, round.Title, round.State, round.OpensAt, round.ClosesAt))
+}
+
+// UpdateRound replaces the title, state and schedule of an existing round.
+func (s *Store) UpdateRound(ctx context.Context, round models.Round) (*models.Round, error) {
+	return scanRound(s.pool.QueryRow(ctx, `
+UPDATE rounds SET title=$2, state=$3, opens_at=$4, closes_at=$5, updated_at=now()
+WHERE id=$1
+RETURNING `+roundColumns, round.ID, round.Title, round.State, round.OpensAt, round.ClosesAt))
+}
+
+// ExtendDeadline sets (or replaces) a personal deadline for one participant.
+func (s *Store) ExtendDeadline(ctx context.Context, ext models.DeadlineExtension) error {
+	_, err := s.pool.Exec(ctx, `
+INSERT INTO deadline_extensions (round_id, participant_code, closes_at, reason)
+VALUES ($1,$2,$3,$4)
+ON CONFLICT (round_id, participant_code)
+DO UPDATE SET closes_at=EXCLUDED.closes_at, reason=EXCLUDED.reason, created_at=now();`,
+		ext.RoundID, ext.ParticipantCode, ext.ClosesAt, ext.Reason)
+	return err
+}
+
+// DeadlineExtension returns the participant's personal deadline, or nil if none was granted.
+func (s *Store) DeadlineExtension(ctx context.Context, roundID int64, participantCode string) (*time.Time, error) {
+	var closesAt time.Time
+	err := s.pool.QueryRow(ctx, `SELECT closes_at FROM deadline_extensions WHERE round_id=$1 AND participant_code=$2`, roundID, participantCode).
+		Scan(&closesAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &closesAt, nil
+}
+
+func (s *Store) ListDeadlineExtensions(ctx context.Context, roundID int64) ([]models.DeadlineExtension, error) {
+	rows, err := s.pool.Query(ctx, `
+SELECT round_id, participant_code, closes_at, reason, created_at
+FROM deadline_extensions WHERE round_id=$1 ORDER BY participant_code`, roundID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.DeadlineExtension
+	for rows.Next() {
+		var e models.DeadlineExtension
+		if err := rows.Scan(&e.RoundID, &e.ParticipantCode, &e.ClosesAt, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, e)
+	}
+	return res, rows.Err()
+}
+
+// DeleteRound removes a round; everything that belongs to it goes with it.
+func (s *Store) DeleteRound(ctx context.Context, id int64) (bool, error) {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM rounds WHERE id=$1`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
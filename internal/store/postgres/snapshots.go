@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"opslab-survey/internal/envelope"
+	"opslab-survey/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ListSnapshots returns every snapshot without its data, newest first.
+func (s *Store) ListSnapshots(ctx context.Context) ([]models.Snapshot, error) {
+	rows, err := s.pool.Query(ctx, `
+SELECT id, created_at, created_by, scope, revisions, length(data), restored_at
+FROM reset_snapshots ORDER BY id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.Snapshot
+	for rows.Next() {
+		var snap models.Snapshot
+		var scope []byte
+		if err := rows.Scan(&snap.ID, &snap.CreatedAt, &snap.CreatedBy, &scope, &snap.Revisions, &snap.Size, &snap.RestoredAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(scope, &snap.Scope); err != nil {
+			return nil, fmt.Errorf("unmarshal scope: %w", err)
+		}
+		res = append(res, snap)
+	}
+	return res, rows.Err()
+}
+
+func (s *Store) SnapshotByID(ctx context.Context, id int64) (*models.Snapshot, error) {
+	var snap models.Snapshot
+	var scope []byte
+	err := s.pool.QueryRow(ctx, `
+SELECT id, created_at, created_by, scope, revisions, data, restored_at
+FROM reset_snapshots WHERE id=$1`, id).Scan(&snap.ID, &snap.CreatedAt, &snap.CreatedBy, &scope, &snap.Revisions, &snap.Data, &snap.RestoredAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(scope, &snap.Scope); err != nil {
+		return nil, fmt.Errorf("unmarshal scope: %w", err)
+	}
+	snap.Size = len(snap.Data)
+	if snap.Data, err = s.keys.Open(snap.Data, envelope.LabelSnapshot); err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+// RestoreSnapshot inserts the revisions that are not stored any more,
+// keeping their IDs, and marks the snapshot restored.
+func (s *Store) RestoreSnapshot(ctx context.Context, id int64, revisions []models.ResponseRevision) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE reset_snapshots SET restored_at = now() WHERE id=$1`, id)
+	if err != nil {
+		return 0, err
+	}
+	if tag.RowsAffected() == 0 {
+		return 0, pgx.ErrNoRows
+	}
+	n, err := s.insertRevisions(ctx, tx, revisions)
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit(ctx)
+}
+
+// insertRevisions writes revisions with their original IDs and timestamps,
+// skipping the ones that are already stored, and moves the ID sequence past
+// them. An ID taken by another revision is an ErrRestoreConflict.
+func (s *Store) insertRevisions(ctx context.Context, tx pgx.Tx, revisions []models.ResponseRevision) (int, error) {
+	var n int
+	for _, rev := range revisions {
+		answersJSON, rankingsJSON, err := s.encode(rev.Answers, rev.Rankings, envelope.LabelAnswers)
+		if err != nil {
+			return 0, err
+		}
+		tag, err := tx.Exec(ctx, `
+INSERT INTO response_revisions (id, round_id, participant_code, answers, rankings, is_test_data, session_id, submitted_at)
+VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+ON CONFLICT (id) DO NOTHING`,
+			rev.ID, rev.RoundID, rev.ParticipantCode, answersJSON, rankingsJSON, rev.IsTestData, rev.SessionID, rev.SubmittedAt)
+		if err != nil {
+			return 0, fmt.Errorf("revision %d: %w", rev.ID, err)
+		}
+		if tag.RowsAffected() == 0 {
+			err := sameRow(ctx, tx, `SELECT 1 FROM response_revisions WHERE id=$1 AND round_id=$2 AND participant_code=$3 AND submitted_at=$4`,
+				rev.ID, rev.RoundID, rev.ParticipantCode, rev.SubmittedAt)
+			if err != nil {
+				return 0, fmt.Errorf("revision %d: %w", rev.ID, err)
+			}
+			continue
+		}
+		if err := s.insertNormalized(ctx, tx, rev.ID, rev.Answers, rev.Rankings); err != nil {
+			return 0, fmt.Errorf("revision %d: %w", rev.ID, err)
+		}
+		n++
+	}
+	_, err := tx.Exec(ctx, `
+SELECT setval(pg_get_serial_sequence('response_revisions', 'id'), max(id))
+FROM response_revisions
+HAVING max(id) > (SELECT last_value FROM response_revisions_id_seq)`)
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// DeleteSnapshots removes snapshots created before the given time.
+func (s *Store) DeleteSnapshots(ctx context.Context, before time.Time) (int, error) {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM reset_snapshots WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,180 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"opslab-survey/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Store) CreateWebhookEndpoint(ctx context.Context, e models.WebhookEndpoint) (*models.WebhookEndpoint, error) {
+	if e.Events == nil {
+		e.Events = []string{}
+	}
+	err := s.pool.QueryRow(ctx, `
+INSERT INTO webhook_endpoints (url, secret, events, active)
+VALUES ($1,$2,$3,$4)
+RETURNING id, created_at`, e.URL, e.Secret, e.Events, e.Active).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (s *Store) ListWebhookEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error) {
+	rows, err := s.pool.Query(ctx, `SELECT id, url, secret, events, active, created_at FROM webhook_endpoints ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.WebhookEndpoint
+	for rows.Next() {
+		var e models.WebhookEndpoint
+		if err := rows.Scan(&e.ID, &e.URL, &e.Secret, &e.Events, &e.Active, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, e)
+	}
+	return res, rows.Err()
+}
+
+// DeleteWebhookEndpoint removes an endpoint together with its outbox and delivery log.
+func (s *Store) DeleteWebhookEndpoint(ctx context.Context, id int64) (bool, error) {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM webhook_endpoints WHERE id=$1`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// EnqueueWebhook adds an outbox entry for every active endpoint subscribed to
+// the event. An endpoint with no event filter receives everything.
+func (s *Store) EnqueueWebhook(ctx context.Context, event string, payload []byte) (int64, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	n, err := enqueueWebhook(ctx, tx, event, payload)
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit(ctx)
+}
+
+// EnqueueWebhookOnce enqueues the event unless key was recorded before.
+func (s *Store) EnqueueWebhookOnce(ctx context.Context, key, event string, payload []byte) (int64, bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback(ctx)
+	tag, err := tx.Exec(ctx, `INSERT INTO webhook_event_keys (key) VALUES ($1) ON CONFLICT (key) DO NOTHING`, key)
+	if err != nil || tag.RowsAffected() == 0 {
+		return 0, false, err
+	}
+	n, err := enqueueWebhook(ctx, tx, event, payload)
+	if err != nil {
+		return 0, false, err
+	}
+	return n, true, tx.Commit(ctx)
+}
+
+func enqueueWebhook(ctx context.Context, tx pgx.Tx, event string, payload []byte) (int64, error) {
+	tag, err := tx.Exec(ctx, `
+INSERT INTO webhook_outbox (endpoint_id, event, payload)
+SELECT id, $1::text, $2::jsonb FROM webhook_endpoints
+WHERE active AND (cardinality(events) = 0 OR $1::text = ANY(events) OR '*' = ANY(events))`, event, payload)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ClaimWebhookJobs leases up to limit due outbox entries. The lease pushes
+// next_attempt_at forward so other server instances skip them while this one
+// delivers.
+func (s *Store) ClaimWebhookJobs(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookJob, error) {
+	rows, err := s.pool.Query(ctx, `
+WITH due AS (
+	SELECT id FROM webhook_outbox
+	WHERE status = 'pending' AND next_attempt_at <= now()
+	ORDER BY id
+	LIMIT $1
+	FOR UPDATE SKIP LOCKED
+)
+UPDATE webhook_outbox o
+SET next_attempt_at = now() + $2 * interval '1 second'
+FROM due, webhook_endpoints e
+WHERE o.id = due.id AND e.id = o.endpoint_id
+RETURNING o.id, o.endpoint_id, e.url, e.secret, o.event, o.payload, o.attempts`, limit, int64(lease.Seconds()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.WebhookJob
+	for rows.Next() {
+		var j models.WebhookJob
+		if err := rows.Scan(&j.ID, &j.EndpointID, &j.URL, &j.Secret, &j.Event, &j.Payload, &j.Attempts); err != nil {
+			return nil, err
+		}
+		res = append(res, j)
+	}
+	return res, rows.Err()
+}
+
+// RecordWebhookAttempt logs a delivery attempt and moves the outbox entry to
+// its next state: delivered, failed, or pending again at retryAt.
+func (s *Store) RecordWebhookAttempt(ctx context.Context, d models.WebhookDelivery, retryAt time.Time) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+INSERT INTO webhook_deliveries (outbox_id, endpoint_id, event, attempt, status_code, error, duration_ms, outcome)
+VALUES ($1,$2,$3,$4,$5,$6,$7,$8)`,
+		d.OutboxID, d.EndpointID, d.Event, d.Attempt, d.StatusCode, d.Error, d.DurationMs, d.Outcome)
+	if err != nil {
+		return err
+	}
+
+	switch d.Outcome {
+	case "delivered":
+		_, err = tx.Exec(ctx, `UPDATE webhook_outbox SET status='delivered', attempts=$2, last_error='', delivered_at=now() WHERE id=$1`, d.OutboxID, d.Attempt)
+	case "failed":
+		_, err = tx.Exec(ctx, `UPDATE webhook_outbox SET status='failed', attempts=$2, last_error=$3 WHERE id=$1`, d.OutboxID, d.Attempt, d.Error)
+	default:
+		_, err = tx.Exec(ctx, `UPDATE webhook_outbox SET attempts=$2, last_error=$3, next_attempt_at=$4 WHERE id=$1`, d.OutboxID, d.Attempt, d.Error, retryAt)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ListWebhookDeliveries returns the newest delivery attempts, optionally for one endpoint.
+func (s *Store) ListWebhookDeliveries(ctx context.Context, endpointID int64, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := s.pool.Query(ctx, `
+SELECT id, outbox_id, endpoint_id, event, attempt, status_code, error, duration_ms, outcome, created_at
+FROM webhook_deliveries
+WHERE $1::bigint = 0 OR endpoint_id = $1::bigint
+ORDER BY id desc
+LIMIT $2`, endpointID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.OutboxID, &d.EndpointID, &d.Event, &d.Attempt, &d.StatusCode, &d.Error, &d.DurationMs, &d.Outcome, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, d)
+	}
+	return res, rows.Err()
+}
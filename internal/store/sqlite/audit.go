@@ -0,0 +1,108 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"opslab-survey/internal/audit"
+	"opslab-survey/internal/models"
+)
+
+const auditColumns = `id, occurred_at, actor, action, target, ip, user_agent, outcome, status, prev_hash, hash`
+
+// AppendAudit stamps e with the current time, chains it to the last entry
+// and stores it. SQLite has a single writer, so the transaction is enough to
+// keep the chain linear.
+func (s *Store) AppendAudit(ctx context.Context, e models.AuditEntry) (*models.AuditEntry, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1`).Scan(&e.PrevHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		e.PrevHash = ""
+	} else if err != nil {
+		return nil, err
+	}
+	e.OccurredAt = time.Now().Truncate(time.Microsecond)
+	e.Hash = audit.Hash(e.PrevHash, e)
+	err = tx.QueryRowContext(ctx, `
+INSERT INTO audit_log (occurred_at, actor, action, target, ip, user_agent, outcome, status, prev_hash, hash)
+VALUES (?,?,?,?,?,?,?,?,?,?)
+RETURNING id`, formatTime(e.OccurredAt), e.Actor, e.Action, e.Target, e.IP, e.UserAgent, e.Outcome, e.Status, e.PrevHash, e.Hash).Scan(&e.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &e, tx.Commit()
+}
+
+func (s *Store) ListAudit(ctx context.Context, f models.AuditFilter) ([]models.AuditEntry, error) {
+	var where []string
+	var args []any
+	for _, c := range []struct{ column, value string }{
+		{"actor", f.Actor}, {"target", f.Target}, {"outcome", f.Outcome},
+	} {
+		if c.value != "" {
+			where = append(where, c.column+" = ?")
+			args = append(args, c.value)
+		}
+	}
+	if f.Action != "" {
+		if strings.HasSuffix(f.Action, ".") {
+			where = append(where, "substr(action, 1, length(?)) = ?")
+			args = append(args, f.Action, f.Action)
+		} else {
+			where = append(where, "action = ?")
+			args = append(args, f.Action)
+		}
+	}
+	if f.From != nil {
+		where = append(where, "occurred_at >= ?")
+		args = append(args, formatTime(*f.From))
+	}
+	if f.To != nil {
+		where = append(where, "occurred_at < ?")
+		args = append(args, formatTime(*f.To))
+	}
+	if f.BeforeID != 0 {
+		where = append(where, "id < ?")
+		args = append(args, f.BeforeID)
+	}
+	query := `SELECT ` + auditColumns + ` FROM audit_log`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY id DESC`
+	if f.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, f.Limit)
+	}
+	return s.queryAudit(ctx, query, args...)
+}
+
+// AuditTrail returns up to limit entries with IDs above afterID, oldest first.
+func (s *Store) AuditTrail(ctx context.Context, afterID int64, limit int) ([]models.AuditEntry, error) {
+	return s.queryAudit(ctx, `SELECT `+auditColumns+` FROM audit_log WHERE id > ? ORDER BY id LIMIT ?`, afterID, limit)
+}
+
+func (s *Store) queryAudit(ctx context.Context, query string, args ...any) ([]models.AuditEntry, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(&e.ID, timeScanner{&e.OccurredAt}, &e.Actor, &e.Action, &e.Target, &e.IP, &e.UserAgent, &e.Outcome, &e.Status, &e.PrevHash, &e.Hash); err != nil {
+			return nil, err
+		}
+		res = append(res, e)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,111 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"opslab-survey/internal/models"
+)
+
+// RestoreDataset writes d in one transaction, keeping the IDs from the
+// backup. Rows skipped in merge mode must be the ones already stored.
+// Deleting the rounds in replace mode cascades to everything that belongs
+// to them. Anonymous responses are written for the rounds inserted here;
+// consents are added to those stored in either mode.
+func (s *Store) RestoreDataset(ctx context.Context, d models.Dataset, mode string) (*models.RestoreResult, error) {
+	participantConflict := `DO NOTHING`
+	switch mode {
+	case models.RestoreMerge:
+	case models.RestoreReplace:
+		participantConflict = `DO UPDATE SET name=excluded.name, email=excluded.email, is_admin=excluded.is_admin`
+	default:
+		return nil, fmt.Errorf("unknown restore mode %q", mode)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if mode == models.RestoreReplace {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM rounds`); err != nil {
+			return nil, fmt.Errorf("clear rounds: %w", err)
+		}
+	}
+	res := &models.RestoreResult{Mode: mode}
+	inserted := map[int64]bool{}
+	for _, p := range d.Participants {
+		r, err := tx.ExecContext(ctx, `
+INSERT INTO participants (code, name, email, is_admin)
+VALUES (?,?,?,?)
+ON CONFLICT (code) `+participantConflict, p.Code, p.Name, p.Email, p.IsAdmin)
+		if err != nil {
+			return nil, fmt.Errorf("participant %s: %w", p.Code, err)
+		}
+		n, err := r.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		res.Participants += int(n)
+	}
+	for _, round := range d.Rounds {
+		r, err := tx.ExecContext(ctx, `
+INSERT INTO rounds (`+roundColumns+`)
+VALUES (?,?,?,?,?,?,?)
+ON CONFLICT (id) DO NOTHING`, round.ID, round.Title, round.State, formatNullTime(round.OpensAt), formatNullTime(round.ClosesAt), formatTime(round.CreatedAt), formatTime(round.UpdatedAt))
+		if err != nil {
+			return nil, fmt.Errorf("round %d: %w", round.ID, err)
+		}
+		if n, err := r.RowsAffected(); err != nil {
+			return nil, err
+		} else if n == 0 {
+			if err := sameRow(ctx, tx, `SELECT 1 FROM rounds WHERE id=? AND created_at=?`, round.ID, formatTime(round.CreatedAt)); err != nil {
+				return nil, fmt.Errorf("round %d: %w", round.ID, err)
+			}
+			res.SkippedRounds++
+			continue
+		}
+		inserted[round.ID] = true
+		res.Rounds++
+	}
+	if res.Revisions, err = s.insertRevisions(ctx, tx, d.Revisions); err != nil {
+		return nil, err
+	}
+	res.SkippedRevisions = len(d.Revisions) - res.Revisions
+	for _, a := range d.Anonymous {
+		if !inserted[a.RoundID] {
+			continue
+		}
+		if err := insertAnonymous(ctx, tx, a.RoundID, a); err != nil {
+			return nil, fmt.Errorf("anonymous response of round %d: %w", a.RoundID, err)
+		}
+		res.Anonymous++
+	}
+	for _, c := range d.Consents {
+		r, err := tx.ExecContext(ctx, `
+INSERT INTO consents (participant_code, version, accepted_at) VALUES (?,?,?)
+ON CONFLICT (participant_code, version) DO NOTHING`, c.ParticipantCode, c.Version, formatTime(c.AcceptedAt))
+		if err != nil {
+			return nil, fmt.Errorf("consent of %s: %w", c.ParticipantCode, err)
+		}
+		n, err := r.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		res.Consents += int(n)
+	}
+	return res, tx.Commit()
+}
+
+// sameRow checks that the row a skipped insert collided with is the one
+// being inserted: query selects it by ID and identifying columns.
+func sameRow(ctx context.Context, tx *sql.Tx, query string, args ...any) error {
+	var one int
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ErrRestoreConflict
+	}
+	return err
+}
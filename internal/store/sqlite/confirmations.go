@@ -0,0 +1,28 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+)
+
+func (s *Store) UseConfirmation(ctx context.Context, jti string, expiresAt time.Time) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM used_confirmations WHERE expires_at < ?`, formatTime(time.Now())); err != nil {
+		return false, err
+	}
+	res, err := tx.ExecContext(ctx, `
+INSERT INTO used_confirmations (jti, expires_at) VALUES (?,?)
+ON CONFLICT (jti) DO NOTHING`, jti, formatTime(expiresAt))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, tx.Commit()
+}
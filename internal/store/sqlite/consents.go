@@ -0,0 +1,85 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"opslab-survey/internal/models"
+)
+
+func (s *Store) RecordConsent(ctx context.Context, participantCode string, version int) (*models.Consent, error) {
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO consents (participant_code, version, accepted_at) VALUES (?,?,?)
+ON CONFLICT (participant_code, version) DO NOTHING`, participantCode, version, formatTime(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	return s.ConsentFor(ctx, participantCode, version)
+}
+
+func (s *Store) ConsentFor(ctx context.Context, participantCode string, version int) (*models.Consent, error) {
+	c := models.Consent{ParticipantCode: participantCode, Version: version}
+	err := s.db.QueryRowContext(ctx, `
+SELECT accepted_at FROM consents WHERE participant_code=? AND version=?`, participantCode, version).Scan(timeScanner{&c.AcceptedAt})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (s *Store) ListConsents(ctx context.Context) ([]models.Consent, error) {
+	return s.queryConsents(ctx, `
+SELECT participant_code, version, accepted_at FROM consents ORDER BY participant_code, version`)
+}
+
+func (s *Store) ConsentsOf(ctx context.Context, participantCode string) ([]models.Consent, error) {
+	return s.queryConsents(ctx, `
+SELECT participant_code, version, accepted_at FROM consents WHERE participant_code=? ORDER BY version`, participantCode)
+}
+
+func (s *Store) queryConsents(ctx context.Context, query string, args ...any) ([]models.Consent, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.Consent
+	for rows.Next() {
+		var c models.Consent
+		if err := rows.Scan(&c.ParticipantCode, &c.Version, timeScanner{&c.AcceptedAt}); err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+func (s *Store) NoticeVersion(ctx context.Context, digest string) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	var (
+		version int
+		latest  string
+	)
+	err = tx.QueryRowContext(ctx, `SELECT version, digest FROM consent_notices ORDER BY version DESC LIMIT 1`).Scan(&version, &latest)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	if version > 0 && latest == digest {
+		return version, nil
+	}
+	version++
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO consent_notices (version, digest, created_at) VALUES (?,?,?)`, version, digest, formatTime(time.Now())); err != nil {
+		return 0, err
+	}
+	return version, tx.Commit()
+}
@@ -0,0 +1,61 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"opslab-survey/internal/envelope"
+	"opslab-survey/internal/models"
+)
+
+func (s *Store) SaveDraft(ctx context.Context, d models.Draft) error {
+	answersJSON, rankingsJSON, err := s.encode(d.Answers, d.Rankings, envelope.LabelDraft)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+INSERT INTO drafts (round_id, participant_code, answers, rankings, progress, updated_at)
+VALUES (?,?,?,?,?,?)
+ON CONFLICT (round_id, participant_code)
+DO UPDATE SET answers=excluded.answers, rankings=excluded.rankings, progress=excluded.progress, updated_at=excluded.updated_at`,
+		d.RoundID, d.ParticipantCode, answersJSON, rankingsJSON, d.Progress, formatTime(time.Now()))
+	return err
+}
+
+func (s *Store) DraftFor(ctx context.Context, roundID int64, participantCode string) (*models.Draft, error) {
+	var d models.Draft
+	var answersJSON, rankingsJSON []byte
+	err := s.db.QueryRowContext(ctx, `
+SELECT round_id, participant_code, answers, rankings, progress, updated_at
+FROM drafts WHERE round_id=? AND participant_code=?`, roundID, participantCode).
+		Scan(&d.RoundID, &d.ParticipantCode, &answersJSON, &rankingsJSON, &d.Progress, timeScanner{&d.UpdatedAt})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.decode(answersJSON, rankingsJSON, envelope.LabelDraft, &d.Answers, &d.Rankings); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (s *Store) DraftProgress(ctx context.Context, roundID int64) (map[string]models.Draft, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT participant_code, progress, updated_at FROM drafts WHERE round_id=?`, roundID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := map[string]models.Draft{}
+	for rows.Next() {
+		d := models.Draft{RoundID: roundID}
+		if err := rows.Scan(&d.ParticipantCode, &d.Progress, timeScanner{&d.UpdatedAt}); err != nil {
+			return nil, err
+		}
+		res[d.ParticipantCode] = d
+	}
+	return res, rows.Err()
+}
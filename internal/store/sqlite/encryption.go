@@ -0,0 +1,99 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"opslab-survey/internal/envelope"
+	"opslab-survey/internal/models"
+)
+
+// SetKeyring makes the store seal answer payloads with k.
+func (s *Store) SetKeyring(k *envelope.Keyring) {
+	s.keys = k
+}
+
+// RotateKeys re-seals every stale value in one transaction and drops the
+// plaintext copies of text answers from the answers table.
+func (s *Store) RotateKeys(ctx context.Context) (*models.RotationResult, error) {
+	if s.keys == nil {
+		return nil, errors.New("rotate keys: no encryption key configured")
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res := &models.RotationResult{KeyID: s.keys.KeyID()}
+	for _, c := range []struct {
+		table, id, column, label string
+		count                    *int
+	}{
+		{"response_revisions", "id", "answers", envelope.LabelAnswers, &res.Revisions},
+		{"response_revisions", "id", "rankings", envelope.LabelRankings, &res.Rankings},
+		{"drafts", "rowid", "answers", envelope.LabelDraft, &res.Drafts},
+		{"drafts", "rowid", "rankings", envelope.LabelDraftRankings, &res.Rankings},
+		{"reset_snapshots", "id", "data", envelope.LabelSnapshot, &res.Snapshots},
+	} {
+		n, err := s.reseal(ctx, tx, c.table, c.id, c.column, c.label)
+		if err != nil {
+			return nil, err
+		}
+		*c.count += n
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE answers SET text_value=NULL WHERE text_value IS NOT NULL`); err != nil {
+		return nil, err
+	}
+	return res, tx.Commit()
+}
+
+// reseal seals every value of column that is not sealed with the current
+// master key yet.
+func (s *Store) reseal(ctx context.Context, tx *sql.Tx, table, idColumn, column, label string) (int, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT `+idColumn+`, `+column+` FROM `+table)
+	if err != nil {
+		return 0, err
+	}
+	type stale struct {
+		id    int64
+		value []byte
+	}
+	var todo []stale
+	for rows.Next() {
+		var v stale
+		if err := rows.Scan(&v.id, &v.value); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if !s.keys.Current(v.value) {
+			todo = append(todo, v)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for _, v := range todo {
+		plain, err := s.keys.Open(v.value, label)
+		if err != nil {
+			return 0, fmt.Errorf("%s %d: %w", table, v.id, err)
+		}
+		sealed, err := s.keys.Seal(plain, label)
+		if err != nil {
+			return 0, err
+		}
+		// Keep the storage class of the column: text for payloads, blob
+		// for snapshots.
+		var value any = sealed
+		if label != envelope.LabelSnapshot {
+			value = string(sealed)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE `+table+` SET `+column+`=? WHERE `+idColumn+`=?`, value, v.id); err != nil {
+			return 0, fmt.Errorf("%s %d: %w", table, v.id, err)
+		}
+	}
+	return len(todo), nil
+}
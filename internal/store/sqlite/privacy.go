@@ -0,0 +1,152 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"opslab-survey/internal/envelope"
+	"opslab-survey/internal/models"
+)
+
+// EraseParticipant deletes what the participant authored and rewrites the
+// revisions and snapshots that referred to them in one transaction.
+// Rewritten revisions get their answers and ranking positions rebuilt.
+func (s *Store) EraseParticipant(ctx context.Context, e models.Erasure) (*models.ErasureResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res := &models.ErasureResult{}
+	for _, d := range []struct {
+		table string
+		count *int
+	}{
+		{"response_revisions", &res.Revisions},
+		{"drafts", &res.Drafts},
+		{"deadline_extensions", &res.Extensions},
+		{"reminders", &res.Reminders},
+	} {
+		if *d.count, err = execCount(ctx, tx, `DELETE FROM `+d.table+` WHERE participant_code=?`, e.ParticipantCode); err != nil {
+			return nil, fmt.Errorf("erase %s: %w", d.table, err)
+		}
+	}
+
+	for _, rev := range e.Revisions {
+		answersJSON, rankingsJSON, err := s.encode(rev.Answers, rev.Rankings, envelope.LabelAnswers)
+		if err != nil {
+			return nil, err
+		}
+		n, err := execCount(ctx, tx, `UPDATE response_revisions SET answers=?, rankings=? WHERE id=?`, answersJSON, rankingsJSON, rev.ID)
+		if err != nil {
+			return nil, fmt.Errorf("revision %d: %w", rev.ID, err)
+		}
+		if n == 0 {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM answers WHERE response_id=?`, rev.ID); err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM ranking_positions WHERE response_id=?`, rev.ID); err != nil {
+			return nil, err
+		}
+		if err := s.insertNormalized(ctx, tx, rev.ID, rev.Answers, rev.Rankings); err != nil {
+			return nil, fmt.Errorf("revision %d: %w", rev.ID, err)
+		}
+		res.Pseudonymized++
+	}
+
+	for _, snap := range e.Snapshots {
+		data, err := s.keys.Seal(snap.Data, envelope.LabelSnapshot)
+		if err != nil {
+			return nil, err
+		}
+		n, err := execCount(ctx, tx, `UPDATE reset_snapshots SET data=?, revisions=? WHERE id=?`, data, snap.Revisions, snap.ID)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot %d: %w", snap.ID, err)
+		}
+		res.Snapshots += n
+	}
+	return res, tx.Commit()
+}
+
+// AnonymizeRound replaces the participant data of a round with anonymous
+// responses in one transaction.
+func (s *Store) AnonymizeRound(ctx context.Context, roundID int64, responses []models.AnonymousResponse) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRowContext(ctx, `SELECT 1 FROM rounds WHERE id=?`, roundID).Scan(&exists); err != nil {
+		return err
+	}
+	for _, table := range []string{"response_revisions", "drafts", "deadline_extensions", "reminders"} {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE round_id=?`, roundID); err != nil {
+			return fmt.Errorf("anonymize %s: %w", table, err)
+		}
+	}
+	for _, a := range responses {
+		if err := insertAnonymous(ctx, tx, roundID, a); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func insertAnonymous(ctx context.Context, tx *sql.Tx, roundID int64, a models.AnonymousResponse) error {
+	answersJSON, err := json.Marshal(a.Answers)
+	if err != nil {
+		return fmt.Errorf("marshal answers: %w", err)
+	}
+	rankingsJSON, err := json.Marshal(a.Rankings)
+	if err != nil {
+		return fmt.Errorf("marshal rankings: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+INSERT INTO anonymous_responses (round_id, respondent, answers, rankings)
+VALUES (?,?,?,?)`, roundID, a.Respondent, string(answersJSON), string(rankingsJSON))
+	return err
+}
+
+// ListAnonymousResponses returns the anonymous responses of a round in the
+// order they were stored.
+func (s *Store) ListAnonymousResponses(ctx context.Context, roundID int64) ([]models.AnonymousResponse, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT round_id, respondent, answers, rankings FROM anonymous_responses
+WHERE round_id=? ORDER BY id`, roundID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.AnonymousResponse
+	for rows.Next() {
+		var a models.AnonymousResponse
+		var answersJSON, rankingsJSON []byte
+		if err := rows.Scan(&a.RoundID, &a.Respondent, &answersJSON, &rankingsJSON); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(answersJSON, &a.Answers); err != nil {
+			return nil, fmt.Errorf("unmarshal answers: %w", err)
+		}
+		if err := json.Unmarshal(rankingsJSON, &a.Rankings); err != nil {
+			return nil, fmt.Errorf("unmarshal rankings: %w", err)
+		}
+		res = append(res, a)
+	}
+	return res, rows.Err()
+}
+
+func execCount(ctx context.Context, tx *sql.Tx, query string, args ...any) (int, error) {
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,47 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"opslab-survey/internal/models"
+)
+
+func (s *Store) RecordReminder(ctx context.Context, r models.Reminder) error {
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO reminders (round_id, participant_code, kind, transport, status, error, sent_at)
+VALUES (?,?,?,?,?,?,?)`, r.RoundID, r.ParticipantCode, r.Kind, r.Transport, r.Status, r.Error, formatTime(time.Now()))
+	return err
+}
+
+func (s *Store) ReminderHistory(ctx context.Context, roundID int64, participantCode, kind string) (models.ReminderHistory, error) {
+	var h models.ReminderHistory
+	var lastFailure *time.Time
+	err := s.db.QueryRowContext(ctx, `
+SELECT coalesce(max(status='sent'), 0), coalesce(sum(status='failed'), 0), max(CASE WHEN status='failed' THEN sent_at END)
+FROM reminders WHERE round_id=? AND participant_code=? AND kind=?`,
+		roundID, participantCode, kind).Scan(&h.Sent, &h.Failures, nullTimeScanner{&lastFailure})
+	if lastFailure != nil {
+		h.LastFailure = *lastFailure
+	}
+	return h, err
+}
+
+func (s *Store) ListReminders(ctx context.Context, roundID int64) ([]models.Reminder, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, round_id, participant_code, kind, transport, status, error, sent_at
+FROM reminders WHERE round_id=? ORDER BY sent_at desc`, roundID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.Reminder
+	for rows.Next() {
+		var r models.Reminder
+		if err := rows.Scan(&r.ID, &r.RoundID, &r.ParticipantCode, &r.Kind, &r.Transport, &r.Status, &r.Error, timeScanner{&r.SentAt}); err != nil {
+			return nil, err
+		}
+		res = append(res, r)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,130 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"opslab-survey/internal/models"
+)
+
+const roundColumns = `id, title, state, opens_at, closes_at, created_at, updated_at`
+
+func scanRound(row scanner) (*models.Round, error) {
+	var r models.Round
+	if err := row.Scan(&r.ID, &r.Title, &r.State, nullTimeScanner{&r.OpensAt}, nullTimeScanner{&r.ClosesAt}, timeScanner{&r.CreatedAt}, timeScanner{&r.UpdatedAt}); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// CurrentRound returns the round picked by models.CurrentRound, or nil when
+// every round is archived.
+func (s *Store) CurrentRound(ctx context.Context) (*models.Round, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+roundColumns+` FROM rounds WHERE state <> 'archived'`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var rounds []models.Round
+	for rows.Next() {
+		r, err := scanRound(rows)
+		if err != nil {
+			return nil, err
+		}
+		rounds = append(rounds, *r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return models.CurrentRound(rounds, time.Now()), nil
+}
+
+func (s *Store) RoundByID(ctx context.Context, id int64) (*models.Round, error) {
+	return scanRound(s.db.QueryRowContext(ctx, `SELECT `+roundColumns+` FROM rounds WHERE id=?`, id))
+}
+
+func (s *Store) ListRounds(ctx context.Context) ([]models.Round, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+roundColumns+` FROM rounds ORDER BY id desc`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.Round
+	for rows.Next() {
+		r, err := scanRound(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, *r)
+	}
+	return res, rows.Err()
+}
+
+func (s *Store) CreateRound(ctx context.Context, round models.Round) (*models.Round, error) {
+	now := formatTime(time.Now())
+	return scanRound(s.db.QueryRowContext(ctx, `
+INSERT INTO rounds (title, state, opens_at, closes_at, created_at, updated_at)
+VALUES (?,?,?,?,?,?)
+RETURNING `+roundColumns, round.Title, round.State, formatNullTime(round.OpensAt), formatNullTime(round.ClosesAt), now, now))
+}
+
+func (s *Store) UpdateRound(ctx context.Context, round models.Round) (*models.Round, error) {
+	return scanRound(s.db.QueryRowContext(ctx, `
+UPDATE rounds SET title=?, state=?, opens_at=?, closes_at=?, updated_at=?
+WHERE id=?
+RETURNING `+roundColumns, round.Title, round.State, formatNullTime(round.OpensAt), formatNullTime(round.ClosesAt), formatTime(time.Now()), round.ID))
+}
+
+func (s *Store) ExtendDeadline(ctx context.Context, ext models.DeadlineExtension) error {
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO deadline_extensions (round_id, participant_code, closes_at, reason, created_at)
+VALUES (?,?,?,?,?)
+ON CONFLICT (round_id, participant_code)
+DO UPDATE SET closes_at=excluded.closes_at, reason=excluded.reason, created_at=excluded.created_at`,
+		ext.RoundID, ext.ParticipantCode, formatTime(ext.ClosesAt), ext.Reason, formatTime(time.Now()))
+	return err
+}
+
+func (s *Store) DeadlineExtension(ctx context.Context, roundID int64, participantCode string) (*time.Time, error) {
+	var closesAt time.Time
+	err := s.db.QueryRowContext(ctx, `SELECT closes_at FROM deadline_extensions WHERE round_id=? AND participant_code=?`, roundID, participantCode).
+		Scan(timeScanner{&closesAt})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &closesAt, nil
+}
+
+func (s *Store) ListDeadlineExtensions(ctx context.Context, roundID int64) ([]models.DeadlineExtension, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT round_id, participant_code, closes_at, reason, created_at
+FROM deadline_extensions WHERE round_id=? ORDER BY participant_code`, roundID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.DeadlineExtension
+	for rows.Next() {
+		var e models.DeadlineExtension
+		if err := rows.Scan(&e.RoundID, &e.ParticipantCode, timeScanner{&e.ClosesAt}, &e.Reason, timeScanner{&e.CreatedAt}); err != nil {
+			return nil, err
+		}
+		res = append(res, e)
+	}
+	return res, rows.Err()
+}
+
+// DeleteRound removes a round; everything that belongs to it goes with it.
+func (s *Store) DeleteRound(ctx context.Context, id int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM rounds WHERE id=?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
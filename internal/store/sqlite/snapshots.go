@@ -0,0 +1,130 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"opslab-survey/internal/envelope"
+	"opslab-survey/internal/models"
+)
+
+// ListSnapshots returns every snapshot without its data, newest first.
+func (s *Store) ListSnapshots(ctx context.Context) ([]models.Snapshot, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, created_at, created_by, scope, revisions, length(data), restored_at
+FROM reset_snapshots ORDER BY id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.Snapshot
+	for rows.Next() {
+		var snap models.Snapshot
+		var scope []byte
+		if err := rows.Scan(&snap.ID, timeScanner{&snap.CreatedAt}, &snap.CreatedBy, &scope, &snap.Revisions, &snap.Size, nullTimeScanner{&snap.RestoredAt}); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(scope, &snap.Scope); err != nil {
+			return nil, fmt.Errorf("unmarshal scope: %w", err)
+		}
+		res = append(res, snap)
+	}
+	return res, rows.Err()
+}
+
+func (s *Store) SnapshotByID(ctx context.Context, id int64) (*models.Snapshot, error) {
+	var snap models.Snapshot
+	var scope []byte
+	err := s.db.QueryRowContext(ctx, `
+SELECT id, created_at, created_by, scope, revisions, data, restored_at
+FROM reset_snapshots WHERE id=?`, id).Scan(&snap.ID, timeScanner{&snap.CreatedAt}, &snap.CreatedBy, &scope, &snap.Revisions, &snap.Data, nullTimeScanner{&snap.RestoredAt})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(scope, &snap.Scope); err != nil {
+		return nil, fmt.Errorf("unmarshal scope: %w", err)
+	}
+	snap.Size = len(snap.Data)
+	if snap.Data, err = s.keys.Open(snap.Data, envelope.LabelSnapshot); err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+// RestoreSnapshot inserts the revisions that are not stored any more,
+// keeping their IDs, and marks the snapshot restored.
+func (s *Store) RestoreSnapshot(ctx context.Context, id int64, revisions []models.ResponseRevision) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE reset_snapshots SET restored_at=? WHERE id=?`, formatTime(time.Now()), id)
+	if err != nil {
+		return 0, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return 0, err
+	} else if n == 0 {
+		return 0, sql.ErrNoRows
+	}
+	n, err := s.insertRevisions(ctx, tx, revisions)
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+// insertRevisions writes revisions with their original IDs and timestamps,
+// skipping the ones that are already stored. An ID taken by another
+// revision is an ErrRestoreConflict.
+func (s *Store) insertRevisions(ctx context.Context, tx *sql.Tx, revisions []models.ResponseRevision) (int, error) {
+	var n int
+	for _, rev := range revisions {
+		answersJSON, rankingsJSON, err := s.encode(rev.Answers, rev.Rankings, envelope.LabelAnswers)
+		if err != nil {
+			return 0, err
+		}
+		res, err := tx.ExecContext(ctx, `
+INSERT INTO response_revisions (id, round_id, participant_code, answers, rankings, is_test_data, session_id, submitted_at)
+VALUES (?,?,?,?,?,?,?,?)
+ON CONFLICT (id) DO NOTHING`,
+			rev.ID, rev.RoundID, rev.ParticipantCode, answersJSON, rankingsJSON, rev.IsTestData, rev.SessionID, formatTime(rev.SubmittedAt))
+		if err != nil {
+			return 0, fmt.Errorf("revision %d: %w", rev.ID, err)
+		}
+		if inserted, err := res.RowsAffected(); err != nil {
+			return 0, err
+		} else if inserted == 0 {
+			err := sameRow(ctx, tx, `SELECT 1 FROM response_revisions WHERE id=? AND round_id=? AND participant_code=? AND submitted_at=?`,
+				rev.ID, rev.RoundID, rev.ParticipantCode, formatTime(rev.SubmittedAt))
+			if err != nil {
+				return 0, fmt.Errorf("revision %d: %w", rev.ID, err)
+			}
+			continue
+		}
+		if err := s.insertNormalized(ctx, tx, rev.ID, rev.Answers, rev.Rankings); err != nil {
+			return 0, fmt.Errorf("revision %d: %w", rev.ID, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// DeleteSnapshots removes snapshots created before the given time.
+func (s *Store) DeleteSnapshots(ctx context.Context, before time.Time) (int, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM reset_snapshots WHERE created_at < ?`, formatTime(before))
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
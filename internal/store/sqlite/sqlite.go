@@ -0,0 +1,705 @@
+// Package sqlite stores survey data in an embedded SQLite database using the
+// pure-Go modernc.org/sqlite driver, so the server runs as a single binary
+// without Postgres.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"opslab-survey/internal/envelope"
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/seed"
+
+	_ "modernc.org/sqlite"
+)
+
+// Store keeps survey data in one SQLite file.
+type Store struct {
+	db   *sql.DB
+	keys *envelope.Keyring
+}
+
+// Open opens (creating if needed) the database file at path. ":memory:"
+// gives a throwaway database.
+func Open(ctx context.Context, path string) (*Store, error) {
+	if path == "" {
+		return nil, fmt.Errorf("sqlite: empty database path")
+	}
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	db, err := sql.Open("sqlite", path+sep+"_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+	// SQLite allows a single writer; one connection also keeps :memory:
+	// databases from being split across connections.
+	db.SetMaxOpenConns(1)
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() {
+	s.db.Close()
+}
+
+// EnsureSchema sets up tables and seeds known participants.
+func (s *Store) EnsureSchema(ctx context.Context, participants []models.Participant) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS participants (
+	code text primary key,
+	name text not null,
+	email text not null unique,
+	is_admin integer not null default 0
+);
+
+CREATE TABLE IF NOT EXISTS rounds (
+	id integer primary key autoincrement,
+	title text not null,
+	state text not null default 'draft' check (state in ('draft','open','closed','archived')),
+	opens_at text,
+	closes_at text,
+	created_at text not null,
+	updated_at text not null
+);
+
+CREATE TABLE IF NOT EXISTS deadline_extensions (
+	round_id integer not null references rounds(id) on delete cascade,
+	participant_code text not null references participants(code) on delete cascade,
+	closes_at text not null,
+	reason text not null default '',
+	created_at text not null,
+	primary key (round_id, participant_code)
+);
+
+CREATE TABLE IF NOT EXISTS response_revisions (
+	id integer primary key autoincrement,
+	round_id integer not null references rounds(id) on delete cascade,
+	participant_code text not null references participants(code) on delete cascade,
+	answers text not null,
+	rankings text not null,
+	is_test_data integer not null default 0,
+	session_id text not null default '',
+	submitted_at text not null
+);
+
+CREATE INDEX IF NOT EXISTS response_revisions_participant_idx ON response_revisions(participant_code, id);
+CREATE INDEX IF NOT EXISTS response_revisions_round_idx ON response_revisions(round_id, participant_code, id);
+
+CREATE TABLE IF NOT EXISTS answers (
+	response_id integer not null references response_revisions(id) on delete cascade,
+	question_id text not null,
+	peer_code text not null default '',
+	numeric_value real,
+	text_value text,
+	choice_value text,
+	primary key (response_id, question_id, peer_code)
+);
+
+CREATE INDEX IF NOT EXISTS answers_question_idx ON answers(question_id, peer_code);
+
+CREATE TABLE IF NOT EXISTS ranking_positions (
+	response_id integer not null references response_revisions(id) on delete cascade,
+	criterion text not null,
+	ratee text not null,
+	position integer not null,
+	primary key (response_id, criterion, ratee)
+);
+
+CREATE INDEX IF NOT EXISTS ranking_positions_ratee_idx ON ranking_positions(criterion, ratee);
+
+CREATE TABLE IF NOT EXISTS drafts (
+	round_id integer not null references rounds(id) on delete cascade,
+	participant_code text not null references participants(code) on delete cascade,
+	answers text not null,
+	rankings text not null,
+	progress integer not null default 0,
+	updated_at text not null,
+	primary key (round_id, participant_code)
+);
+
+CREATE TABLE IF NOT EXISTS reminders (
+	id integer primary key autoincrement,
+	round_id integer not null references rounds(id) on delete cascade,
+	participant_code text not null references participants(code) on delete cascade,
+	kind text not null,
+	transport text not null,
+	status text not null,
+	error text not null default '',
+	sent_at text not null
+);
+
+CREATE INDEX IF NOT EXISTS reminders_round_idx ON reminders(round_id, participant_code, kind);
+
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+	id integer primary key autoincrement,
+	url text not null,
+	secret text not null,
+	events text not null default '[]',
+	active integer not null default 1,
+	created_at text not null
+);
+
+CREATE TABLE IF NOT EXISTS webhook_outbox (
+	id integer primary key autoincrement,
+	endpoint_id integer not null references webhook_endpoints(id) on delete cascade,
+	event text not null,
+	payload text not null,
+	status text not null default 'pending',
+	attempts integer not null default 0,
+	next_attempt_at text not null,
+	last_error text not null default '',
+	created_at text not null,
+	delivered_at text
+);
+
+CREATE INDEX IF NOT EXISTS webhook_outbox_due_idx ON webhook_outbox(next_attempt_at) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id integer primary key autoincrement,
+	outbox_id integer not null references webhook_outbox(id) on delete cascade,
+	endpoint_id integer not null references webhook_endpoints(id) on delete cascade,
+	event text not null,
+	attempt integer not null,
+	status_code integer not null default 0,
+	error text not null default '',
+	duration_ms integer not null default 0,
+	outcome text not null,
+	created_at text not null
+);
+
+-- Events that go out at most once, such as a round closing by schedule.
+CREATE TABLE IF NOT EXISTS webhook_event_keys (
+	key text primary key,
+	created_at text not null
+);
+
+-- Confirmation tokens that were used, kept until they expire.
+CREATE TABLE IF NOT EXISTS used_confirmations (
+	jti text primary key,
+	expires_at text not null
+);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+	id integer primary key autoincrement,
+	occurred_at text not null,
+	actor text not null default '',
+	action text not null,
+	target text not null default '',
+	ip text not null default '',
+	user_agent text not null default '',
+	outcome text not null,
+	status integer not null default 0,
+	prev_hash text not null,
+	hash text not null
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON audit_log(actor, id);
+CREATE INDEX IF NOT EXISTS audit_log_action_idx ON audit_log(action, id);
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+	SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN
+	SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+CREATE TABLE IF NOT EXISTS reset_snapshots (
+	id integer primary key autoincrement,
+	created_at text not null,
+	created_by text not null,
+	scope text not null,
+	revisions integer not null,
+	data blob not null,
+	restored_at text
+);
+
+CREATE TABLE IF NOT EXISTS anonymous_responses (
+	id integer primary key autoincrement,
+	round_id integer not null references rounds(id) on delete cascade,
+	respondent text not null,
+	answers text not null,
+	rankings text not null
+);
+
+CREATE INDEX IF NOT EXISTS anonymous_responses_round_idx ON anonymous_responses(round_id, id);
+
+CREATE TABLE IF NOT EXISTS consents (
+	participant_code text not null references participants(code) on delete cascade,
+	version integer not null,
+	accepted_at text not null,
+	primary key (participant_code, version)
+);
+
+-- The texts of the privacy notice by version, as a SHA-256 digest.
+CREATE TABLE IF NOT EXISTS consent_notices (
+	version integer primary key,
+	digest text not null,
+	created_at text not null
+);
+
+CREATE VIEW IF NOT EXISTS responses AS
+SELECT
+	id,
+	participant_code,
+	answers,
+	rankings,
+	is_test_data,
+	session_id,
+	first_submitted_at AS submitted_at,
+	submitted_at AS updated_at,
+	round_id
+FROM (
+	SELECT *,
+		min(submitted_at) OVER (PARTITION BY round_id, participant_code) AS first_submitted_at,
+		row_number() OVER (PARTITION BY round_id, participant_code ORDER BY id DESC) AS rn
+	FROM response_revisions
+)
+WHERE rn = 1;
+`)
+	if err != nil {
+		return fmt.Errorf("create tables: %w", err)
+	}
+
+	now := formatTime(time.Now())
+	_, err = tx.ExecContext(ctx, `
+INSERT INTO rounds (title, state, created_at, updated_at)
+SELECT 'Раунд 1', 'open', ?, ? WHERE NOT EXISTS (SELECT 1 FROM rounds)`, now, now)
+	if err != nil {
+		return fmt.Errorf("create first round: %w", err)
+	}
+
+	if err := s.backfillNormalized(ctx, tx); err != nil {
+		return fmt.Errorf("backfill answers: %w", err)
+	}
+
+	for _, p := range participants {
+		_, err = tx.ExecContext(ctx, `
+INSERT INTO participants (code, name, email, is_admin)
+VALUES (?,?,?,?)
+ON CONFLICT (code) DO UPDATE SET name=excluded.name, email=excluded.email, is_admin=excluded.is_admin`,
+			p.Code, p.Name, p.Email, p.IsAdmin)
+		if err != nil {
+			return fmt.Errorf("seed participant %s: %w", p.Code, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *Store) ParticipantByEmailAndCode(ctx context.Context, email, code string) (*models.Participant, error) {
+	var p models.Participant
+	err := s.db.QueryRowContext(ctx, `SELECT code, name, email, is_admin FROM participants WHERE email=? AND code=?`, email, code).
+		Scan(&p.Code, &p.Name, &p.Email, &p.IsAdmin)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *Store) ListParticipants(ctx context.Context) ([]models.Participant, error) {
+	return s.queryParticipants(ctx, `SELECT code, name, email, is_admin FROM participants ORDER BY name asc`)
+}
+
+// PendingParticipants lists non-admin participants without any submission in the round.
+func (s *Store) PendingParticipants(ctx context.Context, roundID int64) ([]models.Participant, error) {
+	return s.queryParticipants(ctx, `
+SELECT code, name, email, is_admin FROM participants p
+WHERE NOT p.is_admin
+  AND NOT EXISTS (SELECT 1 FROM response_revisions r WHERE r.round_id=? AND r.participant_code=p.code)
+ORDER BY name asc`, roundID)
+}
+
+func (s *Store) queryParticipants(ctx context.Context, query string, args ...any) ([]models.Participant, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.Participant
+	for rows.Next() {
+		var p models.Participant
+		if err := rows.Scan(&p.Code, &p.Name, &p.Email, &p.IsAdmin); err != nil {
+			return nil, err
+		}
+		res = append(res, p)
+	}
+	return res, rows.Err()
+}
+
+// UpsertResponse appends a new revision for the participant in the given
+// round. The responses view always exposes the latest revision.
+func (s *Store) UpsertResponse(ctx context.Context, roundID int64, participantCode string, answers []models.AnswerPayload, rankings []models.RankingPayload, isTest bool, sessionID string) error {
+	answersJSON, rankingsJSON, err := s.encode(answers, rankings, envelope.LabelAnswers)
+	if err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+INSERT INTO response_revisions (round_id, participant_code, answers, rankings, is_test_data, session_id, submitted_at)
+VALUES (?,?,?,?,?,?,?)`,
+		roundID, participantCode, answersJSON, rankingsJSON, isTest, sessionID, formatTime(time.Now()))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if err := s.insertNormalized(ctx, tx, id, answers, rankings); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertNormalized writes the answers and ranking_positions rows of one
+// revision. With encryption on, text answers are left out of the answers
+// table.
+func (s *Store) insertNormalized(ctx context.Context, tx *sql.Tx, responseID int64, answers []models.AnswerPayload, rankings []models.RankingPayload) error {
+	rows, positions := seed.Normalize(answers, rankings)
+	for _, a := range rows {
+		if s.keys != nil {
+			// Free text stays only in the sealed payload.
+			a.TextValue = nil
+		}
+		_, err := tx.ExecContext(ctx, `
+INSERT INTO answers (response_id, question_id, peer_code, numeric_value, text_value, choice_value)
+VALUES (?,?,?,?,?,?)`, responseID, a.QuestionID, a.PeerCode, a.NumericValue, a.TextValue, a.ChoiceValue)
+		if err != nil {
+			return fmt.Errorf("insert answer %s: %w", a.QuestionID, err)
+		}
+	}
+	for _, p := range positions {
+		_, err := tx.ExecContext(ctx, `
+INSERT INTO ranking_positions (response_id, criterion, ratee, position)
+VALUES (?,?,?,?)`, responseID, p.Criterion, p.Ratee, p.Position)
+		if err != nil {
+			return fmt.Errorf("insert ranking position: %w", err)
+		}
+	}
+	return nil
+}
+
+// backfillNormalized fills the answers and ranking_positions tables for
+// revisions stored before they existed.
+func (s *Store) backfillNormalized(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `
+SELECT id, answers, rankings FROM response_revisions r
+WHERE NOT EXISTS (SELECT 1 FROM answers a WHERE a.response_id = r.id)
+  AND NOT EXISTS (SELECT 1 FROM ranking_positions p WHERE p.response_id = r.id)
+ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	type pending struct {
+		id       int64
+		answers  []models.AnswerPayload
+		rankings []models.RankingPayload
+	}
+	var todo []pending
+	for rows.Next() {
+		var p pending
+		var answersJSON, rankingsJSON []byte
+		if err := rows.Scan(&p.id, &answersJSON, &rankingsJSON); err != nil {
+			rows.Close()
+			return err
+		}
+		if err := s.decode(answersJSON, rankingsJSON, envelope.LabelAnswers, &p.answers, &p.rankings); err != nil {
+			rows.Close()
+			return fmt.Errorf("revision %d: %w", p.id, err)
+		}
+		todo = append(todo, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, p := range todo {
+		if err := s.insertNormalized(ctx, tx, p.id, p.answers, p.rankings); err != nil {
+			return fmt.Errorf("revision %d: %w", p.id, err)
+		}
+	}
+	return nil
+}
+
+// CountValues aggregates the answers table of the current non-admin responses.
+func (s *Store) CountValues(ctx context.Context, roundID int64) ([]models.ValueCount, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT a.question_id, a.peer_code, a.numeric_value, count(*)
+FROM responses r
+JOIN answers a ON a.response_id = r.id
+JOIN participants p ON p.code = r.participant_code
+WHERE r.round_id=? AND a.numeric_value IS NOT NULL AND NOT p.is_admin
+GROUP BY a.question_id, a.peer_code, a.numeric_value
+ORDER BY a.question_id, a.peer_code, a.numeric_value`, roundID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.ValueCount
+	for rows.Next() {
+		var c models.ValueCount
+		if err := rows.Scan(&c.QuestionID, &c.PeerCode, &c.Value, &c.Count); err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+const responseColumns = `id, participant_code, answers, rankings, is_test_data, submitted_at, updated_at, round_id`
+
+// AllResponses returns the current response of every participant in a round.
+func (s *Store) AllResponses(ctx context.Context, roundID int64) ([]models.ResponseRecord, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+responseColumns+` FROM responses WHERE round_id=? ORDER BY submitted_at desc, id desc`, roundID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.ResponseRecord
+	for rows.Next() {
+		r, err := s.scanResponse(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, *r)
+	}
+	return res, rows.Err()
+}
+
+// ResponseByParticipant returns the current response of one participant, or
+// nil when they have not submitted in the round.
+func (s *Store) ResponseByParticipant(ctx context.Context, roundID int64, participantCode string) (*models.ResponseRecord, error) {
+	r, err := s.scanResponse(s.db.QueryRowContext(ctx, `SELECT `+responseColumns+` FROM responses WHERE round_id=? AND participant_code=?`, roundID, participantCode))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return r, err
+}
+
+// ListResponses returns one page of current responses, keyed by
+// (submitted_at, id) so a cursor stays valid while new responses arrive.
+func (s *Store) ListResponses(ctx context.Context, f models.ResponseFilter) (models.ResponsePage, error) {
+	var where []string
+	var args []any
+	if f.RoundID != 0 {
+		where, args = append(where, "round_id = ?"), append(args, f.RoundID)
+	}
+	if f.IsTest != nil {
+		where, args = append(where, "is_test_data = ?"), append(args, *f.IsTest)
+	}
+	if f.From != nil {
+		where, args = append(where, "submitted_at >= ?"), append(args, formatTime(*f.From))
+	}
+	if f.To != nil {
+		where, args = append(where, "submitted_at < ?"), append(args, formatTime(*f.To))
+	}
+	order, cmp := "desc", "<"
+	if f.Sort == models.SortOldest {
+		order, cmp = "asc", ">"
+	}
+	if f.After != nil {
+		where = append(where, "(submitted_at, id) "+cmp+" (?, ?)")
+		args = append(args, formatTime(f.After.SubmittedAt), f.After.ID)
+	}
+	query := `SELECT ` + responseColumns + ` FROM responses`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY submitted_at ` + order + `, id ` + order
+	if f.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, f.Limit+1)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return models.ResponsePage{}, err
+	}
+	defer rows.Close()
+	page := models.ResponsePage{Items: []models.ResponseRecord{}}
+	for rows.Next() {
+		r, err := s.scanResponse(rows)
+		if err != nil {
+			return models.ResponsePage{}, err
+		}
+		page.Items = append(page.Items, *r)
+	}
+	if err := rows.Err(); err != nil {
+		return models.ResponsePage{}, err
+	}
+	if f.Limit > 0 && len(page.Items) > f.Limit {
+		page.Items = page.Items[:f.Limit]
+		page.NextCursor = models.CursorAfter(page.Items[f.Limit-1]).Encode()
+	}
+	return page, nil
+}
+
+func (s *Store) scanResponse(row scanner) (*models.ResponseRecord, error) {
+	var r models.ResponseRecord
+	var answersJSON, rankingsJSON []byte
+	if err := row.Scan(&r.ID, &r.ParticipantCode, &answersJSON, &rankingsJSON, &r.IsTestData, timeScanner{&r.SubmittedAt}, timeScanner{&r.UpdatedAt}, &r.RoundID); err != nil {
+		return nil, err
+	}
+	if err := s.decode(answersJSON, rankingsJSON, envelope.LabelAnswers, &r.Answers, &r.Rankings); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+const revisionColumns = `id, participant_code, answers, rankings, is_test_data, session_id, submitted_at, round_id`
+
+// ListRevisions returns every revision a participant submitted in a round, oldest first.
+func (s *Store) ListRevisions(ctx context.Context, roundID int64, participantCode string) ([]models.ResponseRevision, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+revisionColumns+` FROM response_revisions WHERE round_id=? AND participant_code=? ORDER BY id asc`, roundID, participantCode)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.ResponseRevision
+	for rows.Next() {
+		rev, err := s.scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, *rev)
+	}
+	return res, rows.Err()
+}
+
+func (s *Store) scanRevision(row scanner) (*models.ResponseRevision, error) {
+	var r models.ResponseRevision
+	var answersJSON, rankingsJSON []byte
+	if err := row.Scan(&r.ID, &r.ParticipantCode, &answersJSON, &rankingsJSON, &r.IsTestData, &r.SessionID, timeScanner{&r.SubmittedAt}, &r.RoundID); err != nil {
+		return nil, err
+	}
+	if err := s.decode(answersJSON, rankingsJSON, envelope.LabelAnswers, &r.Answers, &r.Rankings); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ExportRevisions returns the revisions matching f, oldest first.
+func (s *Store) ExportRevisions(ctx context.Context, f models.RevisionFilter) ([]models.ResponseRevision, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT `+revisionColumns+` FROM response_revisions
+WHERE (?1 = 0 OR round_id = ?1)
+  AND (?2 = '' OR participant_code = ?2)
+  AND (NOT ?3 OR is_test_data)
+  AND (?4 = 0 OR id <= ?4)
+ORDER BY id`, f.RoundID, f.ParticipantCode, f.TestOnly, f.UpToID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.ResponseRevision
+	for rows.Next() {
+		rev, err := s.scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, *rev)
+	}
+	return res, rows.Err()
+}
+
+// ResetResponses stores the snapshot and deletes the listed revisions in one
+// transaction; their answers and ranking positions go with them.
+func (s *Store) ResetResponses(ctx context.Context, snapshot models.Snapshot, revisionIDs []int64) (*models.Snapshot, error) {
+	scope, err := json.Marshal(snapshot.Scope)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := json.Marshal(revisionIDs)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.keys.Seal(snapshot.Data, envelope.LabelSnapshot)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+INSERT INTO reset_snapshots (created_at, created_by, scope, revisions, data)
+VALUES (?,?,?,?,?)
+RETURNING id, created_at`, formatTime(time.Now()), snapshot.CreatedBy, string(scope), snapshot.Revisions, data).Scan(&snapshot.ID, timeScanner{&snapshot.CreatedAt})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM response_revisions WHERE id IN (SELECT value FROM json_each(?))`, string(ids)); err != nil {
+		return nil, err
+	}
+	snapshot.Size = len(data)
+	snapshot.Data = nil
+	return &snapshot, tx.Commit()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// encode marshals a payload, sealing the answers and the rankings, whose
+// comments are free text too, when a keyring is set.
+func (s *Store) encode(answers []models.AnswerPayload, rankings []models.RankingPayload, label string) (string, string, error) {
+	answersJSON, err := json.Marshal(answers)
+	if err != nil {
+		return "", "", fmt.Errorf("marshal answers: %w", err)
+	}
+	rankingsJSON, err := json.Marshal(rankings)
+	if err != nil {
+		return "", "", fmt.Errorf("marshal rankings: %w", err)
+	}
+	if answersJSON, err = s.keys.Seal(answersJSON, label); err != nil {
+		return "", "", err
+	}
+	if rankingsJSON, err = s.keys.Seal(rankingsJSON, envelope.RankingsLabel(label)); err != nil {
+		return "", "", err
+	}
+	return string(answersJSON), string(rankingsJSON), nil
+}
+
+func (s *Store) decode(answersJSON, rankingsJSON []byte, label string, answers *[]models.AnswerPayload, rankings *[]models.RankingPayload) error {
+	answersJSON, err := s.keys.Open(answersJSON, label)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(answersJSON, answers); err != nil {
+		return fmt.Errorf("unmarshal answers: %w", err)
+	}
+	if rankingsJSON, err = s.keys.Open(rankingsJSON, envelope.RankingsLabel(label)); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(rankingsJSON, rankings); err != nil {
+		return fmt.Errorf("unmarshal rankings: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package sqlite
+
+import (
+	"fmt"
+	"time"
+)
+
+// Timestamps are stored as fixed-width UTC text so that string comparison
+// and ORDER BY follow time order.
+const timeLayout = "2006-01-02T15:04:05.000000Z"
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+func formatNullTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return formatTime(*t)
+}
+
+func parseTime(v any) (time.Time, error) {
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return time.Time{}, fmt.Errorf("unexpected timestamp %T", v)
+	}
+	t, err := time.Parse(timeLayout, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.Local(), nil
+}
+
+// timeScanner scans a stored timestamp into dst.
+type timeScanner struct{ dst *time.Time }
+
+func (ts timeScanner) Scan(v any) error {
+	t, err := parseTime(v)
+	if err != nil {
+		return err
+	}
+	*ts.dst = t
+	return nil
+}
+
+// nullTimeScanner scans a nullable timestamp, leaving dst nil for NULL.
+type nullTimeScanner struct{ dst **time.Time }
+
+func (ts nullTimeScanner) Scan(v any) error {
+	if v == nil {
+		*ts.dst = nil
+		return nil
+	}
+	t, err := parseTime(v)
+	if err != nil {
+		return err
+	}
+	*ts.dst = &t
+	return nil
+}
@@ -0,0 +1,230 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"opslab-survey/internal/models"
+)
+
+func (s *Store) CreateWebhookEndpoint(ctx context.Context, e models.WebhookEndpoint) (*models.WebhookEndpoint, error) {
+	if e.Events == nil {
+		e.Events = []string{}
+	}
+	events, err := json.Marshal(e.Events)
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.QueryRowContext(ctx, `
+INSERT INTO webhook_endpoints (url, secret, events, active, created_at)
+VALUES (?,?,?,?,?)
+RETURNING id, created_at`, e.URL, e.Secret, string(events), e.Active, formatTime(time.Now())).Scan(&e.ID, timeScanner{&e.CreatedAt})
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (s *Store) ListWebhookEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, url, secret, events, active, created_at FROM webhook_endpoints ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.WebhookEndpoint
+	for rows.Next() {
+		var e models.WebhookEndpoint
+		var events []byte
+		if err := rows.Scan(&e.ID, &e.URL, &e.Secret, &events, &e.Active, timeScanner{&e.CreatedAt}); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(events, &e.Events); err != nil {
+			return nil, fmt.Errorf("unmarshal events: %w", err)
+		}
+		res = append(res, e)
+	}
+	return res, rows.Err()
+}
+
+// DeleteWebhookEndpoint removes an endpoint together with its outbox and delivery log.
+func (s *Store) DeleteWebhookEndpoint(ctx context.Context, id int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM webhook_endpoints WHERE id=?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// EnqueueWebhook adds an outbox entry for every active endpoint subscribed to
+// the event. An endpoint with no event filter receives everything.
+func (s *Store) EnqueueWebhook(ctx context.Context, event string, payload []byte) (int64, error) {
+	endpoints, err := s.ListWebhookEndpoints(ctx)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	n, err := enqueueWebhook(ctx, tx, endpoints, event, payload)
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+// EnqueueWebhookOnce enqueues the event unless key was recorded before.
+func (s *Store) EnqueueWebhookOnce(ctx context.Context, key, event string, payload []byte) (int64, bool, error) {
+	endpoints, err := s.ListWebhookEndpoints(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+	res, err := tx.ExecContext(ctx, `INSERT INTO webhook_event_keys (key, created_at) VALUES (?,?) ON CONFLICT (key) DO NOTHING`, key, formatTime(time.Now()))
+	if err != nil {
+		return 0, false, err
+	}
+	if added, err := res.RowsAffected(); err != nil || added == 0 {
+		return 0, false, err
+	}
+	n, err := enqueueWebhook(ctx, tx, endpoints, event, payload)
+	if err != nil {
+		return 0, false, err
+	}
+	return n, true, tx.Commit()
+}
+
+func enqueueWebhook(ctx context.Context, tx *sql.Tx, endpoints []models.WebhookEndpoint, event string, payload []byte) (int64, error) {
+	now := formatTime(time.Now())
+	var n int64
+	for _, e := range endpoints {
+		if !e.Active || !(len(e.Events) == 0 || slices.Contains(e.Events, event) || slices.Contains(e.Events, "*")) {
+			continue
+		}
+		_, err := tx.ExecContext(ctx, `
+INSERT INTO webhook_outbox (endpoint_id, event, payload, next_attempt_at, created_at)
+VALUES (?,?,?,?,?)`, e.ID, event, string(payload), now, now)
+		if err != nil {
+			return 0, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// ClaimWebhookJobs leases up to limit due outbox entries. The lease pushes
+// next_attempt_at forward so the dispatcher does not pick them up twice.
+func (s *Store) ClaimWebhookJobs(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookJob, error) {
+	now := time.Now()
+	rows, err := s.db.QueryContext(ctx, `
+UPDATE webhook_outbox
+SET next_attempt_at = ?
+WHERE id IN (
+	SELECT id FROM webhook_outbox
+	WHERE status = 'pending' AND next_attempt_at <= ?
+	ORDER BY id
+	LIMIT ?
+)
+RETURNING id, endpoint_id, event, payload, attempts`,
+		formatTime(now.Add(lease.Truncate(time.Second))), formatTime(now), limit)
+	if err != nil {
+		return nil, err
+	}
+	var res []models.WebhookJob
+	for rows.Next() {
+		var j models.WebhookJob
+		if err := rows.Scan(&j.ID, &j.EndpointID, &j.Event, &j.Payload, &j.Attempts); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		res = append(res, j)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, nil
+	}
+
+	endpoints, err := s.ListWebhookEndpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byID := map[int64]models.WebhookEndpoint{}
+	for _, e := range endpoints {
+		byID[e.ID] = e
+	}
+	for i := range res {
+		e := byID[res[i].EndpointID]
+		res[i].URL, res[i].Secret = e.URL, e.Secret
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res, nil
+}
+
+// RecordWebhookAttempt logs a delivery attempt and moves the outbox entry to
+// its next state: delivered, failed, or pending again at retryAt.
+func (s *Store) RecordWebhookAttempt(ctx context.Context, d models.WebhookDelivery, retryAt time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := formatTime(time.Now())
+	_, err = tx.ExecContext(ctx, `
+INSERT INTO webhook_deliveries (outbox_id, endpoint_id, event, attempt, status_code, error, duration_ms, outcome, created_at)
+VALUES (?,?,?,?,?,?,?,?,?)`,
+		d.OutboxID, d.EndpointID, d.Event, d.Attempt, d.StatusCode, d.Error, d.DurationMs, d.Outcome, now)
+	if err != nil {
+		return err
+	}
+
+	switch d.Outcome {
+	case "delivered":
+		_, err = tx.ExecContext(ctx, `UPDATE webhook_outbox SET status='delivered', attempts=?, last_error='', delivered_at=? WHERE id=?`, d.Attempt, now, d.OutboxID)
+	case "failed":
+		_, err = tx.ExecContext(ctx, `UPDATE webhook_outbox SET status='failed', attempts=?, last_error=? WHERE id=?`, d.Attempt, d.Error, d.OutboxID)
+	default:
+		_, err = tx.ExecContext(ctx, `UPDATE webhook_outbox SET attempts=?, last_error=?, next_attempt_at=? WHERE id=?`, d.Attempt, d.Error, formatTime(retryAt), d.OutboxID)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListWebhookDeliveries returns the newest delivery attempts, optionally for one endpoint.
+func (s *Store) ListWebhookDeliveries(ctx context.Context, endpointID int64, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, outbox_id, endpoint_id, event, attempt, status_code, error, duration_ms, outcome, created_at
+FROM webhook_deliveries
+WHERE ? = 0 OR endpoint_id = ?
+ORDER BY id desc
+LIMIT ?`, endpointID, endpointID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.OutboxID, &d.EndpointID, &d.Event, &d.Attempt, &d.StatusCode, &d.Error, &d.DurationMs, &d.Outcome, timeScanner{&d.CreatedAt}); err != nil {
+			return nil, err
+		}
+		res = append(res, d)
+	}
+	return res, rows.Err()
+}
@@ -1,165 +1,252 @@
+// Package store defines the persistence interface of the survey. The
+// postgres, sqlite and memory subpackages implement it; Open picks one from
+// DATABASE_URL.
 package store
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
+	"os"
+	"strings"
 	"time"
 
+	"opslab-survey/internal/envelope"
 	"opslab-survey/internal/models"
-
-	"github.com/jackc/pgx/v5/pgxpool"
+	"opslab-survey/internal/seed"
+	"opslab-survey/internal/store/memory"
+	"opslab-survey/internal/store/postgres"
+	"opslab-survey/internal/store/sqlite"
 )
 
-// Store wraps database access.
-type Store struct {
-	pool *pgxpool.Pool
+// Participants gives access to the seeded participant list.
+type Participants interface {
+	ParticipantByEmailAndCode(ctx context.Context, email, code string) (*models.Participant, error)
+	ListParticipants(ctx context.Context) ([]models.Participant, error)
+	// PendingParticipants lists non-admin participants without any submission in the round.
+	PendingParticipants(ctx context.Context, roundID int64) ([]models.Participant, error)
 }
 
-func New(ctx context.Context, url string) (*Store, error) {
-	if url == "" {
-		return nil, errors.New("DATABASE_URL is required")
-	}
-	cfg, err := pgxpool.ParseConfig(url)
-	if err != nil {
-		return nil, fmt.Errorf("parse DATABASE_URL: %w", err)
-	}
-	pool, err := pgxpool.NewWithConfig(ctx, cfg)
-	if err != nil {
-		return nil, fmt.Errorf("connect db: %w", err)
-	}
-	return &Store{pool: pool}, nil
+// Responses keeps every submitted revision. The current response of a
+// participant is their latest revision in the round. The SQL backends also
+// write each revision to the answers and ranking_positions tables (see
+// seed.Normalize) for aggregation in SQL.
+type Responses interface {
+	UpsertResponse(ctx context.Context, roundID int64, participantCode string, answers []models.AnswerPayload, rankings []models.RankingPayload, isTest bool, sessionID string) error
+	AllResponses(ctx context.Context, roundID int64) ([]models.ResponseRecord, error)
+	// CountValues counts the numeric answers of the current responses in a
+	// round by question, colleague and value, test data included like in
+	// AllResponses; admins' responses are left out.
+	CountValues(ctx context.Context, roundID int64) ([]models.ValueCount, error)
+	// ResponseByParticipant returns nil when the participant has not
+	// submitted in the round.
+	ResponseByParticipant(ctx context.Context, roundID int64, participantCode string) (*models.ResponseRecord, error)
+	ListResponses(ctx context.Context, f models.ResponseFilter) (models.ResponsePage, error)
+	ListRevisions(ctx context.Context, roundID int64, participantCode string) ([]models.ResponseRevision, error)
+	// ExportRevisions returns the revisions matching f, oldest first.
+	ExportRevisions(ctx context.Context, f models.RevisionFilter) ([]models.ResponseRevision, error)
+	// ResetResponses stores the snapshot and deletes the listed revisions in
+	// one transaction.
+	ResetResponses(ctx context.Context, snapshot models.Snapshot, revisionIDs []int64) (*models.Snapshot, error)
 }
 
-func (s *Store) Close() {
-	s.pool.Close()
+// Snapshots keeps the copies of deleted revisions that resets leave behind.
+type Snapshots interface {
+	// ListSnapshots returns every snapshot without its data, newest first.
+	ListSnapshots(ctx context.Context) ([]models.Snapshot, error)
+	// SnapshotByID returns nil when there is no such snapshot.
+	SnapshotByID(ctx context.Context, id int64) (*models.Snapshot, error)
+	// RestoreSnapshot inserts the revisions that are not stored any more,
+	// keeping their IDs, and marks the snapshot restored. It returns how many
+	// revisions it inserted.
+	RestoreSnapshot(ctx context.Context, id int64, revisions []models.ResponseRevision) (int, error)
+	// DeleteSnapshots removes snapshots created before the given time and
+	// returns how many there were.
+	DeleteSnapshots(ctx context.Context, before time.Time) (int, error)
 }
 
-// EnsureSchema sets up tables and seeds known participants.
-func (s *Store) EnsureSchema(ctx context.Context, participants []models.Participant) error {
-	tx, err := s.pool.Begin(ctx)
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback(ctx)
-
-	_, err = tx.Exec(ctx, `
-CREATE TABLE IF NOT EXISTS participants (
-	code text primary key,
-	name text not null,
-	email text not null unique,
-	is_admin boolean default false
-);
-
-CREATE TABLE IF NOT EXISTS responses (
-	id bigserial primary key,
-	participant_code text not null references participants(code) on delete cascade,
-	answers jsonb not null,
-	rankings jsonb not null,
-	is_test_data boolean default false,
-	submitted_at timestamptz not null default now(),
-	updated_at timestamptz not null default now()
-);
-
-CREATE UNIQUE INDEX IF NOT EXISTS responses_participant_code_idx ON responses(participant_code);
-`)
-	if err != nil {
-		return fmt.Errorf("create tables: %w", err)
-	}
+// Privacy carries out erasure requests and the anonymization of old rounds
+// (see package gdpr).
+type Privacy interface {
+	// EraseParticipant deletes the revisions, drafts, deadline extensions
+	// and reminders of e.ParticipantCode and writes the rewritten revisions
+	// and snapshots of e, in one transaction. Revisions that no longer exist
+	// are skipped.
+	EraseParticipant(ctx context.Context, e models.Erasure) (*models.ErasureResult, error)
+	// AnonymizeRound replaces every revision, draft, deadline extension and
+	// reminder of a round with the given anonymous responses, in one
+	// transaction.
+	AnonymizeRound(ctx context.Context, roundID int64, responses []models.AnonymousResponse) error
+	ListAnonymousResponses(ctx context.Context, roundID int64) ([]models.AnonymousResponse, error)
+}
 
-	for _, p := range participants {
-		_, err = tx.Exec(ctx, `
-INSERT INTO participants (code, name, email, is_admin)
-VALUES ($1,$2,$3,$4)
-ON CONFLICT (code) DO UPDATE SET name=EXCLUDED.name, email=EXCLUDED.email, is_admin=EXCLUDED.is_admin;
-`, p.Code, p.Name, p.Email, p.IsAdmin)
-		if err != nil {
-			return fmt.Errorf("seed participant %s: %w", p.Code, err)
-		}
-	}
+// Consents records acceptances of the privacy notice. Every accepted
+// version is kept.
+type Consents interface {
+	// RecordConsent stores that the participant accepted version now;
+	// accepting a version again keeps the first acceptance.
+	RecordConsent(ctx context.Context, participantCode string, version int) (*models.Consent, error)
+	// ConsentFor returns nil when the participant has not accepted version.
+	ConsentFor(ctx context.Context, participantCode string, version int) (*models.Consent, error)
+	// ListConsents returns every acceptance, by participant and version.
+	ListConsents(ctx context.Context) ([]models.Consent, error)
+	// ConsentsOf returns the acceptances of one participant, oldest version
+	// first.
+	ConsentsOf(ctx context.Context, participantCode string) ([]models.Consent, error)
+	// NoticeVersion returns the version of the notice whose text has digest:
+	// the latest version if it has that text, otherwise the next one, which
+	// is recorded for it. The first notice is version 1.
+	NoticeVersion(ctx context.Context, digest string) (int, error)
+}
 
-	return tx.Commit(ctx)
+// Confirmations remembers which confirmation tokens were used, so that each
+// confirms its action once.
+type Confirmations interface {
+	// UseConfirmation records the token with ID jti as used and reports
+	// whether this was its first use. Records of tokens that have expired,
+	// and could not be used anyway, are dropped along the way.
+	UseConfirmation(ctx context.Context, jti string, expiresAt time.Time) (bool, error)
 }
 
-// ParticipantByEmailAndCode finds a participant.
-func (s *Store) ParticipantByEmailAndCode(ctx context.Context, email, code string) (*models.Participant, error) {
-	var p models.Participant
-	err := s.pool.QueryRow(ctx, `SELECT code, name, email, is_admin FROM participants WHERE email=$1 AND code=$2`, email, code).
-		Scan(&p.Code, &p.Name, &p.Email, &p.IsAdmin)
-	if err != nil {
-		return nil, err
-	}
-	return &p, nil
+// Backups loads backup archives (see package backup).
+type Backups interface {
+	// RestoreDataset writes d in one transaction. In merge mode rows whose
+	// ID (or participant code) already exists are kept as they are; in
+	// replace mode all rounds and their dependent rows are deleted first.
+	// Participants are never deleted.
+	RestoreDataset(ctx context.Context, d models.Dataset, mode string) (*models.RestoreResult, error)
 }
 
-func (s *Store) ListParticipants(ctx context.Context) ([]models.Participant, error) {
-	rows, err := s.pool.Query(ctx, `SELECT code, name, email, is_admin FROM participants ORDER BY name asc`)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var res []models.Participant
-	for rows.Next() {
-		var p models.Participant
-		if err := rows.Scan(&p.Code, &p.Name, &p.Email, &p.IsAdmin); err != nil {
-			return nil, err
-		}
-		res = append(res, p)
-	}
-	return res, rows.Err()
+// Encryption seals answer payloads at rest (see package envelope).
+type Encryption interface {
+	// SetKeyring must be called before the store is used. A nil keyring
+	// writes plaintext; sealed values then cannot be read.
+	SetKeyring(k *envelope.Keyring)
+	// RotateKeys re-encrypts, in one transaction, every answer payload,
+	// draft and reset snapshot that is in plaintext or sealed with a
+	// previous master key.
+	RotateKeys(ctx context.Context) (*models.RotationResult, error)
 }
 
-// UpsertResponse stores submission; overwrites if same participant resubmits.
-func (s *Store) UpsertResponse(ctx context.Context, participantCode string, answers []models.AnswerPayload, rankings []models.RankingPayload, isTest bool) error {
-	answersJSON, err := json.Marshal(answers)
-	if err != nil {
-		return fmt.Errorf("marshal answers: %w", err)
-	}
-	rankingsJSON, err := json.Marshal(rankings)
-	if err != nil {
-		return fmt.Errorf("marshal rankings: %w", err)
+// Rounds manages survey cycles and personal deadline extensions.
+type Rounds interface {
+	// CurrentRound returns nil when every round is archived.
+	CurrentRound(ctx context.Context) (*models.Round, error)
+	RoundByID(ctx context.Context, id int64) (*models.Round, error)
+	ListRounds(ctx context.Context) ([]models.Round, error)
+	CreateRound(ctx context.Context, round models.Round) (*models.Round, error)
+	UpdateRound(ctx context.Context, round models.Round) (*models.Round, error)
+	ExtendDeadline(ctx context.Context, ext models.DeadlineExtension) error
+	// DeadlineExtension returns nil when no extension was granted.
+	DeadlineExtension(ctx context.Context, roundID int64, participantCode string) (*time.Time, error)
+	ListDeadlineExtensions(ctx context.Context, roundID int64) ([]models.DeadlineExtension, error)
+	// DeleteRound removes a round with everything that belongs to it.
+	DeleteRound(ctx context.Context, id int64) (bool, error)
+}
+
+// Drafts keeps autosaved, unsubmitted progress.
+type Drafts interface {
+	SaveDraft(ctx context.Context, d models.Draft) error
+	// DraftFor returns nil when the participant has no draft.
+	DraftFor(ctx context.Context, roundID int64, participantCode string) (*models.Draft, error)
+	DraftProgress(ctx context.Context, roundID int64) (map[string]models.Draft, error)
+}
+
+// Reminders logs reminder deliveries.
+type Reminders interface {
+	RecordReminder(ctx context.Context, r models.Reminder) error
+	// ReminderHistory sums up the earlier attempts at a reminder of one kind.
+	ReminderHistory(ctx context.Context, roundID int64, participantCode, kind string) (models.ReminderHistory, error)
+	ListReminders(ctx context.Context, roundID int64) ([]models.Reminder, error)
+}
+
+// Webhooks holds endpoints, the delivery outbox and the delivery log.
+type Webhooks interface {
+	CreateWebhookEndpoint(ctx context.Context, e models.WebhookEndpoint) (*models.WebhookEndpoint, error)
+	ListWebhookEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error)
+	DeleteWebhookEndpoint(ctx context.Context, id int64) (bool, error)
+	EnqueueWebhook(ctx context.Context, event string, payload []byte) (int64, error)
+	// EnqueueWebhookOnce enqueues an event that must go out at most once:
+	// it records key together with the outbox entries and does nothing when
+	// key is already recorded. first reports whether it was new.
+	EnqueueWebhookOnce(ctx context.Context, key, event string, payload []byte) (n int64, first bool, err error)
+	ClaimWebhookJobs(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookJob, error)
+	RecordWebhookAttempt(ctx context.Context, d models.WebhookDelivery, retryAt time.Time) error
+	ListWebhookDeliveries(ctx context.Context, endpointID int64, limit int) ([]models.WebhookDelivery, error)
+}
+
+// Audit is the append-only audit log. Entries are never updated or deleted;
+// each one is chained to its predecessor (see audit.Hash).
+type Audit interface {
+	// AppendAudit stamps e with the current time, chains it to the last
+	// entry and stores it.
+	AppendAudit(ctx context.Context, e models.AuditEntry) (*models.AuditEntry, error)
+	ListAudit(ctx context.Context, f models.AuditFilter) ([]models.AuditEntry, error)
+	// AuditTrail returns up to limit entries with IDs above afterID, oldest first.
+	AuditTrail(ctx context.Context, afterID int64, limit int) ([]models.AuditEntry, error)
+}
+
+// Store is everything the server needs from a database.
+type Store interface {
+	Participants
+	Responses
+	Rounds
+	Drafts
+	Reminders
+	Webhooks
+	Audit
+	Snapshots
+	Backups
+	Encryption
+	Privacy
+	Consents
+	Confirmations
+
+	// EnsureSchema creates or migrates tables, makes sure a first round
+	// exists and seeds the known participants.
+	EnsureSchema(ctx context.Context, participants []models.Participant) error
+	Close()
+}
+
+var (
+	_ Store = (*postgres.Store)(nil)
+	_ Store = (*sqlite.Store)(nil)
+	_ Store = (*memory.Store)(nil)
+)
+
+// Open connects to the database named by url:
+//
+//	postgres://… or postgresql://…  Postgres
+//	sqlite://path/to/file.db        embedded SQLite file (sqlite://:memory: for a throwaway one)
+//	memory://                       in-process maps, lost on exit
+func Open(ctx context.Context, url string) (Store, error) {
+	switch {
+	case url == "":
+		return nil, errors.New("DATABASE_URL is required")
+	case strings.HasPrefix(url, "sqlite://"):
+		return sqlite.Open(ctx, strings.TrimPrefix(url, "sqlite://"))
+	case strings.HasPrefix(url, "memory://"):
+		return memory.New(), nil
+	default:
+		return postgres.New(ctx, url)
 	}
-	_, err = s.pool.Exec(ctx, `
-INSERT INTO responses (participant_code, answers, rankings, is_test_data, submitted_at, updated_at)
-VALUES ($1,$2,$3,$4, now(), now())
-ON CONFLICT (participant_code)
-DO UPDATE SET answers=EXCLUDED.answers, rankings=EXCLUDED.rankings, is_test_data=EXCLUDED.is_test_data, updated_at=now();`,
-		participantCode, answersJSON, rankingsJSON, isTest)
-	return err
 }
 
-func (s *Store) AllResponses(ctx context.Context) ([]models.ResponseRecord, error) {
-	rows, err := s.pool.Query(ctx, `SELECT id, participant_code, answers, rankings, is_test_data, submitted_at, updated_at FROM responses ORDER BY submitted_at desc`)
+// OpenEnv opens DATABASE_URL with the keyring configured in the environment
+// (see envelope.FromEnv) and ensures the schema, as the server and the
+// command-line tools need it.
+func OpenEnv(ctx context.Context) (Store, error) {
+	keys, err := envelope.FromEnv()
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	var res []models.ResponseRecord
-	for rows.Next() {
-		var r models.ResponseRecord
-		var answersJSON, rankingsJSON []byte
-		if err := rows.Scan(&r.ID, &r.ParticipantCode, &answersJSON, &rankingsJSON, &r.IsTestData, &r.SubmittedAt, &r.UpdatedAt); err != nil {
-			return nil, err
-		}
-		if err := json.Unmarshal(answersJSON, &r.Answers); err != nil {
-			return nil, fmt.Errorf("unmarshal answers: %w", err)
-		}
-		if err := json.Unmarshal(rankingsJSON, &r.Rankings); err != nil {
-			return nil, fmt.Errorf("unmarshal rankings: %w", err)
-		}
-		res = append(res, r)
+	st, err := Open(ctx, os.Getenv("DATABASE_URL"))
+	if err != nil {
+		return nil, err
 	}
-	return res, rows.Err()
-}
-
-func (s *Store) ResetResponses(ctx context.Context) error {
-	_, err := s.pool.Exec(ctx, `TRUNCATE TABLE responses`)
-	return err
-}
-
-// MarkNow updates updated_at timestamp (useful when we want to bump without changing payload).
-func (s *Store) Touch(ctx context.Context, participantCode string) error {
-	_, err := s.pool.Exec(ctx, `UPDATE responses SET updated_at=$1 WHERE participant_code=$2`, time.Now(), participantCode)
-	return err
+	st.SetKeyring(keys)
+	if err := st.EnsureSchema(ctx, seed.Participants()); err != nil {
+		st.Close()
+		return nil, err
+	}
+	return st, nil
 }
@@ -0,0 +1,298 @@
+package textanalytics
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// Term is a stemmed word or phrase with its most common spelling.
+type Term struct {
+	Term  string  `json:"term"`
+	Stem  string  `json:"stem"`
+	Count int     `json:"count,omitempty"`
+	Docs  int     `json:"docs,omitempty"`
+	Score float64 `json:"score,omitempty"`
+}
+
+// Theme is one cluster of similar answers. The Other theme collects answers
+// that share no terms with any cluster.
+type Theme struct {
+	ID     int      `json:"id"`
+	Label  string   `json:"label"`
+	Other  bool     `json:"other,omitempty"`
+	Size   int      `json:"size"`
+	Terms  []Term   `json:"terms"`
+	Quotes []string `json:"quotes"`
+}
+
+// Analysis summarises a set of free-text answers.
+type Analysis struct {
+	Answers  int     `json:"answers"`
+	Analysed int     `json:"analysed"`
+	Terms    []Term  `json:"terms"`
+	Bigrams  []Term  `json:"bigrams"`
+	Trigrams []Term  `json:"trigrams"`
+	Themes   []Theme `json:"themes"`
+}
+
+// Options tune an analysis. Zero values pick sensible defaults.
+type Options struct {
+	Lang      string // "", "uk" or "en"; empty auto-detects per answer
+	TopTerms  int
+	TopNgrams int
+	Themes    int // number of clusters; 0 chooses from the answer count
+	Quotes    int
+}
+
+const maxQuote = 280
+
+// Analyze extracts term frequencies, n-grams and TF-IDF themes from texts.
+func Analyze(texts []string, opts Options) Analysis {
+	if opts.TopTerms == 0 {
+		opts.TopTerms = 25
+	}
+	if opts.TopNgrams == 0 {
+		opts.TopNgrams = 15
+	}
+	if opts.Quotes == 0 {
+		opts.Quotes = 3
+	}
+
+	var docs []document
+	for _, text := range texts {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		docs = append(docs, newDocument(text, opts.Lang))
+	}
+	a := Analysis{Answers: len(docs), Terms: []Term{}, Bigrams: []Term{}, Trigrams: []Term{}, Themes: []Theme{}}
+
+	surfaces := newSurfaceCounter()
+	var unigrams, bigrams, trigrams []map[string]int
+	for _, d := range docs {
+		u := map[string]int{}
+		for _, t := range d.tokens {
+			u[t.Stem]++
+			surfaces.add(t.Stem, t.Surface)
+		}
+		unigrams = append(unigrams, u)
+		bigrams = append(bigrams, ngrams(d.tokens, 2, surfaces))
+		trigrams = append(trigrams, ngrams(d.tokens, 3, surfaces))
+		if len(d.tokens) > 0 {
+			a.Analysed++
+		}
+	}
+	a.Terms = topTerms(unigrams, surfaces, opts.TopTerms, 1)
+	a.Bigrams = topTerms(bigrams, surfaces, opts.TopNgrams, 2)
+	a.Trigrams = topTerms(trigrams, surfaces, opts.TopNgrams, 2)
+
+	vectors := tfidf(unigrams)
+	var clustered []int
+	for i, v := range vectors {
+		if len(v) > 0 {
+			clustered = append(clustered, i)
+		}
+	}
+	if len(clustered) == 0 {
+		return a
+	}
+	k := opts.Themes
+	if k == 0 {
+		k = chooseK(len(clustered))
+	}
+	if k > len(clustered) {
+		k = len(clustered)
+	}
+	points := make([]vector, len(clustered))
+	for i, idx := range clustered {
+		points[i] = vectors[idx]
+	}
+	assign, centroids := kmeans(points, k)
+
+	var unassigned []int
+	for i, cl := range assign {
+		if cl < 0 {
+			unassigned = append(unassigned, i)
+		}
+	}
+	if len(unassigned) > 0 {
+		other := vector{}
+		for _, i := range unassigned {
+			for key, w := range points[i] {
+				other[key] += w
+			}
+		}
+		centroids = append(centroids, other.normalise())
+		for _, i := range unassigned {
+			assign[i] = len(centroids) - 1
+		}
+	}
+
+	for c, centroid := range centroids {
+		var members []int
+		for i, cl := range assign {
+			if cl == c {
+				members = append(members, i)
+			}
+		}
+		if len(members) == 0 {
+			continue
+		}
+		theme := Theme{ID: len(a.Themes), Size: len(members), Other: len(unassigned) > 0 && c == len(centroids)-1}
+		for _, tw := range centroid.top(6) {
+			theme.Terms = append(theme.Terms, Term{
+				Term: surfaces.best(tw.key), Stem: tw.key, Score: math.Round(tw.weight*1000) / 1000,
+			})
+		}
+		var labels []string
+		for i, t := range theme.Terms {
+			if i == 3 {
+				break
+			}
+			labels = append(labels, t.Term)
+		}
+		theme.Label = strings.Join(labels, " · ")
+
+		// Scores are rounded because dot sums in map order, and equal
+		// documents must not swap places between runs.
+		score := map[int]float64{}
+		for _, m := range members {
+			score[m] = math.Round(points[m].dot(centroid)*1e9) / 1e9
+		}
+		sort.SliceStable(members, func(i, j int) bool {
+			return score[members[i]] > score[members[j]]
+		})
+		for i, m := range members {
+			if i == opts.Quotes {
+				break
+			}
+			theme.Quotes = append(theme.Quotes, quote(docs[clustered[m]].text))
+		}
+		a.Themes = append(a.Themes, theme)
+	}
+	sort.SliceStable(a.Themes, func(i, j int) bool {
+		if a.Themes[i].Other != a.Themes[j].Other {
+			return !a.Themes[i].Other
+		}
+		return a.Themes[i].Size > a.Themes[j].Size
+	})
+	for i := range a.Themes {
+		a.Themes[i].ID = i
+	}
+	return a
+}
+
+type document struct {
+	text   string
+	tokens []Token
+}
+
+func newDocument(text, lang string) document {
+	return document{text: text, tokens: Tokenize(text, lang)}
+}
+
+// ngrams counts stem n-grams inside each segment and records how they were
+// spelled.
+func ngrams(tokens []Token, n int, surfaces *surfaceCounter) map[string]int {
+	out := map[string]int{}
+	for i := 0; i+n <= len(tokens); i++ {
+		if tokens[i].Segment != tokens[i+n-1].Segment {
+			continue
+		}
+		stems := make([]string, n)
+		words := make([]string, n)
+		for j := 0; j < n; j++ {
+			stems[j] = tokens[i+j].Stem
+			words[j] = tokens[i+j].Surface
+		}
+		key := strings.Join(stems, " ")
+		out[key]++
+		surfaces.add(key, strings.Join(words, " "))
+	}
+	return out
+}
+
+// topTerms ranks keys by total count, then by document frequency, keeping
+// those that appear in at least minDocs answers.
+func topTerms(perDoc []map[string]int, surfaces *surfaceCounter, limit, minDocs int) []Term {
+	count := map[string]int{}
+	docs := map[string]int{}
+	for _, d := range perDoc {
+		for k, c := range d {
+			count[k] += c
+			docs[k]++
+		}
+	}
+	out := []Term{}
+	for k, c := range count {
+		if docs[k] < minDocs {
+			continue
+		}
+		out = append(out, Term{Term: surfaces.best(k), Stem: k, Count: c, Docs: docs[k]})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		if out[i].Docs != out[j].Docs {
+			return out[i].Docs > out[j].Docs
+		}
+		return out[i].Stem < out[j].Stem
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// chooseK picks roughly √(n/2) themes, between 2 and 8.
+func chooseK(n int) int {
+	if n < 4 {
+		return 1
+	}
+	k := int(math.Round(math.Sqrt(float64(n) / 2)))
+	if k < 2 {
+		k = 2
+	}
+	if k > 8 {
+		k = 8
+	}
+	return k
+}
+
+func quote(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if r := []rune(text); len(r) > maxQuote {
+		return string(r[:maxQuote-1]) + "…"
+	}
+	return text
+}
+
+// surfaceCounter remembers how often each stem was spelled each way so that
+// results show real words instead of stems.
+type surfaceCounter struct {
+	counts map[string]map[string]int
+}
+
+func newSurfaceCounter() *surfaceCounter {
+	return &surfaceCounter{counts: map[string]map[string]int{}}
+}
+
+func (s *surfaceCounter) add(stem, surface string) {
+	if s.counts[stem] == nil {
+		s.counts[stem] = map[string]int{}
+	}
+	s.counts[stem][surface]++
+}
+
+func (s *surfaceCounter) best(stem string) string {
+	best, most := stem, 0
+	for surface, c := range s.counts[stem] {
+		if c > most || (c == most && surface < best) {
+			best, most = surface, c
+		}
+	}
+	return best
+}
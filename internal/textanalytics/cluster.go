@@ -0,0 +1,155 @@
+package textanalytics
+
+import (
+	"math"
+	"sort"
+)
+
+// vector is a sparse, L2-normalised TF-IDF vector keyed by stem.
+type vector map[string]float64
+
+type weightedKey struct {
+	key    string
+	weight float64
+}
+
+func (v vector) dot(o vector) float64 {
+	if len(o) < len(v) {
+		v, o = o, v
+	}
+	sum := 0.0
+	for k, w := range v {
+		sum += w * o[k]
+	}
+	return sum
+}
+
+func (v vector) normalise() vector {
+	norm := 0.0
+	for _, w := range v {
+		norm += w * w
+	}
+	if norm == 0 {
+		return v
+	}
+	norm = math.Sqrt(norm)
+	for k := range v {
+		v[k] /= norm
+	}
+	return v
+}
+
+func (v vector) top(n int) []weightedKey {
+	out := make([]weightedKey, 0, len(v))
+	for k, w := range v {
+		out = append(out, weightedKey{k, w})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].weight != out[j].weight {
+			return out[i].weight > out[j].weight
+		}
+		return out[i].key < out[j].key
+	})
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// tfidf weights term counts by smoothed inverse document frequency.
+func tfidf(docs []map[string]int) []vector {
+	df := map[string]int{}
+	for _, d := range docs {
+		for k := range d {
+			df[k]++
+		}
+	}
+	n := float64(len(docs))
+	out := make([]vector, len(docs))
+	for i, d := range docs {
+		total := 0
+		for _, c := range d {
+			total += c
+		}
+		v := vector{}
+		for k, c := range d {
+			idf := math.Log((1+n)/(1+float64(df[k]))) + 1
+			v[k] = float64(c) / float64(total) * idf
+		}
+		out[i] = v.normalise()
+	}
+	return out
+}
+
+// kmeans clusters normalised vectors by cosine similarity (spherical
+// k-means). Seeds are chosen farthest-first from the richest answer, so the
+// result is deterministic. Points sharing no term with any centroid are
+// left unassigned (-1).
+func kmeans(points []vector, k int) ([]int, []vector) {
+	seed := 0
+	for i, p := range points {
+		if len(p) > len(points[seed]) {
+			seed = i
+		}
+	}
+	centroids := []vector{copyVector(points[seed])}
+	for len(centroids) < k {
+		far, farSim := -1, math.Inf(1)
+		for i, p := range points {
+			best := math.Inf(-1)
+			for _, c := range centroids {
+				best = math.Max(best, p.dot(c))
+			}
+			if best < farSim {
+				far, farSim = i, best
+			}
+		}
+		centroids = append(centroids, copyVector(points[far]))
+	}
+
+	assign := make([]int, len(points))
+	for i := range assign {
+		assign[i] = -1
+	}
+	for iter := 0; iter < 50; iter++ {
+		changed := false
+		for i, p := range points {
+			best, bestSim := -1, 0.0
+			for c, centroid := range centroids {
+				if sim := p.dot(centroid); sim > bestSim {
+					best, bestSim = c, sim
+				}
+			}
+			if assign[i] != best {
+				assign[i] = best
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+		for c := range centroids {
+			sum := vector{}
+			for i, p := range points {
+				if assign[i] != c {
+					continue
+				}
+				for key, w := range p {
+					sum[key] += w
+				}
+			}
+			if len(sum) > 0 {
+				centroids[c] = sum.normalise()
+			}
+		}
+	}
+	return assign, centroids
+}
+
+func copyVector(v vector) vector {
+	out := make(vector, len(v))
+	for k, w := range v {
+		out[k] = w
+	}
+	return out
+}
@@ -0,0 +1,91 @@
+package textanalytics
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Stem strips common inflectional endings so that "рішення", "рішень" and
+// "рішеннями" or "decisions" and "decision" count as one term. It is a light
+// suffix stripper, not a full morphological analyser.
+func Stem(word, lang string) string {
+	if lang == LangEN {
+		return stemEN(word)
+	}
+	return stemUK(word)
+}
+
+// ukReflexive endings are removed before the inflectional ones.
+var ukReflexive = []string{"ться", "ся", "сь"}
+
+// ukSuffixes are tried longest first (sorted in init).
+var ukSuffixes = []string{
+	"ністю", "остей",
+	"ують", "юють", "ають", "яють", "ється", "ються",
+	"ими", "іми", "ого", "ому", "ами", "ями", "ові", "еві", "ість", "ості",
+	"ати", "яти", "ити", "іти", "увати", "ювати", "ував", "ила", "или", "ало", "али",
+	"ий", "ій", "ої", "ою", "ею", "єю", "их", "іх", "им", "ім", "ом", "ем",
+	"ам", "ям", "ах", "ях", "ів", "їв", "ає", "ує", "ть", "ла", "ли", "ло",
+	"а", "я", "о", "е", "є", "у", "ю", "і", "ї", "и", "ь",
+}
+
+func init() {
+	sort.SliceStable(ukSuffixes, func(i, j int) bool {
+		return utf8.RuneCountInString(ukSuffixes[i]) > utf8.RuneCountInString(ukSuffixes[j])
+	})
+}
+
+func stemUK(word string) string {
+	word = strings.ReplaceAll(word, "'", "")
+	for _, s := range ukReflexive {
+		if w, ok := cut(word, s, 4); ok {
+			word = w
+			break
+		}
+	}
+	for _, s := range ukSuffixes {
+		if w, ok := cut(word, s, 3); ok {
+			word = w
+			break
+		}
+	}
+	// Verbal nouns double their consonant before the ending ("рішення",
+	// "рішеннями") but not in the genitive plural ("рішень").
+	if r := []rune(word); len(r) > 3 && r[len(r)-1] == r[len(r)-2] && strings.ContainsRune("нтлдсцчж", r[len(r)-1]) {
+		word = string(r[:len(r)-1])
+	}
+	return word
+}
+
+var enSuffixes = []struct{ suffix, replace string }{
+	{"ational", "ate"}, {"ization", "ize"}, {"fulness", "ful"}, {"iveness", "ive"},
+	{"ingly", ""}, {"edly", ""}, {"ments", ""}, {"ness", ""}, {"ment", ""},
+	{"ings", ""}, {"ing", ""}, {"ies", "y"}, {"ied", "y"}, {"ers", ""}, {"er", ""},
+	{"ed", ""}, {"ly", ""}, {"es", ""}, {"s", ""},
+}
+
+func stemEN(word string) string {
+	word = strings.TrimSuffix(word, "'s")
+	if strings.HasSuffix(word, "ss") {
+		return word
+	}
+	for _, s := range enSuffixes {
+		if w, ok := cut(word, s.suffix, 3); ok {
+			return w + s.replace
+		}
+	}
+	return word
+}
+
+// cut removes suffix when at least min runes of stem remain.
+func cut(word, suffix string, min int) (string, bool) {
+	if !strings.HasSuffix(word, suffix) {
+		return word, false
+	}
+	stem := strings.TrimSuffix(word, suffix)
+	if len([]rune(stem)) < min {
+		return word, false
+	}
+	return stem, true
+}
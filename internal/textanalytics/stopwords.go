@@ -0,0 +1,45 @@
+package textanalytics
+
+import "strings"
+
+// Stopwords for Ukrainian and English. Both lists are always applied because
+// answers often mix languages and terms.
+var stopwords = map[string]bool{}
+
+func init() {
+	for _, list := range []string{stopwordsUK, stopwordsEN} {
+		for _, w := range strings.Fields(list) {
+			stopwords[w] = true
+		}
+	}
+}
+
+func isStopword(w string) bool {
+	return stopwords[w]
+}
+
+const stopwordsUK = `
+а аби або адже але аж б без би бо був була були було бути в вам вас ваш ваша
+ваше ваші вже ви від він вона вони воно всі все всього втім г де для до дуже
+є ж же з за завжди значить и і із її їй їм їх й його йому к коли кому котрий
+крім куди ледве лише лиш мабуть мало ми мене мені мені мною мій моя моє мої
+може можна на над нам нами нас наш наша наше наші не нам неї нею ні ніж
+них ним них ну о об однак от ось отже під після по поки при про просто
+саме свій своя своє свої себе собі та так також там твій те тим ти то тобто
+тоді той тому треба тут у усі усе це цей ці цим цих цього цьому цю ця через
+що щоб щодо як яка який яке які якщо я ще теж тих хто чи чому чого часто
+іноді інколи більш більше менш менше найбільш можливо між немає нема
+багато потрібно треба будь інші інший іншого весь вся всьому нашої нашому
+`
+
+const stopwordsEN = `
+a about above after again against all am an and any are as at be because been
+before being below between both but by can could did do does doing down during
+each few for from further had has have having he her here hers herself him
+himself his how i if in into is it its itself just me more most my myself no
+nor not now of off on once only or other our ours ourselves out over own same
+she should so some such than that the their theirs them themselves then there
+these they this those through to too under until up very was we were what when
+where which while who whom why will with would you your yours yourself also
+really maybe often sometimes
+`
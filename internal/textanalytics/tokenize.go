@@ -0,0 +1,83 @@
+package textanalytics
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Languages the analyser understands.
+const (
+	LangUK = "uk"
+	LangEN = "en"
+)
+
+// Token is one word of an answer. Segment increases at sentence punctuation
+// and stopwords so n-grams never span them.
+type Token struct {
+	Surface string
+	Stem    string
+	Segment int
+}
+
+// DetectLanguage picks Ukrainian when most letters are Cyrillic.
+func DetectLanguage(text string) string {
+	cyr, lat := 0, 0
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			cyr++
+		case unicode.Is(unicode.Latin, r):
+			lat++
+		}
+	}
+	if cyr >= lat {
+		return LangUK
+	}
+	return LangEN
+}
+
+// Tokenize lowercases text, splits it into words, drops stopwords and
+// numbers, and stems what remains. lang "" auto-detects.
+func Tokenize(text, lang string) []Token {
+	if lang == "" {
+		lang = DetectLanguage(text)
+	}
+	var out []Token
+	segment := 0
+	var word []rune
+	flush := func() {
+		if len(word) == 0 {
+			return
+		}
+		w := strings.Trim(string(word), "'")
+		word = word[:0]
+		if len([]rune(w)) < 2 || isStopword(w) {
+			segment++
+			return
+		}
+		out = append(out, Token{Surface: w, Stem: Stem(w, lang), Segment: segment})
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r):
+			word = append(word, r)
+		case isApostrophe(r) && len(word) > 0:
+			// Ukrainian apostrophes sit inside words: "п'ять", "об'єкт".
+			word = append(word, '\'')
+		case r == '-' && len(word) > 0:
+			// Keep hyphenated compounds such as "будь-який" together.
+			word = append(word, r)
+		default:
+			flush()
+			if strings.ContainsRune(".,;:!?()[]\"«»—–\n", r) {
+				segment++
+			}
+		}
+	}
+	flush()
+	return out
+}
+
+func isApostrophe(r rune) bool {
+	return r == '\'' || r == '’' || r == 'ʼ' || r == '`'
+}
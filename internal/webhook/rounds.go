@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/store"
+)
+
+// RoundWatcher emits round.opened and round.closed when the schedule, not
+// an admin, opens or closes a round. State changes made through the API
+// are emitted by the handler that makes them.
+type RoundWatcher struct {
+	store    store.Rounds
+	webhooks *Dispatcher
+	interval time.Duration
+	// Now returns the current time; tests may replace it.
+	Now func() time.Time
+}
+
+func NewRoundWatcher(st store.Rounds, d *Dispatcher) *RoundWatcher {
+	return &RoundWatcher{store: st, webhooks: d, interval: time.Minute, Now: time.Now}
+}
+
+// Run checks the schedule every minute until ctx is cancelled.
+func (w *RoundWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := w.Tick(ctx); err != nil {
+			log.Println("round watcher:", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick emits the transitions the schedule has made since the rounds were
+// last saved and returns how many were new. Each transition is keyed by the
+// round and the scheduled time, so it goes out once however many ticks or
+// instances see it; moving the date makes it a new transition.
+func (w *RoundWatcher) Tick(ctx context.Context) (int, error) {
+	rounds, err := w.store.ListRounds(ctx)
+	if err != nil {
+		return 0, err
+	}
+	now := w.Now()
+	emitted := 0
+	for _, round := range rounds {
+		for _, t := range scheduledTransitions(round, now) {
+			key := fmt.Sprintf("round:%d:%s@%s", round.ID, t.event, t.at.UTC().Format(time.RFC3339Nano))
+			first, err := w.webhooks.EmitOnce(ctx, key, t.event, map[string]interface{}{
+				"round":         round,
+				"previousState": t.from,
+				"scheduledAt":   t.at,
+			})
+			if err != nil {
+				return emitted, err
+			}
+			if first {
+				emitted++
+			}
+		}
+	}
+	return emitted, nil
+}
+
+type transition struct {
+	event string
+	from  string
+	at    time.Time
+}
+
+// scheduledTransitions lists the opening and closing of round that are due
+// by now and came after the round was last saved; earlier ones were made
+// by that save.
+func scheduledTransitions(round models.Round, now time.Time) []transition {
+	if round.State != models.RoundDraft && round.State != models.RoundOpen {
+		return nil
+	}
+	due := func(at *time.Time) bool {
+		return at != nil && !now.Before(*at) && at.After(round.UpdatedAt)
+	}
+	var res []transition
+	if due(round.OpensAt) && round.StateAt(*round.OpensAt) == models.RoundOpen {
+		res = append(res, transition{EventRoundOpened, models.RoundDraft, *round.OpensAt})
+	}
+	if due(round.ClosesAt) && round.StateAt(now) == models.RoundClosed {
+		res = append(res, transition{EventRoundClosed, models.RoundOpen, *round.ClosesAt})
+	}
+	return res
+}
@@ -0,0 +1,277 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"opslab-survey/internal/models"
+	"opslab-survey/internal/store"
+)
+
+// Events emitted by the survey.
+const (
+	EventResponseSubmitted = "response.submitted"
+	EventResponsesReset    = "responses.reset"
+	EventCompletionReached = "completion.reached"
+	EventRoundCreated      = "round.created"
+	EventRoundOpened       = "round.opened"
+	EventRoundClosed       = "round.closed"
+	EventRoundArchived     = "round.archived"
+	EventRoundDraft        = "round.draft"
+)
+
+// Events lists every event endpoints can subscribe to.
+var Events = []string{
+	EventResponseSubmitted,
+	EventResponsesReset,
+	EventCompletionReached,
+	EventRoundCreated,
+	EventRoundOpened,
+	EventRoundClosed,
+	EventRoundArchived,
+	EventRoundDraft,
+}
+
+// KnownEvent reports whether name is a subscribable event (or the "*" wildcard).
+func KnownEvent(name string) bool {
+	if name == "*" {
+		return true
+	}
+	for _, e := range Events {
+		if e == name {
+			return true
+		}
+	}
+	return false
+}
+
+// RoundStateEvent maps a round state to the event emitted when a round enters it.
+func RoundStateEvent(state string) string {
+	switch state {
+	case models.RoundOpen:
+		return EventRoundOpened
+	case models.RoundClosed:
+		return EventRoundClosed
+	case models.RoundArchived:
+		return EventRoundArchived
+	default:
+		return EventRoundDraft
+	}
+}
+
+// Envelope is the JSON body POSTed to subscribers.
+type Envelope struct {
+	Event      string      `json:"event"`
+	OccurredAt time.Time   `json:"occurredAt"`
+	Data       interface{} `json:"data"`
+}
+
+// Headers set on every delivery.
+const (
+	HeaderEvent     = "X-Opslab-Event"
+	HeaderDelivery  = "X-Opslab-Delivery"
+	HeaderTimestamp = "X-Opslab-Timestamp"
+	HeaderSignature = "X-Opslab-Signature"
+)
+
+// Sign computes the signature receivers should verify: hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the endpoint secret, prefixed with "sha256=".
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret returns a random signing secret for a new endpoint.
+func NewSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+// Dispatcher writes events to the persistent outbox and delivers them in the
+// background with exponential backoff.
+type Dispatcher struct {
+	store       store.Webhooks
+	client      *http.Client
+	interval    time.Duration
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+	maxAttempts int
+	batch       int
+	wake        chan struct{}
+}
+
+func NewDispatcher(st store.Webhooks) *Dispatcher {
+	return &Dispatcher{
+		store:       st,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		interval:    15 * time.Second,
+		baseBackoff: 30 * time.Second,
+		maxBackoff:  6 * time.Hour,
+		maxAttempts: 8,
+		batch:       20,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Emit enqueues an event for every subscribed endpoint. It only touches the
+// database; delivery happens on the Run loop.
+func (d *Dispatcher) Emit(ctx context.Context, event string, data interface{}) error {
+	body, err := envelope(event, data)
+	if err != nil {
+		return err
+	}
+	n, err := d.store.EnqueueWebhook(ctx, event, body)
+	if err != nil {
+		return fmt.Errorf("enqueue %s: %w", event, err)
+	}
+	d.notify(n)
+	return nil
+}
+
+// EmitOnce is Emit for an event identified by key, such as a round closing
+// at a given time: however often and from however many instances it is
+// called, the event is enqueued only the first time. It reports whether
+// that was this call.
+func (d *Dispatcher) EmitOnce(ctx context.Context, key, event string, data interface{}) (bool, error) {
+	body, err := envelope(event, data)
+	if err != nil {
+		return false, err
+	}
+	n, first, err := d.store.EnqueueWebhookOnce(ctx, key, event, body)
+	if err != nil {
+		return false, fmt.Errorf("enqueue %s: %w", event, err)
+	}
+	d.notify(n)
+	return first, nil
+}
+
+func envelope(event string, data interface{}) ([]byte, error) {
+	body, err := json.Marshal(Envelope{Event: event, OccurredAt: time.Now().UTC(), Data: data})
+	if err != nil {
+		return nil, fmt.Errorf("marshal %s: %w", event, err)
+	}
+	return body, nil
+}
+
+// notify wakes the Run loop when n new outbox entries are waiting.
+func (d *Dispatcher) notify(n int64) {
+	if n > 0 {
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run delivers due outbox entries until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		d.deliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	// Lease long enough to cover a full batch of timed-out requests.
+	lease := time.Duration(d.batch)*d.client.Timeout + time.Minute
+	jobs, err := d.store.ClaimWebhookJobs(ctx, d.batch, lease)
+	if err != nil {
+		log.Println("webhooks: claim:", err)
+		return
+	}
+	for _, job := range jobs {
+		d.deliver(ctx, job)
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, job models.WebhookJob) {
+	attempt := job.Attempts + 1
+	rec := models.WebhookDelivery{
+		OutboxID:   job.ID,
+		EndpointID: job.EndpointID,
+		Event:      job.Event,
+		Attempt:    attempt,
+	}
+
+	start := time.Now()
+	status, err := d.post(ctx, job)
+	rec.DurationMs = time.Since(start).Milliseconds()
+	rec.StatusCode = status
+
+	var retryAt time.Time
+	switch {
+	case err == nil:
+		rec.Outcome = "delivered"
+	case attempt >= d.maxAttempts:
+		rec.Outcome = "failed"
+		rec.Error = err.Error()
+	default:
+		rec.Outcome = "retrying"
+		rec.Error = err.Error()
+		retryAt = time.Now().Add(d.backoff(attempt))
+	}
+	if err := d.store.RecordWebhookAttempt(ctx, rec, retryAt); err != nil {
+		log.Printf("webhooks: record attempt %d for outbox %d: %v", attempt, job.ID, err)
+	}
+}
+
+func (d *Dispatcher) post(ctx context.Context, job models.WebhookJob) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.URL, bytes.NewReader(job.Payload))
+	if err != nil {
+		return 0, err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "opslab-survey-webhooks")
+	req.Header.Set(HeaderEvent, job.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(job.ID, 10))
+	req.Header.Set(HeaderTimestamp, ts)
+	req.Header.Set(HeaderSignature, Sign(job.Secret, ts, job.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff doubles the wait after every failed attempt, capped at maxBackoff.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	wait := d.baseBackoff
+	for i := 1; i < attempt; i++ {
+		wait *= 2
+		if wait >= d.maxBackoff {
+			return d.maxBackoff
+		}
+	}
+	return wait
+}
@@ -0,0 +1,37 @@
+-- Append-only submission history; "responses" becomes a view over the latest revision
+CREATE TABLE IF NOT EXISTS response_revisions (
+  id bigserial primary key,
+  participant_code text not null references participants(code) on delete cascade,
+  answers jsonb not null,
+  rankings jsonb not null,
+  is_test_data boolean not null default false,
+  session_id text not null default '',
+  submitted_at timestamptz not null default now()
+);
+
+CREATE INDEX IF NOT EXISTS response_revisions_participant_idx ON response_revisions(participant_code, id);
+
+-- Each legacy row becomes a first revision at its original submission time;
+-- a row edited since gets a second revision at updated_at, so both times
+-- survive in the view below.
+INSERT INTO response_revisions (participant_code, answers, rankings, is_test_data, session_id, submitted_at)
+SELECT participant_code, answers, rankings, coalesce(is_test_data, false), 'legacy', submitted_at FROM responses;
+
+INSERT INTO response_revisions (participant_code, answers, rankings, is_test_data, session_id, submitted_at)
+SELECT participant_code, answers, rankings, coalesce(is_test_data, false), 'legacy', updated_at FROM responses
+WHERE updated_at > submitted_at;
+
+ALTER TABLE responses RENAME TO responses_legacy;
+
+CREATE OR REPLACE VIEW responses AS
+SELECT DISTINCT ON (participant_code)
+  id,
+  participant_code,
+  answers,
+  rankings,
+  is_test_data,
+  session_id,
+  min(submitted_at) OVER (PARTITION BY participant_code) AS submitted_at,
+  submitted_at AS updated_at
+FROM response_revisions
+ORDER BY participant_code, id DESC;
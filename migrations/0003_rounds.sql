@@ -0,0 +1,41 @@
+-- Survey rounds with schedule and per-participant deadline extensions
+CREATE TABLE IF NOT EXISTS rounds (
+  id bigserial primary key,
+  title text not null,
+  state text not null default 'draft' check (state in ('draft','open','closed','archived')),
+  opens_at timestamptz,
+  closes_at timestamptz,
+  created_at timestamptz not null default now(),
+  updated_at timestamptz not null default now()
+);
+
+INSERT INTO rounds (title, state) SELECT 'Раунд 1', 'open' WHERE NOT EXISTS (SELECT 1 FROM rounds);
+
+CREATE TABLE IF NOT EXISTS deadline_extensions (
+  round_id bigint not null references rounds(id) on delete cascade,
+  participant_code text not null references participants(code) on delete cascade,
+  closes_at timestamptz not null,
+  reason text not null default '',
+  created_at timestamptz not null default now(),
+  primary key (round_id, participant_code)
+);
+
+ALTER TABLE response_revisions ADD COLUMN IF NOT EXISTS round_id bigint references rounds(id) on delete cascade;
+UPDATE response_revisions SET round_id = (SELECT min(id) FROM rounds) WHERE round_id IS NULL;
+ALTER TABLE response_revisions ALTER COLUMN round_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS response_revisions_round_idx ON response_revisions(round_id, participant_code, id);
+
+CREATE OR REPLACE VIEW responses AS
+SELECT DISTINCT ON (round_id, participant_code)
+  id,
+  participant_code,
+  answers,
+  rankings,
+  is_test_data,
+  session_id,
+  min(submitted_at) OVER (PARTITION BY round_id, participant_code) AS submitted_at,
+  submitted_at AS updated_at,
+  round_id
+FROM response_revisions
+ORDER BY round_id, participant_code, id DESC;
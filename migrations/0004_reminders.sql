@@ -0,0 +1,13 @@
+-- Log of reminder emails sent to pending participants
+CREATE TABLE IF NOT EXISTS reminders (
+  id bigserial primary key,
+  round_id bigint not null references rounds(id) on delete cascade,
+  participant_code text not null references participants(code) on delete cascade,
+  kind text not null,
+  transport text not null,
+  status text not null,
+  error text not null default '',
+  sent_at timestamptz not null default now()
+);
+
+CREATE INDEX IF NOT EXISTS reminders_round_idx ON reminders(round_id, participant_code, kind);
@@ -0,0 +1,37 @@
+-- Outbound webhooks: endpoints, persistent outbox and delivery log
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+  id bigserial primary key,
+  url text not null,
+  secret text not null,
+  events text[] not null default '{}',
+  active boolean not null default true,
+  created_at timestamptz not null default now()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_outbox (
+  id bigserial primary key,
+  endpoint_id bigint not null references webhook_endpoints(id) on delete cascade,
+  event text not null,
+  payload jsonb not null,
+  status text not null default 'pending',
+  attempts int not null default 0,
+  next_attempt_at timestamptz not null default now(),
+  last_error text not null default '',
+  created_at timestamptz not null default now(),
+  delivered_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS webhook_outbox_due_idx ON webhook_outbox(next_attempt_at) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  id bigserial primary key,
+  outbox_id bigint not null references webhook_outbox(id) on delete cascade,
+  endpoint_id bigint not null references webhook_endpoints(id) on delete cascade,
+  event text not null,
+  attempt int not null,
+  status_code int not null default 0,
+  error text not null default '',
+  duration_ms bigint not null default 0,
+  outcome text not null,
+  created_at timestamptz not null default now()
+);
@@ -0,0 +1,10 @@
+-- Server-side autosaved drafts (progress shown live in the admin dashboard)
+CREATE TABLE IF NOT EXISTS drafts (
+  round_id bigint not null references rounds(id) on delete cascade,
+  participant_code text not null references participants(code) on delete cascade,
+  answers jsonb not null,
+  rankings jsonb not null,
+  progress int not null default 0,
+  updated_at timestamptz not null default now(),
+  primary key (round_id, participant_code)
+);
@@ -0,0 +1,29 @@
+-- Append-only audit log with a SHA-256 hash chain (see internal/audit)
+CREATE TABLE IF NOT EXISTS audit_log (
+  id bigserial primary key,
+  occurred_at timestamptz not null,
+  actor text not null default '',
+  action text not null,
+  target text not null default '',
+  ip text not null default '',
+  user_agent text not null default '',
+  outcome text not null,
+  status int not null default 0,
+  prev_hash text not null,
+  hash text not null
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON audit_log(actor, id);
+CREATE INDEX IF NOT EXISTS audit_log_action_idx ON audit_log(action, id);
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_log is append-only';
+END $$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_no_change ON audit_log;
+CREATE TRIGGER audit_log_no_change BEFORE UPDATE OR DELETE ON audit_log
+  FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log;
+CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON audit_log
+  FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
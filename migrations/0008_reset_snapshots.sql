@@ -0,0 +1,11 @@
+-- Compressed copies of the revisions deleted by scoped resets, restorable
+-- from the admin panel
+CREATE TABLE IF NOT EXISTS reset_snapshots (
+  id bigserial primary key,
+  created_at timestamptz not null default now(),
+  created_by text not null,
+  scope jsonb not null,
+  revisions int not null,
+  data bytea not null,
+  restored_at timestamptz
+);
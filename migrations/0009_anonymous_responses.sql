@@ -0,0 +1,11 @@
+-- Responses of rounds anonymized by the retention policy, detached from
+-- participants
+CREATE TABLE IF NOT EXISTS anonymous_responses (
+  id bigserial primary key,
+  round_id bigint not null references rounds(id) on delete cascade,
+  respondent text not null,
+  answers jsonb not null,
+  rankings jsonb not null
+);
+
+CREATE INDEX IF NOT EXISTS anonymous_responses_round_idx ON anonymous_responses(round_id, id);
@@ -0,0 +1,7 @@
+-- Acceptances of the privacy notice, one row per participant and version
+CREATE TABLE IF NOT EXISTS consents (
+  participant_code text not null references participants(code) on delete cascade,
+  version int not null,
+  accepted_at timestamptz not null default now(),
+  primary key (participant_code, version)
+);
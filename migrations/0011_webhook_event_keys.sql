@@ -0,0 +1,5 @@
+-- Events that go out at most once, such as a round closing by schedule
+CREATE TABLE IF NOT EXISTS webhook_event_keys (
+  key text primary key,
+  created_at timestamptz not null default now()
+);
@@ -0,0 +1,26 @@
+-- Answers and ranking positions in queryable form, one row per answered
+-- question or ranked colleague. The JSON columns of response_revisions stay
+-- the source of truth; these rows are written alongside them. Revisions
+-- stored before this migration are filled in by the server on start, since
+-- it needs the question types and the encryption keys to do so.
+CREATE TABLE IF NOT EXISTS answers (
+  response_id bigint not null references response_revisions(id) on delete cascade,
+  question_id text not null,
+  peer_code text not null default '',
+  numeric_value double precision,
+  text_value text,
+  choice_value text,
+  primary key (response_id, question_id, peer_code)
+);
+
+CREATE INDEX IF NOT EXISTS answers_question_idx ON answers(question_id, peer_code);
+
+CREATE TABLE IF NOT EXISTS ranking_positions (
+  response_id bigint not null references response_revisions(id) on delete cascade,
+  criterion text not null,
+  ratee text not null,
+  position int not null,
+  primary key (response_id, criterion, ratee)
+);
+
+CREATE INDEX IF NOT EXISTS ranking_positions_ratee_idx ON ranking_positions(criterion, ratee);
@@ -0,0 +1,5 @@
+-- Confirmation tokens that were used, kept until they expire
+CREATE TABLE IF NOT EXISTS used_confirmations (
+  jti text primary key,
+  expires_at timestamptz not null
+);
@@ -0,0 +1,6 @@
+-- The texts of the privacy notice by version, as a SHA-256 digest
+CREATE TABLE IF NOT EXISTS consent_notices (
+  version int primary key,
+  digest text not null,
+  created_at timestamptz not null default now()
+);
@@ -37,13 +37,49 @@ function loadStateFromLocal() {
 
 // Debounce helper for auto-save
 let autoSaveTimeout;
+let draftSaveTimeout;
 function triggerAutoSave() {
   clearTimeout(autoSaveTimeout);
   autoSaveTimeout = setTimeout(saveStateToLocal, 500);
+  clearTimeout(draftSaveTimeout);
+  draftSaveTimeout = setTimeout(saveDraftToServer, 3000);
+}
+
+// Server-side draft so progress survives a device change and shows up live for the admin
+async function saveDraftToServer() {
+  if (!state.me || state.me.isAdmin || $('submitBtn').disabled) return;
+  try {
+    await api('/api/draft', { method: 'POST', body: JSON.stringify(buildPayload()) });
+  } catch (err) {
+    console.warn('Не вдалося зберегти чернетку на сервері:', err.message);
+  }
+}
+
+async function loadDraftFromServer() {
+  try {
+    const res = await api('/api/draft');
+    const draft = res.draft;
+    if (!draft) return;
+    (draft.answers || []).forEach(a => { state.answers[a.questionId] = a.value; });
+    (draft.rankings || []).forEach(r => {
+      state.rankings[r.criteria] = {
+        order: r.order,
+        selfRank: r.selfRank,
+        peerRankings: r.peerRankings || {},
+        comment: r.comment || '',
+      };
+    });
+    console.log('Відновлено чернетку з сервера:', draft.updatedAt);
+  } catch (err) {
+    console.warn('Чернетку не завантажено:', err.message);
+  }
 }
 
 // DOM selectors
 const $ = (id) => document.getElementById(id);
+const escapeHtml = (text) => String(text ?? '').replace(/[&<>"']/g, (c) => ({
+  '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;'
+})[c]);
 
 // API helper
 async function api(path, options = {}) {
@@ -65,7 +101,7 @@ async function api(path, options = {}) {
 // UI State Management
 function showLogin() {
   $('loginCard').classList.remove('hidden');
-  ['surveyCard', 'peerCard', 'rankingCard', 'actionsCard', 'adminCard'].forEach(id => $(id).classList.add('hidden'));
+  ['consentCard', 'surveyCard', 'peerCard', 'rankingCard', 'actionsCard', 'adminCard'].forEach(id => $(id).classList.add('hidden'));
   $('sessionBadge').innerHTML = '<span class="pill">не авторизовано</span>';
 }
 
@@ -76,10 +112,49 @@ async function showLoggedInUI() {
   if (state.me.isAdmin) {
     $('adminCard').classList.remove('hidden');
     await loadAdminData();
+    startLiveUpdates();
   } else {
-    ['surveyCard', 'peerCard', 'rankingCard', 'actionsCard'].forEach(id => $(id).classList.remove('hidden'));
-    $('meBadge').textContent = state.me.name;
-    await loadQuestions();
+    const consent = await api('/api/consent');
+    if (!consent.accepted) {
+      showConsent(consent);
+      return;
+    }
+    await showSurvey();
+  }
+}
+
+async function showSurvey() {
+  $('consentCard').classList.add('hidden');
+  ['surveyCard', 'peerCard', 'rankingCard', 'actionsCard'].forEach(id => $(id).classList.remove('hidden'));
+  $('meBadge').textContent = state.me.name;
+  await loadQuestions();
+}
+
+// showConsent puts the privacy notice in front of the survey until the
+// participant accepts the current version.
+function showConsent(consent) {
+  const notice = consent.notice;
+  state.noticeVersion = notice.version;
+  ['surveyCard', 'peerCard', 'rankingCard', 'actionsCard'].forEach(id => $(id).classList.add('hidden'));
+  $('consentTitle').textContent = notice.title;
+  $('consentChanged').textContent = consent.acceptedVersion
+    ? 'Повідомлення про конфіденційність оновлено — будь ласка, прочитайте його ще раз.'
+    : 'Прочитайте, хто і як бачитиме ваші відповіді.';
+  $('consentSections').innerHTML = notice.sections.map(s => `
+    <div class="question">
+      <div class="title">${escapeHtml(s.heading)}</div>
+      <div class="desc">${escapeHtml(s.text)}</div>
+    </div>`).join('');
+  $('consentCard').classList.remove('hidden');
+}
+
+async function handleConsentAccept() {
+  try {
+    await api('/api/consent/accept', { method: 'POST', body: JSON.stringify({ version: state.noticeVersion }) });
+    await showSurvey();
+  } catch (err) {
+    // The notice changed while it was open: show the new one.
+    showConsent(await api('/api/consent'));
   }
 }
 
@@ -117,6 +192,7 @@ async function handleLogin(e) {
 async function handleLogout() {
   try {
     await api('/api/logout');
+    stopLiveUpdates();
     state.me = null;
     state.questions = null;
     state.answers = {};
@@ -145,14 +221,64 @@ async function loadQuestions() {
     };
   });
 
-  // Restore from localStorage if exists
+  // Restore from localStorage if exists, otherwise from the server draft
   loadStateFromLocal();
+  if (Object.keys(state.answers).length === 0) {
+    await loadDraftFromServer();
+  }
+
+  renderRound(data.round);
 
   renderCommon(data.common);
   renderPeers(data.peer);
   renderBoards(data.criteria);
 }
 
+// Round deadline & countdown
+let countdownTimer;
+function renderRound(round) {
+  clearInterval(countdownTimer);
+  const banner = $('roundBanner');
+  if (!round) {
+    banner.classList.add('hidden');
+    return;
+  }
+  // Offset between server and browser clocks so the countdown matches the server
+  const skew = new Date(round.serverTime).getTime() - Date.now();
+  const update = () => {
+    if (!round.acceptingSubmissions) {
+      banner.textContent = `🔒 ${round.message || 'Опитування закрите.'}`;
+      banner.classList.add('closed');
+      $('submitBtn').disabled = true;
+      return;
+    }
+    $('submitBtn').disabled = false;
+    if (!round.deadline) {
+      banner.textContent = `🟢 ${round.title}: відповіді приймаються`;
+      return;
+    }
+    const left = new Date(round.deadline).getTime() - (Date.now() + skew);
+    if (left <= 0) {
+      round.acceptingSubmissions = false;
+      round.message = 'Термін подання відповідей минув.';
+      clearInterval(countdownTimer);
+      update();
+      return;
+    }
+    const days = Math.floor(left / 86400000);
+    const hours = Math.floor((left % 86400000) / 3600000);
+    const minutes = Math.floor((left % 3600000) / 60000);
+    const seconds = Math.floor((left % 60000) / 1000);
+    const deadline = new Date(round.deadline).toLocaleString('uk-UA');
+    banner.textContent = `⏳ ${round.title}: до дедлайну ${days} д ${hours} год ${minutes} хв ${seconds} с (${deadline})${round.extended ? ' • продовжено для вас' : ''}`;
+  };
+  banner.classList.remove('hidden', 'closed');
+  update();
+  if (round.acceptingSubmissions && round.deadline) {
+    countdownTimer = setInterval(update, 1000);
+  }
+}
+
 function renderCommon(list) {
   $('commonQuestions').innerHTML = '';
   list.forEach(q => {
@@ -465,20 +591,24 @@ function refreshGrid(board, criteria, type) {
 }
 
 // Submit Response
+function buildPayload() {
+  return {
+    answers: Object.entries(state.answers).map(([questionId, value]) => ({ questionId, value })),
+    rankings: Object.entries(state.rankings).map(([criteria, data]) => ({
+      criteria,
+      order: data.order,
+      selfRank: Number(data.selfRank) || 0,
+      peerRankings: data.peerRankings || {},
+      comment: data.comment || '',
+    })),
+  };
+}
+
 async function handleSubmit() {
   $('saveStatus').textContent = 'Збереження...';
 
   try {
-    const payload = {
-      answers: Object.entries(state.answers).map(([questionId, value]) => ({ questionId, value })),
-      rankings: Object.entries(state.rankings).map(([criteria, data]) => ({
-        criteria,
-        order: data.order,
-        selfRank: Number(data.selfRank) || 0,
-        peerRankings: data.peerRankings || {},
-        comment: data.comment || '',
-      })),
-    };
+    const payload = buildPayload();
 
     await api('/api/response', { method: 'POST', body: JSON.stringify(payload) });
     $('saveStatus').textContent = 'Збережено ✓';
@@ -513,33 +643,322 @@ async function loadAdminData() {
     // Pending list
     const pendingList = stats?.pendingList || [];
     $('pendingList').innerHTML = pendingList.length > 0
-      ? pendingList.map(p => `<div class="participant-item">⏳ ${p.name} — ${p.email}</div>`).join('')
+      ? pendingList.map(p => `<div class="participant-item" data-code="${p.code}">⏳ ${p.name} — ${p.email} <span class="chip draft-progress">${p.progress != null ? `чернетка ${p.progress}%` : 'не починали'}</span></div>`).join('')
       : '<div class="hint">Всі заповнили!</div>';
 
-    // Responses list
-    const responsesList = responses || [];
-    $('responsesList').innerHTML = responsesList.length > 0
-      ? responsesList.map(r => `
-          <div class="response-item" data-code="${r.participantCode}">
-            <div class="response-header">
-              <strong>${r.participantName}</strong>
-              <span class="chip">${new Date(r.submittedAt).toLocaleString('uk-UA')}</span>
-            </div>
-            <div class="response-meta">
-              ${r.answersCount} відповідей, ${r.rankingsCount} ранжувань
-              ${r.isTestData ? '<span class="badge">ТЕСТ</span>' : ''}
-            </div>
-          </div>
-        `).join('')
-      : '<div class="hint">Немає відповідей. Натисніть "🧪 Заповнити тестовими" щоб створити дані для перегляду.</div>';
-
-    console.log('Responses rendered:', responsesList.length);
+    // Responses list (paginated)
+    renderResponses(responses, false);
+    console.log('Responses rendered:', responses?.items?.length ?? 0);
+
+    await loadAnalytics();
+    await loadTextAnalytics();
+    await loadRedactions();
+    await loadSociogramOptions();
+    renderSociogram();
+    await loadSnapshots();
+    await loadConsentReport();
+    await loadRetention();
+    await loadAudit();
   } catch (err) {
     console.error('Failed to load admin data:', err);
     $('responsesList').innerHTML = '<div class="hint error">❌ Помилка завантаження даних</div>';
   }
 }
 
+function responseItemHtml(r) {
+  return `
+    <div class="response-item" data-code="${r.participantCode}">
+      <div class="response-header">
+        <strong>${r.participantName}</strong>
+        <span class="chip">${new Date(r.submittedAt).toLocaleString('uk-UA')}</span>
+      </div>
+      <div class="response-meta">
+        ${r.answersCount} відповідей, ${r.rankingsCount} ранжувань
+        ${r.isTestData ? '<span class="badge">ТЕСТ</span>' : ''}
+      </div>
+    </div>
+  `;
+}
+
+// renderResponses shows one page of /api/admin/responses, replacing the list
+// or appending to it, with a "show more" button while pages remain.
+function renderResponses(page, append) {
+  const list = $('responsesList');
+  list.querySelector('.load-more')?.remove();
+  const items = page?.items || [];
+  if (!append && items.length === 0) {
+    list.innerHTML = '<div class="hint">Немає відповідей. Натисніть "🧪 Заповнити тестовими" щоб створити дані для перегляду.</div>';
+    return;
+  }
+  const html = items.map(responseItemHtml).join('');
+  if (append) {
+    list.insertAdjacentHTML('beforeend', html);
+  } else {
+    list.innerHTML = html;
+  }
+  if (page.nextCursor) {
+    list.insertAdjacentHTML('beforeend',
+      `<button class="btn ghost load-more" data-cursor="${page.nextCursor}">Показати ще</button>`);
+  }
+}
+
+async function loadMoreResponses(cursor) {
+  try {
+    const page = await api(`/api/admin/responses?cursor=${encodeURIComponent(cursor)}`);
+    renderResponses(page, true);
+  } catch (err) {
+    console.error('Failed to load more responses:', err);
+  }
+}
+
+function fmtStat(v, digits = 2) {
+  return v == null ? '—' : Number(v).toFixed(digits);
+}
+
+function renderHistogram(distribution) {
+  const max = Math.max(1, ...distribution);
+  const bars = distribution
+    .map((count, i) => `<div class="bar" style="height:${(count / max) * 100}%" title="${i + 1}: ${count}"></div>`)
+    .join('');
+  const axis = distribution.map((_, i) => `<span>${i + 1}</span>`).join('');
+  return `<div class="histogram">${bars}</div><div class="histogram-axis">${axis}</div>`;
+}
+
+function renderAnalyticsItem(q) {
+  const meta = `M = ${fmtStat(q.mean)} · Me = ${fmtStat(q.median, 1)} · SD = ${fmtStat(q.sd)} · n = ${q.n} · пропущено ${q.missing}`;
+  const perRatee = (q.perRatee || []).length > 0
+    ? `<details><summary>По колегах</summary><table>
+        <tr><th>Колега</th><th>M</th><th>SD</th><th>n</th></tr>
+        ${q.perRatee.map(r => `<tr><td>${r.name}</td><td>${fmtStat(r.mean)}</td><td>${fmtStat(r.sd)}</td><td>${r.n}</td></tr>`).join('')}
+      </table></details>`
+    : '';
+  return `
+    <div class="analytics-item">
+      <strong>${q.title}</strong>
+      <div class="analytics-meta">${meta}</div>
+      ${renderHistogram(q.distribution || [])}
+      ${perRatee}
+    </div>`;
+}
+
+// anonymized adds ?anonymize=true to analytics and export URLs while the
+// pseudonymization toggle is on.
+function anonymized(url) {
+  if (!$('anonymizeToggle')?.checked) return url;
+  return url + (url.includes('?') ? '&' : '?') + 'anonymize=true';
+}
+
+async function loadAnalytics() {
+  try {
+    const report = await api(anonymized('/api/admin/analytics'));
+    const rel = report.reliability || {};
+    const iccRows = (rel.perRatee || [])
+      .map(r => `<tr><td>${r.name}</td><td>${fmtStat(r.icc)}</td><td>${fmtStat(r.iccMean)}</td><td>${r.raters}</td></tr>`)
+      .join('');
+    $('analyticsPanel').innerHTML = `
+      <div class="analytics-reliability">
+        <span class="chip">α Кронбаха: ${fmtStat(rel.alpha)} (${rel.alphaCases || 0} пар)</span>
+        <span class="chip">ICC(1): ${fmtStat(rel.icc)}</span>
+        <span class="chip">ICC(1,k): ${fmtStat(rel.iccMean)}</span>
+      </div>
+      ${[...(report.common || []), ...(report.peer || [])].map(renderAnalyticsItem).join('')}
+      <div class="analytics-item">
+        <strong>Узгодженість оцінювачів щодо кожного колеги</strong>
+        <table>
+          <tr><th>Колега</th><th>ICC(1)</th><th>ICC(1,k)</th><th>Оцінювачів</th></tr>
+          ${iccRows}
+        </table>
+      </div>`;
+  } catch (err) {
+    console.error('Failed to load analytics:', err);
+    $('analyticsPanel').innerHTML = '<div class="hint error">❌ Не вдалося завантажити аналітику</div>';
+  }
+}
+
+let textAnalyses = [];
+
+async function loadTextAnalytics() {
+  try {
+    textAnalyses = await api(anonymized('/api/admin/text')) || [];
+    const select = $('textQuestion');
+    const selected = select.value;
+    select.innerHTML = textAnalyses
+      .map(q => `<option value="${q.id}">${q.title} (${q.answers})</option>`)
+      .join('');
+    if (selected) select.value = selected;
+    renderTextAnalysis();
+  } catch (err) {
+    console.error('Failed to load text analytics:', err);
+    $('textPanel').innerHTML = '<div class="hint error">❌ Не вдалося проаналізувати відповіді</div>';
+  }
+}
+
+function renderTextAnalysis() {
+  const q = textAnalyses.find(item => item.id === $('textQuestion').value) || textAnalyses[0];
+  if (!q || q.answers === 0) {
+    $('textPanel').innerHTML = '<div class="hint">Немає текстових відповідей</div>';
+    return;
+  }
+  const chips = (terms) => terms.map(t => `<span class="chip">${escapeHtml(t.term)} · ${t.count}</span>`).join(' ');
+  const themes = (q.themes || []).map(t => `
+    <div class="analytics-item">
+      <strong>${t.other ? 'Інше' : `Тема ${t.id + 1}`}: ${escapeHtml(t.label)}</strong>
+      <div class="analytics-meta">${t.size} відповідей</div>
+      ${(t.quotes || []).map(quote => `<div class="participant-item">«${escapeHtml(quote)}»</div>`).join('')}
+    </div>`).join('');
+  $('textPanel').innerHTML = `
+    <div class="analytics-meta">${q.answers} відповідей</div>
+    <div class="analytics-reliability">${chips(q.terms || [])}</div>
+    ${(q.bigrams || []).length ? `<div class="analytics-reliability">${chips(q.bigrams)}</div>` : ''}
+    ${(q.trigrams || []).length ? `<div class="analytics-reliability">${chips(q.trigrams)}</div>` : ''}
+    ${themes}`;
+}
+
+let sociogramOptionsLoaded = false;
+
+async function loadSociogramOptions() {
+  if (sociogramOptionsLoaded) return;
+  try {
+    const data = await api('/api/admin/sociogram/sources');
+    $('sociogramWeight').innerHTML = (data.weights || [])
+      .map(w => `<option value="${w.value}">${w.label}</option>`)
+      .join('');
+    $('sociogramWeight').value = 'peer:trust-level';
+    sociogramOptionsLoaded = true;
+  } catch (err) {
+    console.error('Failed to load sociogram options:', err);
+  }
+}
+
+const pairLabels = {
+  mutual_positive: 'взаємно високо',
+  one_sided: 'однобічно',
+  mutual_low: 'взаємно низько',
+  neutral: 'нейтрально',
+  incomplete: 'неповні дані'
+};
+
+async function loadReciprocity() {
+  try {
+    const m = await api(anonymized(`/api/admin/reciprocity?weight=${encodeURIComponent($('sociogramWeight').value)}`));
+    const header = m.names.map(n => `<th>${n}</th>`).join('');
+    const rows = m.names.map((name, i) => {
+      const cells = m.names.map((_, j) => {
+        if (i === j) return '<td class="self">—</td>';
+        const value = m.matrix[i][j];
+        const category = m.categories[i][j];
+        return `<td class="${category}" title="${name} → ${m.names[j]}: ${pairLabels[category] || category}">${value ?? '·'}</td>`;
+      }).join('');
+      return `<tr><th class="row-head">${name}</th>${cells}</tr>`;
+    }).join('');
+    const counts = Object.entries(m.counts || {})
+      .map(([k, v]) => `<span class="chip">${pairLabels[k] || k}: ${v}</span>`)
+      .join('');
+    const asymmetric = (m.mostAsymmetric || [])
+      .map(p => `<div class="participant-item">${p.aName} → ${p.bName}: ${p.aToB} · ${p.bName} → ${p.aName}: ${p.bToA} <span class="chip">Δ ${p.asymmetry}</span></div>`)
+      .join('');
+    $('reciprocityPanel').innerHTML = `
+      <div class="analytics-reliability">
+        <span class="chip">Індекс взаємності: ${m.reciprocity == null ? '—' : Math.round(m.reciprocity * 100) + '%'}</span>
+        <span class="chip">r(A→B, B→A): ${fmtStat(m.weightCorrelation)}</span>
+        ${counts}
+      </div>
+      <div class="heatmap-wrap"><table class="heatmap"><tr><th></th>${header}</tr>${rows}</table></div>
+      <div class="participant-list">
+        <strong>Найбільш асиметричні пари</strong>
+        ${asymmetric || '<div class="hint">Немає повних пар</div>'}
+      </div>`;
+  } catch (err) {
+    console.error('Failed to load reciprocity:', err);
+    $('reciprocityPanel').innerHTML = '<div class="hint error">❌ Не вдалося завантажити матрицю взаємності</div>';
+  }
+}
+
+async function loadCommunities() {
+  try {
+    const c = await api(anonymized(`/api/admin/communities?weight=${encodeURIComponent($('sociogramWeight').value)}`));
+    const names = (members) => members.map(m => m.name).join(', ');
+    const groups = (c.groups || [])
+      .map(g => `<div class="participant-item"><span class="chip">Група ${g.id + 1}</span> ${names(g.members)}</div>`)
+      .join('');
+    const cliques = (c.cliques || [])
+      .map(members => `<div class="participant-item">🔺 ${names(members)}</div>`)
+      .join('');
+    const bridges = (c.bridges || [])
+      .map(b => `<div class="participant-item">🌉 ${b.name} — групи ${b.groups.map(id => id + 1).join(', ')} <span class="chip">участь ${b.participation}</span></div>`)
+      .join('');
+    $('communitiesPanel').innerHTML = `
+      <div class="analytics-reliability">
+        <span class="chip">Модулярність Q: ${fmtStat(c.modularity)}</span>
+        <span class="chip">Груп: ${(c.groups || []).length}</span>
+      </div>
+      <div class="participant-list">${groups}</div>
+      <div class="participant-list"><strong>Кліки</strong>${cliques || '<div class="hint">Кліки не знайдено</div>'}</div>
+      <div class="participant-list"><strong>Мости між групами</strong>${bridges || '<div class="hint">Мостів немає</div>'}</div>`;
+  } catch (err) {
+    console.error('Failed to load communities:', err);
+    $('communitiesPanel').innerHTML = '<div class="hint error">❌ Не вдалося знайти підгрупи</div>';
+  }
+}
+
+function renderSociogram() {
+  const params = new URLSearchParams({
+    weight: $('sociogramWeight').value,
+    layout: $('sociogramLayout').value,
+    t: Date.now()
+  });
+  if ($('sociogramMin').value) params.set('min', $('sociogramMin').value);
+  if ($('sociogramTop').value) params.set('top', $('sociogramTop').value);
+  $('sociogramImg').src = anonymized(`/api/admin/sociogram?${params}`);
+  loadReciprocity();
+  loadCommunities();
+}
+
+const auditOutcomeLabels = { success: 'успіх', denied: 'відмова', failure: 'помилка' };
+
+async function loadAudit() {
+  const actorSelect = $('auditActor');
+  if (actorSelect.options.length === 1) {
+    const stats = await api('/api/admin/stats').catch(() => null);
+    const people = [state.me, ...(stats?.completedList || []), ...(stats?.pendingList || [])];
+    actorSelect.innerHTML += people.map(p => `<option value="${p.code}">${escapeHtml(p.name)}</option>`).join('');
+  }
+  const params = new URLSearchParams({ limit: 50 });
+  if (actorSelect.value) params.set('actor', actorSelect.value);
+  if ($('auditAction').value) params.set('action', $('auditAction').value);
+  if ($('auditOutcome').value) params.set('outcome', $('auditOutcome').value);
+  try {
+    const page = await api(`/api/admin/audit?${params}`);
+    const rows = (page.items || []).map(e => `
+      <tr class="${e.outcome}">
+        <td>${new Date(e.occurredAt).toLocaleString('uk-UA')}</td>
+        <td>${e.actor || '—'}</td>
+        <td>${escapeHtml(e.action)}</td>
+        <td>${escapeHtml(e.target)}</td>
+        <td>${auditOutcomeLabels[e.outcome] || e.outcome} (${e.status})</td>
+        <td title="${escapeHtml(e.userAgent)}">${escapeHtml(e.ip)}</td>
+      </tr>`).join('');
+    $('auditPanel').innerHTML = rows
+      ? `<table class="audit-table"><tr><th>Час</th><th>Хто</th><th>Дія</th><th>Об'єкт</th><th>Результат</th><th>IP</th></tr>${rows}</table>`
+      : '<div class="hint">Записів немає</div>';
+  } catch (err) {
+    console.error('Failed to load audit log:', err);
+    $('auditPanel').innerHTML = '<div class="hint error">❌ Не вдалося завантажити журнал аудиту</div>';
+  }
+}
+
+async function handleAuditVerify() {
+  $('adminStatus').textContent = 'Перевіряємо журнал аудиту...';
+  try {
+    const v = await api('/api/admin/audit/verify');
+    $('adminStatus').textContent = v.valid
+      ? `Ланцюжок цілий: ${v.entries} записів, останній хеш ${v.head.slice(0, 12)}… ✓`
+      : `⚠️ Ланцюжок порушено на записі #${v.brokenAt}: ${v.reason}`;
+  } catch (err) {
+    $('adminStatus').textContent = 'Помилка: ' + err.message;
+  }
+}
+
 async function viewResponseDetail(code) {
   console.log('Opening response detail for:', code);
   try {
@@ -598,6 +1017,44 @@ async function viewResponseDetail(code) {
   }
 }
 
+// Live updates over Server-Sent Events
+let liveSource;
+let liveReloadTimeout;
+function startLiveUpdates() {
+  if (liveSource || !window.EventSource) return;
+  liveSource = new EventSource('/api/admin/events');
+
+  liveSource.addEventListener('completion', (e) => {
+    const data = JSON.parse(e.data);
+    $('statCompleted').textContent = data.completed;
+    $('statPending').textContent = data.pending;
+    $('statTotal').textContent = data.total;
+  });
+
+  liveSource.addEventListener('draft', (e) => {
+    const data = JSON.parse(e.data);
+    const chip = document.querySelector(`#pendingList [data-code="${data.participantCode}"] .draft-progress`);
+    if (chip) chip.textContent = `чернетка ${data.progress}% • щойно`;
+  });
+
+  // Submissions and resets change the lists, so reload them (debounced)
+  const reload = () => {
+    clearTimeout(liveReloadTimeout);
+    liveReloadTimeout = setTimeout(loadAdminData, 500);
+  };
+  liveSource.addEventListener('submission', reload);
+  liveSource.addEventListener('reset', reload);
+
+  liveSource.onerror = () => console.warn('Live-оновлення перервано, EventSource перепідключиться');
+}
+
+function stopLiveUpdates() {
+  if (liveSource) {
+    liveSource.close();
+    liveSource = null;
+  }
+}
+
 async function handleRefreshAdmin() {
   $('adminStatus').textContent = 'Оновлення...';
   await loadAdminData();
@@ -609,7 +1066,7 @@ async function handleExport() {
   $('adminStatus').textContent = 'Готуємо експорт...';
 
   try {
-    const data = await api('/api/admin/export');
+    const data = await api(anonymized('/api/admin/export'));
     const blob = new Blob([JSON.stringify(data, null, 2)], { type: 'application/json' });
     const url = URL.createObjectURL(blob);
     const a = document.createElement('a');
@@ -624,6 +1081,82 @@ async function handleExport() {
   }
 }
 
+function handleFileExport(format) {
+  // The session cookie authorises the request, so a plain navigation lets the
+  // browser stream the archive straight to disk.
+  const a = document.createElement('a');
+  a.href = anonymized(`/api/admin/export?format=${format}`);
+  a.click();
+  $('adminStatus').textContent = `${format.toUpperCase()} експортовано ✓`;
+  setTimeout(() => $('adminStatus').textContent = '', 3000);
+}
+
+function handleBackup() {
+  const a = document.createElement('a');
+  a.href = '/api/admin/backup';
+  a.click();
+  $('adminStatus').textContent = 'Резервну копію завантажено ✓';
+  setTimeout(() => $('adminStatus').textContent = '', 3000);
+}
+
+// saveBackup downloads a backup of the current data and resolves once the
+// archive is complete, so nothing can replace the data while it is taken.
+async function saveBackup() {
+  const res = await fetch('/api/admin/backup', { credentials: 'same-origin' });
+  if (!res.ok) throw new Error((await res.text()) || res.statusText);
+  const name = res.headers.get('Content-Disposition')?.match(/filename="([^"]+)"/)?.[1] || 'opslab-survey-backup.tar.gz';
+  const url = URL.createObjectURL(await res.blob());
+  const a = document.createElement('a');
+  a.href = url;
+  a.download = name;
+  a.click();
+  setTimeout(() => URL.revokeObjectURL(url), 1000);
+  return name;
+}
+
+// handleBackupRestore merges an archive straight away. A replace is
+// previewed first: the server says what would be deleted and returns a token
+// that confirms restoring this very archive. The replaced data cannot be
+// brought back from the panel, so a backup of it is downloaded first.
+async function handleBackupRestore() {
+  const file = $('backupFile').files[0];
+  if (!file) {
+    $('adminStatus').textContent = 'Оберіть архів резервної копії';
+    return;
+  }
+  const mode = $('backupMode').value;
+  const restore = (query) => api(`/api/admin/backup/restore?${query}`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/gzip' },
+    body: file
+  });
+
+  try {
+    let query = `mode=${mode}`;
+    if (mode === 'replace') {
+      const preview = await restore(query);
+      const message = `Буде видалено раундів: ${preview.deletes.rounds}, версій відповідей: ${preview.deletes.revisions}, ` +
+        `а також чернетки й продовження термінів. З архіву буде завантажено раундів: ${preview.loads.rounds}, відповідей: ${preview.loads.revisions}.\n` +
+        'Спершу буде завантажено резервну копію поточних даних — лише з неї їх можна повернути (режим «Замінити»). Продовжити?';
+      if (!confirm(message)) return;
+      $('adminStatus').textContent = 'Зберігаємо резервну копію поточних даних...';
+      const saved = await saveBackup();
+      if (!confirm(`Резервну копію збережено як ${saved}. Замінити дані?`)) return;
+      query += `&token=${encodeURIComponent(preview.token)}`;
+    } else if (!confirm('Додати з архіву раунди й відповіді, яких ще немає?')) {
+      return;
+    }
+
+    $('adminStatus').textContent = 'Відновлюємо...';
+    const res = await restore(query);
+    $('adminStatus').textContent = `Відновлено: раундів ${res.rounds}, відповідей ${res.revisions}` +
+      (res.skippedRevisions ? `, вже були: ${res.skippedRevisions}` : '') + ' ✓';
+    await loadAdminData();
+  } catch (err) {
+    $('adminStatus').textContent = 'Помилка: ' + err.message;
+  }
+}
+
 async function handleTestData() {
   $('adminStatus').textContent = 'Записуємо тестові дані...';
 
@@ -636,14 +1169,227 @@ async function handleTestData() {
   }
 }
 
+async function handleNudge() {
+  if (!confirm('Надіслати нагадування всім, хто ще не заповнив анкету?')) return;
+
+  $('adminStatus').textContent = 'Надсилаємо нагадування...';
+
+  try {
+    const res = await api('/api/admin/reminders/nudge', { method: 'POST', body: '{}' });
+    const sent = (res.reminders || []).filter(r => r.status === 'sent').length;
+    const failed = (res.reminders || []).length - sent;
+    $('adminStatus').textContent = `Нагадування надіслано: ${sent}${failed ? `, помилок: ${failed}` : ''} ✓`;
+    setTimeout(() => $('adminStatus').textContent = '', 3000);
+  } catch (err) {
+    $('adminStatus').textContent = 'Помилка: ' + err.message;
+  }
+}
+
+const resetScopeLabels = { all: 'усі відповіді', test: 'тестові дані', round: 'раунд', participant: 'учасник' };
+
+function describeScope(scope) {
+  let text = resetScopeLabels[scope.kind] || scope.kind;
+  if (scope.participantCode) text += ` ${scope.participantCode}`;
+  if (scope.roundId) text += ` (раунд ${scope.roundId})`;
+  return text;
+}
+
+async function loadSnapshots() {
+  const roundSelect = $('resetRound');
+  if (roundSelect.options.length === 1) {
+    const rounds = await api('/api/admin/rounds').catch(() => []);
+    roundSelect.innerHTML += (rounds || []).map(r => `<option value="${r.id}">${escapeHtml(r.title)}</option>`).join('');
+  }
+  const participantSelect = $('resetParticipant');
+  if (participantSelect.options.length === 1) {
+    const stats = await api('/api/admin/stats').catch(() => null);
+    const people = [...(stats?.completedList || []), ...(stats?.pendingList || [])];
+    participantSelect.innerHTML += people.map(p => `<option value="${p.code}">${escapeHtml(p.name)}</option>`).join('');
+  }
+  try {
+    const snapshots = await api('/api/admin/snapshots');
+    const rows = (snapshots || []).map(s => `
+      <tr>
+        <td>${new Date(s.createdAt).toLocaleString('uk-UA')}</td>
+        <td>${s.createdBy}</td>
+        <td>${escapeHtml(describeScope(s.scope))}</td>
+        <td>${s.revisions}</td>
+        <td>${(s.size / 1024).toFixed(1)} КБ</td>
+        <td>${s.restoredAt
+          ? `відновлено ${new Date(s.restoredAt).toLocaleString('uk-UA')}`
+          : `<button class="btn ghost" data-snapshot="${s.id}">↩️ Відновити</button>`}</td>
+      </tr>`).join('');
+    $('snapshotsPanel').innerHTML = rows
+      ? `<table class="audit-table"><tr><th>Час</th><th>Хто</th><th>Що</th><th>Відповідей</th><th>Розмір</th><th></th></tr>${rows}</table>`
+      : '<div class="hint">Знімків ще немає</div>';
+  } catch (err) {
+    console.error('Failed to load snapshots:', err);
+    $('snapshotsPanel').innerHTML = '<div class="hint error">❌ Не вдалося завантажити знімки</div>';
+  }
+}
+
+// handleReset asks the server what the chosen scope would delete, shows it,
+// and only then confirms with the short-lived token the preview returned.
 async function handleReset() {
-  if (!confirm('Ви впевнені? Це видалить ВСІ відповіді безповоротно!')) return;
+  const scope = { kind: $('resetScope').value };
+  if ($('resetRound').value) scope.roundId = Number($('resetRound').value);
+  if (scope.kind === 'participant') scope.participantCode = $('resetParticipant').value;
 
-  $('adminStatus').textContent = 'Очищення...';
+  try {
+    const preview = await api('/api/admin/reset', { method: 'POST', body: JSON.stringify({ scope }) });
+    const message = `Буде видалено ${preview.revisions} версій відповідей (${describeScope(preview.scope)}), учасників: ${preview.participants.length}.\n` +
+      'Перед видаленням збережеться знімок, який можна відновити. Продовжити?';
+    if (!confirm(message)) return;
+
+    $('adminStatus').textContent = 'Очищення...';
+    const res = await api('/api/admin/reset/confirm', { method: 'POST', body: JSON.stringify({ token: preview.token }) });
+    $('adminStatus').textContent = `Видалено: ${res.deleted}, знімок #${res.snapshot.id} збережено ✓`;
+    await loadAdminData();
+    setTimeout(() => $('adminStatus').textContent = '', 3000);
+  } catch (err) {
+    $('adminStatus').textContent = 'Помилка: ' + err.message;
+  }
+}
 
+async function handleSnapshotRestore(id) {
+  if (!confirm(`Відновити відповіді зі знімка #${id}?`)) return;
+  $('adminStatus').textContent = 'Відновлення...';
   try {
-    await api('/api/admin/reset', { method: 'POST' });
-    $('adminStatus').textContent = 'База очищена ✓';
+    const res = await api('/api/admin/snapshots/restore', { method: 'POST', body: JSON.stringify({ id }) });
+    $('adminStatus').textContent = `Відновлено: ${res.restored}${res.skipped ? `, пропущено: ${res.skipped}` : ''} ✓`;
+    await loadAdminData();
+    setTimeout(() => $('adminStatus').textContent = '', 3000);
+  } catch (err) {
+    $('adminStatus').textContent = 'Помилка: ' + err.message;
+  }
+}
+
+const consentLabels = { accepted: '✅ прийнято', outdated: '🔄 попередня версія', pending: '⏳ не прийнято' };
+
+async function loadConsentReport() {
+  try {
+    const report = await api('/api/admin/consent');
+    const rows = report.participants.map(p => `
+      <tr>
+        <td>${escapeHtml(p.name)}</td>
+        <td>${consentLabels[p.status] || p.status}</td>
+        <td>${p.acceptedVersion ? `v${p.acceptedVersion}` : '—'}</td>
+        <td>${p.acceptedAt ? new Date(p.acceptedAt).toLocaleString('uk-UA') : '—'}</td>
+      </tr>`).join('');
+    $('consentPanel').innerHTML =
+      `<div class="hint">Поточна версія: v${report.version} • прийняли: ${report.counts.accepted}, ` +
+      `попередню версію: ${report.counts.outdated}, не приймали: ${report.counts.pending}</div>` +
+      `<table class="audit-table"><tr><th>Учасник</th><th>Статус</th><th>Версія</th><th>Коли</th></tr>${rows}</table>`;
+  } catch (err) {
+    console.error('Failed to load consent report:', err);
+    $('consentPanel').innerHTML = '<div class="hint error">❌ Не вдалося завантажити звіт про згоду</div>';
+  }
+}
+
+const redactionKinds = { name: 'імена', email: 'email', phone: 'телефони' };
+
+// markRedacted escapes text and highlights the pieces that were redacted.
+function markRedacted(text, redactions) {
+  let html = escapeHtml(text);
+  new Set(redactions.map(r => escapeHtml(r.text))).forEach(piece => {
+    html = html.split(piece).join(`<mark>${piece}</mark>`);
+  });
+  return html;
+}
+
+async function loadRedactions() {
+  try {
+    const report = await api('/api/admin/redactions');
+    const counts = Object.entries(redactionKinds)
+      .map(([kind, label]) => `${label}: ${report.counts[kind] || 0}`)
+      .join(', ');
+    const rows = report.items.map(item => `
+      <tr>
+        <td>${escapeHtml(item.participantName || item.participantCode)}</td>
+        <td>${escapeHtml(item.questionId)}</td>
+        <td>${markRedacted(item.original, item.redactions)}</td>
+        <td>${escapeHtml(item.scrubbed)}</td>
+      </tr>`).join('');
+    $('redactionsPanel').innerHTML = report.items.length === 0
+      ? '<div class="hint">У текстових відповідях нічого не приховано</div>'
+      : `<div class="hint">Приховано ${counts}</div>` +
+        `<table class="audit-table"><tr><th>Автор</th><th>Питання</th><th>Відповідь</th><th>Як її побачать</th></tr>${rows}</table>`;
+  } catch (err) {
+    console.error('Failed to load redactions:', err);
+    $('redactionsPanel').innerHTML = '<div class="hint error">❌ Не вдалося завантажити приховане</div>';
+  }
+}
+
+const retentionLabels = { anonymize: 'анонімізувати', purge: 'видалити' };
+
+async function loadRetention() {
+  const participantSelect = $('gdprParticipant');
+  if (participantSelect.options.length === 1) {
+    const stats = await api('/api/admin/stats').catch(() => null);
+    const people = [...(stats?.completedList || []), ...(stats?.pendingList || [])];
+    participantSelect.innerHTML += people.map(p => `<option value="${p.code}">${escapeHtml(p.name)}</option>`).join('');
+  }
+  try {
+    const retention = await api('/api/admin/gdpr/retention');
+    const policy = retention.policy;
+    const rows = (retention.planned || []).map(a => `
+      <tr>
+        <td>${escapeHtml(a.title)}</td>
+        <td>${new Date(a.closedAt).toLocaleString('uk-UA')}</td>
+        <td>${retentionLabels[a.kind] || a.kind}</td>
+      </tr>`).join('');
+    $('retentionPanel').innerHTML = !retention.enabled
+      ? '<div class="hint">Політику зберігання не налаштовано</div>'
+      : `<div class="hint">Анонімізація: ${policy.anonymizeAfter}, видалення: ${policy.purgeAfter} після закриття раунду</div>` +
+        (rows
+          ? `<table class="audit-table"><tr><th>Раунд</th><th>Закрито</th><th>Дія</th></tr>${rows}</table>`
+          : '<div class="hint">Зараз нічого робити не потрібно</div>');
+  } catch (err) {
+    $('retentionPanel').innerHTML = '<div class="hint">Політика зберігання недоступна</div>';
+  }
+}
+
+function handleSubjectExport() {
+  const code = $('gdprParticipant').value;
+  if (!code) {
+    $('adminStatus').textContent = 'Оберіть учасника';
+    return;
+  }
+  const a = document.createElement('a');
+  a.href = `/api/admin/gdpr/export?participant=${encodeURIComponent(code)}`;
+  a.click();
+}
+
+// handleErase previews an erasure and confirms it with the token the preview
+// returned, like handleReset.
+async function handleErase() {
+  const participantCode = $('gdprParticipant').value;
+  if (!participantCode) {
+    $('adminStatus').textContent = 'Оберіть учасника';
+    return;
+  }
+  try {
+    const preview = await api('/api/admin/gdpr/erase', { method: 'POST', body: JSON.stringify({ participantCode }) });
+    const message = `Буде видалено ${preview.revisions} версій відповідей учасника ${participantCode}, ` +
+      `псевдонімізовано згадок у чужих відповідях: ${preview.mentions}, знімків: ${preview.snapshots}.\n` +
+      'Стирання не можна скасувати. Продовжити?';
+    if (!confirm(message)) return;
+
+    $('adminStatus').textContent = 'Стирання...';
+    const res = await api('/api/admin/gdpr/erase/confirm', { method: 'POST', body: JSON.stringify({ token: preview.token }) });
+    $('adminStatus').textContent = `Видалено: ${res.result.revisions}, псевдонімізовано: ${res.result.pseudonymized} ✓`;
+    await loadAdminData();
+    setTimeout(() => $('adminStatus').textContent = '', 3000);
+  } catch (err) {
+    $('adminStatus').textContent = 'Помилка: ' + err.message;
+  }
+}
+
+async function handleRetentionRun() {
+  if (!confirm('Анонімізувати та видалити раунди, строк зберігання яких минув?')) return;
+  try {
+    const res = await api('/api/admin/gdpr/retention', { method: 'POST' });
+    $('adminStatus').textContent = `Оброблено раундів: ${res.actions.length}, видалено знімків: ${res.snapshots} ✓`;
     await loadAdminData();
     setTimeout(() => $('adminStatus').textContent = '', 3000);
   } catch (err) {
@@ -660,17 +1406,50 @@ document.addEventListener('DOMContentLoaded', () => {
   $('logoutBtn')?.addEventListener('click', handleLogout);
   $('adminLogoutBtn')?.addEventListener('click', handleLogout);
 
+  // Consent
+  $('consentAcceptBtn')?.addEventListener('click', handleConsentAccept);
+
   // Submit response
   $('submitBtn')?.addEventListener('click', handleSubmit);
 
   // Admin actions
   $('refreshAdminBtn')?.addEventListener('click', handleRefreshAdmin);
   $('exportBtn')?.addEventListener('click', handleExport);
+  $('textQuestion')?.addEventListener('change', renderTextAnalysis);
+  $('sociogramWeight')?.addEventListener('change', renderSociogram);
+  $('sociogramLayout')?.addEventListener('change', renderSociogram);
+  $('sociogramMin')?.addEventListener('change', renderSociogram);
+  $('sociogramTop')?.addEventListener('change', renderSociogram);
+  $('anonymizeToggle')?.addEventListener('change', async () => {
+    await loadAnalytics();
+    await loadTextAnalytics();
+    renderSociogram();
+  });
+  $('exportCsvBtn')?.addEventListener('click', () => handleFileExport('csv'));
+  $('exportXlsxBtn')?.addEventListener('click', () => handleFileExport('xlsx'));
+  $('exportSpssBtn')?.addEventListener('click', () => handleFileExport('spss'));
   $('testDataBtn')?.addEventListener('click', handleTestData);
+  $('nudgeBtn')?.addEventListener('click', handleNudge);
   $('resetBtn')?.addEventListener('click', handleReset);
+  $('backupBtn')?.addEventListener('click', handleBackup);
+  $('backupRestoreBtn')?.addEventListener('click', handleBackupRestore);
+  $('gdprExportBtn')?.addEventListener('click', handleSubjectExport);
+  $('gdprEraseBtn')?.addEventListener('click', handleErase);
+  $('retentionRunBtn')?.addEventListener('click', handleRetentionRun);
+  $('snapshotsPanel')?.addEventListener('click', (e) => {
+    const btn = e.target.closest('[data-snapshot]');
+    if (btn) handleSnapshotRestore(Number(btn.dataset.snapshot));
+  });
+  ['auditActor', 'auditAction', 'auditOutcome'].forEach(id => $(id)?.addEventListener('change', loadAudit));
+  $('auditVerifyBtn')?.addEventListener('click', handleAuditVerify);
 
   // Response items click delegation
   $('responsesList')?.addEventListener('click', (e) => {
+    const more = e.target.closest('.load-more');
+    if (more) {
+      loadMoreResponses(more.dataset.cursor);
+      return;
+    }
     const item = e.target.closest('.response-item');
     if (item && item.dataset.code) {
       viewResponseDetail(item.dataset.code);